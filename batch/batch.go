@@ -100,6 +100,26 @@ func WithBatching(ctx context.Context) context.Context {
 	return context.WithValue(ctx, batchContextKey{}, bctx)
 }
 
+// debounceKey is a context.Value key used for the time.Duration set by
+// WithDebounce.
+type debounceKey struct{}
+
+// WithDebounce returns a context in which batches wait for at least window
+// after the latest invocation before invoking Func.Many, if that is longer
+// than the Func's WaitInterval. Under bursty load, eg. when a federated
+// gateway fans out to many objects at once, this trades a little latency for
+// larger batches. MaxDuration and MaxSize still bound each batch. A zero
+// window, the default, leaves the Func's WaitInterval unchanged.
+func WithDebounce(ctx context.Context, window time.Duration) context.Context {
+	return context.WithValue(ctx, debounceKey{}, window)
+}
+
+// Debounce returns the window set by WithDebounce on ctx, or 0.
+func Debounce(ctx context.Context) time.Duration {
+	window, _ := ctx.Value(debounceKey{}).(time.Duration)
+	return window
+}
+
 // HasBatching returns if the given context has batching support.
 func HasBatching(ctx context.Context) bool {
 	return ctx.Value(batchContextKey{}) != nil
@@ -146,6 +166,9 @@ func (f *Func) Invoke(ctx context.Context, arg interface{}) (interface{}, error)
 	if f.WaitInterval > 0 {
 		waitInterval = f.WaitInterval
 	}
+	if window := Debounce(ctx); window > waitInterval {
+		waitInterval = window
+	}
 
 	bctx.mu.Lock()
 	// Look up the batchGroup for the Func shard, if any.
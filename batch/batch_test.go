@@ -154,6 +154,64 @@ func TestBackToBack(t *testing.T) {
 	}
 }
 
+// TestDebounce tests that WithDebounce keeps a batch open for invocations
+// spaced further apart than the Func's WaitInterval.
+func TestDebounce(t *testing.T) {
+	const loopCount = 5
+	const sleepDuration = 5 * time.Millisecond
+
+	testcases := []struct {
+		description   string
+		window        time.Duration
+		expectedCount int
+	}{
+		{
+			description:   "Expect invocations spaced over WaitInterval to be invoked separately without a window.",
+			expectedCount: loopCount,
+		},
+		{
+			description:   "Expect invocations spaced within the window to be batched together.",
+			window:        10 * sleepDuration,
+			expectedCount: 1,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			var mu sync.Mutex
+			calls := 0
+			f := (&batch.Func{
+				Many: func(ctx context.Context, args []interface{}) ([]interface{}, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					calls++
+					return args, nil
+				},
+				MaxDuration: time.Second,
+			}).Invoke
+
+			ctx := batch.WithBatching(context.Background())
+			ctx = batch.WithDebounce(ctx, testcase.window)
+			assert.Equal(t, testcase.window, batch.Debounce(ctx))
+
+			var wg sync.WaitGroup
+			for i := 0; i < loopCount; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					if result, err := f(ctx, i); err != nil || result != i {
+						t.Error(err, i)
+					}
+				}(i)
+				time.Sleep(sleepDuration)
+			}
+			wg.Wait()
+
+			assert.Equal(t, testcase.expectedCount, calls)
+		})
+	}
+}
+
 // TestShard tests that Func.Shard shards invocations according to the shard.
 func TestShard(t *testing.T) {
 	var mu sync.Mutex
@@ -0,0 +1,374 @@
+package federation
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/introspection"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+)
+
+// apolloFederationLink imports the Apollo Federation v2 directives used in
+// the SDL of a subgraph.
+const apolloFederationLink = `extend schema @link(url: "https://specs.apollo.dev/federation/v2.0", import: ["@key"])`
+
+// ServerOption configures a Server.
+type ServerOption func(*Server)
+
+// WithApolloFederation exposes the Apollo Federation subgraph contract on the
+// server, so that it can be part of an Apollo Federation supergraph. The
+// server resolves
+//   { _service { sdl } }
+// with the SDL of its schema, without the fields Thunder federation uses
+// internally, in which every object that the server can fetch from its keys
+// with schemabuilder.FetchObjectFromKeys has an @key directive listing the
+// fields of its keys.
+//
+// The server also resolves
+//   { _entities(representations: [{__typename: "Foo", name: "bar"}]) { ... on Foo { ... } } }
+// by fetching every representation with the FetchObjectFromKeys function of
+// its type, returning the entities in the order of the representations.
+// Representations that fail to fetch resolve to null, see EntityError.
+func WithApolloFederation() ServerOption {
+	return func(s *Server) {
+		s.apolloFederation = true
+	}
+}
+
+type apolloService struct {
+	Sdl string
+}
+
+// addApolloFederation adds the root fields of the Apollo Federation subgraph
+// contract to schema.
+func addApolloFederation(schema *graphql.Schema) error {
+	entities, err := apolloEntities(schema)
+	if err != nil {
+		return oops.Wrapf(err, "finding entities")
+	}
+	sdl := apolloSDL(schema, entities)
+
+	apollo := schemabuilder.NewSchema()
+	apollo.Object("_Service", apolloService{})
+	apollo.Query().FieldFunc("_service", func() apolloService {
+		return apolloService{Sdl: sdl}
+	})
+	built, err := apollo.Build()
+	if err != nil {
+		return oops.Wrapf(err, "building subgraph fields")
+	}
+
+	query, ok := schema.Query.(*graphql.Object)
+	if !ok {
+		return oops.Errorf("query type is not an object")
+	}
+	for name, field := range built.Query.(*graphql.Object).Fields {
+		if _, ok := query.Fields[name]; ok {
+			return oops.Errorf("schema already has a %s field", name)
+		}
+		query.Fields[name] = field
+	}
+
+	// Apollo Federation leaves out _entities if there are no entities.
+	if len(entities) == 0 {
+		return nil
+	}
+	if _, ok := query.Fields["_entities"]; ok {
+		return oops.Errorf("schema already has a _entities field")
+	}
+	query.Fields["_entities"] = apolloEntitiesField(query, entities)
+	return nil
+}
+
+// apolloEntitiesField builds the _entities field, which fetches entities from
+// their representations.
+func apolloEntitiesField(query *graphql.Object, entities map[string]*apolloEntity) *graphql.Field {
+	union := &graphql.Union{
+		Name:  "_Entity",
+		Types: make(map[string]*graphql.Object, len(entities)),
+		MemberOf: func(value interface{}) (string, interface{}, error) {
+			entity, ok := value.(apolloEntityValue)
+			if !ok || entity.value == nil {
+				return "", nil, nil
+			}
+			return entity.typeName, entity.value, nil
+		},
+	}
+	for name, entity := range entities {
+		union.Types[name] = entity.object
+	}
+
+	return &graphql.Field{
+		Type: &graphql.NonNull{Type: &graphql.List{Type: union}},
+		Args: map[string]graphql.Type{
+			"representations": &graphql.NonNull{Type: &graphql.List{Type: &graphql.NonNull{Type: &graphql.Scalar{Type: "_Any"}}}},
+		},
+		ParseArguments: parseApolloRepresentations,
+		Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+			federation, err := graphql.SafeExecuteResolver(ctx, query.Fields[federationField], source, nil, nil)
+			if err != nil {
+				return nil, err
+			}
+			return resolveApolloEntities(ctx, federation, entities, args.([]map[string]interface{}))
+		},
+		Expensive: true,
+	}
+}
+
+// apolloEntityValue is an entity resolved by _entities.
+type apolloEntityValue struct {
+	typeName string
+	value    interface{}
+}
+
+// parseApolloRepresentations parses the representations argument of
+// _entities, which are objects with a __typename and the keys of an entity.
+func parseApolloRepresentations(args interface{}) (interface{}, error) {
+	argsMap, ok := args.(map[string]interface{})
+	if !ok {
+		return nil, oops.Errorf("expected arguments")
+	}
+	list, ok := argsMap["representations"].([]interface{})
+	if !ok {
+		return nil, oops.Errorf("expected a list of representations")
+	}
+	representations := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		representation, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, oops.Errorf("expected representation to be an object, got %T", item)
+		}
+		if _, ok := representation["__typename"].(string); !ok {
+			return nil, oops.Errorf("representation is missing __typename")
+		}
+		representations = append(representations, representation)
+	}
+	return representations, nil
+}
+
+// EntityError is the error of a representation that _entities failed to
+// fetch. The representation resolves to null and the error is recorded in
+// graphql.FieldErrors, so that the other representations are still returned.
+type EntityError struct {
+	// Index is the index of the representation in the representations
+	// argument.
+	Index    int
+	TypeName string
+	Err      error
+}
+
+func (e *EntityError) Error() string {
+	return fmt.Sprintf("_entities.%d: fetching %s: %s", e.Index, e.TypeName, e.Err)
+}
+
+func (e *EntityError) SanitizedError() string {
+	return fmt.Sprintf("_entities.%d: fetching %s: %s", e.Index, e.TypeName, graphql.SanitizeError(oops.Cause(e.Err)))
+}
+
+func (e *EntityError) Unwrap() error {
+	return e.Err
+}
+
+// resolveApolloEntities fetches the entities of representations, calling the
+// federation lookup of every type once with the keys of its representations.
+// Representations that fail to fetch resolve to null with an EntityError if
+// ctx records graphql.FieldErrors, and fail the query otherwise.
+func resolveApolloEntities(ctx context.Context, federation interface{}, entities map[string]*apolloEntity, representations []map[string]interface{}) ([]interface{}, error) {
+	results := make([]interface{}, len(representations))
+	var entityErrs []*EntityError
+
+	// indices groups the indices of the representations by type, in order.
+	indices := make(map[string][]int)
+	var typeNames []string
+	for i, representation := range representations {
+		typeName := representation["__typename"].(string)
+		if _, ok := entities[typeName]; !ok {
+			entityErrs = append(entityErrs, &EntityError{Index: i, TypeName: typeName, Err: graphql.NewClientError("unknown entity type")})
+			continue
+		}
+		if _, ok := indices[typeName]; !ok {
+			typeNames = append(typeNames, typeName)
+		}
+		indices[typeName] = append(indices[typeName], i)
+	}
+
+	for _, typeName := range typeNames {
+		values, err := fetchApolloEntities(ctx, federation, entities[typeName], representations, indices[typeName])
+		if err != nil {
+			for _, i := range indices[typeName] {
+				entityErrs = append(entityErrs, &EntityError{Index: i, TypeName: typeName, Err: err})
+			}
+			continue
+		}
+		for j, i := range indices[typeName] {
+			value := values.Index(j)
+			if value.Kind() == reflect.Ptr && value.IsNil() {
+				continue
+			}
+			results[i] = apolloEntityValue{typeName: typeName, value: value.Interface()}
+		}
+	}
+
+	sort.Slice(entityErrs, func(i, j int) bool { return entityErrs[i].Index < entityErrs[j].Index })
+	for _, err := range entityErrs {
+		if !graphql.RecordFieldError(ctx, err) {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// fetchApolloEntities fetches the entities of the representations at indices,
+// all of entity's type, with its federation lookup. The entities are returned
+// in the order of indices.
+func fetchApolloEntities(ctx context.Context, federation interface{}, entity *apolloEntity, representations []map[string]interface{}, indices []int) (reflect.Value, error) {
+	keys := make([]interface{}, 0, len(indices))
+	for _, i := range indices {
+		key := make(map[string]interface{}, len(representations[i]))
+		for name, value := range representations[i] {
+			if name != "__typename" {
+				key[name] = value
+			}
+		}
+		keys = append(keys, key)
+	}
+
+	args, err := entity.lookup.ParseArguments(map[string]interface{}{"keys": keys})
+	if err != nil {
+		return reflect.Value{}, oops.Wrapf(err, "parsing keys")
+	}
+	fetched, err := graphql.SafeExecuteResolver(ctx, entity.lookup, federation, args, nil)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	values := reflect.ValueOf(fetched)
+	if values.Kind() != reflect.Slice {
+		return reflect.Value{}, oops.Errorf("lookup returned a %s rather than a slice", values.Kind())
+	}
+	if values.Len() != len(keys) {
+		return reflect.Value{}, oops.Errorf("lookup returned %d entities for %d keys", values.Len(), len(keys))
+	}
+	return values, nil
+}
+
+// apolloEntity is an object that the server can fetch from its keys.
+type apolloEntity struct {
+	object *graphql.Object
+	// keys are the fields of the object's keys.
+	keys []string
+	// lookup is the field of the Federation object fetching the object from
+	// its keys.
+	lookup *graphql.Field
+}
+
+// apolloEntities returns the objects of schema that can be fetched from their
+// keys, by name.
+func apolloEntities(schema *graphql.Schema) (map[string]*apolloEntity, error) {
+	entities := make(map[string]*apolloEntity)
+	query, ok := schema.Query.(*graphql.Object)
+	if !ok {
+		return nil, oops.Errorf("query type is not an object")
+	}
+	field, ok := query.Fields[federationField]
+	if !ok {
+		return entities, nil
+	}
+	federation, ok := unwrapType(field.Type).(*graphql.Object)
+	if !ok {
+		return nil, oops.Errorf("%s field is not an object", federationField)
+	}
+	for name, lookup := range federation.Fields {
+		object, ok := unwrapType(lookup.Type).(*graphql.Object)
+		if !ok {
+			return nil, oops.Errorf("federation field %s does not return objects", name)
+		}
+		input, ok := unwrapType(lookup.Args["keys"]).(*graphql.InputObject)
+		if !ok {
+			return nil, oops.Errorf("federation field %s has no keys", name)
+		}
+		keys := make([]string, 0, len(input.InputFields))
+		for key := range input.InputFields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		entities[object.Name] = &apolloEntity{object: object, keys: keys, lookup: lookup}
+	}
+	return entities, nil
+}
+
+// apolloSDL prints the SDL of schema, with the given entities, as an Apollo
+// Federation subgraph.
+func apolloSDL(schema *graphql.Schema, entities map[string]*apolloEntity) string {
+	// Print a copy of the schema without the "_federation" fields, which
+	// leaves out the types used only by Thunder federation. Entities are
+	// printed even if no field returns them.
+	copies := make(map[graphql.Type]graphql.Type)
+	query := withoutFederationFields(schema.Query, copies)
+	mutation := withoutFederationFields(schema.Mutation, copies)
+	objects := make([]graphql.Type, 0, len(entities))
+	for _, entity := range entities {
+		objects = append(objects, withoutFederationFields(entity.object, copies))
+	}
+	sdl := introspection.PrintSchema(&graphql.Schema{Query: query, Mutation: mutation}, objects...)
+
+	// PrintSchema prints objects as "type Name {".
+	for name, entity := range entities {
+		sdl = strings.Replace(sdl, fmt.Sprintf("\ntype %s {\n", name),
+			fmt.Sprintf("\ntype %s @key(fields: %q) {\n", name, strings.Join(entity.keys, " ")), 1)
+	}
+	return apolloFederationLink + "\n\n" + sdl
+}
+
+// withoutFederationFields copies typ and the types it references, leaving
+// out the "_federation" fields of objects. copies maps the types already
+// copied to their copies.
+func withoutFederationFields(typ graphql.Type, copies map[graphql.Type]graphql.Type) graphql.Type {
+	if copied, ok := copies[typ]; ok {
+		return copied
+	}
+	switch typ := typ.(type) {
+	case *graphql.Object:
+		copied := &graphql.Object{
+			Name:        typ.Name,
+			Description: typ.Description,
+			Fields:      make(map[string]*graphql.Field, len(typ.Fields)),
+		}
+		copies[typ] = copied
+		for name, field := range typ.Fields {
+			if name == federationField {
+				continue
+			}
+			fieldCopy := *field
+			fieldCopy.Type = withoutFederationFields(field.Type, copies)
+			copied.Fields[name] = &fieldCopy
+		}
+		return copied
+
+	case *graphql.Union:
+		copied := &graphql.Union{
+			Name:        typ.Name,
+			Description: typ.Description,
+			Types:       make(map[string]*graphql.Object, len(typ.Types)),
+		}
+		copies[typ] = copied
+		for name, member := range typ.Types {
+			copied.Types[name] = withoutFederationFields(member, copies).(*graphql.Object)
+		}
+		return copied
+
+	case *graphql.List:
+		return &graphql.List{Type: withoutFederationFields(typ.Type, copies)}
+
+	case *graphql.NonNull:
+		return &graphql.NonNull{Type: withoutFederationFields(typ.Type, copies)}
+
+	default:
+		// Scalars, enums and input objects have no "_federation" fields.
+		return typ
+	}
+}
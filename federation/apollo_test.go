@@ -0,0 +1,157 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerApolloFederation(t *testing.T) {
+	ctx := context.Background()
+	server, err := NewServer(buildTestSchema2().MustBuild(), WithApolloFederation())
+	require.NoError(t, err)
+
+	resp, err := (&DirectExecutorClient{Client: server}).Execute(ctx, &QueryRequest{
+		Query: graphql.MustParse(`{ _service { sdl } }`, map[string]interface{}{}),
+	})
+	require.NoError(t, err)
+	var res struct {
+		Service struct {
+			Sdl string `json:"sdl"`
+		} `json:"_service"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Result, &res))
+
+	// Foo is printed with its keys although no field returns it.
+	assert.Equal(t, `extend schema @link(url: "https://specs.apollo.dev/federation/v2.0", import: ["@key"])
+
+"""Client-side-only directive that instructs the type generator to mark this field as optional. This is useful for making the generated types compliant with Troy persistence schema."""
+directive @type_as_optional on FIELD
+
+type Bar @key(fields: "id") {
+  id: int64!
+}
+
+type Foo @key(fields: "name") {
+  name: string!
+  s2bar: Bar
+  s2ids(ids: [int64!]!): [int64!]!
+  s2ok: int!
+  s2ok2: int!
+  s2score(weight: int64): int64!
+}
+
+type Query {
+  s2root: string!
+}
+
+scalar int
+
+scalar int64
+
+scalar string
+`, res.Service.Sdl)
+
+	// Thunder federation keeps working.
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{"schema1": buildTestSchema1()})
+	require.NoError(t, err)
+	execs["schema2"] = &DirectExecutorClient{Client: server}
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	require.NoError(t, err)
+	runAndValidateQueryResults(t, ctx, e, `{ s1fff { name s2ok } }`, `
+		{
+			"s1fff": [
+				{"name": "jimbo", "s2ok": 5},
+				{"name": "bob", "s2ok": 3}
+			]
+		}`)
+}
+
+func TestServerApolloEntities(t *testing.T) {
+	ctx := context.Background()
+	server, err := NewServer(buildTestSchema2().MustBuild(), WithApolloFederation())
+	require.NoError(t, err)
+	client := &DirectExecutorClient{Client: server}
+
+	resp, err := client.Execute(ctx, &QueryRequest{
+		Query: graphql.MustParse(`{
+			_entities(representations: [
+				{__typename: "Foo", name: "jimbo"},
+				{__typename: "Bar", id: 7},
+				{__typename: "Foo", name: "bob"}
+			]) {
+				__typename
+				... on Foo { name s2ok }
+				... on Bar { id }
+			}
+		}`, map[string]interface{}{}),
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"_entities": [
+			{"__typename": "Foo", "name": "jimbo", "s2ok": 5},
+			{"__typename": "Bar", "id": 7},
+			{"__typename": "Foo", "name": "bob", "s2ok": 3}
+		]
+	}`, string(resp.Result))
+
+	_, err = client.Execute(ctx, &QueryRequest{
+		Query: graphql.MustParse(`{
+			_entities(representations: [{__typename: "Unknown", name: "jimbo"}]) { __typename }
+		}`, map[string]interface{}{}),
+	})
+	assert.Error(t, err)
+}
+
+func TestServerApolloEntitiesErrors(t *testing.T) {
+	type Baz struct {
+		Id int64
+	}
+	s2 := buildTestSchema2()
+	s2.Object("Baz", Baz{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Baz }) ([]*Baz, error) {
+		return nil, errors.New("baz lookup failed")
+	}))
+	server, err := NewServer(s2.MustBuild(), WithApolloFederation())
+	require.NoError(t, err)
+	client := &DirectExecutorClient{Client: server}
+
+	// Representations that fail to fetch resolve to null with their own
+	// errors, without failing the others.
+	ctx := graphql.WithFieldErrors(context.Background())
+	resp, err := client.Execute(ctx, &QueryRequest{
+		Query: graphql.MustParse(`{
+			_entities(representations: [
+				{__typename: "Foo", name: "jimbo"},
+				{__typename: "Baz", id: 1},
+				{__typename: "Unknown", name: "bob"},
+				{__typename: "Baz", id: 2}
+			]) {
+				__typename
+				... on Foo { name }
+				... on Baz { id }
+			}
+		}`, map[string]interface{}{}),
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"_entities": [{"__typename": "Foo", "name": "jimbo"}, null, null, null]
+	}`, string(resp.Result))
+	var messages []string
+	for _, err := range graphql.FieldErrors(ctx) {
+		var entityErr *EntityError
+		require.True(t, errors.As(err, &entityErr))
+		messages = append(messages, graphql.SanitizeError(err))
+	}
+	assert.Equal(t, []string{
+		"_entities.1: fetching Baz: Internal server error",
+		"_entities.2: fetching Unknown: unknown entity type",
+		"_entities.3: fetching Baz: Internal server error",
+	}, messages)
+	assert.Contains(t, graphql.FieldErrors(ctx)[0].Error(), "baz lookup failed")
+}
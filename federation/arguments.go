@@ -0,0 +1,186 @@
+package federation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// validateArguments checks the arguments of the fields selected by
+// selectionSet on typ against their types in the merged schema, so that a
+// wrongly-typed argument fails the query when it is planned rather than with
+// a coercion error from the service resolving the field.
+func (e *Planner) validateArguments(typ graphql.Type, selectionSet *graphql.SelectionSet) error {
+	if selectionSet == nil {
+		return nil
+	}
+	obj, ok := unwrapType(typ).(*graphql.Object)
+	if ok {
+		for _, selection := range selectionSet.Selections {
+			field, ok := obj.Fields[selection.Name]
+			if !ok {
+				continue
+			}
+			for name, value := range selection.UnparsedArgs {
+				// Unknown and missing arguments are left to the service to
+				// report.
+				argType, ok := field.Args[name]
+				if !ok {
+					continue
+				}
+				if err := validateArgumentValue(argType, value); err != nil {
+					return graphql.NewClientErrorAt(selection.Location, "argument %s of %s.%s: %v", name, obj.Name, selection.Name, err)
+				}
+			}
+			if err := e.validateArguments(field.Type, selection.SelectionSet); err != nil {
+				return err
+			}
+		}
+	}
+	for _, fragment := range selectionSet.Fragments {
+		fragmentType, ok := e.flattener.types[fragment.On]
+		if !ok {
+			continue
+		}
+		if err := e.validateArguments(fragmentType, fragment.SelectionSet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateArgumentValue checks that value, decoded from JSON, is a valid
+// value of typ.
+func validateArgumentValue(typ graphql.Type, value interface{}) error {
+	if nonNull, ok := typ.(*graphql.NonNull); ok {
+		if value == nil {
+			return fmt.Errorf("expected %s, got null", typ)
+		}
+		typ = nonNull.Type
+	}
+	if value == nil {
+		return nil
+	}
+
+	switch typ := typ.(type) {
+	case *graphql.List:
+		list, ok := value.([]interface{})
+		if !ok {
+			// A single value is coerced to a list of one value.
+			return validateArgumentValue(typ.Type, value)
+		}
+		for i, elem := range list {
+			if err := validateArgumentValue(typ.Type, elem); err != nil {
+				return fmt.Errorf("element %d: %v", i, err)
+			}
+		}
+
+	case *graphql.InputObject:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected %s, got %s", typ.Name, describeValue(value))
+		}
+		for name, fieldValue := range obj {
+			fieldType, ok := typ.InputFields[name]
+			if !ok {
+				continue
+			}
+			if err := validateArgumentValue(fieldType, fieldValue); err != nil {
+				return fmt.Errorf("field %s: %v", name, err)
+			}
+		}
+
+	case *graphql.Enum:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected %s, got %s", typ.Type, describeValue(value))
+		}
+		for _, enumValue := range typ.Values {
+			if enumValue == s {
+				return nil
+			}
+		}
+		return fmt.Errorf("expected %s, got unknown value %q", typ.Type, s)
+
+	case *graphql.Scalar:
+		return validateScalarValue(typ.Type, value)
+	}
+	return nil
+}
+
+// validateScalarValue checks that value is a valid value of the scalar named
+// name. Values of custom scalars are not checked.
+func validateScalarValue(name string, value interface{}) error {
+	switch name {
+	case "string", "String", "Time", "bytes":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("expected %s, got %s", name, describeValue(value))
+		}
+
+	case "bool", "Boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("expected %s, got %s", name, describeValue(value))
+		}
+
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "Int":
+		n, ok := numberValue(value)
+		if !ok {
+			return fmt.Errorf("expected %s, got %s", name, describeValue(value))
+		}
+		if n != math.Trunc(n) {
+			return fmt.Errorf("expected %s, got non-integer %v", name, n)
+		}
+		if strings.HasPrefix(name, "uint") && n < 0 {
+			return fmt.Errorf("expected %s, got negative %v", name, n)
+		}
+
+	case "float32", "float64", "Float":
+		if _, ok := numberValue(value); !ok {
+			return fmt.Errorf("expected %s, got %s", name, describeValue(value))
+		}
+	}
+	return nil
+}
+
+// numberValue returns value as a float64 if it is a number.
+func numberValue(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case float64:
+		return value, true
+	case float32:
+		return float64(value), true
+	case int:
+		return float64(value), true
+	case int32:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case json.Number:
+		n, err := value.Float64()
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// describeValue describes a value decoded from JSON for error messages.
+func describeValue(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return fmt.Sprintf("string %q", value)
+	case bool:
+		return fmt.Sprintf("boolean %v", value)
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "object"
+	default:
+		if n, ok := numberValue(value); ok {
+			return fmt.Sprintf("number %v", n)
+		}
+		return fmt.Sprintf("%T", value)
+	}
+}
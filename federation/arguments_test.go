@@ -0,0 +1,98 @@
+package federation
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorArgumentTypes(t *testing.T) {
+	ctx := context.Background()
+	s2 := buildTestSchema2()
+	s2.Query().FieldFunc("s2flag", func(args struct {
+		Enabled bool
+		Label   *string
+	}) string {
+		return fmt.Sprint(args.Enabled)
+	})
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": s2,
+	})
+	require.NoError(t, err)
+	counting := &countingExecutorClient{ExecutorClient: execs["schema2"]}
+	execs["schema2"] = counting
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		Name          string
+		Query         string
+		Variables     map[string]interface{}
+		ExpectedError string
+	}{
+		{
+			Name:          "string for int",
+			Query:         `{ s1fff { name s2score(weight: "2") } }`,
+			ExpectedError: `argument weight of Foo.s2score: expected int64, got string "2"`,
+		},
+		{
+			Name:          "int for string",
+			Query:         `{ s1echo(foo: 1, required: {a: 1, b: 2}) }`,
+			ExpectedError: `argument foo of Query.s1echo: expected string, got number 1`,
+		},
+		{
+			Name:          "bool for string",
+			Query:         `{ s2flag(enabled: true, label: false) }`,
+			ExpectedError: `argument label of Query.s2flag: expected string, got boolean false`,
+		},
+		{
+			Name:          "string for bool",
+			Query:         `{ s2flag(enabled: "true") }`,
+			ExpectedError: `argument enabled of Query.s2flag: expected bool, got string "true"`,
+		},
+		{
+			Name:          "variable of the wrong type",
+			Query:         `query Q($weight: int64) { s1fff { name s2score(weight: $weight) } }`,
+			Variables:     map[string]interface{}{"weight": "heavy"},
+			ExpectedError: `argument weight of Foo.s2score: expected int64, got string "heavy"`,
+		},
+		{
+			Name:          "non-integer for int",
+			Query:         `{ s1fff { name s2score(weight: 1.5) } }`,
+			ExpectedError: `argument weight of Foo.s2score: expected int64, got non-integer 1.5`,
+		},
+		{
+			Name:          "wrong type in input object",
+			Query:         `{ s1echo(foo: "foo", required: {a: "1", b: 2}) }`,
+			ExpectedError: `argument required of Query.s1echo: field a: expected int64, got string "1"`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			counting.reset()
+			query, err := graphql.Parse(testCase.Query, testCase.Variables)
+			require.NoError(t, err)
+			_, _, err = e.Execute(ctx, query, nil)
+			require.Error(t, err)
+			assert.Equal(t, testCase.ExpectedError, oops.Cause(err).Error())
+			// The query fails before any subquery is sent.
+			assert.Equal(t, 0, counting.count)
+		})
+	}
+
+	runAndValidateQueryResults(t, ctx, e, `{ s2flag(enabled: true, label: "x") s1fff { name s2score(weight: 2) } }`, `
+		{
+			"s2flag": "true",
+			"s1fff": [
+				{"name": "jimbo", "s2score": 10},
+				{"name": "bob", "s2score": 6}
+			]
+		}`)
+}
@@ -0,0 +1,285 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// FieldChecker is implemented by ExecutorClients that know which fields the
+// server they send requests to can resolve. During a rolling deploy, that
+// server might not resolve all the fields of the merged schema yet.
+type FieldChecker interface {
+	// HasField returns whether the server resolves the field on the type
+	// typeName.
+	HasField(typeName, field string) bool
+}
+
+// WithMissingFieldsAsNull resolves the fields that a service's client reports
+// it cannot resolve as null, instead of sending them to the service and
+// failing the query. As in graphql, objects missing a non-null field are
+// resolved as null instead, up to the nearest parent that can be null; if
+// that is an object looked up from the service, or its root, the query
+// fails. It only affects services whose ExecutorClient implements
+// FieldChecker, such as FieldCheckingExecutorClient.
+func WithMissingFieldsAsNull() ExecutorOption {
+	return func(e *Executor) {
+		e.nullMissingFields = true
+	}
+}
+
+// FieldCheckingExecutorClient is an ExecutorClient that knows which fields the
+// server it sends requests to resolves, from the server's introspected schema.
+// An Executor introspects the schema again with Refresh every time it syncs
+// its schema, so that fields deployed since are resolved.
+type FieldCheckingExecutorClient struct {
+	ExecutorClient
+	metadata interface{}
+
+	mu     sync.RWMutex
+	fields map[string]map[string]bool
+}
+
+var _ FieldChecker = &FieldCheckingExecutorClient{}
+
+// NewFieldCheckingExecutorClient introspects the schema of the server that
+// client sends requests to, sending metadata with the introspection query.
+func NewFieldCheckingExecutorClient(ctx context.Context, client ExecutorClient, metadata interface{}) (*FieldCheckingExecutorClient, error) {
+	c := &FieldCheckingExecutorClient{ExecutorClient: client, metadata: metadata}
+	if err := c.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Refresh introspects the schema of the server again. If introspection
+// fails, the fields of the previous schema are kept.
+func (c *FieldCheckingExecutorClient) Refresh(ctx context.Context) error {
+	resp, err := fetchSchema(ctx, c.ExecutorClient, c.metadata)
+	if err != nil {
+		return oops.Wrapf(err, "fetching schema")
+	}
+	var iq IntrospectionQueryResult
+	if err := json.Unmarshal(resp.Result, &iq); err != nil {
+		return oops.Wrapf(err, "unmarshaling schema")
+	}
+
+	fields := make(map[string]map[string]bool, len(iq.Schema.Types))
+	for _, typ := range iq.Schema.Types {
+		fields[typ.Name] = make(map[string]bool, len(typ.Fields))
+		for _, field := range typ.Fields {
+			fields[typ.Name][field.Name] = true
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fields = fields
+	return nil
+}
+
+// HasField returns whether the server's schema has the field on the type
+// typeName.
+func (c *FieldCheckingExecutorClient) HasField(typeName, field string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.fields[typeName][field]
+}
+
+// refreshFieldCheckers refreshes the clients of e that are
+// FieldCheckingExecutorClients, eg. after syncing the schema.
+func (e *Executor) refreshFieldCheckers(ctx context.Context) {
+	for _, client := range e.Executors {
+		if checker, ok := client.(*FieldCheckingExecutorClient); ok {
+			// A failed refresh keeps the fields of the previous schema.
+			checker.Refresh(ctx)
+		}
+	}
+}
+
+// missingFields are the fields removed from a selection set because a service
+// cannot resolve them.
+type missingFields struct {
+	// null are the aliases of the fields to resolve as null.
+	null []string
+	// nonNull is set if a non-null field is missing, so that the objects
+	// themselves are resolved as null.
+	nonNull bool
+	// fields are the missing fields below the selected fields, by alias.
+	fields map[string]*missingChildFields
+	// fragments are the missing fields of fragments, by type name.
+	fragments map[string]*missingFields
+	// typename is set if __typename was selected in place of the missing
+	// fields, so that the selection set isn't empty.
+	typename bool
+}
+
+// missingChildFields are the missing fields below a selected field.
+type missingChildFields struct {
+	*missingFields
+	// nullable is whether the field's value, and then the elements of each
+	// of its lists, can be null, eg. [true, false] for [Foo!].
+	nullable []bool
+}
+
+// nullablePositions returns whether a value of type typ, and then the
+// elements of each of its lists, can be null.
+func nullablePositions(typ graphql.Type) []bool {
+	var nullable []bool
+	for {
+		nonNull, ok := typ.(*graphql.NonNull)
+		if ok {
+			typ = nonNull.Type
+		}
+		nullable = append(nullable, !ok)
+		list, ok := typ.(*graphql.List)
+		if !ok {
+			return nullable
+		}
+		typ = list.Type
+	}
+}
+
+// removeMissingFields returns selectionSet on typ without the fields that
+// checker cannot resolve, and the removed fields, or nil if there are none.
+// originals maps namespaced type names to the names the service knows.
+func removeMissingFields(checker FieldChecker, typ graphql.Type, selectionSet *graphql.SelectionSet, originals map[string]string) (*graphql.SelectionSet, *missingFields) {
+	switch inner := typ.(type) {
+	case *graphql.NonNull:
+		return removeMissingFields(checker, inner.Type, selectionSet, originals)
+	case *graphql.List:
+		return removeMissingFields(checker, inner.Type, selectionSet, originals)
+	}
+	if selectionSet == nil {
+		return nil, nil
+	}
+
+	var missing *missingFields
+	kept := &graphql.SelectionSet{
+		Selections: make([]*graphql.Selection, 0, len(selectionSet.Selections)),
+	}
+	if obj, ok := typ.(*graphql.Object); ok {
+		typeName := obj.Name
+		if original, ok := originals[typeName]; ok {
+			typeName = original
+		}
+		for _, selection := range selectionSet.Selections {
+			field, ok := obj.Fields[selection.Name]
+			if !ok {
+				// Fields like __typename are always resolvable.
+				kept.Selections = append(kept.Selections, selection)
+				continue
+			}
+			if !checker.HasField(typeName, selection.Name) {
+				if missing == nil {
+					missing = &missingFields{}
+				}
+				if _, ok := field.Type.(*graphql.NonNull); ok {
+					missing.nonNull = true
+				} else {
+					missing.null = append(missing.null, selection.Alias)
+				}
+				continue
+			}
+			childSelectionSet, childMissing := removeMissingFields(checker, field.Type, selection.SelectionSet, originals)
+			if childMissing != nil {
+				if missing == nil {
+					missing = &missingFields{}
+				}
+				if missing.fields == nil {
+					missing.fields = make(map[string]*missingChildFields)
+				}
+				missing.fields[selection.Alias] = &missingChildFields{
+					missingFields: childMissing,
+					nullable:      nullablePositions(field.Type),
+				}
+				selectionCopy := *selection
+				selectionCopy.SelectionSet = childSelectionSet
+				selection = &selectionCopy
+			}
+			kept.Selections = append(kept.Selections, selection)
+		}
+		if missing != nil && len(kept.Selections) == 0 && len(selectionSet.Fragments) == 0 {
+			kept.Selections = append(kept.Selections, &graphql.Selection{
+				Name:         "__typename",
+				Alias:        "__typename",
+				UnparsedArgs: map[string]interface{}{},
+			})
+			missing.typename = true
+		}
+	} else {
+		kept.Selections = append(kept.Selections, selectionSet.Selections...)
+	}
+
+	for _, fragment := range selectionSet.Fragments {
+		var fragmentType graphql.Type = typ
+		if union, ok := typ.(*graphql.Union); ok {
+			if member, ok := union.Types[fragment.On]; ok {
+				fragmentType = member
+			}
+		}
+		fragmentSelectionSet, fragmentMissing := removeMissingFields(checker, fragmentType, fragment.SelectionSet, originals)
+		if fragmentMissing != nil {
+			if missing == nil {
+				missing = &missingFields{}
+			}
+			if missing.fragments == nil {
+				missing.fragments = make(map[string]*missingFields)
+			}
+			missing.fragments[fragment.On] = fragmentMissing
+		}
+		kept.Fragments = append(kept.Fragments, &graphql.Fragment{
+			On:           fragment.On,
+			SelectionSet: fragmentSelectionSet,
+		})
+	}
+	if missing == nil {
+		return selectionSet, nil
+	}
+	return kept, missing
+}
+
+// fill sets the missing fields of the objects in res, an object or a list of
+// objects whose values can be null as given by nullable, to null. As in
+// graphql, objects missing a non-null field are null, and so are their
+// parents up to the nearest one that can be null. It returns whether res
+// itself must be null.
+func (m *missingFields) fill(res interface{}, nullable []bool) bool {
+	switch res := res.(type) {
+	case []interface{}:
+		for i, elem := range res {
+			if m.fill(elem, nullable[1:]) {
+				if !nullable[1] {
+					return true
+				}
+				res[i] = nil
+			}
+		}
+	case map[string]interface{}:
+		if m.nonNull {
+			return true
+		}
+		for typeName, missing := range m.fragments {
+			if typename, ok := res["__typename"].(string); (!ok || typename == typeName) && missing.fill(res, nullable) {
+				return true
+			}
+		}
+		for alias, missing := range m.fields {
+			if missing.fill(res[alias], missing.nullable) {
+				if !missing.nullable[0] {
+					return true
+				}
+				res[alias] = nil
+			}
+		}
+		if m.typename {
+			delete(res, "__typename")
+		}
+		for _, alias := range m.null {
+			res[alias] = nil
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package federation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorMissingFieldsAsNull(t *testing.T) {
+	ctx := context.Background()
+
+	// The schema is synced from an upgraded replica of schema2 with s2new,
+	// but queries are sent to a replica without it.
+	upgraded := buildTestSchema2()
+	upgraded.Object("Foo", Foo{}).FieldFunc("s2new", func(in *Foo) *string {
+		s := "new " + in.Name
+		return &s
+	})
+	upgraded.Object("Foo", Foo{}).FieldFunc("s2newNonNull", func(in *Foo) string {
+		return "new " + in.Name
+	})
+	upgraded.Object("Bar", Bar{}).FieldFunc("s2newNonNull", func(in *Bar) int64 {
+		return in.Id
+	})
+	upgraded.Query().FieldFunc("s2newroot", func() *string {
+		s := "new"
+		return &s
+	})
+	syncExecs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": upgraded,
+	})
+	require.NoError(t, err)
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": buildTestSchema2(),
+	})
+	require.NoError(t, err)
+
+	newExecutor := func(opts ...ExecutorOption) *Executor {
+		e, _, err := newTestExecutor(t, map[string]*schemabuilder.Schema{
+			"schema1": buildTestSchema1(),
+			"schema2": upgraded,
+		}, opts...)
+		require.NoError(t, err)
+		replica, err := NewFieldCheckingExecutorClient(ctx, execs["schema2"], nil)
+		require.NoError(t, err)
+		e.Executors["schema2"] = replica
+		return e
+	}
+
+	e := newExecutor(WithMissingFieldsAsNull())
+	runAndValidateQueryResults(t, ctx, e, `{
+		s1fff { name s2ok s2new }
+		s2root
+		s2newroot
+	}`, `
+		{
+			"s1fff": [
+				{"name": "jimbo", "s2ok": 5, "s2new": null},
+				{"name": "bob", "s2ok": 3, "s2new": null}
+			],
+			"s2root": "hello",
+			"s2newroot": null
+		}`)
+
+	// Subqueries with only missing fields aren't empty.
+	runAndValidateQueryResults(t, ctx, e, `{ s1fff { name s2new } }`, `
+		{
+			"s1fff": [
+				{"name": "jimbo", "s2new": null},
+				{"name": "bob", "s2new": null}
+			]
+		}`)
+
+	// Objects missing a non-null field are null, up to the nearest parent
+	// that can be null.
+	runAndValidateQueryResults(t, ctx, e, `{ s1fff { name s2bar { id s2newNonNull } } }`, `
+		{
+			"s1fff": [
+				{"name": "jimbo", "s2bar": null},
+				{"name": "bob", "s2bar": null}
+			]
+		}`)
+
+	t.Run("non-null fields of looked up objects fail the query", func(t *testing.T) {
+		runAndValidateQueryError(t, ctx, e, `{ s1fff { name s2newNonNull } }`, "", "cannot resolve non-null fields")
+	})
+
+	t.Run("without the option", func(t *testing.T) {
+		e := newExecutor()
+		runAndValidateQueryError(t, ctx, e, `{ s1fff { name s2new } }`, "", "s2new")
+	})
+
+	t.Run("checker", func(t *testing.T) {
+		replica, err := NewFieldCheckingExecutorClient(ctx, execs["schema2"], nil)
+		require.NoError(t, err)
+		assert.True(t, replica.HasField("Foo", "s2ok"))
+		assert.False(t, replica.HasField("Foo", "s2new"))
+		assert.False(t, replica.HasField("Missing", "s2ok"))
+
+		// Once the replica is upgraded, a refresh finds its new fields.
+		replica.ExecutorClient = syncExecs["schema2"]
+		require.NoError(t, replica.Refresh(ctx))
+		assert.True(t, replica.HasField("Foo", "s2new"))
+	})
+}
@@ -0,0 +1,70 @@
+package federation
+
+import (
+	"context"
+	"errors"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// ErrorCategory classifies the errors returned by an ExecutorClient, to decide
+// eg. whether a failed request should be retried.
+type ErrorCategory int
+
+const (
+	// ErrorCategoryTransient errors might not happen again if the request is
+	// retried, eg. timeouts and connection errors.
+	ErrorCategoryTransient ErrorCategory = iota
+	// ErrorCategoryPermanent errors will happen again if the request is
+	// retried.
+	ErrorCategoryPermanent
+	// ErrorCategoryClient errors are caused by the request itself, eg. an
+	// invalid query or a cancelled context.
+	ErrorCategoryClient
+)
+
+func (c ErrorCategory) String() string {
+	switch c {
+	case ErrorCategoryTransient:
+		return "transient"
+	case ErrorCategoryPermanent:
+		return "permanent"
+	case ErrorCategoryClient:
+		return "client"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrorClassifier classifies the errors returned by an ExecutorClient.
+type ErrorClassifier interface {
+	Classify(err error) ErrorCategory
+}
+
+// ErrorClassifierFunc is an ErrorClassifier implemented by a function.
+type ErrorClassifierFunc func(err error) ErrorCategory
+
+// Classify calls f(err).
+func (f ErrorClassifierFunc) Classify(err error) ErrorCategory {
+	return f(err)
+}
+
+// DefaultErrorClassifier classifies deadline errors as transient, and
+// cancellations and graphql client errors, such as validation errors, as
+// client errors. All other errors are classified as transient.
+var DefaultErrorClassifier ErrorClassifier = ErrorClassifierFunc(classifyError)
+
+func classifyError(err error) ErrorCategory {
+	cause := oops.Cause(err)
+	switch {
+	case errors.Is(cause, context.DeadlineExceeded):
+		return ErrorCategoryTransient
+	case errors.Is(cause, context.Canceled):
+		return ErrorCategoryClient
+	case errors.As(cause, &graphql.ClientError{}):
+		return ErrorCategoryClient
+	default:
+		return ErrorCategoryTransient
+	}
+}
@@ -0,0 +1,35 @@
+package federation
+
+import "context"
+
+type clientOverridesKey struct{}
+
+// WithClientOverride returns a context in which queries executed by an
+// Executor send the requests for service to client, rather than to the
+// executor's client for service, eg. to route a fraction of traffic to a
+// canary build:
+//   if rand.Float64() < 0.01 {
+//     ctx = federation.WithClientOverride(ctx, "schema2", canaryClient)
+//   }
+//   res, _, err := executor.Execute(ctx, query, nil)
+//
+// Other queries and the executor itself are not affected. The schema of the
+// executor is still the one introspected from its own clients, so the client
+// should serve a compatible schema.
+func WithClientOverride(ctx context.Context, service string, client ExecutorClient) context.Context {
+	existing, _ := ctx.Value(clientOverridesKey{}).(map[string]ExecutorClient)
+	overrides := make(map[string]ExecutorClient, len(existing)+1)
+	for name, override := range existing {
+		overrides[name] = override
+	}
+	overrides[service] = client
+	return context.WithValue(ctx, clientOverridesKey{}, overrides)
+}
+
+// clientOverride returns the client overriding the executor's client for
+// service in ctx, if any.
+func clientOverride(ctx context.Context, service string) (ExecutorClient, bool) {
+	overrides, _ := ctx.Value(clientOverridesKey{}).(map[string]ExecutorClient)
+	client, ok := overrides[service]
+	return client, ok
+}
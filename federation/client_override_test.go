@@ -0,0 +1,45 @@
+package federation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorClientOverride(t *testing.T) {
+	ctx := context.Background()
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": buildTestSchema2(),
+	})
+	require.NoError(t, err)
+	schema2 := &countingExecutorClient{ExecutorClient: execs["schema2"]}
+	execs["schema2"] = schema2
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	require.NoError(t, err)
+
+	// The canary runs the same build in the test.
+	canaryExecs, err := makeExecutors(map[string]*schemabuilder.Schema{"schema2": buildTestSchema2()})
+	require.NoError(t, err)
+	canary := &countingExecutorClient{ExecutorClient: canaryExecs["schema2"]}
+
+	schema2.reset()
+	runAndValidateQueryResults(t, WithClientOverride(ctx, "schema2", canary), e, `{ s2root s1fff { name s2ok } }`, `
+		{
+			"s2root": "hello",
+			"s1fff": [
+				{"name": "jimbo", "s2ok": 5},
+				{"name": "bob", "s2ok": 3}
+			]
+		}`)
+	assert.Equal(t, 0, schema2.count)
+	assert.Equal(t, 2, canary.count)
+
+	canary.reset()
+	runAndValidateQueryResults(t, ctx, e, `{ s2root }`, `{"s2root": "hello"}`)
+	assert.Equal(t, 1, schema2.count)
+	assert.Equal(t, 0, canary.count)
+}
@@ -0,0 +1,100 @@
+package federation
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/introspection"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+)
+
+// CheckFederationCompatibility statically checks that the schemas of all
+// services, by name, can be federated, without standing up an Executor. It
+// checks that
+//   - every object shared between services is federated and every extended
+//     object has at least one required key,
+//   - every key is a field of the object on all services that resolve it,
+//   - no two services define the same field with conflicting types.
+//
+// It is meant to be run in CI, eg. in a test that builds the schemas of all
+// services before deploying any of them.
+func CheckFederationCompatibility(schemas map[string]*schemabuilder.Schema) error {
+	services := make([]string, 0, len(schemas))
+	for service := range schemas {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	introspected := make(map[string]*IntrospectionQueryResult, len(schemas))
+	for _, service := range services {
+		built, err := schemas[service].Build()
+		if err != nil {
+			return oops.Wrapf(err, "building schema %s", service)
+		}
+		iq, err := introspectSchema(built)
+		if err != nil {
+			return oops.Wrapf(err, "introspecting schema %s", service)
+		}
+		if err := checkExtendedObjectKeys(iq); err != nil {
+			return oops.Wrapf(err, "schema %s", service)
+		}
+		introspected[service] = iq
+	}
+
+	types, err := convertSchema(introspected)
+	if err != nil {
+		return oops.Wrapf(err, "incompatible schemas")
+	}
+	if _, err := NewPlanner(types, nil); err != nil {
+		return oops.Wrapf(err, "incompatible schemas")
+	}
+	return nil
+}
+
+// introspectSchema returns the introspected schema, as returned to the
+// executor by a service's Server.
+func introspectSchema(schema *graphql.Schema) (*IntrospectionQueryResult, error) {
+	introspection.AddIntrospectionToSchema(schema)
+	out, err := introspection.RunIntrospectionQuery(schema)
+	if err != nil {
+		return nil, err
+	}
+	var iq IntrospectionQueryResult
+	if err := json.Unmarshal(out, &iq); err != nil {
+		return nil, oops.Wrapf(err, "unmarshaling schema")
+	}
+	return &iq, nil
+}
+
+// checkExtendedObjectKeys checks that every object the service fetches from
+// keys has at least one required key.
+func checkExtendedObjectKeys(iq *IntrospectionQueryResult) error {
+	byName := make(map[string]introspectionType, len(iq.Schema.Types))
+	for _, typ := range iq.Schema.Types {
+		byName[typ.Name] = typ
+	}
+	for _, field := range byName["Federation"].Fields {
+		names := strings.SplitN(field.Name, "_", 2)
+		if len(names) != 2 {
+			return oops.Errorf("Field %s doesnt have an object name and service name", field.Name)
+		}
+		hasKey := false
+		for _, arg := range field.Args {
+			if arg.Name != "keys" {
+				continue
+			}
+			for _, key := range byName[getRootType(arg.Type).Name].InputFields {
+				if key.Type.Kind == "NON_NULL" {
+					hasKey = true
+				}
+			}
+		}
+		if !hasKey {
+			return oops.Errorf("object %s has no required keys", names[1])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package federation
+
+import (
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckFederationCompatibility(t *testing.T) {
+	type Foo struct {
+		Name string
+	}
+	type FooKeys struct {
+		Name *string
+	}
+
+	require.NoError(t, CheckFederationCompatibility(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": buildTestSchema2(),
+	}))
+
+	testCases := []struct {
+		Name  string
+		Build func() map[string]*schemabuilder.Schema
+		Error string
+	}{
+		{
+			Name: "object is not federated",
+			Build: func() map[string]*schemabuilder.Schema {
+				s1 := schemabuilder.NewSchemaWithName("s1")
+				s1.Object("Foo", Foo{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Foo }) []*Foo { return args.Keys }))
+				s1.Query().FieldFunc("foo", func() *Foo { return &Foo{} })
+				s2 := schemabuilder.NewSchemaWithName("s2")
+				s2.Object("Foo", Foo{})
+				s2.Query().FieldFunc("bar", func() *Foo { return &Foo{} })
+				return map[string]*schemabuilder.Schema{"s1": s1, "s2": s2}
+			},
+			Error: "Object Foo exists on another server and is not federated",
+		},
+		{
+			Name: "extended object without required keys",
+			Build: func() map[string]*schemabuilder.Schema {
+				s1 := schemabuilder.NewSchemaWithName("s1")
+				s1.Object("Foo", Foo{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*FooKeys }) []*Foo { return nil }))
+				s1.Query().FieldFunc("foo", func() *Foo { return &Foo{} })
+				return map[string]*schemabuilder.Schema{"s1": s1}
+			},
+			Error: "object Foo has no required keys",
+		},
+		{
+			Name: "key is not resolved by the owner",
+			Build: func() map[string]*schemabuilder.Schema {
+				type Bar struct {
+					Id int64
+				}
+				type BarKeys struct {
+					Id   int64
+					Name string
+				}
+				s1 := schemabuilder.NewSchemaWithName("s1")
+				s1.Object("Bar", Bar{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Bar }) []*Bar { return args.Keys }))
+				s1.Query().FieldFunc("bar", func() *Bar { return &Bar{} })
+				s2 := schemabuilder.NewSchemaWithName("s2")
+				bar := s2.Object("Bar", BarKeys{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*BarKeys }) []*BarKeys { return args.Keys }))
+				bar.FieldFunc("ok", func() bool { return true })
+				return map[string]*schemabuilder.Schema{"s1": s1, "s2": s2}
+			},
+			Error: "Invalid federation key name",
+		},
+		{
+			Name: "conflicting field types",
+			Build: func() map[string]*schemabuilder.Schema {
+				s1 := schemabuilder.NewSchemaWithName("s1")
+				s1.Query().FieldFunc("count", func() int64 { return 0 })
+				s2 := schemabuilder.NewSchemaWithName("s2")
+				s2.Query().FieldFunc("count", func() string { return "" })
+				return map[string]*schemabuilder.Schema{"s1": s1, "s2": s2}
+			},
+			Error: "types must be identical",
+		},
+		{
+			Name: "invalid schema",
+			Build: func() map[string]*schemabuilder.Schema {
+				s1 := schemabuilder.NewSchemaWithName("s1")
+				s1.Query().FieldFunc("bad", func() chan int { return nil })
+				return map[string]*schemabuilder.Schema{"s1": s1}
+			},
+			Error: "building schema s1",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			err := CheckFederationCompatibility(testCase.Build())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), testCase.Error)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrTooManyRequests is returned for requests to services that exceed the
+// limits set with WithMaxConcurrentRequests.
+var ErrTooManyRequests = errors.New("too many concurrent requests to services")
+
+// WithMaxConcurrentRequests limits the number of requests that the executor
+// sends to services at once, across all queries, to max. This provides
+// backpressure under load spikes rather than overwhelming the services.
+//
+// Requests over the limit wait for an earlier request to complete. At most
+// maxQueued requests wait at once, for at most queueTimeout, or indefinitely
+// if queueTimeout is 0; other requests fail with ErrTooManyRequests. If max
+// is 0 or less, the number of requests is not limited.
+func WithMaxConcurrentRequests(max, maxQueued int, queueTimeout time.Duration) ExecutorOption {
+	return func(e *Executor) {
+		if max <= 0 {
+			e.requestLimiter = nil
+			return
+		}
+		e.requestLimiter = &requestLimiter{
+			slots:        make(chan struct{}, max),
+			maxQueued:    int64(maxQueued),
+			queueTimeout: queueTimeout,
+		}
+	}
+}
+
+// requestLimiter limits the number of concurrent requests to services.
+type requestLimiter struct {
+	// slots holds a value for every request in flight.
+	slots chan struct{}
+	// queued is the number of requests waiting for a slot.
+	queued       int64
+	maxQueued    int64
+	queueTimeout time.Duration
+}
+
+// acquire waits for a slot for a request, which must be released once the
+// request completes.
+func (l *requestLimiter) acquire(ctx context.Context) (release func(), err error) {
+	release = func() { <-l.slots }
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	if atomic.AddInt64(&l.queued, 1) > l.maxQueued {
+		atomic.AddInt64(&l.queued, -1)
+		return nil, ErrTooManyRequests
+	}
+	defer atomic.AddInt64(&l.queued, -1)
+
+	var timeout <-chan time.Time
+	if l.queueTimeout > 0 {
+		timer := time.NewTimer(l.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	case <-timeout:
+		return nil, ErrTooManyRequests
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
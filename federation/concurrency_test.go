@@ -0,0 +1,107 @@
+package federation
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingExecutorClient blocks requests until unblocked, counting the
+// requests in flight.
+type blockingExecutorClient struct {
+	ExecutorClient
+	started   chan struct{}
+	unblock   chan struct{}
+	inFlight  int64
+	maxFlight int64
+}
+
+func (c *blockingExecutorClient) Execute(ctx context.Context, request *QueryRequest) (*QueryResponse, error) {
+	n := atomic.AddInt64(&c.inFlight, 1)
+	defer atomic.AddInt64(&c.inFlight, -1)
+	for {
+		max := atomic.LoadInt64(&c.maxFlight)
+		if n <= max || atomic.CompareAndSwapInt64(&c.maxFlight, max, n) {
+			break
+		}
+	}
+	c.started <- struct{}{}
+	<-c.unblock
+	return c.ExecutorClient.Execute(ctx, request)
+}
+
+func TestExecutorMaxConcurrentRequests(t *testing.T) {
+	ctx := context.Background()
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": buildTestSchema2(),
+	})
+	require.NoError(t, err)
+	syncer := NewIntrospectionSchemaSyncer(ctx, execs, nil)
+
+	newExecutor := func(t *testing.T, queueTimeout time.Duration) (*Executor, *blockingExecutorClient) {
+		client := &blockingExecutorClient{
+			ExecutorClient: execs["schema2"],
+			started:        make(chan struct{}, 10),
+			unblock:        make(chan struct{}),
+		}
+		blockingExecs := map[string]ExecutorClient{"schema1": execs["schema1"], "schema2": client}
+		e, err := NewExecutor(ctx, blockingExecs, &SchemaSyncerConfig{SchemaSyncer: syncer}, WithMaxConcurrentRequests(1, 1, queueTimeout))
+		require.NoError(t, err)
+		return e, client
+	}
+	execute := func(e *Executor) chan error {
+		errs := make(chan error, 1)
+		go func() {
+			_, _, err := e.Execute(ctx, graphql.MustParse(`{ s2root }`, map[string]interface{}{}), nil)
+			errs <- err
+		}()
+		return errs
+	}
+
+	t.Run("requests over the limit queue", func(t *testing.T) {
+		e, client := newExecutor(t, 0)
+
+		first := execute(e)
+		<-client.started
+		second := execute(e)
+		require.Eventually(t, func() bool {
+			return atomic.LoadInt64(&e.requestLimiter.queued) == 1
+		}, time.Second, time.Millisecond)
+
+		// The queue is full.
+		third := execute(e)
+		assert.Equal(t, ErrTooManyRequests, oops.Cause(<-third))
+
+		close(client.unblock)
+		assert.NoError(t, <-first)
+		assert.NoError(t, <-second)
+		assert.Equal(t, int64(1), atomic.LoadInt64(&client.maxFlight))
+	})
+
+	t.Run("queued requests time out", func(t *testing.T) {
+		e, client := newExecutor(t, 10*time.Millisecond)
+
+		first := execute(e)
+		<-client.started
+		second := execute(e)
+		assert.Equal(t, ErrTooManyRequests, oops.Cause(<-second))
+
+		close(client.unblock)
+		assert.NoError(t, <-first)
+	})
+
+	t.Run("a limit of 0 does not limit requests", func(t *testing.T) {
+		e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: syncer}, WithMaxConcurrentRequests(0, 10, 0))
+		require.NoError(t, err)
+		assert.Nil(t, e.requestLimiter)
+		assert.NoError(t, <-execute(e))
+	})
+}
@@ -0,0 +1,178 @@
+package federation
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// QueryCost is a static estimate of the cost of a query, computed from its
+// plan without executing it. The estimate does not depend on the results, eg.
+// the length of returned lists, so it is a lower bound of the cost of
+// executing the query.
+type QueryCost struct {
+	// Cost is the estimated cost of the query: the cost of every field
+	// resolved by a service, see WithCostFunc, and one for every subquery
+	// sent to a service.
+	Cost int64 `json:"cost"`
+	// Fields is the number of fields resolved by services.
+	Fields int64 `json:"fields"`
+	// Subqueries is the number of subqueries sent to services.
+	Subqueries int64 `json:"subqueries"`
+	// Depth is the deepest nesting of fields in the query.
+	Depth int `json:"depth"`
+	// Hops is the number of subqueries along the longest chain of subqueries
+	// that each wait on the previous one, see WithMaxHops.
+	Hops int `json:"hops"`
+	// Services are the services the query is sent to, sorted by name.
+	Services []string `json:"services"`
+}
+
+// WithCostFunc makes EstimateCost charge costFunc for every field resolved by
+// a service, the same CostFunc that graphql.WithCostBudget charges as
+// resolvers run, so that the estimate and the budget of a query agree. As the
+// results are not known when estimating, costFunc is called with a nil
+// result, eg. charging lists as if they were empty. By default, fields cost
+// graphql.DefaultCostFunc, ie. 1.
+func WithCostFunc(costFunc graphql.CostFunc) ExecutorOption {
+	return func(e *Executor) {
+		e.costFunc = costFunc
+	}
+}
+
+// EstimateCost plans query and estimates its cost from the plan, so that
+// clients can check how expensive a query is without running it.
+func (e *Executor) EstimateCost(query *graphql.Query) (*QueryCost, error) {
+	plan, err := e.Plan(query)
+	if err != nil {
+		return nil, err
+	}
+
+	cost := &QueryCost{
+		Depth: selectionDepth(query.SelectionSet),
+		Hops:  len(plan.hopChain()),
+	}
+	costFunc := e.costFunc
+	if costFunc == nil {
+		costFunc = graphql.DefaultCostFunc
+	}
+	services := make(map[string]bool)
+	var fieldsCost int64
+	plan.Walk(func(p *Plan) error {
+		if p.Service == gatewayCoordinatorServiceName {
+			return nil
+		}
+		cost.Subqueries++
+		fields, fieldCost := estimateFields(p.SelectionSet, costFunc)
+		cost.Fields += fields
+		fieldsCost += fieldCost
+		services[p.Service] = true
+		return nil
+	})
+	for service := range services {
+		cost.Services = append(cost.Services, service)
+	}
+	sort.Strings(cost.Services)
+	cost.Cost = fieldsCost + cost.Subqueries
+	return cost, nil
+}
+
+// estimateFields counts the fields selected by selectionSet and sums their
+// cost, except for __typename and "_federation" fields, which the executor
+// adds to queries.
+func estimateFields(selectionSet *graphql.SelectionSet, costFunc graphql.CostFunc) (fields int64, cost int64) {
+	if selectionSet == nil {
+		return 0, 0
+	}
+	for _, selection := range selectionSet.Selections {
+		if selection.Name == "__typename" || selection.Name == federationField {
+			continue
+		}
+		subFields, subCost := estimateFields(selection.SelectionSet, costFunc)
+		fields += 1 + subFields
+		cost += costFunc(selection, nil) + subCost
+	}
+	for _, fragment := range selectionSet.Fragments {
+		subFields, subCost := estimateFields(fragment.SelectionSet, costFunc)
+		fields += subFields
+		cost += subCost
+	}
+	return fields, cost
+}
+
+// selectionDepth returns the deepest nesting of fields in selectionSet.
+func selectionDepth(selectionSet *graphql.SelectionSet) int {
+	if selectionSet == nil {
+		return 0
+	}
+	depth := 0
+	for _, selection := range selectionSet.Selections {
+		if d := 1 + selectionDepth(selection.SelectionSet); d > depth {
+			depth = d
+		}
+	}
+	for _, fragment := range selectionSet.Fragments {
+		if d := selectionDepth(fragment.SelectionSet); d > depth {
+			depth = d
+		}
+	}
+	return depth
+}
+
+type costRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+type costResponse struct {
+	Cost   *QueryCost `json:"cost"`
+	Errors []string   `json:"errors"`
+}
+
+// CostHandler returns an HTTP handler estimating the cost of queries with
+// e.EstimateCost. It accepts POST requests with the same body as GraphQL
+// requests, ie. a query, variables and an operation name, and responds with
+// the estimate, eg.
+//   {"cost": {"cost": 7, "fields": 5, "subqueries": 2, "depth": 2, "hops": 2, "services": ["schema1", "schema2"]}}
+// or with the errors planning the query.
+func CostHandler(e *Executor) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeResponse := func(cost *QueryCost, err error) {
+			response := costResponse{Cost: cost}
+			if err != nil {
+				// Errors are sanitized as by the graphql handlers: planning
+				// errors describing the query are client errors, and are
+				// returned without the stack of the executor.
+				response.Errors = []string{graphql.SanitizeError(oops.Cause(err))}
+			}
+			responseJSON, err := json.Marshal(response)
+			if err != nil {
+				http.Error(w, graphql.SanitizeError(err), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(responseJSON)
+		}
+
+		if r.Method != "POST" {
+			writeResponse(nil, graphql.NewClientError("request must be a POST"))
+			return
+		}
+		var request costRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeResponse(nil, graphql.NewClientError("decoding request: %v", err))
+			return
+		}
+		query, err := graphql.ParseOperation(request.Query, request.Variables, request.OperationName)
+		if err != nil {
+			writeResponse(nil, err)
+			return
+		}
+		cost, err := e.EstimateCost(query)
+		writeResponse(cost, err)
+	})
+}
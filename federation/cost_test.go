@@ -0,0 +1,101 @@
+package federation
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorEstimateCost(t *testing.T) {
+	e, clients := createKitchenSinkExecutor(t)
+
+	cost, err := e.EstimateCost(graphql.MustParse(`{ s1fff { name s2ok s2bar { s1baz } } }`, nil))
+	require.NoError(t, err)
+	assert.Equal(t, &QueryCost{
+		// s1fff, name, s2ok, s2bar and s1baz, in three subqueries.
+		Cost:       8,
+		Fields:     5,
+		Subqueries: 3,
+		Depth:      3,
+		Hops:       3,
+		Services:   []string{"schema1", "schema2"},
+	}, cost)
+
+	// Estimating the cost does not run the query.
+	assert.Equal(t, 0, clients["schema1"].count)
+	assert.Equal(t, 0, clients["schema2"].count)
+
+	_, err = e.EstimateCost(graphql.MustParse(`{ s1fff { missing } }`, nil))
+	assert.Error(t, err)
+}
+
+func TestExecutorEstimateCostWithCostFunc(t *testing.T) {
+	// s2bar is expensive, as it would be charged by graphql.WithCostBudget.
+	e, _ := createKitchenSinkExecutor(t, WithCostFunc(func(selection *graphql.Selection, result interface{}) int64 {
+		if selection.Name == "s2bar" {
+			return 10
+		}
+		return graphql.DefaultCostFunc(selection, result)
+	}))
+
+	cost, err := e.EstimateCost(graphql.MustParse(`{ s1fff { name s2ok s2bar { s1baz } } }`, nil))
+	require.NoError(t, err)
+	// s1fff, name, s2ok and s1baz cost 1, s2bar 10, and three subqueries.
+	assert.Equal(t, int64(17), cost.Cost)
+	assert.Equal(t, int64(5), cost.Fields)
+}
+
+func TestCostHandler(t *testing.T) {
+	e, _ := createKitchenSinkExecutor(t)
+	handler := CostHandler(e)
+
+	testCases := []struct {
+		Name     string
+		Method   string
+		Body     string
+		Response string
+	}{
+		{
+			Name:     "query with variables",
+			Method:   "POST",
+			Body:     `{"query": "query Q($w: int64) { s1f { s2score(weight: $w) } }", "variables": {"w": 2}, "operationName": "Q"}`,
+			Response: `{"cost": {"cost": 4, "fields": 2, "subqueries": 2, "depth": 2, "hops": 2, "services": ["schema1", "schema2"]}, "errors": null}`,
+		},
+		{
+			Name:     "invalid query",
+			Method:   "POST",
+			Body:     `{"query": "{ s1f { missing } }"}`,
+			Response: `{"cost": null, "errors": ["unknown field missing on typ Foo"]}`,
+		},
+		{
+			Name:     "invalid body",
+			Method:   "POST",
+			Body:     `{"query": 1}`,
+			Response: `{"cost": null, "errors": ["decoding request: json: cannot unmarshal number into Go struct field costRequest.query of type string"]}`,
+		},
+		{
+			Name:     "not a POST",
+			Method:   "GET",
+			Response: `{"cost": null, "errors": ["request must be a POST"]}`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(testCase.Method, "/cost", strings.NewReader(testCase.Body)))
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.JSONEq(t, testCase.Response, w.Body.String())
+		})
+	}
+
+	// Planning errors describing the query are returned, as client errors.
+	limited, _ := createKitchenSinkExecutor(t, WithMaxServicesPerQuery(1))
+	w := httptest.NewRecorder()
+	CostHandler(limited).ServeHTTP(w, httptest.NewRequest("POST", "/cost", strings.NewReader(`{"query": "{ s1f { s2ok } }"}`)))
+	assert.JSONEq(t, `{"cost": null, "errors": ["query touches 2 services, more than the maximum of 1"]}`, w.Body.String())
+}
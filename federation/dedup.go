@@ -0,0 +1,146 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/samsarahq/go/oops"
+)
+
+// fetchDedup shares the federated object fetches of several plans executed
+// together, so that an object is only fetched once per selection set even if
+// more than one plan needs it.
+type fetchDedup struct {
+	mu      sync.Mutex
+	fetches map[string]*dedupedFetch
+}
+
+// errFetchAborted is returned to plans waiting on a fetch that was aborted
+// before it completed.
+var errFetchAborted = errors.New("deduplicated fetch aborted")
+
+// dedupedFetch is the result of fetching a single object. done is closed once
+// result or err is set.
+type dedupedFetch struct {
+	done   chan struct{}
+	result interface{}
+	err    error
+}
+
+func newFetchDedup() *fetchDedup {
+	return &fetchDedup{fetches: make(map[string]*dedupedFetch)}
+}
+
+// fetch returns the results of p for keys, calling run only with the keys that
+// no other plan has fetched or is fetching. The metadata returned is that of
+// the call to run, if any.
+//
+// Waiting on fetches started by other plans is cancelled with ctx, whether or
+// not the plan that started the fetch is cancelled too.
+func (d *fetchDedup) fetch(ctx context.Context, p *Plan, keys []interface{}, run func(keys []interface{}) ([]interface{}, interface{}, error)) ([]interface{}, interface{}, error) {
+	selectionSet, err := marshalPbSelections(p.SelectionSet)
+	if err != nil {
+		return nil, nil, oops.Wrapf(err, "marshaling selections")
+	}
+	prefix, err := json.Marshal([]interface{}{p.Service, p.Type, p.Kind, selectionSet})
+	if err != nil {
+		return nil, nil, oops.Wrapf(err, "computing fetch key")
+	}
+
+	ids := make([]string, len(keys))
+	for i, key := range keys {
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, nil, oops.Wrapf(err, "computing fetch key")
+		}
+		ids[i] = string(prefix) + string(k)
+	}
+
+	fetches := make([]*dedupedFetch, len(keys))
+	var missingKeys []interface{}
+	var missing []*dedupedFetch
+
+	d.mu.Lock()
+	for i, id := range ids {
+		f, ok := d.fetches[id]
+		if !ok {
+			f = &dedupedFetch{done: make(chan struct{})}
+			d.fetches[id] = f
+			missingKeys = append(missingKeys, keys[i])
+			missing = append(missing, f)
+		}
+		fetches[i] = f
+	}
+	d.mu.Unlock()
+
+	var metadata interface{}
+	if len(missing) > 0 {
+		// Other plans wait on the fetches registered above, so they must be
+		// completed even if run panics.
+		completed := false
+		defer func() {
+			if !completed {
+				for _, f := range missing {
+					f.err = errFetchAborted
+					close(f.done)
+				}
+			}
+		}()
+
+		var results []interface{}
+		results, metadata, err = run(missingKeys)
+		if err == nil && len(results) != len(missing) {
+			err = oops.Errorf("got %d results for %d keys", len(results), len(missing))
+		}
+		for i, f := range missing {
+			if err != nil {
+				f.err = err
+			} else {
+				f.result = results[i]
+			}
+			close(f.done)
+		}
+		completed = true
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	res := make([]interface{}, len(fetches))
+	for i, f := range fetches {
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+		if f.err != nil {
+			return nil, nil, f.err
+		}
+		// Results are stitched together in place, so every plan needs its
+		// own copy.
+		res[i] = deepCopy(f.result)
+	}
+	return res, metadata, nil
+}
+
+// deepCopy copies a value decoded from JSON.
+func deepCopy(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, e := range v {
+			c[i] = deepCopy(e)
+		}
+		return c
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			c[k] = deepCopy(e)
+		}
+		return c
+	default:
+		return v
+	}
+}
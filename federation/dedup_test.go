@@ -0,0 +1,112 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchDedupCancellation(t *testing.T) {
+	p := &Plan{
+		Service:      "s1",
+		Type:         "Foo",
+		Kind:         "query",
+		SelectionSet: &graphql.SelectionSet{Selections: []*graphql.Selection{{Name: "name", Alias: "name"}}},
+	}
+	keys := []interface{}{map[string]interface{}{"id": 1}, map[string]interface{}{"id": 2}}
+
+	for _, respectsCancellation := range []bool{true, false} {
+		name := "fetch ignores cancellation"
+		if respectsCancellation {
+			name = "fetch respects cancellation"
+		}
+		t.Run(name, func(t *testing.T) {
+			d := newFetchDedup()
+			ctx, cancel := context.WithCancel(context.Background())
+			started := make(chan struct{})
+			release := make(chan struct{})
+			defer close(release)
+
+			run := func(keys []interface{}) ([]interface{}, interface{}, error) {
+				close(started)
+				if respectsCancellation {
+					<-ctx.Done()
+					return nil, nil, ctx.Err()
+				}
+				<-release
+				return make([]interface{}, len(keys)), nil, nil
+			}
+
+			// The first fetch is issued, the others wait on it.
+			go d.fetch(ctx, p, keys, run)
+			<-started
+
+			const waiters = 5
+			errs := make(chan error, waiters)
+			var wg sync.WaitGroup
+			for i := 0; i < waiters; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, _, err := d.fetch(ctx, p, keys, func([]interface{}) ([]interface{}, interface{}, error) {
+						return nil, nil, errors.New("fetched twice")
+					})
+					errs <- err
+				}()
+			}
+
+			cancel()
+			done := make(chan struct{})
+			go func() {
+				wg.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("waiters did not return after cancellation")
+			}
+
+			close(errs)
+			for err := range errs {
+				assert.True(t, errors.Is(err, context.Canceled), "expected a context error, got %v", err)
+			}
+		})
+	}
+
+	t.Run("fetch panics", func(t *testing.T) {
+		d := newFetchDedup()
+		started := make(chan struct{})
+		release := make(chan struct{})
+		go func() {
+			defer func() { recover() }()
+			d.fetch(context.Background(), p, keys, func([]interface{}) ([]interface{}, interface{}, error) {
+				close(started)
+				<-release
+				panic("boom")
+			})
+		}()
+		<-started
+
+		errs := make(chan error, 1)
+		go func() {
+			_, _, err := d.fetch(context.Background(), p, keys, nil)
+			errs <- err
+		}()
+		close(release)
+
+		select {
+		case err := <-errs:
+			require.Error(t, err)
+			assert.Equal(t, errFetchAborted, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("waiter did not return after the fetch panicked")
+		}
+	})
+}
@@ -0,0 +1,206 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/samsarahq/go/oops"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WithElementErrors isolates the errors of federated lookups to the objects
+// that caused them. When fetching several objects from a service fails, eg.
+// enriching the elements of a list, the objects are fetched again in halves,
+// and the halves that fail are halved again, until the objects that fail on
+// their own are found; the fields of those objects are resolved as null, and
+// the other objects keep their fields. An error is recorded in the context's
+// PartialErrors for every failed object, with its response path, eg.
+// ["s1fff", 3].
+//
+// Only errors that can be caused by a single object are isolated: lookups
+// that fail with transient errors, eg. because the service is unavailable,
+// or with client errors are not retried, see WithElementErrorClassifier. At
+// most WithMaxElementErrorRequests requests are made to isolate the errors of
+// a lookup; the objects that are not isolated by then fail together.
+//
+// Queries whose context does not collect partial errors with
+// WithPartialErrors fail instead, with a LookupError naming only the objects
+// that failed, eg. s1fff.3. Lookups that select non-null fields, which cannot
+// be resolved as null, still fail the query.
+func WithElementErrors() ExecutorOption {
+	return func(e *Executor) {
+		e.elementErrors = true
+	}
+}
+
+// WithElementErrorClassifier sets the classifier that decides which errors of
+// failed lookups WithElementErrors isolates: only permanent errors are. By
+// default, DefaultElementErrorClassifier is used.
+func WithElementErrorClassifier(classifier ErrorClassifier) ExecutorOption {
+	return func(e *Executor) {
+		e.elementErrorClassifier = classifier
+	}
+}
+
+// WithMaxElementErrorRequests limits the number of requests WithElementErrors
+// makes to isolate the errors of one failed lookup. By default, at most
+// defaultMaxElementErrorRequests requests are made.
+func WithMaxElementErrorRequests(max int) ExecutorOption {
+	return func(e *Executor) {
+		e.maxElementErrorRequests = max
+	}
+}
+
+// defaultMaxElementErrorRequests is the default limit on the number of
+// requests made to isolate the errors of one failed lookup, enough to find
+// a few failing objects among thousands.
+const defaultMaxElementErrorRequests = 32
+
+// DefaultElementErrorClassifier classifies the errors of failed lookups for
+// WithElementErrors. Deadlines and transport errors, such as unavailable gRPC
+// services and network errors, are transient, and cancellations and graphql
+// client errors are client errors. All other errors, eg. the errors of
+// resolvers, are permanent.
+var DefaultElementErrorClassifier ErrorClassifier = ErrorClassifierFunc(classifyElementError)
+
+func classifyElementError(err error) ErrorCategory {
+	cause := oops.Cause(err)
+	if category := classifyError(cause); category != ErrorCategoryTransient || errors.Is(cause, context.DeadlineExceeded) {
+		return category
+	}
+	var netErr net.Error
+	if errors.As(cause, &netErr) || errors.Is(cause, io.ErrUnexpectedEOF) {
+		return ErrorCategoryTransient
+	}
+	if s, ok := status.FromError(cause); ok {
+		switch s.Code() {
+		case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+			return ErrorCategoryTransient
+		case codes.Canceled, codes.InvalidArgument, codes.Unauthenticated, codes.PermissionDenied:
+			return ErrorCategoryClient
+		}
+	}
+	return ErrorCategoryPermanent
+}
+
+// collectsPartialErrors returns whether ctx records partial errors, see
+// WithPartialErrors.
+func collectsPartialErrors(ctx context.Context) bool {
+	_, ok := ctx.Value(partialErrorsKey{}).(*partialErrors)
+	return ok
+}
+
+// isolateElementErrors finds the objects of p that fail to be fetched on
+// their own with run, after fetching all the objects with keys together
+// failed with err, by fetching them again in halves. The fields of the
+// objects that fail are resolved as null, or, if ctx does not collect partial
+// errors, a LookupError for those objects is returned. It returns false if
+// err is not permanent or if the fields of p cannot be resolved as null.
+func (e *Executor) isolateElementErrors(ctx context.Context, p *Plan, planner *Planner, keys []interface{}, err error, run func(ctx context.Context, keys []interface{}) ([]interface{}, interface{}, error)) ([]interface{}, error, bool) {
+	classifier := e.elementErrorClassifier
+	if classifier == nil {
+		classifier = DefaultElementErrorClassifier
+	}
+	if classifier.Classify(err) != ErrorCategoryPermanent {
+		return nil, nil, false
+	}
+	nulls, ok := nullResults(planner, p, keys)
+	if !ok {
+		return nil, nil, false
+	}
+	paths := responsePathsFromContext(ctx)
+	pathsOf := func(lo, hi int) [][]interface{} {
+		if hi > len(paths) {
+			return nil
+		}
+		return paths[lo:hi]
+	}
+
+	// budget is the number of requests that can still be made.
+	var budgetMu sync.Mutex
+	budget := e.maxElementErrorRequests
+	if budget <= 0 {
+		budget = defaultMaxElementErrorRequests
+	}
+	reserve := func(n int) bool {
+		budgetMu.Lock()
+		defer budgetMu.Unlock()
+		if budget < n {
+			return false
+		}
+		budget -= n
+		return true
+	}
+
+	res := make([]interface{}, len(keys))
+	errs := make([]error, len(keys))
+	// bisect fetches the two halves of keys[lo:hi], which failed together
+	// with err, and the halves of the halves that fail with permanent errors.
+	// The objects that cannot be fetched again fail with err.
+	var bisect func(lo, hi int, err error)
+	bisect = func(lo, hi int, err error) {
+		if hi-lo == 1 || classifier.Classify(err) != ErrorCategoryPermanent || ctx.Err() != nil || !reserve(2) {
+			for i := lo; i < hi; i++ {
+				errs[i] = err
+			}
+			return
+		}
+		mid := (lo + hi) / 2
+		var wg sync.WaitGroup
+		for _, half := range [][2]int{{lo, mid}, {mid, hi}} {
+			lo, hi := half[0], half[1]
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				halfRes, _, err := run(withResponsePaths(ctx, pathsOf(lo, hi)), keys[lo:hi])
+				if err == nil && len(halfRes) != hi-lo {
+					err = oops.Errorf("got %d results for %d keys", len(halfRes), hi-lo)
+				}
+				if err != nil {
+					bisect(lo, hi, err)
+					return
+				}
+				copy(res[lo:hi], halfRes)
+			}()
+		}
+		wg.Wait()
+	}
+	bisect(0, len(keys), err)
+
+	if !collectsPartialErrors(ctx) {
+		var failed [][]interface{}
+		var firstErr error
+		for i, err := range errs {
+			if err == nil {
+				continue
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed = append(failed, []interface{}{i})
+		}
+		if firstErr == nil {
+			return res, nil, true
+		}
+		lookupErr := newLookupError(p, planner.internalFieldName, keys, firstErr).(*LookupError)
+		lookupErr.Paths = failed
+		return nil, lookupErr, true
+	}
+
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		res[i] = nulls[i]
+		partialErr := &PartialError{Service: p.Service, Err: err}
+		if i < len(paths) {
+			partialErr.Paths = [][]interface{}{paths[i]}
+		}
+		recordPartialError(ctx, partialErr)
+	}
+	return res, nil, true
+}
@@ -0,0 +1,170 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestExecutorElementErrors(t *testing.T) {
+	ctx := context.Background()
+
+	s2 := schemabuilder.NewSchemaWithName("schema2")
+	s2.Query().FieldFunc("s2root", func() string {
+		return "hello"
+	})
+	foo := s2.Object("Foo", Foo{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Foo }) []*Foo {
+		return args.Keys
+	}))
+	foo.FieldFunc("s2enrich", func(in *Foo) (*int64, error) {
+		if in.Name == "bob" {
+			return nil, errors.New("cannot enrich bob")
+		}
+		n := int64(len(in.Name))
+		return &n, nil
+	})
+	foo.FieldFunc("s2enrichNonNull", func(in *Foo) (int64, error) {
+		if in.Name == "bob" {
+			return 0, errors.New("cannot enrich bob")
+		}
+		return int64(len(in.Name)), nil
+	})
+
+	s1 := buildTestSchema1()
+	s1.Query().FieldFunc("s1many", func() []*Foo {
+		foos := make([]*Foo, 16)
+		for i := range foos {
+			foos[i] = &Foo{Name: fmt.Sprintf("foo%d", i)}
+		}
+		foos[11].Name = "bob"
+		return foos
+	})
+
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": s1,
+		"schema2": s2,
+	})
+	require.NoError(t, err)
+	syncer := NewIntrospectionSchemaSyncer(ctx, execs, nil)
+	schema2 := &countingExecutorClient{ExecutorClient: execs["schema2"]}
+	execs = map[string]ExecutorClient{"schema1": execs["schema1"], "schema2": schema2}
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: syncer}, WithElementErrors())
+	require.NoError(t, err)
+
+	t.Run("failed elements are null", func(t *testing.T) {
+		ctx := WithPartialErrors(ctx)
+		res, _, err := e.Execute(ctx, graphql.MustParse(`{ s1fff { name s2enrich } }`, map[string]interface{}{}), nil)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"s1fff": []interface{}{
+				map[string]interface{}{"name": "jimbo", "s2enrich": json.Number("5")},
+				map[string]interface{}{"name": "bob", "s2enrich": nil},
+			},
+		}, res)
+
+		errs := PartialErrors(ctx)
+		require.Len(t, errs, 1)
+		assert.Equal(t, "schema2", errs[0].Service)
+		assert.Equal(t, [][]interface{}{{"s1fff", 1}}, errs[0].Paths)
+		assert.Contains(t, errs[0].Error(), "cannot enrich bob")
+	})
+
+	t.Run("failed elements are found by halving", func(t *testing.T) {
+		ctx := WithPartialErrors(ctx)
+		schema2.reset()
+		_, _, err := e.Execute(ctx, graphql.MustParse(`{ s1many { name s2enrich } }`, map[string]interface{}{}), nil)
+		require.NoError(t, err)
+		errs := PartialErrors(ctx)
+		require.Len(t, errs, 1)
+		assert.Equal(t, [][]interface{}{{"s1many", 11}}, errs[0].Paths)
+		// The failed lookup, then both halves of each of the 4 failing
+		// halves.
+		assert.Equal(t, 1+2*4, schema2.count)
+	})
+
+	t.Run("without partial errors the failed elements fail the query", func(t *testing.T) {
+		_, _, err := e.Execute(ctx, graphql.MustParse(`{ s1fff { name s2enrich } }`, map[string]interface{}{}), nil)
+		require.Error(t, err)
+		lookupErr, ok := oops.Cause(err).(*LookupError)
+		require.True(t, ok, "expected a LookupError, got %v", err)
+		assert.Equal(t, [][]interface{}{{"s1fff", 1}}, lookupErr.Paths)
+		assert.Contains(t, err.Error(), "for s1fff.1: ")
+		assert.Contains(t, err.Error(), "cannot enrich bob")
+	})
+
+	t.Run("non-null fields fail the query", func(t *testing.T) {
+		ctx := WithPartialErrors(ctx)
+		_, _, err := e.Execute(ctx, graphql.MustParse(`{ s1fff { name s2enrichNonNull } }`, map[string]interface{}{}), nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot enrich bob")
+		assert.Empty(t, PartialErrors(ctx))
+	})
+
+	t.Run("isolation is limited to permanent errors", func(t *testing.T) {
+		e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: syncer}, WithElementErrors(), WithElementErrorClassifier(ErrorClassifierFunc(func(err error) ErrorCategory {
+			return ErrorCategoryTransient
+		})))
+		require.NoError(t, err)
+		ctx := WithPartialErrors(ctx)
+		schema2.reset()
+		_, _, err = e.Execute(ctx, graphql.MustParse(`{ s1many { name s2enrich } }`, map[string]interface{}{}), nil)
+		require.Error(t, err)
+		assert.Equal(t, 1, schema2.count)
+	})
+
+	t.Run("isolation is limited to a number of requests", func(t *testing.T) {
+		e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: syncer}, WithElementErrors(), WithMaxElementErrorRequests(2))
+		require.NoError(t, err)
+		ctx := WithPartialErrors(ctx)
+		schema2.reset()
+		res, _, err := e.Execute(ctx, graphql.MustParse(`{ s1many { name s2enrich } }`, map[string]interface{}{}), nil)
+		require.NoError(t, err)
+		assert.Equal(t, 1+2, schema2.count)
+
+		// The second half failed, and could not be halved again.
+		elements := res.(map[string]interface{})["s1many"].([]interface{})
+		for i, element := range elements {
+			if i < 8 {
+				assert.NotNil(t, element.(map[string]interface{})["s2enrich"])
+			} else {
+				assert.Nil(t, element.(map[string]interface{})["s2enrich"])
+			}
+		}
+		assert.Len(t, PartialErrors(ctx), 8)
+	})
+
+	t.Run("without the option the query fails", func(t *testing.T) {
+		e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: syncer})
+		require.NoError(t, err)
+		_, _, err = e.Execute(ctx, graphql.MustParse(`{ s1fff { name s2enrich } }`, map[string]interface{}{}), nil)
+		require.Error(t, err)
+	})
+}
+
+func TestDefaultElementErrorClassifier(t *testing.T) {
+	for _, tc := range []struct {
+		err      error
+		category ErrorCategory
+	}{
+		{errors.New("cannot enrich bob"), ErrorCategoryPermanent},
+		{oops.Wrapf(context.DeadlineExceeded, "executing query"), ErrorCategoryTransient},
+		{context.Canceled, ErrorCategoryClient},
+		{graphql.NewClientError("invalid query"), ErrorCategoryClient},
+		{status.Error(codes.Unavailable, "connection refused"), ErrorCategoryTransient},
+		{status.Error(codes.Unknown, "cannot enrich bob"), ErrorCategoryPermanent},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, ErrorCategoryTransient},
+	} {
+		assert.Equal(t, tc.category, DefaultElementErrorClassifier.Classify(tc.err), tc.err.Error())
+	}
+}
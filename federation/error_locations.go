@@ -0,0 +1,48 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// errorLocationsHeader is the gRPC trailer that carries the locations in the
+// query of the error a server fails a request with, see
+// graphql.ErrorLocations.
+const errorLocationsHeader = "x-graphql-error-locations"
+
+// SetErrorLocations sends the locations of err, if any, in the trailer of the
+// gRPC request with ctx, so that GrpcExecutorClient returns an error with
+// them, eg. for a gateway to write them in the "locations" of its errors.
+// Server sets them for the errors it returns.
+func SetErrorLocations(ctx context.Context, err error) {
+	locations := graphql.ErrorLocations(oops.Cause(err))
+	if len(locations) == 0 {
+		return
+	}
+	data, err := json.Marshal(locations)
+	if err != nil {
+		return
+	}
+	// SetTrailer fails outside of gRPC requests, which have no trailer.
+	grpc.SetTrailer(ctx, metadata.Pairs(errorLocationsHeader, string(data)))
+}
+
+// withErrorLocations returns err, the error of a gRPC request, as a client
+// error at the location sent in its trailer by SetErrorLocations, if any.
+func withErrorLocations(err error, trailer metadata.MD) error {
+	values := trailer.Get(errorLocationsHeader)
+	if len(values) == 0 {
+		return err
+	}
+	var locations []graphql.Location
+	if json.Unmarshal([]byte(values[0]), &locations) != nil || len(locations) == 0 {
+		return err
+	}
+	return graphql.NewClientErrorAt(&locations[0], "%s", status.Convert(err).Message())
+}
@@ -0,0 +1,62 @@
+package federation
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/require"
+)
+
+type BarResult struct {
+	schemabuilder.Union
+	*Bar
+	*schemabuilder.Error
+}
+
+func TestExecutorErrorUnion(t *testing.T) {
+	ctx := context.Background()
+
+	s2 := schemabuilder.NewSchemaWithName("schema2")
+	s2.Query().FieldFunc("s2root", func() string {
+		return "hello"
+	})
+	foo := s2.Object("Foo", Foo{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Foo }) []*Foo {
+		return args.Keys
+	}))
+	foo.FieldFunc("s2barResult", func(in *Foo) *BarResult {
+		if in.Name == "bob" {
+			return &BarResult{Error: schemabuilder.NewError(fmt.Errorf("%s has no bar", in.Name))}
+		}
+		return &BarResult{Bar: &Bar{Id: int64(len(in.Name))}}
+	})
+	s2.Object("Bar", Bar{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Bar }) []*Bar {
+		return args.Keys
+	}))
+
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": s2,
+	})
+	require.NoError(t, err)
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	require.NoError(t, err)
+
+	runAndValidateQueryResults(t, ctx, e, `{
+		s1fff {
+			name
+			s2barResult {
+				__typename
+				... on Bar { id s1baz }
+				... on Error { message }
+			}
+		}
+	}`, `
+		{
+			"s1fff": [
+				{"name": "jimbo", "s2barResult": {"__typename": "Bar", "id": 5, "s1baz": "5"}},
+				{"name": "bob", "s2barResult": {"__typename": "Error", "message": "bob has no bar"}}
+			]
+		}`)
+}
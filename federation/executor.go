@@ -3,8 +3,11 @@ package federation
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 	"bytes"
@@ -14,6 +17,7 @@ import (
 
 	"github.com/samsarahq/thunder/graphql"
 	"github.com/samsarahq/thunder/graphql/introspection"
+	"github.com/samsarahq/thunder/logger"
 )
 
 const keyField = "__key"
@@ -27,6 +31,9 @@ type QueryRequest struct {
 	// Metadata is an optional custom field which can be used to send metadata such as authentication
 	// along with the query.
 	Metadata interface{}
+	// RequestID is the ID of the gateway request the query is part of, if
+	// any. It is set from the context with WithRequestID.
+	RequestID string
 }
 
 // QueryResponse is the marshalled json reponse from federated GraphQL servers.
@@ -35,6 +42,11 @@ type QueryResponse struct {
 	// Metadata is an optional custom field which can be used to receive metadata such as query duration
 	// along with the response.
 	Metadata interface{}
+	// Extensions are the extensions of the GraphQL response, eg. timings or
+	// cache hints, which are collected in contexts created with
+	// WithExtensions. Servers return the extensions set with
+	// SetResponseExtension.
+	Extensions map[string]interface{}
 }
 
 // ExecutorClient is used to send GraphQL requests from the gateway service to federated GraphQL servers.
@@ -42,12 +54,164 @@ type ExecutorClient interface {
 	Execute(ctx context.Context, request *QueryRequest) (*QueryResponse, error)
 }
 
+// GraphqlExecutor plans and executes queries across the federated GraphQL servers.
+// It is implemented by Executor, and can be implemented by decorators that wrap
+// an Executor to add behavior such as caching, authorization or metrics.
+type GraphqlExecutor interface {
+	Plan(query *graphql.Query) (*Plan, error)
+	Execute(ctx context.Context, query *graphql.Query, metadata interface{}) (interface{}, []interface{}, error)
+}
+
+var _ GraphqlExecutor = &Executor{}
+
 // Executor has a map of all the executor clients such that it can execute a
 // subquery on any of the federated servers.
 // The planner allows it to coordinate the subqueries being sent to the federated servers
 type Executor struct {
 	Executors map[string]ExecutorClient
 	syncer    *Syncer
+
+	// maxServicesPerQuery limits the number of distinct services a single
+	// query can be dispatched to. A value of 0 means there is no limit.
+	maxServicesPerQuery int
+	// maxHops limits the number of sequential subqueries a single query can
+	// require. A value of 0 means there is no limit.
+	maxHops int
+	// externalFields are fields that services reference but do not own.
+	externalFields []externalField
+	// requiredFields are the sibling fields that fields need to be resolved.
+	requiredFields []requiredFields
+	// providedFields are the fields that services can resolve inline below
+	// other fields.
+	providedFields []providedFields
+	// rootFieldOwners are the services that resolve shared root fields.
+	rootFieldOwners []rootFieldOwner
+	// fallbackService resolves the fields that no other service resolves.
+	fallbackService string
+	// rewriteSubquery rewrites subqueries before they are sent to services.
+	rewriteSubquery SubqueryRewriter
+	// generateRequestID generates request IDs for queries without one.
+	generateRequestID func() string
+	// disableIntrospection rejects queries for __schema and __type.
+	disableIntrospection bool
+	// nullMissingFields resolves fields that services cannot resolve as null.
+	nullMissingFields bool
+	// responseMiddlewares post-process the results of queries.
+	responseMiddlewares []ResponseMiddleware
+	// tracer starts spans for the requests sent to services.
+	tracer Tracer
+	// validateResults checks merged results against the merged schema.
+	validateResults bool
+	// maxResponseSize limits the size in bytes of the serialized result of
+	// a single query. A value of 0 means there is no limit.
+	maxResponseSize int64
+	// partialTimeout is the time after which subqueries are resolved as
+	// null. A value of 0 means there is no timeout.
+	partialTimeout time.Duration
+	// fieldTimeouts limits how long subqueries selecting fields with
+	// timeouts may take.
+	fieldTimeouts bool
+	// fetchCache caches the fields of federated objects across queries.
+	fetchCache *fetchCache
+	// requestLimiter, if set, limits the number of concurrent requests to
+	// services.
+	requestLimiter *requestLimiter
+	// elementErrors isolates the errors of federated lookups to the objects
+	// that caused them.
+	elementErrors bool
+	// elementErrorClassifier classifies the errors of failed lookups, of
+	// which only permanent ones are isolated, see WithElementErrorClassifier.
+	elementErrorClassifier ErrorClassifier
+	// maxElementErrorRequests limits the number of requests made to isolate
+	// the errors of one failed lookup.
+	maxElementErrorRequests int
+	// maxKeys limits the number of keys a single query can send to each
+	// service.
+	maxKeys map[string]int
+	// maxAliases limits the number of times a field can be selected in one
+	// selection set. A value of 0 means there is no limit.
+	maxAliases int
+	// internalFieldName is the alias under which the "_federation" fields of
+	// services are selected, if not "_federation".
+	internalFieldName string
+	// commonUnionFields selects the fields common to all members of unions
+	// on the unions themselves.
+	commonUnionFields bool
+	// strictTypenames fails queries with union members of unknown types.
+	strictTypenames bool
+	// planLogger, if set, logs the plans of queries that fail.
+	planLogger logger.Logger
+	// mockedNullServices are the services whose fields are all resolved as
+	// null, without sending them requests.
+	mockedNullServices map[string]bool
+	// virtualFields are the fields computed at the gateway, added with
+	// AddVirtualField.
+	virtualFields   []virtualField
+	virtualFieldsMu sync.Mutex
+	// costFunc is the cost of the fields of queries, as estimated by
+	// EstimateCost.
+	costFunc graphql.CostFunc
+}
+
+// ExecutorOption configures optional behavior of an Executor.
+type ExecutorOption func(*Executor)
+
+// WithMaxResponseSize fails queries whose result exceeds n bytes once
+// serialized as JSON, so that a fan-out that produces an enormous response is
+// not returned to clients. The size is that of the final result, after it is
+// stitched together and post-processed, rather than that of the responses of
+// services, which include fields the executor adds to subqueries and miss
+// fields served from the fetch cache.
+func WithMaxResponseSize(n int64) ExecutorOption {
+	return func(e *Executor) {
+		e.maxResponseSize = n
+	}
+}
+
+// SubqueryRewriter rewrites the selection set of a subquery before it is sent
+// to service. It must return a new selection set rather than modify the one
+// passed in, which is part of the plan.
+type SubqueryRewriter func(service string, selectionSet *graphql.SelectionSet) *graphql.SelectionSet
+
+// WithSubqueryRewriter rewrites every subquery with rewrite right before it is
+// sent to a service, eg. to add a field the service requires. Fields that
+// rewrite adds are removed from the service's response, so they are never
+// returned to clients.
+func WithSubqueryRewriter(rewrite SubqueryRewriter) ExecutorOption {
+	return func(e *Executor) {
+		e.rewriteSubquery = rewrite
+	}
+}
+
+// ErrIntrospectionDisabled is returned when planning an introspection query
+// on an executor created with WithIntrospectionDisabled.
+var ErrIntrospectionDisabled = errors.New("introspection is disabled")
+
+// WithIntrospectionDisabled rejects queries that select the introspection
+// fields __schema or __type, so that clients of a public gateway cannot
+// enumerate the merged schema.
+func WithIntrospectionDisabled() ExecutorOption {
+	return func(e *Executor) {
+		e.disableIntrospection = true
+	}
+}
+
+// WithMaxServicesPerQuery rejects queries that would be dispatched to more
+// than n distinct services during planning.
+func WithMaxServicesPerQuery(n int) ExecutorOption {
+	return func(e *Executor) {
+		e.maxServicesPerQuery = n
+	}
+}
+
+// WithMaxHops rejects queries that would require a chain of more than n
+// sequential subqueries during planning, eg. a query that fetches an object
+// from one service, then fields of that object from a second service, then
+// fields of the second service's objects from a third.
+func WithMaxHops(n int) ExecutorOption {
+	return func(e *Executor) {
+		e.maxHops = n
+	}
 }
 
 // Syncer checks if there is a new schema available and then updates the planner as needed
@@ -71,15 +235,41 @@ func (e *Executor) setPlanner(p *Planner) {
 }
 
 func fetchSchema(ctx context.Context, e ExecutorClient, metadata interface{}) (*QueryResponse, error) {
-	query, err := graphql.Parse(introspection.IntrospectionQuery, map[string]interface{}{})
-	if err != nil {
-		return nil, err
+	var firstErr error
+	for _, source := range introspectionQueries {
+		query, err := graphql.Parse(source, map[string]interface{}{})
+		if err != nil {
+			return nil, err
+		}
+
+		// Schemas are polled in the background, where a panicking client
+		// would crash the gateway.
+		response, err := safeExecute(ctx, e, &QueryRequest{
+			Query:    query,
+			Metadata: metadata,
+		})
+		if err == nil {
+			return response, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
 	}
+	return nil, firstErr
+}
 
-	return e.Execute(ctx, &QueryRequest{
-		Query:    query,
-		Metadata: metadata,
-	})
+// introspectionQueries are the queries that fetchSchema tries in turn. Servers
+// that predate the extensions of the federation introspection query, or the
+// deprecation of arguments and input fields, reject the earlier queries;
+// their fields have no timeouts, and their arguments and input fields are
+// never deprecated.
+var introspectionQueries = []string{
+	introspection.FederationIntrospectionQuery,
+	introspection.IntrospectionQuery,
+	introspection.LegacyIntrospectionQuery,
 }
 
 type SchemaSyncerConfig struct {
@@ -87,7 +277,7 @@ type SchemaSyncerConfig struct {
 	SchemaSyncIntervalSeconds func(ctx context.Context) int64
 }
 
-func NewExecutor(ctx context.Context, executors map[string]ExecutorClient, c *SchemaSyncerConfig) (*Executor, error) {
+func NewExecutor(ctx context.Context, executors map[string]ExecutorClient, c *SchemaSyncerConfig, opts ...ExecutorOption) (*Executor, error) {
 	if c.SchemaSyncer == nil {
 		return nil, oops.Errorf("SchemaSyncer should not be nil")
 	}
@@ -111,17 +301,63 @@ func NewExecutor(ctx context.Context, executors map[string]ExecutorClient, c *Sc
 			planner:      planner,
 		},
 	}
+	for _, opt := range opts {
+		opt(executor)
+	}
+	if err := executor.configurePlanner(planner); err != nil {
+		return nil, err
+	}
 	go executor.poll(ctx)
 	return executor, nil
 }
 
+// configurePlanner applies the executor's options that affect planning to a
+// newly fetched planner.
+func (e *Executor) configurePlanner(planner *Planner) error {
+	if err := e.applyProvidedFields(planner); err != nil {
+		return oops.Wrapf(err, "invalid provided fields")
+	}
+	if err := e.applyExternalFields(planner); err != nil {
+		return oops.Wrapf(err, "invalid external fields")
+	}
+	if err := e.applyRequiredFields(planner); err != nil {
+		return oops.Wrapf(err, "invalid required fields")
+	}
+	if err := e.applyRootFieldOwners(planner); err != nil {
+		return oops.Wrapf(err, "invalid root field owners")
+	}
+	if err := e.applyFallbackExecutor(planner); err != nil {
+		return oops.Wrapf(err, "invalid fallback executor")
+	}
+	if err := e.applyVirtualFields(planner); err != nil {
+		return oops.Wrapf(err, "invalid virtual fields")
+	}
+	if err := e.applyMaxAliases(planner); err != nil {
+		return oops.Wrapf(err, "invalid max aliases")
+	}
+	if err := e.applyInternalFieldName(planner); err != nil {
+		return oops.Wrapf(err, "invalid internal field name")
+	}
+	if err := e.applyCommonUnionFields(planner); err != nil {
+		return oops.Wrapf(err, "invalid common union fields")
+	}
+	if err := e.applyFieldTimeouts(planner); err != nil {
+		return oops.Wrapf(err, "invalid field timeouts")
+	}
+	return nil
+}
+
 func (e *Executor) poll(ctx context.Context) error {
 	for {
 		select {
 		case <-e.syncer.ticker.C:
 			newPlanner, err := e.syncer.schemaSyncer.FetchPlanner(ctx)
+			if err == nil && newPlanner != nil {
+				err = e.configurePlanner(newPlanner)
+			}
 			if err == nil && newPlanner != nil {
 				e.setPlanner(newPlanner)
+				e.refreshFieldCheckers(ctx)
 			}
 		case <-ctx.Done():
 			e.syncer.ticker.Stop()
@@ -131,12 +367,62 @@ func (e *Executor) poll(ctx context.Context) error {
 	return nil
 }
 
+// safeExecute executes request with client, recovering a panic in the client
+// as a *graphql.PanicError so that it fails the query rather than crashing the
+// gateway.
+func safeExecute(ctx context.Context, client ExecutorClient, request *QueryRequest) (response *QueryResponse, err error) {
+	defer func() {
+		if panicErr := recover(); panicErr != nil {
+			response, err = nil, graphql.NewPanicError(panicErr)
+		}
+	}()
+	return client.Execute(ctx, request)
+}
+
 func (e *Executor) runOnService(ctx context.Context, service string, typName string, keys []interface{}, kind string, selectionSet *graphql.SelectionSet, metadata interface{}, planner *Planner) ([]interface{}, interface{}, error) {
 	// Execute query on specified service
 	executorClient, ok := e.Executors[service]
+	if override, overridden := clientOverride(ctx, service); overridden && ok {
+		executorClient = override
+	}
+	if !ok && service == introspectionServiceName && planner.introspectionClient != nil {
+		executorClient, ok = planner.introspectionClient, true
+	}
 	if !ok {
 		return nil, nil, oops.Errorf("service %s not recognized", service)
 	}
+	if e.mockedNullServices[service] {
+		return nullObjects(typName, keys, selectionSet), nil, nil
+	}
+
+	// Fields added by the rewriter are removed from the results using the
+	// original selections.
+	originalSelectionSet := selectionSet
+	if e.rewriteSubquery != nil {
+		selectionSet = e.rewriteSubquery(service, selectionSet)
+	}
+	originals := planner.namespaces[service]
+
+	// Fields that the service cannot resolve are resolved as null.
+	var missing *missingFields
+	if checker, ok := executorClient.(FieldChecker); ok && e.nullMissingFields {
+		var typ graphql.Type
+		switch {
+		case keys != nil:
+			typ = planner.flattener.types[typName]
+		case kind == mutationString:
+			typ = planner.schema.Schema.Mutation
+		default:
+			typ = planner.schema.Schema.Query
+		}
+		if typ != nil {
+			selectionSet, missing = removeMissingFields(checker, typ, selectionSet, originals)
+		}
+	}
+
+	if len(originals) > 0 {
+		selectionSet = namespaceSelectionSet(selectionSet, originals)
+	}
 
 	// If it is not a root query, nest the subquery on the federation field
 	// and pass the keys in to find the object that the subquery is nested on
@@ -149,6 +435,8 @@ func (e *Executor) runOnService(ctx context.Context, service string, typName str
 	//   }
 	// }
 	isRoot := keys == nil
+	var keyed keySelections
+	var newKeys []interface{}
 	if !isRoot {
 		federatedName := fmt.Sprintf("%s_%s", service, typName)
 
@@ -168,7 +456,7 @@ func (e *Executor) runOnService(ctx context.Context, service string, typName str
 
 		// If it is a federated key on that service, add it to the input args
 		// passed in to the federated field func as one of the federated keys
-		newKeys := make([]interface{}, len(keys))
+		newKeys = make([]interface{}, len(keys))
 
 		for i, key := range keys {
 			keyFields, ok := key.(map[string]interface{})
@@ -183,6 +471,9 @@ func (e *Executor) runOnService(ctx context.Context, service string, typName str
 				for fieldName, field := range rootObject.Fields {
 					if fieldName == name {
 						_, ok := field.FederatedKey[service]
+						if info := planner.schema.Fields[field]; info != nil && info.OptionalKey[service] {
+							ok = true
+						}
 						if ok {
 							newKey[name] = keyField
 						}
@@ -191,21 +482,28 @@ func (e *Executor) runOnService(ctx context.Context, service string, typName str
 			}
 			newKeys[i] = newKey
 		}
+		// Select the key fields too, to match the results to the keys even
+		// if the service returns objects for keys that were not requested.
+		var names []string
+		for name, field := range rootObject.Fields {
+			if field.FederatedKey[service] {
+				names = append(names, name)
+			}
+		}
+		selectionSet, keyed = addKeySelections(selectionSet, planner.internalFieldName, names)
 
 		selectionSet = &graphql.SelectionSet{
 			Selections: []*graphql.Selection{
 				{
 					Name:  federationField,
-					Alias: federationField,
+					Alias: planner.internalFieldName,
 					Args:  map[string]interface{}{},
 					SelectionSet: &graphql.SelectionSet{
 						Selections: []*graphql.Selection{
 							{
 								Name:  federatedName,
 								Alias: federatedName,
-								UnparsedArgs: map[string]interface{}{
-									"keys": newKeys,
-								},
+								UnparsedArgs: lookupArguments(ctx, planner, federatedName, newKeys),
 								SelectionSet: selectionSet,
 							},
 						},
@@ -223,43 +521,149 @@ func (e *Executor) runOnService(ctx context.Context, service string, typName str
 		},
 		Metadata: metadata,
 	}
-	response, err := executorClient.Execute(ctx, request)
-	if err != nil {
-		return nil, nil, oops.Wrapf(err, "execute remotely")
+	request.RequestID, _ = RequestIDFromContext(ctx)
+	if !isRoot {
+		ctx = withKeyPathMapper(ctx, planner.internalFieldName, responsePathsFromContext(ctx))
 	}
-	// Unmarshal json from results
+	if e.requestLimiter != nil {
+		release, err := e.requestLimiter.acquire(ctx)
+		if err != nil {
+			return nil, nil, oops.Wrapf(err, "waiting to execute on %s", service)
+		}
+		defer release()
+	}
+	spanCtx, finishSpan := e.startExecuteSpan(ctx, service, keys)
 	var res interface{}
-	d := json.NewDecoder(bytes.NewReader(response.Result))
-	d.UseNumber()
-	if err := d.Decode(&res); err != nil {
-		return nil, nil, oops.Wrapf(err, "unmarshal res")
+	var responseMetadata interface{}
+	if streamingClient, ok := executorClient.(StreamingExecutorClient); ok {
+		var err error
+		// Stream the objects fetched by keyed subqueries one by one.
+		var listPath []string
+		if !isRoot {
+			listPath = []string{planner.internalFieldName, fmt.Sprintf("%s_%s", service, typName)}
+		}
+		res, responseMetadata, err = e.executeStream(ctx, spanCtx, service, streamingClient, request, listPath, finishSpan)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		response, err := safeExecute(spanCtx, executorClient, request)
+		finishSpan(err)
+		if err != nil {
+			return nil, nil, oops.Wrapf(err, "execute remotely")
+		}
+		recordExtensions(ctx, service, response.Extensions)
+		// Unmarshal json from results
+		d := json.NewDecoder(bytes.NewReader(response.Result))
+		d.UseNumber()
+		if err := d.Decode(&res); err != nil {
+			return nil, nil, oops.Wrapf(err, "unmarshal res")
+		}
+		responseMetadata = response.Metadata
 	}
 
+	r := []interface{}{res}
 	if !isRoot {
 		result, ok := res.(map[string]interface{})
 		if !ok {
 			return nil, nil, oops.Errorf("executor res not a map[string]interface{}")
 		}
-		result, ok = result[federationField].(map[string]interface{})
+		result, ok = result[planner.internalFieldName].(map[string]interface{})
 		if !ok {
 			return nil, nil, oops.Errorf("executor res not a map[string]interface{}")
 		}
 		federatedName := fmt.Sprintf("%s_%s", service, typName)
-		r, ok := result[federatedName].([]interface{})
+		r, ok = result[federatedName].([]interface{})
 		if !ok {
 			return nil, nil, fmt.Errorf("root did not have a federation map, got %v", res)
 		}
-		return r, response.Metadata, nil
+		var err error
+		if r, err = keyed.match(r, newKeys); err != nil {
+			return nil, nil, oops.Wrapf(err, "matching results to keys")
+		}
+	}
+	if e.rewriteSubquery != nil {
+		pruneResult(r, originalSelectionSet)
+	}
+	if len(originals) > 0 {
+		names := make(map[string]string, len(originals))
+		for name, original := range originals {
+			names[original] = name
+		}
+		namespaceTypenames(r, names)
+	}
+	// The objects of r, which are the results of lookups or the root object,
+	// cannot be null.
+	if missing != nil && missing.fill(r, []bool{false, false}) {
+		return nil, nil, oops.Errorf("%s cannot resolve non-null fields", service)
+	}
+	return r, responseMetadata, nil
+}
 
+// pruneResult removes the fields of res that were not selected in
+// selectionSet.
+func pruneResult(res interface{}, selectionSet *graphql.SelectionSet) {
+	switch res := res.(type) {
+	case []interface{}:
+		for _, elem := range res {
+			pruneResult(elem, selectionSet)
+		}
+	case map[string]interface{}:
+		selections := make(map[string]*graphql.SelectionSet)
+		collectSelectionsByAlias(selectionSet, selections)
+		for k, v := range res {
+			subSelectionSet, ok := selections[k]
+			if !ok {
+				if k != keyField {
+					delete(res, k)
+				}
+				continue
+			}
+			if subSelectionSet != nil {
+				pruneResult(v, subSelectionSet)
+			}
+		}
 	}
-	return []interface{}{res}, response.Metadata, nil
 }
 
-func (pathTargets *pathSubqueryMetadata) extractKeys(node interface{}, path []PathStep) error {
+// collectSelectionsByAlias collects the selection sets of the selections in
+// selectionSet and its fragments by alias, merging the selection sets of
+// selections with the same alias.
+func collectSelectionsByAlias(selectionSet *graphql.SelectionSet, selections map[string]*graphql.SelectionSet) {
+	if selectionSet == nil {
+		return
+	}
+	for _, selection := range selectionSet.Selections {
+		existing := selections[selection.Alias]
+		switch {
+		case existing == nil:
+			selections[selection.Alias] = selection.SelectionSet
+		case selection.SelectionSet != nil:
+			selections[selection.Alias] = &graphql.SelectionSet{
+				Selections: append(append([]*graphql.Selection{}, existing.Selections...), selection.SelectionSet.Selections...),
+				Fragments:  append(append([]*graphql.Fragment{}, existing.Fragments...), selection.SelectionSet.Fragments...),
+			}
+		}
+	}
+	for _, fragment := range selectionSet.Fragments {
+		collectSelectionsByAlias(fragment.SelectionSet, selections)
+	}
+}
+
+// extractKeys collects the objects at path in node, and their keys. null
+// replaces node with null in its parent.
+func (pathTargets *pathSubqueryMetadata) extractKeys(node interface{}, path []PathStep, responsePath []interface{}, null func()) error {
+	// A null parent, or a null object on the way to it, has no fields to
+	// fetch from other services.
+	if node == nil {
+		return nil
+	}
+
 	// Extract key for every element in the slice
 	if slice, ok := node.([]interface{}); ok {
 		for i, elem := range slice {
-			if err := pathTargets.extractKeys(elem, path); err != nil {
+			i := i
+			if err := pathTargets.extractKeys(elem, path, append(responsePath, i), func() { slice[i] = nil }); err != nil {
 				return oops.Errorf("idx %d: %v", i, err)
 			}
 		}
@@ -271,13 +675,20 @@ func (pathTargets *pathSubqueryMetadata) extractKeys(node interface{}, path []Pa
 		if !ok {
 			return fmt.Errorf("not an object: %v", obj)
 		}
-		key, ok := obj[federationField]
+		key, ok := obj[pathTargets.internalFieldName]
 		if !ok {
-			return fmt.Errorf("missing _federation: %v", obj)
+			return fmt.Errorf("missing %s: %v", pathTargets.internalFieldName, obj)
+		}
+		if key == nil {
+			// The object has no federated key, so there is nothing to
+			// dispatch and the subquery's fields resolve to null.
+			pathTargets.unkeyed = append(pathTargets.unkeyed, unkeyedObject{obj: obj, null: null})
+			return nil
 		}
 		// Add a pointer to the object for where the results from
 		// the subquery will be added into the final result
 		pathTargets.results = append(pathTargets.results, obj)
+		pathTargets.paths = append(pathTargets.paths, append([]interface{}{}, responsePath...))
 		// Keys from the "_federation" field func are passed to
 		// the subquery
 		pathTargets.keys = append(pathTargets.keys, key)
@@ -297,7 +708,7 @@ func (pathTargets *pathSubqueryMetadata) extractKeys(node interface{}, path []Pa
 		if !ok {
 			return fmt.Errorf("does not have key %s", step.Name)
 		}
-		if err := pathTargets.extractKeys(next, path[1:]); err != nil {
+		if err := pathTargets.extractKeys(next, path[1:], append(responsePath, step.Name), func() { obj[step.Name] = nil }); err != nil {
 			return fmt.Errorf("elem %s: %v", next, err)
 		}
 	case KindType:
@@ -306,7 +717,7 @@ func (pathTargets *pathSubqueryMetadata) extractKeys(node interface{}, path []Pa
 			return fmt.Errorf("does not have string key __typename")
 		}
 		if typ == step.Name {
-			if err := pathTargets.extractKeys(obj, path[1:]); err != nil {
+			if err := pathTargets.extractKeys(obj, path[1:], responsePath, null); err != nil {
 				return fmt.Errorf("typ %s: %v", typ, err)
 			}
 		}
@@ -317,25 +728,103 @@ func (pathTargets *pathSubqueryMetadata) extractKeys(node interface{}, path []Pa
 	return nil
 }
 
-func (e *Executor) execute(ctx context.Context, p *Plan, keys []interface{}, metadata interface{}, planner *Planner) ([]interface{}, []interface{}, error) {
+func (e *Executor) execute(ctx context.Context, p *Plan, keys []interface{}, metadata interface{}, planner *Planner, dedup *fetchDedup) ([]interface{}, []interface{}, error) {
 	var res []interface{}
 	optionalRespMetadata := make([]interface{}, 0)
 	// var optionalResponseArg interface{}
 	// Executes that part of the plan (the subquery) on one of the federated gqlservers
-	if p.Service != gatewayCoordinatorServiceName {
+	if p.Service != gatewayCoordinatorServiceName && keys != nil && len(keys) == 0 {
+		// There are no objects to fetch, so skip dispatching to the service.
+		res = []interface{}{}
+	} else if p.Service != gatewayCoordinatorServiceName {
+		if keys != nil {
+			if err := e.countKeys(ctx, p.Service, len(keys)); err != nil {
+				return nil, nil, err
+			}
+		}
+		runWithContext := func(ctx context.Context, keys []interface{}) ([]interface{}, interface{}, error) {
+			return e.runOnService(ctx, p.Service, p.Type, keys, p.Kind, p.SelectionSet, metadata, planner)
+		}
+		if e.fetchCache != nil && keys != nil {
+			runWithContext = e.fetchCache.wrap(p, planner, runWithContext)
+		}
+		// runWithTimeout runs p like runWithContext, resolving its fields as
+		// null if the service does not respond in time.
+		runWithTimeout := runWithContext
+		if timeout := e.subqueryTimeout(planner, p); timeout > 0 {
+			runWithTimeout = func(ctx context.Context, keys []interface{}) ([]interface{}, interface{}, error) {
+				return e.withPartialTimeout(ctx, p, planner, timeout, runWithContext)(keys)
+			}
+		}
+		run := func(keys []interface{}) ([]interface{}, interface{}, error) {
+			return runWithTimeout(ctx, keys)
+		}
 		var err error
 		var optionalRespQueryMetaData interface{}
-		res, optionalRespQueryMetaData, err = e.runOnService(ctx, p.Service, p.Type, keys, p.Kind, p.SelectionSet, metadata, planner)
+		if dedup != nil && keys != nil {
+			res, optionalRespQueryMetaData, err = dedup.fetch(ctx, p, keys, run)
+		} else {
+			res, optionalRespQueryMetaData, err = run(keys)
+		}
+		if err != nil && keys != nil && e.elementErrors && ctx.Err() == nil {
+			if isolated, isolatedErr, ok := e.isolateElementErrors(ctx, p, planner, keys, err, runWithTimeout); ok {
+				res, optionalRespQueryMetaData, err = isolated, nil, isolatedErr
+			}
+		}
+		if _, ok := err.(*LookupError); err != nil && keys != nil && !ok {
+			err = newLookupError(p, planner.internalFieldName, keys, err)
+		}
 		if err != nil {
+			recordFailedStep(ctx, p, keys)
 			return nil, nil, oops.Wrapf(err, "run on service")
 		}
 		optionalRespMetadata = append(optionalRespMetadata, optionalRespQueryMetaData)
 	} else {
-		res = []interface{}{
-			map[string]interface{}{},
+		// The gateway resolves __typename on the root type itself.
+		root := map[string]interface{}{}
+		for _, selection := range p.SelectionSet.Selections {
+			if selection.Name == "__typename" {
+				root[selection.Alias] = p.Type
+			}
+		}
+		res = []interface{}{root}
+	}
+
+	// Subplans of later stages need the results of earlier stages, so each
+	// stage runs once the previous one has been stitched into res.
+	for _, stage := range subPlanStages(p.After) {
+		stageMetadata, err := e.executeStage(ctx, p, stage, res, metadata, planner, dedup)
+		if err != nil {
+			return nil, nil, err
+		}
+		optionalRespMetadata = append(optionalRespMetadata, stageMetadata...)
+	}
+
+	return res, optionalRespMetadata, nil
+}
+
+// subPlanStages groups subPlans by their stage, in the order of the stages.
+func subPlanStages(subPlans []*Plan) [][]*Plan {
+	var stages []int
+	byStage := make(map[int][]*Plan)
+	for _, subPlan := range subPlans {
+		if _, ok := byStage[subPlan.Stage]; !ok {
+			stages = append(stages, subPlan.Stage)
 		}
+		byStage[subPlan.Stage] = append(byStage[subPlan.Stage], subPlan)
+	}
+	sort.Ints(stages)
+	grouped := make([][]*Plan, 0, len(stages))
+	for _, stage := range stages {
+		grouped = append(grouped, byStage[stage])
 	}
+	return grouped
+}
 
+// executeStage executes subPlans of p concurrently and stitches their results
+// into res, the results of p.
+func (e *Executor) executeStage(ctx context.Context, p *Plan, subPlans []*Plan, res []interface{}, metadata interface{}, planner *Planner, dedup *fetchDedup) ([]interface{}, error) {
+	var optionalRespMetadata []interface{}
 	g, ctx := errgroup.WithContext(ctx)
 	// resMu protects the results (res) as we stitch the results together from seperate goroutines
 	// executing in different parts of the plan on different services
@@ -343,7 +832,7 @@ func (e *Executor) execute(ctx context.Context, p *Plan, keys []interface{}, met
 
 	// For every nested query in the plan, execute it on the specified service and stitch
 	// the results into a response
-	for _, currentSubPlan := range p.After {
+	for _, currentSubPlan := range subPlans {
 		subPlan := currentSubPlan
 		var subPlanMetaData pathSubqueryMetadata
 		if p.Service == gatewayCoordinatorServiceName {
@@ -353,21 +842,31 @@ func (e *Executor) execute(ctx context.Context, p *Plan, keys []interface{}, met
 			subPlanMetaData.results = []map[string]interface{}{
 				res[0].(map[string]interface{}),
 			}
+			subPlanMetaData.paths = [][]interface{}{{}}
 			subPlanMetaData.optionalResponseMetatda = nil
 		} else {
-			if err := subPlanMetaData.extractKeys(res, subPlan.Path); err != nil {
-				return nil, nil, fmt.Errorf("failed to extract keys %v: %v", subPlan.Path, err)
+			subPlanMetaData.keys = []interface{}{}
+			subPlanMetaData.internalFieldName = planner.internalFieldName
+			if err := subPlanMetaData.extractKeys(res, subPlan.Path, nil, nil); err != nil {
+				return nil, fmt.Errorf("failed to extract keys %v: %v", subPlan.Path, err)
 			}
 		}
 
+		// The paths of the subplan's results in the gateway's query.
+		subCtx := withResponsePaths(ctx, [][]interface{}{{}})
+		if p.Service != gatewayCoordinatorServiceName {
+			subCtx = withResponsePaths(ctx, rebasePaths(subPlanMetaData.paths, responsePathsFromContext(ctx)))
+		}
+		if e.tracer != nil {
+			subCtx = withPlanPath(subCtx, subPlanPath(planPathFromContext(ctx), subPlan))
+		}
 		g.Go(func() error {
 			// Execute the subquery on the specified service
-			executionResults, subQueryRespMetadata, err := e.execute(ctx, subPlan, subPlanMetaData.keys, metadata, planner)
+			executionResults, subQueryRespMetadata, err := e.execute(subCtx, subPlan, subPlanMetaData.keys, metadata, planner, dedup)
 			if err != nil {
+				err = rebaseLookupError(err, subPlanMetaData.paths)
 				return oops.Wrapf(err, "executing sub plan: %v", err)
 			}
-			optionalRespMetadata = append(optionalRespMetadata, subQueryRespMetadata...)
-
 			if len(executionResults) != len(subPlanMetaData.results) {
 				return fmt.Errorf("got %d results for %d targets", len(executionResults), len(subPlanMetaData.results))
 			}
@@ -375,6 +874,20 @@ func (e *Executor) execute(ctx context.Context, p *Plan, keys []interface{}, met
 			// Acquire mutex lock before modifying results
 			resMu.Lock()
 			defer resMu.Unlock()
+			optionalRespMetadata = append(optionalRespMetadata, subQueryRespMetadata...)
+			if len(subPlanMetaData.unkeyed) > 0 {
+				nullable := pathNullable(planner, p, subPlan.Path)
+				for _, unkeyed := range subPlanMetaData.unkeyed {
+					if nullUnkeyed(planner, subPlan.Type, subPlan.SelectionSet, unkeyed.obj) {
+						continue
+					}
+					// A non-null field makes the object itself null.
+					if !nullable || unkeyed.null == nil {
+						return oops.Errorf("%s without a federated key cannot resolve non-null fields", subPlan.Type)
+					}
+					unkeyed.null()
+				}
+			}
 			for i, result := range subPlanMetaData.results {
 				executionResult, ok := executionResults[i].(map[string]interface{})
 				if !ok {
@@ -384,6 +897,13 @@ func (e *Executor) execute(ctx context.Context, p *Plan, keys []interface{}, met
 				for k, v := range executionResult {
 					if _, ok := result[k]; !ok {
 						result[k] = v
+					} else if k == planner.internalFieldName {
+						// Fields fetched for the keys of a later stage.
+						merged, err := mergeFederationKeys(result[k], v)
+						if err != nil {
+							return err
+						}
+						result[k] = merged
 					} else {
 						if k != keyField || v != result[k] {
 							return oops.Errorf("key already exists in results: %v", k)
@@ -396,10 +916,35 @@ func (e *Executor) execute(ctx context.Context, p *Plan, keys []interface{}, met
 	}
 
 	if err := g.Wait(); err != nil {
-		return nil, nil, err
+		return nil, err
 	}
+	return optionalRespMetadata, nil
+}
 
-	return res, optionalRespMetadata, nil
+// mergeFederationKeys returns the keys of an object with the fields of
+// fetched added. The keys are copied rather than modified, as they may still
+// be read by other subplans of the same stage.
+func mergeFederationKeys(keys, fetched interface{}) (interface{}, error) {
+	if keys == nil || fetched == nil {
+		// Objects without keys aren't dispatched to later stages.
+		return keys, nil
+	}
+	keysObj, ok := keys.(map[string]interface{})
+	if !ok {
+		return nil, oops.Errorf("keys are not an object: %v", keys)
+	}
+	fetchedObj, ok := fetched.(map[string]interface{})
+	if !ok {
+		return nil, oops.Errorf("fetched keys are not an object: %v", fetched)
+	}
+	merged := make(map[string]interface{}, len(keysObj)+len(fetchedObj))
+	for k, v := range fetchedObj {
+		merged[k] = v
+	}
+	for k, v := range keysObj {
+		merged[k] = v
+	}
+	return merged, nil
 }
 
 func deleteKey(v interface{}, k string) {
@@ -416,21 +961,126 @@ func deleteKey(v interface{}, k string) {
 	}
 }
 
+// unkeyedObject is an object without a federated key, and a func that
+// replaces it with null in the results.
+type unkeyedObject struct {
+	obj  map[string]interface{}
+	null func()
+}
+
 // Metadata for a subquery
 type pathSubqueryMetadata struct {
 	keys                    []interface{}            // Federated Keys passed into subquery
+	internalFieldName       string                   // Alias of the "_federation" field carrying the keys
 	results                 []map[string]interface{} // Results from subquery
+	unkeyed                 []unkeyedObject          // Objects without a federated key, which are not dispatched
+	paths                   [][]interface{}          // Response paths of the results, relative to the parent plan's results
 	optionalResponseMetatda []interface{}
 }
 
-func (e *Executor) Execute(ctx context.Context, query *graphql.Query, metadata interface{}) (interface{}, []interface{}, error) {
-	planner := e.getPlanner()
+// Ownership returns the services that can resolve each object type and field
+// in the current federated schema, eg. to keep a schema registry in sync.
+func (e *Executor) Ownership() (*Ownership, error) {
+	return e.getPlanner().schema.Ownership()
+}
+
+// Schema returns the current merged schema of the executor's services, eg. to
+// print it with introspection.PrintSchema.
+func (e *Executor) Schema() *graphql.Schema {
+	return e.getPlanner().schema.Schema
+}
+
+// Topology returns, for every executor, the fields it resolves and the
+// federated objects it can fetch from their keys, eg. for a debug endpoint
+// describing the federation graph.
+func (e *Executor) Topology() (map[string]*ServiceTopology, error) {
+	topology, err := e.getPlanner().schema.Topology()
+	if err != nil {
+		return nil, err
+	}
+	for service := range e.Executors {
+		if _, ok := topology[service]; !ok {
+			topology[service] = &ServiceTopology{
+				Fields: make(map[string][]string),
+				Keys:   make(map[string][]string),
+			}
+		}
+	}
+	return topology, nil
+}
+
+// Plan builds the plan used to execute query, and checks it against the
+// executor's limits.
+func (e *Executor) Plan(query *graphql.Query) (*Plan, error) {
+	return e.plan(e.getPlanner(), query)
+}
+
+func (e *Executor) plan(planner *Planner, query *graphql.Query) (*Plan, error) {
 	plan, err := planner.planRoot(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.disableIntrospection {
+		for _, subPlan := range plan.After {
+			if subPlan.Service == introspectionServiceName {
+				return nil, ErrIntrospectionDisabled
+			}
+		}
+	}
+
+	if e.maxServicesPerQuery > 0 {
+		services := make(map[string]struct{})
+		plan.Walk(func(p *Plan) error {
+			if p.Service != gatewayCoordinatorServiceName {
+				services[p.Service] = struct{}{}
+			}
+			return nil
+		})
+		if len(services) > e.maxServicesPerQuery {
+			return nil, graphql.NewClientError("query touches %d services, more than the maximum of %d", len(services), e.maxServicesPerQuery)
+		}
+	}
+
+	if e.maxHops > 0 {
+		if hops := plan.hopChain(); len(hops) > e.maxHops {
+			return nil, graphql.NewClientError("query requires %d sequential hops, more than the maximum of %d: %s", len(hops), e.maxHops, strings.Join(hops, " -> "))
+		}
+	}
+	return plan, nil
+}
+
+func (e *Executor) Execute(ctx context.Context, query *graphql.Query, metadata interface{}) (_ interface{}, _ []interface{}, err error) {
+	ctx = e.withGeneratedRequestID(ctx)
+	ctx = e.withKeyCounts(ctx)
+	ctx = e.withFailedStep(ctx)
+	planner := e.getPlanner()
+	plan, err := e.plan(planner, query)
 	if err != nil {
 		return nil, nil, err
 	}
+	defer func() {
+		if err != nil {
+			e.logFailedPlan(ctx, plan, err)
+		}
+	}()
+
+	if subPlan, ok := plan.singleService(); ok && e.subqueryTimeout(planner, subPlan) == 0 {
+		// Fast path: forward the selection set straight to the only service
+		// involved. Its response has no federation bookkeeping to strip.
+		r, responseMetadata, err := e.runOnService(ctx, subPlan.Service, subPlan.Type, nil, subPlan.Kind, subPlan.SelectionSet, metadata, planner)
+		if err != nil {
+			recordFailedStep(ctx, subPlan, nil)
+			return nil, nil, oops.Wrapf(err, "run on service")
+		}
+		res, err := e.finishResult(ctx, planner, plan, query, r[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		return res, []interface{}{responseMetadata}, nil
+	}
 
-	r, responseMetadata, err := e.execute(ctx, plan, nil, metadata, planner)
+	r, responseMetadata, err := e.execute(ctx, plan, nil, metadata, planner, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -442,6 +1092,293 @@ func (e *Executor) Execute(ctx context.Context, query *graphql.Query, metadata i
 	// On the root query, we know there is only one object (a query or mutation)
 	// So we expect only one item in this list
 	res := r[0]
-	deleteKey(res, federationField)
+	deleteKey(res, planner.internalFieldName)
+	res, err = e.finishResult(ctx, planner, plan, query, res)
+	if err != nil {
+		return nil, nil, err
+	}
 	return res, responseMetadata, nil
 }
+
+// nullUnkeyed resolves the fields that selectionSet, planned on typeName,
+// selects on obj, an object without a federated key, to null, unless obj
+// already has them. It returns false if one of the fields is non-null.
+func nullUnkeyed(planner *Planner, typeName string, selectionSet *graphql.SelectionSet, obj map[string]interface{}) bool {
+	typ, _ := planner.flattener.types[typeName].(*graphql.Object)
+	for _, selection := range selectionSet.Selections {
+		if selection.Alias == planner.internalFieldName {
+			continue
+		}
+		if _, ok := obj[selection.Alias]; ok {
+			continue
+		}
+		if selection.Name == "__typename" {
+			obj[selection.Alias] = typeName
+			continue
+		}
+		if typ != nil {
+			if field, ok := typ.Fields[selection.Name]; ok {
+				if _, ok := field.Type.(*graphql.NonNull); ok {
+					return false
+				}
+			}
+		}
+		obj[selection.Alias] = nil
+	}
+	for _, fragment := range selectionSet.Fragments {
+		// Fragments on other object types do not apply to obj.
+		if _, ok := planner.flattener.types[fragment.On].(*graphql.Object); ok && fragment.On != typeName {
+			continue
+		}
+		if !nullUnkeyed(planner, typeName, fragment.SelectionSet, obj) {
+			return false
+		}
+	}
+	return true
+}
+
+// pathNullable returns whether the objects at path in the results of p may be
+// null.
+func pathNullable(planner *Planner, p *Plan, path []PathStep) bool {
+	typ := planner.resultType(p)
+	selectionSet := p.SelectionSet
+	var fieldType graphql.Type
+	for _, step := range path {
+		if selectionSet == nil {
+			return false
+		}
+		switch step.Kind {
+		case KindField:
+			obj, ok := unwrapType(typ).(*graphql.Object)
+			if !ok {
+				return false
+			}
+			var selection *graphql.Selection
+			for _, candidate := range selectionSet.Selections {
+				if candidate.Alias == step.Name {
+					selection = candidate
+					break
+				}
+			}
+			if selection == nil {
+				return false
+			}
+			field, ok := obj.Fields[selection.Name]
+			if !ok {
+				return false
+			}
+			typ, fieldType, selectionSet = field.Type, field.Type, selection.SelectionSet
+		case KindType:
+			var fragment *graphql.Fragment
+			for _, candidate := range selectionSet.Fragments {
+				if candidate.On == step.Name {
+					fragment = candidate
+					break
+				}
+			}
+			if fragment == nil {
+				return false
+			}
+			typ, selectionSet = planner.flattener.types[step.Name], fragment.SelectionSet
+		}
+	}
+	if fieldType == nil {
+		return false
+	}
+	// The objects are the innermost elements of the field's lists.
+	for {
+		nonNull, ok := fieldType.(*graphql.NonNull)
+		if ok {
+			fieldType = nonNull.Type
+		}
+		if list, isList := fieldType.(*graphql.List); isList {
+			fieldType = list.Type
+			continue
+		}
+		return !ok
+	}
+}
+
+// finishResult checks and post-processes res, the result of query planned as
+// plan, before it is returned.
+func (e *Executor) finishResult(ctx context.Context, planner *Planner, plan *Plan, query *graphql.Query, res interface{}) (interface{}, error) {
+	if err := e.checkTypenames(planner, plan, res); err != nil {
+		return nil, err
+	}
+	typ := planner.resultType(plan)
+	if err := e.resolveVirtualFields(ctx, planner, typ, query, res); err != nil {
+		return nil, err
+	}
+	if err := e.validateResult(typ, query, res); err != nil {
+		return nil, err
+	}
+	res, err := e.processResponse(ctx, typ, query, res)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkResponseSize(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// checkResponseSize returns an error if res exceeds the maximum response
+// size once serialized. It counts the bytes of res as JSON without
+// serializing it as a whole, and stops as soon as they exceed the maximum.
+func (e *Executor) checkResponseSize(res interface{}) error {
+	if e.maxResponseSize <= 0 {
+		return nil
+	}
+	size := &responseSize{max: e.maxResponseSize}
+	if err := size.add(res); err != nil {
+		return err
+	}
+	return nil
+}
+
+// responseSize counts the bytes of a result serialized as JSON.
+type responseSize struct {
+	n, max int64
+}
+
+// add counts the bytes of v serialized as JSON, and returns an error once
+// they exceed the maximum.
+func (s *responseSize) add(v interface{}) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		// Braces, and a colon and a comma or closing brace per field.
+		s.n += 2 + 2*int64(len(v))
+		if len(v) > 0 {
+			s.n--
+		}
+		for k, elem := range v {
+			if err := s.addValue(k); err != nil {
+				return err
+			}
+			if err := s.add(elem); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		// Brackets, and a comma between elements.
+		s.n += 2 + int64(len(v))
+		if len(v) > 0 {
+			s.n--
+		}
+		for _, elem := range v {
+			if err := s.add(elem); err != nil {
+				return err
+			}
+		}
+	case nil:
+		s.n += int64(len("null"))
+	case json.Number:
+		s.n += int64(len(v))
+	default:
+		return s.addValue(v)
+	}
+	if s.n > s.max {
+		return oops.Errorf("response size exceeds the maximum of %d bytes", s.max)
+	}
+	return nil
+}
+
+// addValue counts the bytes of the scalar v serialized as JSON.
+func (s *responseSize) addValue(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return oops.Wrapf(err, "serializing result")
+	}
+	s.n += int64(len(data))
+	if s.n > s.max {
+		return oops.Errorf("response size exceeds the maximum of %d bytes", s.max)
+	}
+	return nil
+}
+
+// ExecuteBatch executes several independent plans together and returns their
+// results in the same order, checked and post-processed like those of
+// Execute. Federated objects needed by more than one of the
+// plans, with the same selections, are only fetched once for the whole batch.
+func (e *Executor) ExecuteBatch(ctx context.Context, plans []*Plan, metadata interface{}) ([]interface{}, []interface{}, error) {
+	ctx = e.withGeneratedRequestID(ctx)
+	ctx = e.withKeyCounts(ctx)
+	planner := e.getPlanner()
+	dedup := newFetchDedup()
+
+	results := make([]interface{}, len(plans))
+	var responseMetadataMu sync.Mutex
+	var responseMetadata []interface{}
+	g, ctx := errgroup.WithContext(ctx)
+	for i, plan := range plans {
+		if plan.query == nil {
+			return nil, nil, oops.Errorf("plan %d has no query, plans must be built with Plan or LoadPlan", i)
+		}
+	}
+	for i, plan := range plans {
+		i, plan := i, plan
+		g.Go(func() (err error) {
+			ctx := e.withFailedStep(ctx)
+			defer func() {
+				if err != nil {
+					e.logFailedPlan(ctx, plan, err)
+				}
+			}()
+			r, planMetadata, err := e.execute(ctx, plan, nil, metadata, planner, dedup)
+			if err != nil {
+				return oops.Wrapf(err, "executing plan %d", i)
+			}
+			if len(r) != 1 {
+				return oops.Errorf("Multiple results, expected one %v", r)
+			}
+			res := r[0]
+			deleteKey(res, planner.internalFieldName)
+			res, err = e.finishResult(ctx, planner, plan, plan.query, res)
+			if err != nil {
+				return oops.Wrapf(err, "executing plan %d", i)
+			}
+			results[i] = res
+
+			responseMetadataMu.Lock()
+			defer responseMetadataMu.Unlock()
+			responseMetadata = append(responseMetadata, planMetadata...)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, nil, err
+	}
+	return results, responseMetadata, nil
+}
+
+// ResolveEntity fetches selectionSet on the federated object typeName
+// identified by key, without constructing a full query. For example, the name
+// of the Foo with key {"id": 1} can be fetched with
+//   e.ResolveEntity(ctx, "Foo", map[string]interface{}{"id": 1}, selectionSet, nil)
+// where selectionSet is the parsed selection set `{ name }`.
+func (e *Executor) ResolveEntity(ctx context.Context, typeName string, key map[string]interface{}, selectionSet *graphql.SelectionSet, metadata interface{}) (_ interface{}, err error) {
+	ctx = e.withGeneratedRequestID(ctx)
+	ctx = e.withKeyCounts(ctx)
+	ctx = e.withFailedStep(ctx)
+	planner := e.getPlanner()
+	plan, err := planner.planEntity(typeName, selectionSet)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			e.logFailedPlan(ctx, plan, err)
+		}
+	}()
+
+	r, _, err := e.execute(ctx, plan, []interface{}{key}, metadata, planner, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(r) != 1 {
+		return nil, oops.Errorf("expected one %s, got %d", typeName, len(r))
+	}
+	res := r[0]
+	deleteKey(res, planner.internalFieldName)
+	return e.finishResult(ctx, planner, plan, &graphql.Query{Kind: queryString, SelectionSet: selectionSet}, res)
+}
@@ -6,17 +6,23 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 	"bytes"
 
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/batch"
 	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/introspection"
 	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/samsarahq/thunder/thunderpb"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func createExecutorWithFederatedUser() (*Executor, *schemabuilder.Schema, *schemabuilder.Schema, *schemabuilder.Schema, error) {
+func createExecutorWithFederatedUser(opts ...ExecutorOption) (*Executor, *schemabuilder.Schema, *schemabuilder.Schema, *schemabuilder.Schema, error) {
 	/*
 		Schema: s1
 		Query {
@@ -235,11 +241,11 @@ func createExecutorWithFederatedUser() (*Executor, *schemabuilder.Schema, *schem
 		return nil, nil, nil, nil, err
 	}
 
-	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)}, opts...)
 	return e, s1, s2, s3, err
 }
 
-func runAndValidateQueryResults(t *testing.T, ctx context.Context, e *Executor, query string, out string) {
+func runAndValidateQueryResults(t *testing.T, ctx context.Context, e GraphqlExecutor, query string, out string) {
 	res, _, err := e.Execute(ctx, graphql.MustParse(query, map[string]interface{}{}), nil)
 	require.NoError(t, err)
 	var expected interface{}
@@ -250,7 +256,7 @@ func runAndValidateQueryResults(t *testing.T, ctx context.Context, e *Executor,
 	assert.Equal(t, expected, res)
 }
 
-func runAndValidateQueryError(t *testing.T, ctx context.Context, e *Executor, query string, out string, expectedError string) {
+func runAndValidateQueryError(t *testing.T, ctx context.Context, e GraphqlExecutor, query string, out string, expectedError string) {
 	_, _, err := e.Execute(ctx, graphql.MustParse(query, map[string]interface{}{}), nil)
 	assert.True(t, strings.Contains(err.Error(), expectedError))
 }
@@ -341,6 +347,52 @@ func TestExecutorQueriesBasic(t *testing.T) {
 	}
 }
 
+func TestExecutorMaxServicesPerQuery(t *testing.T) {
+	e, _, _, _, err := createExecutorWithFederatedUser(WithMaxServicesPerQuery(2))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Querying s1 and s2 is within the limit.
+	runAndValidateQueryResults(t, ctx, e, `
+		query Foo {
+			users {
+				id
+				secret
+			}
+		}`, `
+		{
+			"users":[
+				{
+					"__key":1,
+					"id":1,
+					"secret": "shhhhh"
+				},
+				{
+					"__key":2,
+					"id":2,
+					"secret": "shhhhh"
+				}
+			]
+		}`)
+
+	// Querying s1, s2 and s3 exceeds the limit and is rejected while planning.
+	query := graphql.MustParse(`
+		query Foo {
+			users {
+				id
+				isAdmin
+				secret
+			}
+		}`, map[string]interface{}{})
+	_, err = e.Plan(query)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "query touches 3 services, more than the maximum of 2")
+
+	_, _, err = e.Execute(ctx, query, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "query touches 3 services, more than the maximum of 2")
+}
+
 func TestExecutorQueriesNestedObjects(t *testing.T) {
 	e, _, _, _, err := createExecutorWithFederatedUser()
 	require.NoError(t, err)
@@ -835,61 +887,1070 @@ func TestExecutorQueriesWithBatching(t *testing.T) {
 	}
 }
 
-func TestExecutorWithInvalidFederationKeys(t *testing.T) {
-	type User struct {
-		Id          int64
-		OrgId       int64
-		Name        string
-		Email       string
-		PhoneNumber string
-	}
-	s1 := schemabuilder.NewSchemaWithName("s1")
-	type UserKey struct {
-		Id int64
-	}
-	user := s1.Object("User", User{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*UserKey }) []*User {
-		users := make([]*User, 0, 2)
-		users = append(users, &User{Id: int64(1), OrgId: int64(1), Name: "testUser", Email: "email@gmail.com", PhoneNumber: "555-5555"})
-		users = append(users, &User{Id: int64(2), OrgId: int64(2), Name: "testUser2", Email: "email@gmail.com", PhoneNumber: "555-5555"})
-		return users
+func TestExecutorWithInvalidFederationKeys(t *testing.T) {
+	type User struct {
+		Id          int64
+		OrgId       int64
+		Name        string
+		Email       string
+		PhoneNumber string
+	}
+	s1 := schemabuilder.NewSchemaWithName("s1")
+	type UserKey struct {
+		Id int64
+	}
+	user := s1.Object("User", User{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*UserKey }) []*User {
+		users := make([]*User, 0, 2)
+		users = append(users, &User{Id: int64(1), OrgId: int64(1), Name: "testUser", Email: "email@gmail.com", PhoneNumber: "555-5555"})
+		users = append(users, &User{Id: int64(2), OrgId: int64(2), Name: "testUser2", Email: "email@gmail.com", PhoneNumber: "555-5555"})
+		return users
+	}))
+	user.Key("id")
+	type UserIds struct {
+		Id    int64
+		OrgId int64
+	}
+	s1.Query().FieldFunc("users", func(ctx context.Context) ([]*User, error) {
+		users := make([]*User, 0, 2)
+		users = append(users, &User{Id: int64(1), OrgId: int64(1), Name: "testUser", Email: "email@gmail.com", PhoneNumber: "555-5555"})
+		users = append(users, &User{Id: int64(2), OrgId: int64(2), Name: "testUser2", Email: "email@gmail.com", PhoneNumber: "555-5555"})
+		return users, nil
+	})
+
+	type UserWithExtraKey struct {
+		Id          int64
+		OrgId       int64
+		Name        string
+		UnkownField string
+	}
+
+	s2 := schemabuilder.NewSchemaWithName("s2")
+	s2.Object("User", User{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*UserWithExtraKey }) []*User {
+		users := make([]*User, 0, 2)
+		users = append(users, &User{Id: int64(1), OrgId: int64(1), Name: "testUser", Email: "email@gmail.com", PhoneNumber: "555-5555"})
+		users = append(users, &User{Id: int64(2), OrgId: int64(2), Name: "testUser2", Email: "email@gmail.com", PhoneNumber: "555-5555"})
+		return users
+	}))
+
+	// Create the executor with all the schemas
+	ctx := context.Background()
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"s1": s1,
+		"s2": s2,
+	})
+	assert.NoError(t, err)
+
+	_, err = NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	assert.True(t, strings.Contains(err.Error(), "Invalid federation key unkownField"))
+}
+
+// countingExecutorClient counts the requests sent to the wrapped client.
+type countingExecutorClient struct {
+	ExecutorClient
+	mu    sync.Mutex
+	count int
+}
+
+func (c *countingExecutorClient) Execute(ctx context.Context, request *QueryRequest) (*QueryResponse, error) {
+	c.mu.Lock()
+	c.count++
+	c.mu.Unlock()
+	return c.ExecutorClient.Execute(ctx, request)
+}
+
+func (c *countingExecutorClient) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count = 0
+}
+
+func TestExecutorSkipsUnkeyedObjects(t *testing.T) {
+	type User struct {
+		Id   int64
+		Name string
+	}
+	s1 := schemabuilder.NewSchemaWithName("s1")
+	user := s1.Object("User", User{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*User }) []*User {
+		return args.Keys
+	}))
+	user.Key("id")
+	// Users without a name have not been created yet, and can't be federated.
+	user.FederationKey(func(u *User) (*User, bool) {
+		return u, u.Name != ""
+	})
+	var users []*User
+	s1.Query().FieldFunc("users", func() []*User {
+		return users
+	})
+	s1.Query().FieldFunc("user", func() *User {
+		if len(users) == 0 {
+			return nil
+		}
+		return users[0]
+	})
+
+	s2 := schemabuilder.NewSchemaWithName("s2")
+	s2user := s2.Object("User", User{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*User }) []*User {
+		return args.Keys
+	}))
+	s2user.FieldFunc("greeting", func(u *User) *string {
+		greeting := "hello " + u.Name
+		return &greeting
+	})
+	s2user.FieldFunc("nameLength", func(u *User) int64 {
+		return int64(len(u.Name))
+	})
+
+	ctx := context.Background()
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"s1": s1,
+		"s2": s2,
+	})
+	require.NoError(t, err)
+	s2Client := &countingExecutorClient{ExecutorClient: execs["s2"]}
+	execs["s2"] = s2Client
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	require.NoError(t, err)
+
+	query := `
+		{
+			users {
+				id
+				greeting
+			}
+		}`
+
+	// Only keyed users are dispatched, unkeyed users resolve to null.
+	users = []*User{{Id: 1, Name: "bob"}, {Id: 2}}
+	s2Client.reset()
+	runAndValidateQueryResults(t, ctx, e, query, `
+		{
+			"users": [
+				{"__key": 1, "id": 1, "greeting": "hello bob"},
+				{"__key": 2, "id": 2, "greeting": null}
+			]
+		}`)
+	assert.Equal(t, 1, s2Client.count)
+
+	// Selecting the key fields does not bypass the key function.
+	users = []*User{{Id: 1, Name: "bob"}, {Id: 2}}
+	s2Client.reset()
+	runAndValidateQueryResults(t, ctx, e, `
+		{
+			users {
+				id
+				name
+				greeting
+			}
+		}`, `
+		{
+			"users": [
+				{"__key": 1, "id": 1, "name": "bob", "greeting": "hello bob"},
+				{"__key": 2, "id": 2, "name": "", "greeting": null}
+			]
+		}`)
+	assert.Equal(t, 1, s2Client.count)
+
+	// No users are keyed, so nothing is dispatched.
+	users = []*User{{Id: 2}}
+	s2Client.reset()
+	runAndValidateQueryResults(t, ctx, e, query, `
+		{
+			"users": [
+				{"__key": 2, "id": 2, "greeting": null}
+			]
+		}`)
+	assert.Equal(t, 0, s2Client.count)
+
+	// Non-null fields of unkeyed objects make the objects null, if they are
+	// nullable.
+	s2Client.reset()
+	runAndValidateQueryResults(t, ctx, e, `
+		{
+			user { id nameLength }
+			users { id greeting }
+		}`, `
+		{
+			"user": null,
+			"users": [
+				{"__key": 2, "id": 2, "greeting": null}
+			]
+		}`)
+	assert.Equal(t, 0, s2Client.count)
+	runAndValidateQueryError(t, ctx, e, `{ users { id nameLength } }`, "", "User without a federated key cannot resolve non-null fields")
+
+	// The fields of fragments are resolved as null too.
+	runAndValidateQueryResults(t, ctx, e, `
+		{
+			users { id ... on User { greeting } }
+		}`, `
+		{
+			"users": [
+				{"__key": 2, "id": 2, "greeting": null}
+			]
+		}`)
+}
+
+// createKitchenSinkExecutor creates an executor over the kitchen sink schemas,
+// counting the requests sent to each service.
+func createKitchenSinkExecutor(t *testing.T, opts ...ExecutorOption) (*Executor, map[string]*countingExecutorClient) {
+	e, clients, err := newTestExecutor(t, map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": buildTestSchema2(),
+	}, opts...)
+	require.NoError(t, err)
+	return e, clients
+}
+
+// newTestExecutor returns an executor of the services schemas, whose clients
+// count their requests, and the error of NewExecutor. The syncer polls its own
+// copy of the clients, so that tests can replace the executor's clients while
+// it runs, and stops polling when the test ends.
+func newTestExecutor(t *testing.T, schemas map[string]*schemabuilder.Schema, opts ...ExecutorOption) (*Executor, map[string]*countingExecutorClient, error) {
+	execs, err := makeExecutors(schemas)
+	require.NoError(t, err)
+
+	clients := make(map[string]*countingExecutorClient)
+	syncerExecs := make(map[string]ExecutorClient, len(execs))
+	for name, exec := range execs {
+		clients[name] = &countingExecutorClient{ExecutorClient: exec}
+		execs[name] = clients[name]
+		syncerExecs[name] = clients[name]
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, syncerExecs, nil)}, opts...)
+	for _, client := range clients {
+		client.reset()
+	}
+	return e, clients, err
+}
+
+func TestExecutorUnionTypenameOnly(t *testing.T) {
+	e, clients := createKitchenSinkExecutor(t)
+	ctx := context.Background()
+
+	query := `
+		{
+			s1both {
+				__typename
+			}
+		}`
+
+	// Only __typename is fetched for the union, with no subplans.
+	plan, err := e.Plan(graphql.MustParse(query, map[string]interface{}{}))
+	require.NoError(t, err)
+	require.Len(t, plan.After, 1)
+	assert.Empty(t, plan.After[0].After)
+	union := plan.After[0].SelectionSet.Selections[0].SelectionSet
+	for _, selection := range union.Selections {
+		assert.Equal(t, "__typename", selection.Name)
+	}
+	for _, fragment := range union.Fragments {
+		for _, selection := range fragment.SelectionSet.Selections {
+			assert.Equal(t, "__typename", selection.Name)
+		}
+	}
+
+	runAndValidateQueryResults(t, ctx, e, query, `
+		{
+			"s1both": [
+				{"__typename": "Foo"},
+				{"__typename": "Bar"}
+			]
+		}`)
+	assert.Equal(t, 1, clients["schema1"].count)
+	assert.Equal(t, 0, clients["schema2"].count)
+}
+
+func TestExecutorRootTypename(t *testing.T) {
+	ctx := context.Background()
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": buildTestSchema2(),
+	})
+	require.NoError(t, err)
+	schema1 := &recordingExecutorClient{ExecutorClient: execs["schema1"]}
+	schema2 := &recordingExecutorClient{ExecutorClient: execs["schema2"]}
+	execs["schema1"], execs["schema2"] = schema1, schema2
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	require.NoError(t, err)
+	schema1.queries, schema2.queries = nil, nil
+
+	runAndValidateQueryResults(t, ctx, e, `{ __typename s2root }`, `
+		{
+			"__typename": "Query",
+			"s2root": "hello"
+		}`)
+	assert.Empty(t, schema1.queries)
+	require.Len(t, schema2.queries, 1)
+	require.Len(t, schema2.queries[0].SelectionSet.Selections, 1)
+	assert.Equal(t, "s2root", schema2.queries[0].SelectionSet.Selections[0].Name)
+
+	// __typename alone is not sent to any service.
+	schema2.queries = nil
+	runAndValidateQueryResults(t, ctx, e, `{ kind: __typename }`, `{"kind": "Query"}`)
+	assert.Empty(t, schema1.queries)
+	assert.Empty(t, schema2.queries)
+}
+
+func TestExecutorMaxResponseSize(t *testing.T) {
+	ctx := context.Background()
+	query := `
+		{
+			s1fff {
+				name
+				s2ok
+			}
+		}`
+
+	e, _ := createKitchenSinkExecutor(t, WithMaxResponseSize(1024))
+	runAndValidateQueryResults(t, ctx, e, query, `
+		{
+			"s1fff": [
+				{"name": "jimbo", "s2ok": 5},
+				{"name": "bob", "s2ok": 3}
+			]
+		}`)
+
+	// The limit applies to the serialized result, which is 61 bytes, rather
+	// than to the larger responses of the services, which include keys.
+	e, _ = createKitchenSinkExecutor(t, WithMaxResponseSize(61))
+	runAndValidateQueryResults(t, ctx, e, query, `
+		{
+			"s1fff": [
+				{"name": "jimbo", "s2ok": 5},
+				{"name": "bob", "s2ok": 3}
+			]
+		}`)
+
+	e, _ = createKitchenSinkExecutor(t, WithMaxResponseSize(60))
+	runAndValidateQueryError(t, ctx, e, query, "", "response size exceeds the maximum of 60 bytes")
+}
+
+func TestResponseSize(t *testing.T) {
+	res := map[string]interface{}{
+		"empty":  map[string]interface{}{},
+		"list":   []interface{}{json.Number("1"), "<a & b>", nil, true, []interface{}{}},
+		"nested": map[string]interface{}{"s": "quote \"é\"", "f": 1.5, "i": int64(-3)},
+	}
+	data, err := json.Marshal(res)
+	require.NoError(t, err)
+
+	size := &responseSize{max: int64(len(data))}
+	require.NoError(t, size.add(res))
+	assert.Equal(t, int64(len(data)), size.n)
+
+	// Counting stops once the maximum is exceeded.
+	size = &responseSize{max: 10}
+	err = size.add(map[string]interface{}{
+		"a": strings.Repeat("a", 20),
+		"b": strings.Repeat("b", 20),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds the maximum of 10 bytes")
+	assert.Less(t, size.n, int64(40))
+}
+
+// loggingExecutor is a GraphqlExecutor decorator that logs the services each
+// query is planned on.
+type loggingExecutor struct {
+	GraphqlExecutor
+	mu   sync.Mutex
+	logs []string
+}
+
+func (e *loggingExecutor) Plan(query *graphql.Query) (*Plan, error) {
+	plan, err := e.GraphqlExecutor.Plan(query)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	plan.Walk(func(p *Plan) error {
+		e.logs = append(e.logs, fmt.Sprintf("plan %s on %s", p.Type, p.Service))
+		return nil
+	})
+	return plan, nil
+}
+
+func (e *loggingExecutor) Execute(ctx context.Context, query *graphql.Query, metadata interface{}) (interface{}, []interface{}, error) {
+	if _, err := e.Plan(query); err != nil {
+		return nil, nil, err
+	}
+	res, respMetadata, err := e.GraphqlExecutor.Execute(ctx, query, metadata)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.logs = append(e.logs, fmt.Sprintf("execute %s: err=%v", query.Kind, err))
+	return res, respMetadata, err
+}
+
+func TestExecutorDecorator(t *testing.T) {
+	inner, _ := createKitchenSinkExecutor(t)
+	e := &loggingExecutor{GraphqlExecutor: inner}
+	ctx := context.Background()
+
+	runAndValidateQueryResults(t, ctx, e, `
+		{
+			s1fff {
+				name
+				s2ok
+			}
+		}`, `
+		{
+			"s1fff": [
+				{"name": "jimbo", "s2ok": 5},
+				{"name": "bob", "s2ok": 3}
+			]
+		}`)
+	assert.Equal(t, []string{
+		"plan Query on " + gatewayCoordinatorServiceName,
+		"plan Query on schema1",
+		"plan Foo on schema2",
+		"execute query: err=<nil>",
+	}, e.logs)
+}
+
+func TestExecutorResolveEntity(t *testing.T) {
+	e, clients := createKitchenSinkExecutor(t)
+	ctx := context.Background()
+
+	res, err := e.ResolveEntity(ctx, "Foo", map[string]interface{}{"name": "jimbo"}, mustParse(`{
+		name
+		s1hmm
+		s2ok
+		s2bar {
+			id
+			s1baz
+		}
+	}`), nil)
+	require.NoError(t, err)
+
+	var expected interface{}
+	d := json.NewDecoder(strings.NewReader(`{
+		"name": "jimbo",
+		"s1hmm": "jimbo!!!",
+		"s2ok": 5,
+		"s2bar": {
+			"id": 14,
+			"s1baz": "14"
+		}
+	}`))
+	d.UseNumber()
+	require.NoError(t, d.Decode(&expected))
+	assert.Equal(t, expected, res)
+
+	// The lookup starts on schema2, which resolves most of the fields.
+	assert.Equal(t, 1, clients["schema2"].count)
+	assert.Equal(t, 2, clients["schema1"].count)
+
+	_, err = e.ResolveEntity(ctx, "Unknown", map[string]interface{}{}, mustParse(`{ name }`), nil)
+	assert.Error(t, err)
+
+	t.Run("results are checked like those of Execute", func(t *testing.T) {
+		e, _ := createKitchenSinkExecutor(t, WithMaxResponseSize(10), WithResultValidation())
+		_, err := e.ResolveEntity(ctx, "Foo", map[string]interface{}{"name": "jimbo"}, mustParse(`{ name s2ok }`), nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "exceeds the maximum of 10 bytes")
+	})
+}
+
+func TestExecutorOperationName(t *testing.T) {
+	e, _ := createKitchenSinkExecutor(t)
+	ctx := context.Background()
+
+	document := `
+		query Foos {
+			s1fff {
+				name
+				s2ok
+			}
+		}
+
+		query Root {
+			s2root
+		}`
+
+	testCases := []struct {
+		Operation string
+		Output    string
+	}{
+		{
+			Operation: "Foos",
+			Output:    `{"s1fff": [{"name": "jimbo", "s2ok": 5}, {"name": "bob", "s2ok": 3}]}`,
+		},
+		{
+			Operation: "Root",
+			Output:    `{"s2root": "hello"}`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Operation, func(t *testing.T) {
+			query, err := graphql.ParseOperation(document, map[string]interface{}{}, testCase.Operation)
+			require.NoError(t, err)
+			res, _, err := e.Execute(ctx, query, nil)
+			require.NoError(t, err)
+
+			var expected interface{}
+			d := json.NewDecoder(strings.NewReader(testCase.Output))
+			d.UseNumber()
+			require.NoError(t, d.Decode(&expected))
+			assert.Equal(t, expected, res)
+		})
+	}
+}
+
+func TestExecutorVariableDefaults(t *testing.T) {
+	e, _ := createKitchenSinkExecutor(t)
+	ctx := context.Background()
+
+	document := `
+		query Scores($weight: int64 = 10) {
+			s1fff {
+				name
+				s2score(weight: $weight)
+			}
+		}`
+
+	testCases := []struct {
+		Name      string
+		Variables map[string]interface{}
+		Weight    interface{}
+		Output    string
+	}{
+		{
+			Name:      "omitted variable uses default",
+			Variables: map[string]interface{}{},
+			Weight:    float64(10),
+			Output:    `{"s1fff": [{"name": "jimbo", "s2score": 50}, {"name": "bob", "s2score": 30}]}`,
+		},
+		{
+			Name:      "provided variable overrides default",
+			Variables: map[string]interface{}{"weight": float64(2)},
+			Weight:    float64(2),
+			Output:    `{"s1fff": [{"name": "jimbo", "s2score": 10}, {"name": "bob", "s2score": 6}]}`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			query, err := graphql.Parse(document, testCase.Variables)
+			require.NoError(t, err)
+
+			// The subquery to schema2 is planned with the variable's value.
+			plan, err := e.Plan(query)
+			require.NoError(t, err)
+			var weights []interface{}
+			require.NoError(t, plan.Walk(func(p *Plan) error {
+				if p.SelectionSet == nil {
+					return nil
+				}
+				for _, selection := range p.SelectionSet.Selections {
+					if selection.Name == "s2score" {
+						weights = append(weights, selection.UnparsedArgs["weight"])
+					}
+				}
+				return nil
+			}))
+			assert.Equal(t, []interface{}{testCase.Weight}, weights)
+
+			res, _, err := e.Execute(ctx, query, nil)
+			require.NoError(t, err)
+
+			var expected interface{}
+			d := json.NewDecoder(strings.NewReader(testCase.Output))
+			d.UseNumber()
+			require.NoError(t, d.Decode(&expected))
+			assert.Equal(t, expected, res)
+		})
+	}
+}
+
+func TestExecutorPaginatedFields(t *testing.T) {
+	type Item struct {
+		Id   int64
+		Name string
+	}
+	s1 := schemabuilder.NewSchemaWithName("s1")
+	item := s1.Object("Item", Item{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Item }) []*Item {
+		return args.Keys
+	}))
+	item.Key("id")
+	s1.Query().FieldFunc("items", func() []*Item {
+		return []*Item{{Id: 1, Name: "a"}, {Id: 2, Name: "b"}, {Id: 3, Name: "c"}}
+	}, schemabuilder.Paginated)
+
+	s2 := schemabuilder.NewSchemaWithName("s2")
+	s2item := s2.Object("Item", Item{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Item }) []*Item {
+		return args.Keys
+	}))
+	s2item.FieldFunc("price", func(i *Item) int64 {
+		return i.Id * 100
+	})
+
+	ctx := context.Background()
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"s1": s1,
+		"s2": s2,
+	})
+	require.NoError(t, err)
+	s2Client := &countingExecutorClient{ExecutorClient: execs["s2"]}
+	execs["s2"] = s2Client
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		Name   string
+		Query  string
+		Output string
+		Calls  int
+	}{
+		{
+			Name: "first page",
+			Query: `
+				{
+					items(first: 2) {
+						totalCount
+						edges {
+							cursor
+							node { id name price }
+						}
+						pageInfo { hasNextPage endCursor }
+					}
+				}`,
+			Output: `
+				{
+					"items": {
+						"totalCount": 3,
+						"edges": [
+							{"cursor": "MQ==", "node": {"__key": 1, "id": 1, "name": "a", "price": 100}},
+							{"cursor": "Mg==", "node": {"__key": 2, "id": 2, "name": "b", "price": 200}}
+						],
+						"pageInfo": {"hasNextPage": true, "endCursor": "Mg=="}
+					}
+				}`,
+			Calls: 1,
+		},
+		{
+			Name: "page after a cursor",
+			Query: `
+				{
+					items(first: 2, after: "Mg==") {
+						edges {
+							node { id price }
+						}
+						pageInfo { hasNextPage }
+					}
+				}`,
+			Output: `
+				{
+					"items": {
+						"edges": [
+							{"node": {"__key": 3, "id": 3, "price": 300}}
+						],
+						"pageInfo": {"hasNextPage": false}
+					}
+				}`,
+			Calls: 1,
+		},
+		{
+			Name: "empty page",
+			Query: `
+				{
+					items(first: 2, after: "Mw==") {
+						edges {
+							node { id price }
+						}
+					}
+				}`,
+			Output: `
+				{
+					"items": {
+						"edges": []
+					}
+				}`,
+			Calls: 0,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			s2Client.reset()
+			runAndValidateQueryResults(t, ctx, e, testCase.Query, testCase.Output)
+			assert.Equal(t, testCase.Calls, s2Client.count)
+		})
+	}
+}
+
+func TestExecutorIntrospection(t *testing.T) {
+	ctx := context.Background()
+	query := `
+		{
+			__schema { queryType { name } }
+			__type(name: "Foo") { name kind }
+		}`
+
+	t.Run("enabled", func(t *testing.T) {
+		e, _ := createKitchenSinkExecutor(t)
+		runAndValidateQueryResults(t, ctx, e, query, `
+			{
+				"__schema": {"queryType": {"name": "Query"}},
+				"__type": {"name": "Foo", "kind": "OBJECT"}
+			}`)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		e, clients := createKitchenSinkExecutor(t, WithIntrospectionDisabled())
+		for _, q := range []string{
+			`{ __schema { queryType { name } } }`,
+			`{ s1f { name } __type(name: "Foo") { name } }`,
+		} {
+			_, err := e.Plan(graphql.MustParse(q, map[string]interface{}{}))
+			assert.Equal(t, ErrIntrospectionDisabled, err)
+			_, _, err = e.Execute(ctx, graphql.MustParse(q, map[string]interface{}{}), nil)
+			assert.Equal(t, ErrIntrospectionDisabled, err)
+		}
+		for _, client := range clients {
+			assert.Equal(t, 0, client.count)
+		}
+
+		// Other queries are unaffected.
+		runAndValidateQueryResults(t, ctx, e, `{ s2root }`, `{"s2root": "hello"}`)
+	})
+}
+
+func TestExecutorExecuteBatch(t *testing.T) {
+	e, clients := createKitchenSinkExecutor(t)
+	ctx := context.Background()
+
+	var plans []*Plan
+	for _, query := range []string{
+		`{ s1fff { name s2bar { s1baz } } }`,
+		`{ s1fff { s2bar { s1baz } } }`,
+	} {
+		plan, err := e.Plan(graphql.MustParse(query, map[string]interface{}{}))
+		require.NoError(t, err)
+		plans = append(plans, plan)
+	}
+
+	results, _, err := e.ExecuteBatch(ctx, plans, nil)
+	require.NoError(t, err)
+	for i, expected := range []string{
+		`{
+			"s1fff": [
+				{"name": "jimbo", "s2bar": {"s1baz": "14"}},
+				{"name": "bob", "s2bar": {"s1baz": "10"}}
+			]
+		}`,
+		`{
+			"s1fff": [
+				{"s2bar": {"s1baz": "14"}},
+				{"s2bar": {"s1baz": "10"}}
+			]
+		}`,
+	} {
+		out, err := json.Marshal(results[i])
+		require.NoError(t, err)
+		assert.JSONEq(t, expected, string(out))
+	}
+
+	// Each plan fetches s1fff on its own, but the Foo and Bar objects they
+	// both need are only fetched once.
+	assert.Equal(t, 3, clients["schema1"].count)
+	assert.Equal(t, 1, clients["schema2"].count)
+}
+
+// recordingExecutorClient records the queries sent to the wrapped client.
+type recordingExecutorClient struct {
+	ExecutorClient
+	mu      sync.Mutex
+	queries []*graphql.Query
+}
+
+func (c *recordingExecutorClient) Execute(ctx context.Context, request *QueryRequest) (*QueryResponse, error) {
+	c.mu.Lock()
+	c.queries = append(c.queries, request.Query)
+	c.mu.Unlock()
+	return c.ExecutorClient.Execute(ctx, request)
+}
+
+func (c *recordingExecutorClient) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queries = nil
+}
+
+// federationAliases returns the aliases of the "_federation" selections of
+// the recorded queries.
+func (c *recordingExecutorClient) federationAliases() map[string]bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	aliases := make(map[string]bool)
+	var record func(selectionSet *graphql.SelectionSet)
+	record = func(selectionSet *graphql.SelectionSet) {
+		if selectionSet == nil {
+			return
+		}
+		for _, selection := range selectionSet.Selections {
+			if selection.Name == federationField {
+				aliases[selection.Alias] = true
+			}
+			record(selection.SelectionSet)
+		}
+		for _, fragment := range selectionSet.Fragments {
+			record(fragment.SelectionSet)
+		}
+	}
+	for _, query := range c.queries {
+		record(query.SelectionSet)
+	}
+	return aliases
+}
+
+// federatedKeys returns the keys passed to the federated field funcs of the
+// recorded queries.
+func (c *recordingExecutorClient) federatedKeys() []interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var keys []interface{}
+	for _, query := range c.queries {
+		for _, selection := range query.SelectionSet.Selections {
+			if selection.Name != federationField {
+				continue
+			}
+			for _, federated := range selection.SelectionSet.Selections {
+				if federatedKeys, ok := federated.UnparsedArgs["keys"].([]interface{}); ok {
+					keys = append(keys, federatedKeys...)
+				}
+			}
+		}
+	}
+	return keys
+}
+
+func TestExecutorSubqueryRewriter(t *testing.T) {
+	ctx := context.Background()
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": buildTestSchema2(),
+	})
+	require.NoError(t, err)
+	schema2 := &recordingExecutorClient{ExecutorClient: execs["schema2"]}
+	execs["schema2"] = schema2
+
+	rewrite := func(service string, selectionSet *graphql.SelectionSet) *graphql.SelectionSet {
+		if service != "schema2" {
+			return selectionSet
+		}
+		return &graphql.SelectionSet{
+			Selections: append(append([]*graphql.Selection{}, selectionSet.Selections...), &graphql.Selection{
+				Name:         "s2ok2",
+				Alias:        "s2ok2",
+				UnparsedArgs: map[string]interface{}{},
+			}),
+			Fragments: selectionSet.Fragments,
+		}
+	}
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)}, WithSubqueryRewriter(rewrite))
+	require.NoError(t, err)
+	schema2.queries = nil
+
+	runAndValidateQueryResults(t, ctx, e, `{ s1fff { name s2ok } }`, `
+		{
+			"s1fff": [
+				{"name": "jimbo", "s2ok": 5},
+				{"name": "bob", "s2ok": 3}
+			]
+		}`)
+
+	require.Len(t, schema2.queries, 1)
+	federation := schema2.queries[0].SelectionSet.Selections[0]
+	require.Equal(t, federationField, federation.Name)
+	var sent []string
+	for _, selection := range federation.SelectionSet.Selections[0].SelectionSet.Selections {
+		sent = append(sent, selection.Name)
+	}
+	// The key field is selected after the rewrite to match the results.
+	assert.Equal(t, []string{"s2ok", "s2ok2", "name"}, sent)
+}
+
+func TestExecutorSingleLookupPerService(t *testing.T) {
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var lookups [][]string
+	s2 := schemabuilder.NewSchemaWithName("schema2")
+	s2.Query().FieldFunc("s2root", func() string {
+		return "hello"
+	})
+	foo := s2.Object("Foo", Foo{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Foo }) []*Foo {
+		var names []string
+		for _, key := range args.Keys {
+			names = append(names, key.Name)
+		}
+		mu.Lock()
+		lookups = append(lookups, names)
+		mu.Unlock()
+		return args.Keys
 	}))
-	user.Key("id")
-	type UserIds struct {
-		Id    int64
-		OrgId int64
-	}
-	s1.Query().FieldFunc("users", func(ctx context.Context) ([]*User, error) {
-		users := make([]*User, 0, 2)
-		users = append(users, &User{Id: int64(1), OrgId: int64(1), Name: "testUser", Email: "email@gmail.com", PhoneNumber: "555-5555"})
-		users = append(users, &User{Id: int64(2), OrgId: int64(2), Name: "testUser2", Email: "email@gmail.com", PhoneNumber: "555-5555"})
-		return users, nil
+	foo.FieldFunc("s2ok", func(in *Foo) int {
+		return len(in.Name)
+	})
+	foo.FieldFunc("s2ok2", func(in *Foo) int {
+		return len(in.Name) * 2
+	})
+	foo.FieldFunc("s2score", func(in *Foo, args struct{ Weight int64 }) int64 {
+		return int64(len(in.Name)) * args.Weight
 	})
 
-	type UserWithExtraKey struct {
-		Id          int64
-		OrgId       int64
-		Name        string
-		UnkownField string
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": s2,
+	})
+	require.NoError(t, err)
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	require.NoError(t, err)
+
+	// All of schema2's fields on the Foos are fetched in a single lookup,
+	// even when selected in several fragments.
+	lookups = nil
+	runAndValidateQueryResults(t, ctx, e, `{
+		s1fff {
+			name
+			s2ok
+			... on Foo { s2ok2 a: s2score(weight: 1) }
+			b: s2score(weight: 3)
+		}
+	}`, `
+		{
+			"s1fff": [
+				{"name": "jimbo", "s2ok": 5, "s2ok2": 10, "a": 5, "b": 15},
+				{"name": "bob", "s2ok": 3, "s2ok2": 6, "a": 3, "b": 9}
+			]
+		}`)
+	assert.Equal(t, [][]string{{"jimbo", "bob"}}, lookups)
+
+	// Foos at different paths have separate keys, and are each fetched once.
+	lookups = nil
+	runAndValidateQueryResults(t, ctx, e, `{
+		s1fff {
+			s2ok
+			s1nest { s2ok s2ok2 }
+		}
+	}`, `
+		{
+			"s1fff": [
+				{"s2ok": 5, "s1nest": {"s2ok": 5, "s2ok2": 10}},
+				{"s2ok": 3, "s1nest": {"s2ok": 3, "s2ok2": 6}}
+			]
+		}`)
+	assert.Len(t, lookups, 2)
+	for _, lookup := range lookups {
+		assert.Equal(t, []string{"jimbo", "bob"}, lookup)
 	}
+}
 
-	s2 := schemabuilder.NewSchemaWithName("s2")
-	s2.Object("User", User{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*UserWithExtraKey }) []*User {
-		users := make([]*User, 0, 2)
-		users = append(users, &User{Id: int64(1), OrgId: int64(1), Name: "testUser", Email: "email@gmail.com", PhoneNumber: "555-5555"})
-		users = append(users, &User{Id: int64(2), OrgId: int64(2), Name: "testUser2", Email: "email@gmail.com", PhoneNumber: "555-5555"})
-		return users
+func BenchmarkExecutorSingleService(b *testing.B) {
+	ctx := context.Background()
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": buildTestSchema2(),
+	})
+	require.NoError(b, err)
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	require.NoError(b, err)
+
+	query := graphql.MustParse(`{ s1fff { name s1hmm s1nest { name s1enum } } }`, map[string]interface{}{})
+	planner := e.getPlanner()
+
+	b.Run("fast path", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, _, err := e.Execute(ctx, query, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("full plan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			plan, err := e.plan(planner, query)
+			if err != nil {
+				b.Fatal(err)
+			}
+			r, _, err := e.execute(ctx, plan, nil, nil, planner, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			deleteKey(r[0], federationField)
+		}
+	})
+}
+
+func TestExecutorEmptyListMakesNoFederatedCall(t *testing.T) {
+	s1 := schemabuilder.NewSchemaWithName("schema1")
+	s1.Object("Foo", Foo{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Foo }) []*Foo {
+		return args.Keys
 	}))
+	s1.Query().FieldFunc("s1fff", func() []*Foo {
+		return []*Foo{}
+	})
 
-	// Create the executor with all the schemas
 	ctx := context.Background()
 	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
-		"s1": s1,
-		"s2": s2,
+		"schema1": s1,
+		"schema2": buildTestSchema2(),
 	})
-	assert.NoError(t, err)
+	require.NoError(t, err)
+	schema2 := &countingExecutorClient{ExecutorClient: execs["schema2"]}
+	execs["schema2"] = schema2
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	require.NoError(t, err)
+	schema2.reset()
 
-	_, err = NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
-	assert.True(t, strings.Contains(err.Error(), "Invalid federation key unkownField"))
+	runAndValidateQueryResults(t, ctx, e, `{ s1fff { name s2ok s2bar { id } } }`, `{"s1fff": []}`)
+	assert.Equal(t, 0, schema2.count)
+}
+
+func TestExecutorMapValuedFields(t *testing.T) {
+	s1 := schemabuilder.NewSchemaWithName("schema1")
+	s1.Object("Foo", Foo{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Foo }) []*Foo {
+		return args.Keys
+	}))
+	s1.Query().FieldFunc("s1fooMap", func(args struct{ Kind string }) map[string]*Foo {
+		switch args.Kind {
+		case "nil":
+			return nil
+		case "empty":
+			return map[string]*Foo{}
+		default:
+			return map[string]*Foo{
+				"second": {Name: "bob"},
+				"first":  {Name: "jimbo"},
+			}
+		}
+	})
+
+	ctx := context.Background()
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": s1,
+		"schema2": buildTestSchema2(),
+	})
+	require.NoError(t, err)
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	require.NoError(t, err)
+
+	// Each value is enriched by schema2 under its key.
+	runAndValidateQueryResults(t, ctx, e, `{
+		s1fooMap(kind: "full") { key value { name s2ok } }
+		empty: s1fooMap(kind: "empty") { key value { name s2ok } }
+		nil: s1fooMap(kind: "nil") { key value { name s2ok } }
+	}`, `
+		{
+			"s1fooMap": [
+				{"key": "first", "value": {"name": "jimbo", "s2ok": 5}},
+				{"key": "second", "value": {"name": "bob", "s2ok": 3}}
+			],
+			"empty": [],
+			"nil": null
+		}`)
 }
 
 func createMutationExecutor() (map[string]ExecutorClient, error) {
@@ -1591,3 +2652,461 @@ func TestBasicFederatedObjectFetchAllFields(t *testing.T) {
 		})
 	}
 }
+
+func TestExecutorMaxHops(t *testing.T) {
+	ctx := context.Background()
+	e, _ := createKitchenSinkExecutor(t, WithMaxHops(2))
+
+	// schema1 -> schema2 is within the limit.
+	runAndValidateQueryResults(t, ctx, e, `{ s1fff { name s2ok } }`, `
+		{
+			"s1fff": [
+				{"name": "jimbo", "s2ok": 5},
+				{"name": "bob", "s2ok": 3}
+			]
+		}`)
+
+	// schema1 -> schema2 -> schema1 is not.
+	_, err := e.Plan(graphql.MustParse(`{ s1fff { s2bar { s1baz } } }`, map[string]interface{}{}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "query requires 3 sequential hops, more than the maximum of 2: schema1 -> schema2 (s1fff) -> schema1 (s1fff.s2bar)")
+
+	// Parallel hops don't add up.
+	_, err = e.Plan(graphql.MustParse(`{ s1fff { s2ok s1nest { s2ok } } s2root }`, map[string]interface{}{}))
+	require.NoError(t, err)
+}
+
+func TestExecutorNestedSingleParents(t *testing.T) {
+	ctx := context.Background()
+	e, _ := createKitchenSinkExecutor(t)
+
+	// Keys are extracted from a single object nested in single objects.
+	runAndValidateQueryResults(t, ctx, e, `{ s1f { s1nest { s1nest { name s2ok } } } }`, `
+		{
+			"s1f": {
+				"s1nest": {
+					"s1nest": {"name": "jimbob", "s2ok": 6}
+				}
+			}
+		}`)
+
+	t.Run("null intermediate parent", func(t *testing.T) {
+		s1 := schemabuilder.NewSchemaWithName("schema1")
+		foo := s1.Object("Foo", Foo{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Foo }) []*Foo {
+			return args.Keys
+		}))
+		foo.FieldFunc("s1maybe", func(f *Foo) *Foo {
+			if f.Name == "" {
+				return nil
+			}
+			return &Foo{Name: f.Name[1:]}
+		})
+		s1.Query().FieldFunc("s1f", func(args struct{ Name string }) *Foo {
+			return &Foo{Name: args.Name}
+		})
+
+		execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+			"schema1": s1,
+			"schema2": buildTestSchema2(),
+		})
+		require.NoError(t, err)
+		schema2 := &countingExecutorClient{ExecutorClient: execs["schema2"]}
+		execs["schema2"] = schema2
+		e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+		require.NoError(t, err)
+
+		runAndValidateQueryResults(t, ctx, e, `{
+			full: s1f(name: "ab") { s1maybe { s1maybe { name s2ok } } }
+			short: s1f(name: "a") { s1maybe { s1maybe { name s2ok } } }
+		}`, `
+			{
+				"full": {"s1maybe": {"s1maybe": {"name": "", "s2ok": 0}}},
+				"short": {"s1maybe": {"s1maybe": null}}
+			}`)
+
+		// No objects to enrich means no call to schema2.
+		schema2.reset()
+		runAndValidateQueryResults(t, ctx, e, `{ s1f(name: "") { s1maybe { s1maybe { name s2ok } } } }`, `
+			{
+				"s1f": {"s1maybe": null}
+			}`)
+		assert.Equal(t, 0, schema2.count)
+	})
+}
+
+func TestExecutorExtensionFieldArguments(t *testing.T) {
+	ctx := context.Background()
+	e, executors := createKitchenSinkExecutor(t)
+
+	// Both the key and the arguments are forwarded to the extension field,
+	// including different arguments for aliases of the same field.
+	runAndValidateQueryResults(t, ctx, e, `{
+		s1fff {
+			name
+			s2score
+			heavy: s2score(weight: 10)
+			s1nest { light: s2score(weight: 2) }
+		}
+	}`, `
+		{
+			"s1fff": [
+				{"name": "jimbo", "s2score": 5, "heavy": 50, "s1nest": {"light": 10}},
+				{"name": "bob", "s2score": 3, "heavy": 30, "s1nest": {"light": 6}}
+			]
+		}`)
+
+	// Arguments from variables are forwarded as values.
+	executors["schema2"].reset()
+	res, _, err := e.Execute(ctx, graphql.MustParse(`query Q($weight: int64) { s1fff { s2score(weight: $weight) } }`, map[string]interface{}{
+		"weight": float64(3),
+	}), nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"s1fff": []interface{}{
+			map[string]interface{}{"s2score": json.Number("15")},
+			map[string]interface{}{"s2score": json.Number("9")},
+		},
+	}, res)
+	assert.Equal(t, 1, executors["schema2"].count)
+}
+
+type deprecatedEnum int
+
+func TestExecutorEnumValueDeprecation(t *testing.T) {
+	ctx := context.Background()
+	s1 := schemabuilder.NewSchemaWithName("s1")
+	s1.Enum(deprecatedEnum(0), map[string]deprecatedEnum{"old": 1, "new": 2})
+	s1.DeprecateEnumValue(deprecatedEnum(0), "old", "use new")
+	s1.Query().FieldFunc("s1enum", func() deprecatedEnum { return 2 })
+	s2 := schemabuilder.NewSchemaWithName("s2")
+	s2.Enum(deprecatedEnum(0), map[string]deprecatedEnum{"old": 1, "new": 2})
+	s2.Query().FieldFunc("s2enum", func(args struct{ Value deprecatedEnum }) deprecatedEnum { return args.Value })
+
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{"s1": s1, "s2": s2})
+	require.NoError(t, err)
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	require.NoError(t, err)
+
+	// The value is deprecated on the gateway because s1 deprecates it.
+	runAndValidateQueryResults(t, ctx, e, `{
+		__type(name: "deprecatedEnum") {
+			enumValues(includeDeprecated: true) { name isDeprecated deprecationReason }
+		}
+	}`, `
+		{
+			"__type": {
+				"enumValues": [
+					{"name": "new", "isDeprecated": false, "deprecationReason": ""},
+					{"name": "old", "isDeprecated": true, "deprecationReason": "use new"}
+				]
+			}
+		}`)
+
+	// Deprecated values can still be used.
+	runAndValidateQueryResults(t, ctx, e, `{ s2enum(value: old) }`, `{"s2enum": "old"}`)
+}
+
+type deprecatedFilter struct {
+	Name     *string
+	Nickname *string
+}
+
+func TestExecutorArgumentDeprecation(t *testing.T) {
+	ctx := context.Background()
+	search := func(args struct {
+		Query  *string
+		Q      *string
+		Filter *deprecatedFilter
+	}) string {
+		return "found"
+	}
+	s1 := schemabuilder.NewSchemaWithName("s1")
+	s1.Query().FieldFunc("search", search)
+	s2 := schemabuilder.NewSchemaWithName("s2")
+	s2.DeprecateInputField(deprecatedFilter{}, "nickname", "use name")
+	s2.Query().FieldFunc("search", search, schemabuilder.DeprecatedArg("q", "use query"))
+
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{"s1": s1, "s2": s2})
+	require.NoError(t, err)
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)}, WithRootFieldOwner("Query", "search", "s2"))
+	require.NoError(t, err)
+
+	// The argument and input field are deprecated on the gateway because s2
+	// deprecates them.
+	runAndValidateQueryResults(t, ctx, e, `{
+		query: __type(name: "Query") {
+			fields { name args { name isDeprecated deprecationReason } }
+		}
+		filter: __type(name: "deprecatedFilter_InputObject") {
+			inputFields { name isDeprecated deprecationReason }
+		}
+	}`, `
+		{
+			"query": {
+				"fields": [
+					{"name": "__schema", "args": []},
+					{
+						"name": "__type",
+						"args": [{"name": "name", "isDeprecated": false, "deprecationReason": ""}]
+					},
+					{
+						"name": "search",
+						"args": [
+							{"name": "filter", "isDeprecated": false, "deprecationReason": ""},
+							{"name": "q", "isDeprecated": true, "deprecationReason": "use query"},
+							{"name": "query", "isDeprecated": false, "deprecationReason": ""}
+						]
+					}
+				]
+			},
+			"filter": {
+				"inputFields": [
+					{"name": "name", "isDeprecated": false, "deprecationReason": ""},
+					{"name": "nickname", "isDeprecated": true, "deprecationReason": "use name"}
+				]
+			}
+		}`)
+
+	// Deprecated arguments can still be used.
+	runAndValidateQueryResults(t, ctx, e, `{ search(q: "x", filter: {nickname: "y"}) }`, `{"search": "found"}`)
+
+	t.Run("servers without input value deprecation", func(t *testing.T) {
+		execs, err := makeExecutors(map[string]*schemabuilder.Schema{"s1": s1, "s2": s2})
+		require.NoError(t, err)
+		execs["s1"] = legacyIntrospectionExecutorClient{ExecutorClient: execs["s1"]}
+		e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)}, WithRootFieldOwner("Query", "search", "s2"))
+		require.NoError(t, err)
+		runAndValidateQueryResults(t, ctx, e, `{ search(q: "x") }`, `{"search": "found"}`)
+	})
+}
+
+// legacyIntrospectionExecutorClient rejects the introspection query, like
+// servers whose __InputValue has no isDeprecated field.
+type legacyIntrospectionExecutorClient struct {
+	ExecutorClient
+}
+
+func (c legacyIntrospectionExecutorClient) Execute(ctx context.Context, request *QueryRequest) (*QueryResponse, error) {
+	marshaled, err := MarshalQuery(request.Query)
+	if err != nil {
+		return nil, err
+	}
+	if source, ok := introspectionRequestSource(&thunderpb.ExecuteRequest{Query: marshaled}); ok && source != introspection.LegacyIntrospectionQuery {
+		return nil, errors.New(`unknown field "isDeprecated"`)
+	}
+	return c.ExecutorClient.Execute(ctx, request)
+}
+
+func TestExecutorComplexVariables(t *testing.T) {
+	ctx := context.Background()
+	e, _ := createKitchenSinkExecutor(t)
+
+	// JSON-decoded variables hold numbers as float64.
+	query := graphql.MustParse(`
+		query Q($ids: [int64!]!, $pair: Pair!) {
+			s1fff { name s2ids(ids: $ids) }
+			s1echo(foo: "echo", required: $pair)
+		}`, map[string]interface{}{
+		"ids":  []interface{}{float64(1), float64(2), float64(3)},
+		"pair": map[string]interface{}{"a": float64(4), "b": float64(5)},
+	})
+	res, _, err := e.Execute(ctx, query, nil)
+	require.NoError(t, err)
+
+	ids := []interface{}{json.Number("1"), json.Number("2"), json.Number("3")}
+	assert.Equal(t, map[string]interface{}{
+		"s1fff": []interface{}{
+			map[string]interface{}{"name": "jimbo", "s2ids": ids},
+			map[string]interface{}{"name": "bob", "s2ids": ids},
+		},
+		"s1echo": "echo {4 5} <nil>",
+	}, res)
+}
+
+func TestExecutorPrintSchema(t *testing.T) {
+	e, _ := createKitchenSinkExecutor(t)
+	sdl := introspection.PrintSchema(e.Schema())
+
+	// Types merge the fields of all services.
+	assert.Contains(t, sdl, `type Foo {
+  _federation: Foo
+  name: string!
+  s1enum: Enum!
+  s1hmm: string
+  s1nest: Foo
+  s2bar: Bar
+  s2ids(ids: [int64!]!): [int64!]!
+  s2ok: int!
+  s2ok2: int!
+  s2score(weight: int64): int64!
+}`)
+	assert.Contains(t, sdl, `type Query {
+  _federation: Federation!
+  s1both: [FooOrBar!]!
+  s1echo(foo: string!, optional: int64, required: Pair_InputObject!): string!
+  s1f: Foo
+  s1fff: [Foo!]!
+  s2root: string!
+}`)
+	assert.Contains(t, sdl, "union FooOrBar = Bar | Foo")
+	assert.Contains(t, sdl, `input Pair_InputObject {
+  a: int64!
+  b: int64!
+}`)
+	assert.Contains(t, sdl, `enum Enum {
+  one
+}`)
+	assert.NotContains(t, sdl, "__Type")
+}
+
+// node is implemented by objects fetched with the node field.
+type node interface{}
+
+func TestExecutorInterfaceUnion(t *testing.T) {
+	ctx := context.Background()
+	s2 := buildTestSchema2()
+	s2.InterfaceUnion("Node", (*node)(nil), &Foo{}, &Bar{})
+	s2.Query().FieldFunc("node", func(args struct{ Id string }) node {
+		if args.Id == "bar" {
+			return &Bar{Id: 12}
+		}
+		return &Foo{Name: args.Id}
+	})
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": s2,
+	})
+	require.NoError(t, err)
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	require.NoError(t, err)
+
+	// The members of the union resolved by schema2 are routed to schema1 for
+	// the fields that it resolves.
+	query := `
+		{
+			foo: node(id: "jimbo") {
+				__typename
+				... on Foo { name s1hmm s2ok }
+				... on Bar { id s1baz }
+			}
+			bar: node(id: "bar") {
+				__typename
+				... on Foo { name s1hmm s2ok }
+				... on Bar { id s1baz }
+			}
+		}`
+	runAndValidateQueryResults(t, ctx, e, query, `
+		{
+			"foo": {"__typename": "Foo", "name": "jimbo", "s1hmm": "jimbo!!!", "s2ok": 5},
+			"bar": {"__typename": "Bar", "id": 12, "s1baz": "12"}
+		}`)
+}
+
+func TestExecutorErrorLocations(t *testing.T) {
+	e, _ := createKitchenSinkExecutor(t)
+
+	testCases := []struct {
+		Name          string
+		Query         string
+		ExpectedError string
+		Locations     []graphql.Location
+	}{
+		{
+			Name: "unknown field",
+			Query: `{
+				s1fff {
+					name
+					missing
+				}
+			}`,
+			ExpectedError: "unknown field missing on typ Foo",
+			Locations:     []graphql.Location{{Line: 4, Column: 6}},
+		},
+		{
+			Name:          "argument of the wrong type",
+			Query:         `{ s1fff { name s2score(weight: "2") } }`,
+			ExpectedError: `argument weight of Foo.s2score: expected int64, got string "2"`,
+			Locations:     []graphql.Location{{Line: 1, Column: 16}},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			_, err := e.Plan(graphql.MustParse(testCase.Query, nil))
+			require.Error(t, err)
+			cause := oops.Cause(err)
+			assert.Equal(t, testCase.ExpectedError, cause.Error())
+			assert.Equal(t, testCase.Locations, graphql.ErrorLocations(cause))
+		})
+	}
+}
+
+// countingScheduler counts the queries it runs.
+type countingScheduler struct {
+	graphql.WorkScheduler
+	mu   sync.Mutex
+	runs int
+}
+
+func (s *countingScheduler) Run(resolver graphql.UnitResolver, initialUnits ...*graphql.WorkUnit) {
+	s.mu.Lock()
+	s.runs++
+	s.mu.Unlock()
+	s.WorkScheduler.Run(resolver, initialUnits...)
+}
+
+func TestServerScheduler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	scheduler := &countingScheduler{WorkScheduler: graphql.NewSequentialScheduler()}
+	s2, err := NewServer(buildTestSchema2().MustBuild(), WithScheduler(scheduler))
+	require.NoError(t, err)
+	s1, err := NewServer(buildTestSchema1().MustBuild())
+	require.NoError(t, err)
+	execs := map[string]ExecutorClient{
+		"schema1": &DirectExecutorClient{Client: s1},
+		"schema2": &DirectExecutorClient{Client: s2},
+	}
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	require.NoError(t, err)
+
+	scheduler.mu.Lock()
+	scheduler.runs = 0
+	scheduler.mu.Unlock()
+	runAndValidateQueryResults(t, ctx, e, `{ s1fff { name s2ok } }`, `
+		{
+			"s1fff": [
+				{"name": "jimbo", "s2ok": 5},
+				{"name": "bob", "s2ok": 3}
+			]
+		}`)
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	assert.Equal(t, 1, scheduler.runs)
+}
+
+func TestServerDebounce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	s2 := buildTestSchema2()
+	s2.Object("Foo", Foo{}).FieldFunc("s2debounce", func(ctx context.Context, in *Foo) int64 {
+		return int64(batch.Debounce(ctx) / time.Millisecond)
+	})
+	server2, err := NewServer(s2.MustBuild(), WithDebounce(20*time.Millisecond), WithScheduler(graphql.NewSequentialScheduler()))
+	require.NoError(t, err)
+	server1, err := NewServer(buildTestSchema1().MustBuild())
+	require.NoError(t, err)
+	execs := map[string]ExecutorClient{
+		"schema1": &DirectExecutorClient{Client: server1},
+		"schema2": &DirectExecutorClient{Client: server2},
+	}
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	require.NoError(t, err)
+
+	runAndValidateQueryResults(t, ctx, e, `{ s1fff { name s2debounce } }`, `
+		{
+			"s1fff": [
+				{"name": "jimbo", "s2debounce": 20},
+				{"name": "bob", "s2debounce": 20}
+			]
+		}`)
+}
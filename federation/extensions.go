@@ -0,0 +1,121 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/thunderpb"
+)
+
+type extensionsKey struct{}
+
+// collectedExtensions collects the extensions returned by services.
+type collectedExtensions struct {
+	mu       sync.Mutex
+	services map[string]map[string]interface{}
+}
+
+// WithExtensions returns a context that collects the extensions, eg. timings
+// or cache hints, returned by services in QueryResponse.Extensions for the
+// queries executed with it, which can be read with Extensions.
+func WithExtensions(ctx context.Context) context.Context {
+	return context.WithValue(ctx, extensionsKey{}, &collectedExtensions{})
+}
+
+// Extensions returns the extensions collected in a context created with
+// WithExtensions, nested by service to be added to the response, eg.
+//   {"services": {"schema2": {"timing": 12}}}
+// or nil if no service returned extensions. Keys returned by several
+// responses of the same service take the value of the last response.
+func Extensions(ctx context.Context) map[string]interface{} {
+	collected, ok := ctx.Value(extensionsKey{}).(*collectedExtensions)
+	if !ok {
+		return nil
+	}
+	collected.mu.Lock()
+	defer collected.mu.Unlock()
+	if len(collected.services) == 0 {
+		return nil
+	}
+	services := make(map[string]interface{}, len(collected.services))
+	for service, extensions := range collected.services {
+		copied := make(map[string]interface{}, len(extensions))
+		for key, value := range extensions {
+			copied[key] = value
+		}
+		services[service] = copied
+	}
+	return map[string]interface{}{"services": services}
+}
+
+func recordExtensions(ctx context.Context, service string, extensions map[string]interface{}) {
+	collected, ok := ctx.Value(extensionsKey{}).(*collectedExtensions)
+	if !ok || len(extensions) == 0 {
+		return
+	}
+	collected.mu.Lock()
+	defer collected.mu.Unlock()
+	if collected.services == nil {
+		collected.services = make(map[string]map[string]interface{})
+	}
+	if collected.services[service] == nil {
+		collected.services[service] = make(map[string]interface{}, len(extensions))
+	}
+	for key, value := range extensions {
+		collected.services[service][key] = value
+	}
+}
+
+type responseExtensionsKey struct{}
+
+// responseExtensions collects the extensions of the response of a Server.
+type responseExtensions struct {
+	mu         sync.Mutex
+	extensions map[string]interface{}
+}
+
+// SetResponseExtension sets the extension key of the response to the query
+// executed by a Server with ctx, eg. from a resolver. The executor collects
+// the extensions of the responses of each service, see WithExtensions. It
+// does nothing outside of a query executed by a Server.
+func SetResponseExtension(ctx context.Context, key string, value interface{}) {
+	collected, ok := ctx.Value(responseExtensionsKey{}).(*responseExtensions)
+	if !ok {
+		return
+	}
+	collected.mu.Lock()
+	defer collected.mu.Unlock()
+	if collected.extensions == nil {
+		collected.extensions = make(map[string]interface{})
+	}
+	collected.extensions[key] = value
+}
+
+// withResponseExtensions returns a context collecting the extensions set with
+// SetResponseExtension, which marshal returns as JSON.
+func withResponseExtensions(ctx context.Context) (context.Context, func() ([]byte, error)) {
+	collected := &responseExtensions{}
+	marshal := func() ([]byte, error) {
+		collected.mu.Lock()
+		defer collected.mu.Unlock()
+		if len(collected.extensions) == 0 {
+			return nil, nil
+		}
+		return json.Marshal(collected.extensions)
+	}
+	return context.WithValue(ctx, responseExtensionsKey{}, collected), marshal
+}
+
+// newQueryResponse converts resp, the response of a server, to a
+// QueryResponse, unmarshaling its extensions.
+func newQueryResponse(resp *thunderpb.ExecuteResponse) (*QueryResponse, error) {
+	response := &QueryResponse{Result: resp.Result}
+	if len(resp.Extensions) > 0 {
+		if err := json.Unmarshal(resp.Extensions, &response.Extensions); err != nil {
+			return nil, oops.Wrapf(err, "unmarshaling extensions")
+		}
+	}
+	return response, nil
+}
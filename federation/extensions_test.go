@@ -0,0 +1,114 @@
+package federation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/samsarahq/thunder/thunderpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// extensionsExecutorClient adds extensions to the responses of a client.
+type extensionsExecutorClient struct {
+	ExecutorClient
+	extensions map[string]interface{}
+}
+
+func (c *extensionsExecutorClient) Execute(ctx context.Context, request *QueryRequest) (*QueryResponse, error) {
+	response, err := c.ExecutorClient.Execute(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	response.Extensions = c.extensions
+	return response, nil
+}
+
+func TestExecutorExtensions(t *testing.T) {
+	ctx := context.Background()
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": buildTestSchema2(),
+	})
+	require.NoError(t, err)
+	execs["schema2"] = &extensionsExecutorClient{
+		ExecutorClient: execs["schema2"],
+		extensions:     map[string]interface{}{"timing": 12, "cacheHint": "public"},
+	}
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	require.NoError(t, err)
+
+	ctx = WithExtensions(ctx)
+	runAndValidateQueryResults(t, ctx, e, `{ s1fff { name s2ok } }`, `
+		{
+			"s1fff": [
+				{"name": "jimbo", "s2ok": 5},
+				{"name": "bob", "s2ok": 3}
+			]
+		}`)
+	assert.Equal(t, map[string]interface{}{
+		"services": map[string]interface{}{
+			"schema2": map[string]interface{}{"timing": 12, "cacheHint": "public"},
+		},
+	}, Extensions(ctx))
+
+	// Contexts without WithExtensions do not collect extensions.
+	assert.Nil(t, Extensions(context.Background()))
+}
+
+func TestServerExtensions(t *testing.T) {
+	ctx := context.Background()
+	s2 := buildTestSchema2()
+	s2.Object("Foo", Foo{}).FieldFunc("s2timed", func(ctx context.Context, in *Foo) int {
+		SetResponseExtension(ctx, "timing", 12)
+		return len(in.Name)
+	})
+	server, err := NewServer(s2.MustBuild())
+	require.NoError(t, err)
+
+	for name, client := range map[string]ExecutorClient{
+		"direct":   &DirectExecutorClient{Client: server},
+		"protobuf": &DirectExecutorClient{Client: protobufExecutorServer{server}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s1, err := NewServer(buildTestSchema1().MustBuild())
+			require.NoError(t, err)
+			execs := map[string]ExecutorClient{
+				"schema1": &DirectExecutorClient{Client: s1},
+				"schema2": client,
+			}
+			e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+			require.NoError(t, err)
+
+			ctx := WithExtensions(ctx)
+			runAndValidateQueryResults(t, ctx, e, `{ s1fff { name s2timed } }`, `
+				{
+					"s1fff": [
+						{"name": "jimbo", "s2timed": 5},
+						{"name": "bob", "s2timed": 3}
+					]
+				}`)
+			assert.Equal(t, map[string]interface{}{
+				"services": map[string]interface{}{
+					"schema2": map[string]interface{}{"timing": float64(12)},
+				},
+			}, Extensions(ctx))
+		})
+	}
+
+	// The extensions survive the protobuf encoding of the response.
+	marshaled, err := MarshalQuery(graphql.MustParse(`{ s2root }`, map[string]interface{}{}))
+	require.NoError(t, err)
+	resp, err := server.Execute(ctx, &thunderpb.ExecuteRequest{Query: marshaled})
+	require.NoError(t, err)
+	assert.Nil(t, resp.Extensions)
+	resp.Extensions = []byte(`{"timing":12}`)
+	data, err := proto.Marshal(resp)
+	require.NoError(t, err)
+	var decoded thunderpb.ExecuteResponse
+	require.NoError(t, proto.Unmarshal(data, &decoded))
+	assert.Equal(t, resp, &decoded)
+}
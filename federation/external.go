@@ -0,0 +1,102 @@
+package federation
+
+import (
+	"sort"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// externalField is a field that a service references, but that another
+// service owns.
+type externalField struct {
+	service  string
+	typeName string
+	field    string
+}
+
+// WithExternalField declares that the field on the object typeName is external
+// to service: the service references the field, eg. because it is part of a
+// federated key, but another service owns and resolves it. The field is never
+// sent to service, and NewExecutor fails unless another service can resolve
+// it. Services built with schemabuilder can instead declare their external
+// fields with schemabuilder.Object.External, which introspection reports.
+func WithExternalField(service, typeName, field string) ExecutorOption {
+	return func(e *Executor) {
+		e.externalFields = append(e.externalFields, externalField{
+			service:  service,
+			typeName: typeName,
+			field:    field,
+		})
+	}
+}
+
+// declaredExternalFields returns the external fields that services declared
+// in their schemas, sorted.
+func declaredExternalFields(planner *Planner) []externalField {
+	var fields []externalField
+	for typeName, typ := range planner.flattener.types {
+		obj, ok := typ.(*graphql.Object)
+		if !ok {
+			continue
+		}
+		for name, field := range obj.Fields {
+			info, ok := planner.schema.Fields[field]
+			if !ok {
+				continue
+			}
+			for service := range info.External {
+				fields = append(fields, externalField{
+					service:  service,
+					typeName: typeName,
+					field:    name,
+				})
+			}
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		a, b := fields[i], fields[j]
+		if a.typeName != b.typeName {
+			return a.typeName < b.typeName
+		}
+		if a.field != b.field {
+			return a.field < b.field
+		}
+		return a.service < b.service
+	})
+	return fields
+}
+
+// applyExternalFields removes external fields from the services that declared
+// them in planner, checking that every external field is still resolved by
+// some other service.
+func (e *Executor) applyExternalFields(planner *Planner) error {
+	externalFields := append(declaredExternalFields(planner), e.externalFields...)
+	for _, external := range externalFields {
+		obj, ok := planner.flattener.types[external.typeName].(*graphql.Object)
+		if !ok {
+			return oops.Errorf("external field %s.%s of service %s: unknown object type %s", external.typeName, external.field, external.service, external.typeName)
+		}
+		field, ok := obj.Fields[external.field]
+		if !ok {
+			return oops.Errorf("external field %s.%s of service %s: unknown field", external.typeName, external.field, external.service)
+		}
+		info, ok := planner.schema.Fields[field]
+		if !ok {
+			return oops.Errorf("external field %s.%s of service %s: no services resolve the field", external.typeName, external.field, external.service)
+		}
+
+		satisfied := false
+		for service, ok := range info.Services {
+			if ok && service != external.service {
+				satisfied = true
+				break
+			}
+		}
+		if !satisfied {
+			return oops.Errorf("external field %s.%s of service %s is not resolved by any other service", external.typeName, external.field, external.service)
+		}
+		delete(info.Services, external.service)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package federation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorExternalFields(t *testing.T) {
+	ctx := context.Background()
+	newExecutor := func(opts ...ExecutorOption) (*Executor, error) {
+		e, _, err := newTestExecutor(t, map[string]*schemabuilder.Schema{
+			"schema1": buildTestSchema1(),
+			"schema2": buildTestSchema2(),
+		}, opts...)
+		return e, err
+	}
+
+	t.Run("satisfied", func(t *testing.T) {
+		e, err := newExecutor(WithExternalField("schema2", "Foo", "name"))
+		require.NoError(t, err)
+
+		ownership, err := e.Ownership()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"schema1"}, ownership.Fields["Foo"]["name"])
+
+		runAndValidateQueryResults(t, ctx, e, `{ s1fff { name s2ok } }`, `
+			{
+				"s1fff": [
+					{"name": "jimbo", "s2ok": 5},
+					{"name": "bob", "s2ok": 3}
+				]
+			}`)
+	})
+
+	testCases := []struct {
+		Name  string
+		Opt   ExecutorOption
+		Error string
+	}{
+		{
+			Name:  "not resolved by another service",
+			Opt:   WithExternalField("schema2", "Foo", "s2ok"),
+			Error: "external field Foo.s2ok of service schema2 is not resolved by any other service",
+		},
+		{
+			Name:  "unknown field",
+			Opt:   WithExternalField("schema2", "Foo", "missing"),
+			Error: "external field Foo.missing of service schema2: unknown field",
+		},
+		{
+			Name:  "unknown type",
+			Opt:   WithExternalField("schema2", "Missing", "name"),
+			Error: "unknown object type Missing",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			_, err := newExecutor(testCase.Opt)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), testCase.Error)
+		})
+	}
+
+	t.Run("declared in the schema", func(t *testing.T) {
+		newExecutor := func(external string) (*Executor, error) {
+			schema2 := buildTestSchema2()
+			schema2.Object("Foo", Foo{}).External(external)
+			e, _, err := newTestExecutor(t, map[string]*schemabuilder.Schema{
+				"schema1": buildTestSchema1(),
+				"schema2": schema2,
+			})
+			return e, err
+		}
+
+		e, err := newExecutor("name")
+		require.NoError(t, err)
+		ownership, err := e.Ownership()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"schema1"}, ownership.Fields["Foo"]["name"])
+
+		_, err = newExecutor("s2ok")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "external field Foo.s2ok of service schema2 is not resolved by any other service")
+	})
+}
@@ -0,0 +1,44 @@
+package federation
+
+import (
+	"github.com/samsarahq/go/oops"
+)
+
+// WithFallbackExecutor makes the executor named service, eg. a monolith that
+// is being migrated to federation, resolve only the fields that no other
+// service resolves. Fields that other services resolve as well are routed to
+// those services, so that fields move out of the fallback executor as soon as
+// a federated service starts resolving them. The fallback executor must also be
+// one of the executors whose schemas are merged.
+func WithFallbackExecutor(service string) ExecutorOption {
+	return func(e *Executor) {
+		e.fallbackService = service
+	}
+}
+
+// applyFallbackExecutor records the fallback service in planner, checking
+// that it is one of the executors.
+func (e *Executor) applyFallbackExecutor(planner *Planner) error {
+	if e.fallbackService == "" {
+		return nil
+	}
+	if _, ok := e.Executors[e.fallbackService]; !ok {
+		return oops.Errorf("unknown executor %s", e.fallbackService)
+	}
+	planner.fallbackService = e.fallbackService
+	return nil
+}
+
+// claimedElsewhere reports whether a service other than the fallback service
+// resolves the field, in which case the fallback service must not resolve it.
+func (e *Planner) claimedElsewhere(fieldInfo *FieldInfo) bool {
+	if e.fallbackService == "" {
+		return false
+	}
+	for service, hasField := range fieldInfo.Services {
+		if hasField && service != e.fallbackService {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package federation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildTestLegacySchema() *schemabuilder.Schema {
+	schema := schemabuilder.NewSchemaWithName("legacy")
+	type FooKeys struct {
+		Name string
+	}
+
+	schema.Query().FieldFunc("legacyRoot", func() string {
+		return "from the monolith"
+	})
+	// s2root and Foo.s2ok have moved to schema2.
+	schema.Query().FieldFunc("s2root", func() string {
+		return "stale"
+	})
+
+	foo := schema.Object("Foo", Foo{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*FooKeys }) []*Foo {
+		foos := make([]*Foo, 0, len(args.Keys))
+		for _, key := range args.Keys {
+			foos = append(foos, &Foo{Name: key.Name})
+		}
+		return foos
+	}))
+	foo.FieldFunc("s2ok", func(in *Foo) int {
+		return -1
+	})
+	foo.FieldFunc("legacyName", func(in *Foo) string {
+		return "legacy " + in.Name
+	})
+	return schema
+}
+
+func TestExecutorFallbackExecutor(t *testing.T) {
+	ctx := context.Background()
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": buildTestSchema2(),
+		"legacy":  buildTestLegacySchema(),
+	})
+	require.NoError(t, err)
+	legacy := &recordingExecutorClient{ExecutorClient: execs["legacy"]}
+	execs["legacy"] = legacy
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)}, WithFallbackExecutor("legacy"))
+	require.NoError(t, err)
+
+	t.Run("fields only on the fallback executor", func(t *testing.T) {
+		legacy.queries = nil
+		runAndValidateQueryResults(t, ctx, e, `{
+			legacyRoot
+			s1fff { name legacyName }
+		}`, `
+			{
+				"legacyRoot": "from the monolith",
+				"s1fff": [
+					{"name": "jimbo", "legacyName": "legacy jimbo"},
+					{"name": "bob", "legacyName": "legacy bob"}
+				]
+			}`)
+		assert.Len(t, legacy.queries, 2)
+	})
+
+	t.Run("fields claimed by federated services", func(t *testing.T) {
+		legacy.queries = nil
+		runAndValidateQueryResults(t, ctx, e, `{
+			s2root
+			s1fff { name s2ok }
+		}`, `
+			{
+				"s2root": "hello",
+				"s1fff": [
+					{"name": "jimbo", "s2ok": 5},
+					{"name": "bob", "s2ok": 3}
+				]
+			}`)
+		assert.Empty(t, legacy.queries)
+	})
+
+	t.Run("unknown fallback executor", func(t *testing.T) {
+		_, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)}, WithFallbackExecutor("monolith"))
+		assert.Error(t, err)
+	})
+}
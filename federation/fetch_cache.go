@@ -0,0 +1,412 @@
+package federation
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// WithFetchCache caches the fields of federated objects fetched from services
+// for ttl, across queries, so that subqueries selecting only cached fields of
+// an object are not sent to its service again. Fields are cached by service,
+// object type, object key, lookup arguments (see WithLookupArguments), and
+// the field's name, arguments and selections. At most 10000 fields are
+// cached, see NewMemoryCache.
+//
+// The cache is shared by all queries, whatever their metadata, so it should
+// only be used for services whose results are the same for every caller.
+func WithFetchCache(ttl time.Duration) ExecutorOption {
+	return WithFetchCacheStore(NewMemoryCache(defaultMemoryCacheMaxEntries), "", ttl)
+}
+
+// Cache stores the fields cached with WithFetchCacheStore, eg. in an external
+// store such as Redis. The executor gets and sets all the fields of the
+// objects of a subquery at once. Implementations must be safe for concurrent
+// use.
+type Cache interface {
+	// GetMulti returns the values stored for keys, in the same order, with
+	// nil for the keys that have no value or whose value expired.
+	GetMulti(ctx context.Context, keys []string) ([][]byte, error)
+	// SetMulti stores the values of entries by key, for ttl.
+	SetMulti(ctx context.Context, entries map[string][]byte, ttl time.Duration) error
+}
+
+// WithFetchCacheStore is like WithFetchCache, but stores the cached fields in
+// store, so that several executors, eg. the replicas of a gateway, can share
+// them. The keys of the fields start with namespace, so that executors whose
+// results differ, eg. the gateways of different environments, can share a
+// store without sharing their fields. Errors of the store are treated as
+// cache misses, and do not fail queries.
+func WithFetchCacheStore(store Cache, namespace string, ttl time.Duration) ExecutorOption {
+	return func(e *Executor) {
+		e.fetchCache = &fetchCache{store: store, namespace: namespace, ttl: ttl}
+	}
+}
+
+// fetchCache caches the fields of federated objects fetched from services.
+type fetchCache struct {
+	store     Cache
+	namespace string
+	ttl       time.Duration
+}
+
+const defaultMemoryCacheMaxEntries = 10000
+
+// memoryCache is a Cache storing values in memory, up to maxEntries of them,
+// evicting the least recently used values first.
+type memoryCache struct {
+	maxEntries int
+	// now returns the current time, and is replaced in tests.
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// lru holds the *memoryCacheEntry of entries, most recently used first.
+	lru *list.List
+}
+
+type memoryCacheEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCache returns a Cache storing at most maxEntries values in memory,
+// evicting the least recently used values first, eg. for
+// WithFetchCacheStore to cache more or fewer fields than WithFetchCache.
+func NewMemoryCache(maxEntries int) Cache {
+	return newMemoryCache(maxEntries)
+}
+
+func newMemoryCache(maxEntries int) *memoryCache {
+	if maxEntries <= 0 {
+		maxEntries = defaultMemoryCacheMaxEntries
+	}
+	return &memoryCache{
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+func (c *memoryCache) GetMulti(ctx context.Context, keys []string) ([][]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		elem, ok := c.entries[key]
+		if !ok {
+			continue
+		}
+		entry := elem.Value.(*memoryCacheEntry)
+		if now.After(entry.expires) {
+			c.remove(elem)
+			continue
+		}
+		c.lru.MoveToFront(elem)
+		values[i] = entry.value
+	}
+	return values, nil
+}
+
+func (c *memoryCache) SetMulti(ctx context.Context, entries map[string][]byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires := c.now().Add(ttl)
+	for key, value := range entries {
+		entry := &memoryCacheEntry{key: key, value: value, expires: expires}
+		if elem, ok := c.entries[key]; ok {
+			elem.Value = entry
+			c.lru.MoveToFront(elem)
+			continue
+		}
+		c.entries[key] = c.lru.PushFront(entry)
+	}
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+	return nil
+}
+
+func (c *memoryCache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*memoryCacheEntry).key)
+}
+
+// cachedSelection is a selection of a subquery and the prefix of its cache
+// keys.
+type cachedSelection struct {
+	alias  string
+	prefix string
+}
+
+// cacheableSelections returns the selections of p with their cache key
+// prefixes, or false if the results of p cannot be cached field by field.
+func cacheableSelections(p *Plan) ([]cachedSelection, bool) {
+	if p.SelectionSet == nil || len(p.SelectionSet.Fragments) > 0 {
+		return nil, false
+	}
+	selections := make([]cachedSelection, 0, len(p.SelectionSet.Selections))
+	for _, selection := range p.SelectionSet.Selections {
+		if len(selection.Directives) > 0 || selection.Name == federationField {
+			return nil, false
+		}
+		subSelections, err := marshalPbSelections(selection.SelectionSet)
+		if err != nil {
+			return nil, false
+		}
+		var args map[string]interface{}
+		if len(selection.UnparsedArgs) > 0 {
+			args = selection.UnparsedArgs
+		}
+		prefix, err := json.Marshal([]interface{}{p.Service, p.Type, selection.Name, args, subSelections})
+		if err != nil {
+			return nil, false
+		}
+		selections = append(selections, cachedSelection{alias: selection.Alias, prefix: string(prefix)})
+	}
+	return selections, true
+}
+
+// wrap returns a function fetching the objects of p with run, which only
+// calls run with the keys of objects that do not have all of their selected
+// fields cached, and caches the fields it fetches.
+func (c *fetchCache) wrap(p *Plan, planner *Planner, run func(ctx context.Context, keys []interface{}) ([]interface{}, interface{}, error)) func(ctx context.Context, keys []interface{}) ([]interface{}, interface{}, error) {
+	selections, ok := cacheableSelections(p)
+	if !ok || p.Kind == mutationString {
+		return run
+	}
+
+	return func(ctx context.Context, keys []interface{}) ([]interface{}, interface{}, error) {
+		scope, err := lookupScope(ctx, planner, p.Service, p.Type)
+		if err != nil {
+			return nil, nil, oops.Wrapf(err, "computing cache key")
+		}
+		ids := make([]string, len(keys))
+		for i, key := range keys {
+			id, err := json.Marshal(key)
+			if err != nil {
+				return nil, nil, oops.Wrapf(err, "computing cache key")
+			}
+			ids[i] = scope + string(id)
+		}
+
+		results := make([]interface{}, len(keys))
+		cached := c.get(ctx, selections, ids)
+		var missing []int
+		var missingKeys []interface{}
+		for i := range keys {
+			if cached[i] != nil {
+				results[i] = cached[i]
+			} else {
+				missing = append(missing, i)
+				missingKeys = append(missingKeys, keys[i])
+			}
+		}
+		if len(missing) == 0 {
+			return results, nil, nil
+		}
+
+		fetched, metadata, err := run(ctx, missingKeys)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(fetched) != len(missing) {
+			return nil, nil, oops.Errorf("got %d results for %d keys", len(fetched), len(missing))
+		}
+		entries := make(map[string][]byte)
+		for j, i := range missing {
+			results[i] = fetched[j]
+			c.addEntries(entries, selections, ids[i], fetched[j])
+		}
+		c.set(ctx, entries)
+		return results, metadata, nil
+	}
+}
+
+// lookupScope returns the lookup arguments of ctx sent to the lookup of
+// typName on service, see WithLookupArguments, as a prefix of the ids of the
+// objects it fetches, so that objects fetched with different arguments are
+// cached separately.
+func lookupScope(ctx context.Context, planner *Planner, service string, typName string) (string, error) {
+	args := lookupArguments(ctx, planner, fmt.Sprintf("%s_%s", service, typName), nil)
+	delete(args, "keys")
+	if len(args) == 0 {
+		return "", nil
+	}
+	scope, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+	return string(scope), nil
+}
+
+// key returns the cache key of the selection with the given prefix of the
+// object with the id id, its key prefixed by its lookupScope.
+func (c *fetchCache) key(prefix string, id string) string {
+	return c.namespace + prefix + id
+}
+
+// get returns the cached objects with the selected fields for the keys ids,
+// in the same order, with nil for the objects with any field not cached. All
+// the fields are read from the store at once.
+func (c *fetchCache) get(ctx context.Context, selections []cachedSelection, ids []string) []map[string]interface{} {
+	results := make([]map[string]interface{}, len(ids))
+	keys := make([]string, 0, len(ids)*len(selections))
+	for _, id := range ids {
+		for _, selection := range selections {
+			keys = append(keys, c.key(selection.prefix, id))
+		}
+	}
+	values, err := c.store.GetMulti(ctx, keys)
+	if err != nil || len(values) != len(keys) {
+		return results
+	}
+
+objects:
+	for i := range ids {
+		result := make(map[string]interface{}, len(selections))
+		for j, selection := range selections {
+			data := values[i*len(selections)+j]
+			if data == nil {
+				continue objects
+			}
+			// Every query decodes its own copy of the value, as results are
+			// stitched together in place.
+			var value interface{}
+			d := json.NewDecoder(bytes.NewReader(data))
+			d.UseNumber()
+			if err := d.Decode(&value); err != nil {
+				continue objects
+			}
+			result[selection.alias] = value
+		}
+		results[i] = result
+	}
+	return results
+}
+
+// addEntries adds the cache entries of the selected fields of result, the
+// object with the key id, to entries.
+func (c *fetchCache) addEntries(entries map[string][]byte, selections []cachedSelection, id string, result interface{}) {
+	obj, ok := result.(map[string]interface{})
+	if !ok {
+		// Objects that could not be fetched are not cached.
+		return
+	}
+	for _, selection := range selections {
+		value, ok := obj[selection.alias]
+		if !ok {
+			continue
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		entries[c.key(selection.prefix, id)] = data
+	}
+}
+
+// set stores entries, all at once.
+func (c *fetchCache) set(ctx context.Context, entries map[string][]byte) {
+	if len(entries) == 0 {
+		return
+	}
+	// Objects that fail to be cached are fetched again next time.
+	c.store.SetMulti(ctx, entries, c.ttl)
+}
+
+// Warm fetches the objects of typeName with the given keys into the cache
+// configured with WithFetchCache, so that queries for them soon after are not
+// sent to their services. keys are the federated keys of the objects, eg.
+//   e.Warm(ctx, "Bar", []interface{}{map[string]interface{}{"id": 1}})
+//
+// Warm fetches, from every service that can fetch typeName, the fields of the
+// objects that the service resolves and that have no arguments or
+// selections.
+func (e *Executor) Warm(ctx context.Context, typeName string, keys []interface{}) error {
+	if e.fetchCache == nil {
+		return oops.Errorf("executor has no fetch cache")
+	}
+	ctx = e.withGeneratedRequestID(ctx)
+	planner := e.getPlanner()
+	obj, ok := planner.flattener.types[typeName].(*graphql.Object)
+	if !ok {
+		return oops.Errorf("unknown object type %s", typeName)
+	}
+	federation, ok := obj.Fields[federationField]
+	if !ok {
+		return oops.Errorf("object %s is not federated", typeName)
+	}
+
+	var services []string
+	for service := range planner.schema.Fields[federation].Services {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	for _, service := range services {
+		selectionSet := &graphql.SelectionSet{}
+		for _, name := range sortedFieldNames(obj) {
+			field := obj.Fields[name]
+			if info := planner.schema.Fields[field]; name == federationField || len(field.Args) > 0 || info == nil || !info.Services[service] {
+				continue
+			}
+			switch unwrapType(field.Type).(type) {
+			case *graphql.Scalar, *graphql.Enum:
+				selectionSet.Selections = append(selectionSet.Selections, &graphql.Selection{
+					Name:         name,
+					Alias:        name,
+					UnparsedArgs: map[string]interface{}{},
+				})
+			}
+		}
+		if len(selectionSet.Selections) == 0 {
+			continue
+		}
+
+		// Objects are fetched even if they are already cached, to refresh
+		// them.
+		selections, _ := cacheableSelections(&Plan{Service: service, Type: typeName, SelectionSet: selectionSet})
+		results, _, err := e.runOnService(ctx, service, typeName, keys, queryString, selectionSet, nil, planner)
+		if err != nil {
+			return oops.Wrapf(err, "warming %s from %s", typeName, service)
+		}
+		if len(results) != len(keys) {
+			return oops.Errorf("warming %s from %s: got %d results for %d keys", typeName, service, len(results), len(keys))
+		}
+		scope, err := lookupScope(ctx, planner, service, typeName)
+		if err != nil {
+			return oops.Wrapf(err, "computing cache key")
+		}
+		entries := make(map[string][]byte)
+		for i, key := range keys {
+			id, err := json.Marshal(key)
+			if err != nil {
+				return oops.Wrapf(err, "computing cache key")
+			}
+			e.fetchCache.addEntries(entries, selections, scope+string(id), results[i])
+		}
+		e.fetchCache.set(ctx, entries)
+	}
+	return nil
+}
+
+func sortedFieldNames(obj *graphql.Object) []string {
+	names := make([]string, 0, len(obj.Fields))
+	for name := range obj.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
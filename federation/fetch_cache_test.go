@@ -0,0 +1,182 @@
+package federation
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorFetchCache(t *testing.T) {
+	ctx := context.Background()
+	e, clients := createKitchenSinkExecutor(t, WithFetchCache(time.Minute))
+	schema2 := clients["schema2"]
+
+	query := `{ s1fff { name s2ok } }`
+	output := `
+		{
+			"s1fff": [
+				{"name": "jimbo", "s2ok": 5},
+				{"name": "bob", "s2ok": 3}
+			]
+		}`
+
+	t.Run("warmed keys skip the service", func(t *testing.T) {
+		require.NoError(t, e.Warm(ctx, "Foo", []interface{}{
+			map[string]interface{}{"name": "jimbo"},
+			map[string]interface{}{"name": "bob"},
+		}))
+		schema2.reset()
+
+		runAndValidateQueryResults(t, ctx, e, query, output)
+		assert.Equal(t, 0, schema2.count)
+	})
+
+	t.Run("fetched fields are cached", func(t *testing.T) {
+		schema2.reset()
+		runAndValidateQueryResults(t, ctx, e, `{ s1fff { name s2ok2 s2score(weight: 2) } }`, `
+			{
+				"s1fff": [
+					{"name": "jimbo", "s2ok2": 5, "s2score": 10},
+					{"name": "bob", "s2ok2": 3, "s2score": 6}
+				]
+			}`)
+		assert.Equal(t, 1, schema2.count)
+
+		runAndValidateQueryResults(t, ctx, e, `{ s1fff { name s2ok2 s2score(weight: 2) } }`, `
+			{
+				"s1fff": [
+					{"name": "jimbo", "s2ok2": 5, "s2score": 10},
+					{"name": "bob", "s2ok2": 3, "s2score": 6}
+				]
+			}`)
+		assert.Equal(t, 1, schema2.count)
+
+		// Other arguments are not cached.
+		runAndValidateQueryResults(t, ctx, e, `{ s1fff { name s2score(weight: 3) } }`, `
+			{
+				"s1fff": [
+					{"name": "jimbo", "s2score": 15},
+					{"name": "bob", "s2score": 9}
+				]
+			}`)
+		assert.Equal(t, 2, schema2.count)
+	})
+
+	t.Run("cached fields expire", func(t *testing.T) {
+		e, clients := createKitchenSinkExecutor(t, WithFetchCache(time.Millisecond))
+		schema2 := clients["schema2"]
+		require.NoError(t, e.Warm(ctx, "Foo", []interface{}{
+			map[string]interface{}{"name": "jimbo"},
+			map[string]interface{}{"name": "bob"},
+		}))
+		time.Sleep(5 * time.Millisecond)
+		schema2.reset()
+
+		runAndValidateQueryResults(t, ctx, e, query, output)
+		assert.Equal(t, 1, schema2.count)
+	})
+}
+
+// mapCache is a Cache standing in for an external store.
+type mapCache struct {
+	mu     sync.Mutex
+	values map[string][]byte
+	gets   int
+	sets   int
+}
+
+func (c *mapCache) GetMulti(ctx context.Context, keys []string) ([][]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gets++
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = c.values[key]
+	}
+	return values, nil
+}
+
+func (c *mapCache) SetMulti(ctx context.Context, entries map[string][]byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, value := range entries {
+		c.values[key] = value
+	}
+	c.sets++
+	return nil
+}
+
+func TestExecutorFetchCacheStore(t *testing.T) {
+	ctx := context.Background()
+	store := &mapCache{values: make(map[string][]byte)}
+
+	newExecutor := func(namespace string) (*Executor, *countingExecutorClient) {
+		e, clients := createKitchenSinkExecutor(t, WithFetchCacheStore(store, namespace, time.Minute))
+		return e, clients["schema2"]
+	}
+
+	query := `{ s1fff { name s2ok s2ok2 } }`
+	output := `
+		{
+			"s1fff": [
+				{"name": "jimbo", "s2ok": 5, "s2ok2": 5},
+				{"name": "bob", "s2ok": 3, "s2ok2": 3}
+			]
+		}`
+
+	first, firstSchema2 := newExecutor("prod:")
+	runAndValidateQueryResults(t, ctx, first, query, output)
+	assert.Equal(t, 1, firstSchema2.count)
+	// The fields of both objects are read and written at once.
+	assert.Equal(t, 1, store.gets)
+	assert.Equal(t, 1, store.sets)
+	assert.Len(t, store.values, 4)
+	for key := range store.values {
+		assert.True(t, strings.HasPrefix(key, "prod:"), key)
+	}
+
+	// Another executor sharing the store reuses the fetched fields.
+	second, secondSchema2 := newExecutor("prod:")
+	runAndValidateQueryResults(t, ctx, second, query, output)
+	assert.Equal(t, 0, secondSchema2.count)
+	assert.Equal(t, 2, store.gets)
+
+	// Executors in other namespaces do not.
+	other, otherSchema2 := newExecutor("staging:")
+	runAndValidateQueryResults(t, ctx, other, query, output)
+	assert.Equal(t, 1, otherSchema2.count)
+}
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := newMemoryCache(2)
+	cache.now = func() time.Time { return now }
+
+	require.NoError(t, cache.SetMulti(ctx, map[string][]byte{"a": []byte("1")}, time.Minute))
+	values, err := cache.GetMulti(ctx, []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("1"), nil}, values)
+
+	// Values expire after their TTL.
+	now = now.Add(time.Minute + time.Second)
+	values, err = cache.GetMulti(ctx, []string{"a"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{nil}, values)
+	assert.Empty(t, cache.entries)
+
+	// The least recently used value is evicted once the cache is full.
+	require.NoError(t, cache.SetMulti(ctx, map[string][]byte{"a": []byte("1"), "b": []byte("2")}, time.Minute))
+	_, err = cache.GetMulti(ctx, []string{"a"})
+	require.NoError(t, err)
+	require.NoError(t, cache.SetMulti(ctx, map[string][]byte{"c": []byte("3")}, time.Minute))
+	values, err = cache.GetMulti(ctx, []string{"a", "b", "c"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("1"), nil, []byte("3")}, values)
+	assert.Len(t, cache.entries, 2)
+}
@@ -0,0 +1,112 @@
+package federation
+
+import (
+	"time"
+
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// WithFieldTimeouts limits how long the subqueries selecting fields with a
+// timeout, set with schemabuilder.Timeout in the schemas of services, may
+// take. Services report the timeouts of their fields when the executor
+// introspects their schemas; servers that predate that report none.
+//
+// Fields with a timeout are fetched in subqueries of their own, so that the
+// timeout does not affect their siblings, unless they require other fields,
+// see WithRequiredFields, or their parent objects cannot be fetched again by
+// their keys. A subquery is given the smallest timeout of the fields it
+// selects, nested ones included, and of WithPartialTimeout. Like with
+// WithPartialTimeout, the fields of a subquery that times out are resolved as
+// null if possible, and the rest of the query is returned.
+func WithFieldTimeouts() ExecutorOption {
+	return func(e *Executor) {
+		e.fieldTimeouts = true
+	}
+}
+
+// applyFieldTimeouts records whether planner fetches fields with timeouts in
+// subqueries of their own.
+func (e *Executor) applyFieldTimeouts(planner *Planner) error {
+	planner.fieldTimeouts = e.fieldTimeouts
+	return nil
+}
+
+// fieldTimeout returns the timeout of field on service, or 0.
+func (e *Planner) fieldTimeout(field *graphql.Field, service string) time.Duration {
+	info, ok := e.schema.Fields[field]
+	if !ok {
+		return 0
+	}
+	return info.Timeouts[service]
+}
+
+// isolatesTimeout returns whether the selection of field, with a timeout on
+// service, on the object typ planned on parentService is fetched in a
+// subquery of its own.
+func (e *Planner) isolatesTimeout(typ *graphql.Object, field *graphql.Field, service, parentService string) bool {
+	if !e.fieldTimeouts || e.fieldTimeout(field, service) == 0 || len(e.requires[field]) > 0 {
+		return false
+	}
+	switch {
+	case typ == e.schema.Schema.Mutation:
+		// Mutations run in a single subquery, in order.
+		return false
+	case parentService == gatewayCoordinatorServiceName || service != parentService:
+		// Root fields and fields of other services are already fetched in
+		// subqueries.
+		return true
+	}
+	// The object is fetched again from service by its keys.
+	for _, field := range typ.Fields {
+		if field.FederatedKey[service] {
+			return true
+		}
+	}
+	return false
+}
+
+// subqueryTimeout returns how long the subquery of p may take, or 0 if it
+// is not limited.
+func (e *Executor) subqueryTimeout(planner *Planner, p *Plan) time.Duration {
+	timeout := e.partialTimeout
+	if e.fieldTimeouts {
+		if typ, ok := planner.flattener.types[p.Type]; ok {
+			timeout = planner.selectionSetTimeout(typ, p.Service, p.SelectionSet, timeout)
+		} else if p.Service != gatewayCoordinatorServiceName {
+			// Root subqueries are planned on the query or mutation type.
+			typ := planner.schema.Schema.Query
+			if p.Kind == mutationString {
+				typ = planner.schema.Schema.Mutation
+			}
+			timeout = planner.selectionSetTimeout(typ, p.Service, p.SelectionSet, timeout)
+		}
+	}
+	return timeout
+}
+
+// selectionSetTimeout returns the smallest of timeout and the timeouts on
+// service of the fields selected by selectionSet on typ, including nested
+// fields.
+func (e *Planner) selectionSetTimeout(typ graphql.Type, service string, selectionSet *graphql.SelectionSet, timeout time.Duration) time.Duration {
+	if selectionSet == nil {
+		return timeout
+	}
+	if obj, ok := unwrapType(typ).(*graphql.Object); ok {
+		for _, selection := range selectionSet.Selections {
+			field, ok := obj.Fields[selection.Name]
+			if !ok {
+				continue
+			}
+			if fieldTimeout := e.fieldTimeout(field, service); fieldTimeout > 0 && (timeout == 0 || fieldTimeout < timeout) {
+				timeout = fieldTimeout
+			}
+			timeout = e.selectionSetTimeout(field.Type, service, selection.SelectionSet, timeout)
+		}
+	}
+	for _, fragment := range selectionSet.Fragments {
+		if fragmentType, ok := e.flattener.types[fragment.On]; ok {
+			timeout = e.selectionSetTimeout(fragmentType, service, fragment.SelectionSet, timeout)
+		}
+	}
+	return timeout
+}
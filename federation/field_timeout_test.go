@@ -0,0 +1,102 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorFieldTimeouts(t *testing.T) {
+	ctx := context.Background()
+
+	s2 := schemabuilder.NewSchemaWithName("schema2")
+	s2.Query().FieldFunc("s2root", func() string {
+		return "hello"
+	})
+	foo := s2.Object("Foo", Foo{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Foo }) []*Foo {
+		return args.Keys
+	}))
+	foo.FieldFunc("s2slow", func(ctx context.Context, in *Foo) (*int64, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+		n := int64(len(in.Name))
+		return &n, nil
+	}, schemabuilder.Timeout(50*time.Millisecond))
+	foo.FieldFunc("s2fast", func(in *Foo) int64 {
+		return int64(len(in.Name))
+	})
+	foo.FieldFunc("s2self", func(in *Foo) *Foo {
+		return in
+	})
+	s2Built := s2.MustBuild()
+
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+	})
+	require.NoError(t, err)
+	srv, err := NewServer(s2Built)
+	require.NoError(t, err)
+	execs["schema2"] = &DirectExecutorClient{Client: srv}
+
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)}, WithFieldTimeouts())
+	require.NoError(t, err)
+
+	t.Run("slow fields are null", func(t *testing.T) {
+		ctx := WithPartialErrors(ctx)
+		res, _, err := e.Execute(ctx, graphql.MustParse(`{
+			s1fff { name s2fast s2slow }
+			s2root
+		}`, map[string]interface{}{}), nil)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"s1fff": []interface{}{
+				map[string]interface{}{"name": "jimbo", "s2fast": json.Number("5"), "s2slow": nil},
+				map[string]interface{}{"name": "bob", "s2fast": json.Number("3"), "s2slow": nil},
+			},
+			"s2root": "hello",
+		}, res)
+
+		errs := PartialErrors(ctx)
+		require.Len(t, errs, 1)
+		assert.Equal(t, "schema2", errs[0].Service)
+		assert.Contains(t, errs[0].Error(), "context deadline exceeded")
+	})
+
+	t.Run("fields without timeouts are unaffected", func(t *testing.T) {
+		ctx := WithPartialErrors(ctx)
+		runAndValidateQueryResults(t, ctx, e, `{ s1fff { name s2fast } s2root }`, `
+			{
+				"s1fff": [
+					{"name": "jimbo", "s2fast": 5},
+					{"name": "bob", "s2fast": 3}
+				],
+				"s2root": "hello"
+			}`)
+		assert.Empty(t, PartialErrors(ctx))
+	})
+
+	t.Run("nested slow fields are null", func(t *testing.T) {
+		ctx := WithPartialErrors(ctx)
+		start := time.Now()
+		res, _, err := e.Execute(ctx, graphql.MustParse(`{ s1fff { name s2self { s2fast s2slow } } }`, map[string]interface{}{}), nil)
+		require.NoError(t, err)
+		assert.Less(t, int64(time.Since(start)), int64(time.Second))
+		assert.Equal(t, map[string]interface{}{
+			"s1fff": []interface{}{
+				map[string]interface{}{"name": "jimbo", "s2self": map[string]interface{}{"s2fast": json.Number("5"), "s2slow": nil}},
+				map[string]interface{}{"name": "bob", "s2self": map[string]interface{}{"s2fast": json.Number("3"), "s2slow": nil}},
+			},
+		}, res)
+		require.Len(t, PartialErrors(ctx), 1)
+	})
+
+}
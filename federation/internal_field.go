@@ -0,0 +1,64 @@
+package federation
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// WithInternalFieldName makes the executor select the "_federation" fields of
+// services under the alias name, eg. "_thunder_fed", rather than
+// "_federation". The alias is the key under which the federated keys of
+// objects are carried between subqueries before being stripped from results,
+// so it can be changed when "_federation" would collide with a field of the
+// clients or of another gateway. Services still expose their "_federation"
+// fields under that name.
+//
+// Queries that use the alias, or an alias with the prefix name + "_" under
+// which the key fields of objects are selected, are rejected when they are
+// planned, since their results would collide with the executor's.
+func WithInternalFieldName(name string) ExecutorOption {
+	return func(e *Executor) {
+		e.internalFieldName = name
+	}
+}
+
+var graphqlNameRegexp = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// applyInternalFieldName records the internal field name in planner, checking
+// that it is a valid alias.
+func (e *Executor) applyInternalFieldName(planner *Planner) error {
+	if e.internalFieldName == "" {
+		return nil
+	}
+	if !graphqlNameRegexp.MatchString(e.internalFieldName) || e.internalFieldName == "__typename" {
+		return oops.Errorf("%q is not a valid alias", e.internalFieldName)
+	}
+	planner.internalFieldName = e.internalFieldName
+	return nil
+}
+
+// validateInternalAliases checks that the flattened selectionSet of a client
+// query does not use the aliases the executor selects its internal fields
+// under, see WithInternalFieldName.
+func (e *Planner) validateInternalAliases(selectionSet *graphql.SelectionSet) error {
+	if selectionSet == nil {
+		return nil
+	}
+	for _, selection := range selectionSet.Selections {
+		if selection.Alias == e.internalFieldName || strings.HasPrefix(selection.Alias, e.internalFieldName+"_") {
+			return graphql.NewClientErrorAt(selection.Location, "alias %s is reserved", selection.Alias)
+		}
+		if err := e.validateInternalAliases(selection.SelectionSet); err != nil {
+			return err
+		}
+	}
+	for _, fragment := range selectionSet.Fragments {
+		if err := e.validateInternalAliases(fragment.SelectionSet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package federation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorInternalFieldName(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": buildTestSchema2(),
+	})
+	require.NoError(t, err)
+	clients := make(map[string]*recordingExecutorClient)
+	for name, exec := range execs {
+		clients[name] = &recordingExecutorClient{ExecutorClient: exec}
+		execs[name] = clients[name]
+	}
+
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)}, WithInternalFieldName("_thunder_fed"))
+	require.NoError(t, err)
+	for _, client := range clients {
+		client.reset()
+	}
+
+	runAndValidateQueryResults(t, ctx, e, `
+		{
+			s1fff {
+				name
+				s2ok
+				s2bar { id s1baz }
+				s1nest { name s2ok }
+			}
+			s1both {
+				... on Foo { name s2ok }
+				... on Bar { id s1baz }
+			}
+			s2root
+		}`, `
+		{
+			"s1fff": [
+				{"name": "jimbo", "s2ok": 5, "s2bar": {"id": 14, "s1baz": "14"}, "s1nest": {"name": "jimbo", "s2ok": 5}},
+				{"name": "bob", "s2ok": 3, "s2bar": {"id": 10, "s1baz": "10"}, "s1nest": {"name": "bob", "s2ok": 3}}
+			],
+			"s1both": [
+				{"__typename": "Foo", "name": "this is the foo", "s2ok": 15},
+				{"__typename": "Bar", "id": 1234, "s1baz": "1234"}
+			],
+			"s2root": "hello"
+		}`)
+
+	for name, client := range clients {
+		assert.Equal(t, map[string]bool{"_thunder_fed": true}, client.federationAliases(), name)
+	}
+
+	// Clients can use "_federation" as an alias, but not the aliases of the
+	// executor's internal fields.
+	runAndValidateQueryResults(t, ctx, e, `{ s1fff { _federation: name s2ok } }`, `
+		{
+			"s1fff": [
+				{"_federation": "jimbo", "s2ok": 5},
+				{"_federation": "bob", "s2ok": 3}
+			]
+		}`)
+	runAndValidateQueryError(t, ctx, e, `{ s1fff { _thunder_fed: name s2ok } }`, "", "alias _thunder_fed is reserved")
+	runAndValidateQueryError(t, ctx, e, `{ s1fff { name s2bar { _thunder_fed_id: id } } }`, "", "alias _thunder_fed_id is reserved")
+
+	_, err = NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)}, WithInternalFieldName("thunder-fed"))
+	assert.Error(t, err)
+}
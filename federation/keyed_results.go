@@ -0,0 +1,103 @@
+package federation
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// keySelections are the key fields selected on the objects fetched by a
+// subquery, so that its results can be matched to the keys it was sent even
+// when the service returns objects for keys that were not requested.
+type keySelections map[string]string // alias -> key field name
+
+// addKeySelections returns selectionSet with the key fields names selected
+// too, aliased with internalFieldName as prefix, eg. "_federation_id".
+func addKeySelections(selectionSet *graphql.SelectionSet, internalFieldName string, names []string) (*graphql.SelectionSet, keySelections) {
+	sort.Strings(names)
+	selections := make(keySelections, len(names))
+	withKeys := &graphql.SelectionSet{Fragments: selectionSet.Fragments}
+	withKeys.Selections = append(withKeys.Selections, selectionSet.Selections...)
+	for _, name := range names {
+		alias := internalFieldName + "_" + name
+		selections[alias] = name
+		withKeys.Selections = append(withKeys.Selections, &graphql.Selection{
+			Name:         name,
+			Alias:        alias,
+			UnparsedArgs: map[string]interface{}{},
+		})
+	}
+	return withKeys, selections
+}
+
+// match returns the results of a subquery in the order of the keys it was
+// sent, and removes the key fields selected by addKeySelections. Results are
+// matched by key rather than by position, so that objects returned for keys
+// that were not requested are ignored instead of shifting the results of the
+// requested keys. A key without a result is null if the service returned null
+// in its place, and an error otherwise.
+func (s keySelections) match(results []interface{}, keys []interface{}) ([]interface{}, error) {
+	if len(s) == 0 {
+		return results, nil
+	}
+	byKey := make(map[string][]interface{})
+	for _, result := range results {
+		obj, ok := result.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, err := s.id(obj)
+		if err != nil {
+			return nil, err
+		}
+		for alias := range s {
+			delete(obj, alias)
+		}
+		byKey[id] = append(byKey[id], result)
+	}
+	matched := make([]interface{}, len(keys))
+	for i, key := range keys {
+		obj, _ := key.(map[string]interface{})
+		id, err := s.keyID(obj)
+		if err != nil {
+			return nil, err
+		}
+		candidates := byKey[id]
+		if len(candidates) == 0 {
+			if len(results) == len(keys) && results[i] == nil {
+				continue
+			}
+			return nil, oops.Errorf("no result for key %s", id)
+		}
+		matched[i], byKey[id] = candidates[0], candidates[1:]
+	}
+	return matched, nil
+}
+
+// id identifies the object obj, a result, by the values of its key fields.
+func (s keySelections) id(obj map[string]interface{}) (string, error) {
+	values := make(map[string]interface{}, len(s))
+	for alias, name := range s {
+		values[name] = obj[alias]
+	}
+	id, err := json.Marshal(values)
+	if err != nil {
+		return "", oops.Wrapf(err, "computing key")
+	}
+	return string(id), nil
+}
+
+// keyID identifies the object with key as id does.
+func (s keySelections) keyID(key map[string]interface{}) (string, error) {
+	values := make(map[string]interface{}, len(s))
+	for _, name := range s {
+		values[name] = key[name]
+	}
+	id, err := json.Marshal(values)
+	if err != nil {
+		return "", oops.Wrapf(err, "computing key")
+	}
+	return string(id), nil
+}
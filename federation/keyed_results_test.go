@@ -0,0 +1,66 @@
+package federation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorIgnoresUnrequestedObjects(t *testing.T) {
+	ctx := context.Background()
+
+	// schema2 returns Foos that were not requested ahead of the requested
+	// ones, and replaces "missing" with one.
+	s2 := schemabuilder.NewSchemaWithName("schema2")
+	s2.Query().FieldFunc("s2root", func() string {
+		return "hello"
+	})
+	foo := s2.Object("Foo", Foo{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Foo }) []*Foo {
+		var foos []*Foo
+		for _, key := range args.Keys {
+			if key.Name != "missing" {
+				foos = append(foos, key)
+			}
+		}
+		if len(foos) < len(args.Keys) {
+			return append([]*Foo{{Name: "unrequested"}}, foos...)
+		}
+		return append([]*Foo{{Name: "unrequested"}, {Name: "unrequested too"}}, foos...)
+	}))
+	foo.FieldFunc("s2ok", func(in *Foo) int {
+		return len(in.Name)
+	})
+
+	s1 := buildTestSchema1()
+	s1.Query().FieldFunc("s1missing", func() []*Foo {
+		return []*Foo{{Name: "missing"}}
+	})
+
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": s1,
+		"schema2": s2,
+	})
+	require.NoError(t, err)
+	schema2 := &countingExecutorClient{ExecutorClient: execs["schema2"]}
+	execs["schema2"] = schema2
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	require.NoError(t, err)
+	schema2.reset()
+
+	runAndValidateQueryResults(t, ctx, e, `{ s1fff { name s2ok } }`, `{
+		"s1fff": [{"name": "jimbo", "s2ok": 5}, {"name": "bob", "s2ok": 3}]
+	}`)
+	// The objects are fetched along with their keys to match them, once.
+	assert.Equal(t, 1, schema2.count)
+
+	// An unrequested object in place of a dropped one does not shift the
+	// results either.
+	_, _, err = e.Execute(ctx, graphql.MustParse(`{ s1missing { s2ok } }`, map[string]interface{}{}), nil)
+	require.Error(t, err)
+	assert.Contains(t, oops.Cause(err).Error(), `no result for key {"name":"missing"}`)
+}
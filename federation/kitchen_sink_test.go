@@ -143,6 +143,18 @@ func buildTestSchema2() *schemabuilder.Schema {
 		return len(in.Name), nil
 	})
 
+	foo.FieldFunc("s2score", func(in *Foo, args struct{ Weight *int64 }) int64 {
+		weight := int64(1)
+		if args.Weight != nil {
+			weight = *args.Weight
+		}
+		return int64(len(in.Name)) * weight
+	})
+
+	foo.FieldFunc("s2ids", func(in *Foo, args struct{ Ids []int64 }) []int64 {
+		return args.Ids
+	})
+
 	foo.FieldFunc("s2bar", func(in *Foo) *Bar {
 		return &Bar{
 			Id: int64(len(in.Name)*2 + 4),
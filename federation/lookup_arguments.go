@@ -0,0 +1,50 @@
+package federation
+
+import (
+	"context"
+
+	"github.com/samsarahq/thunder/graphql"
+)
+
+type lookupArgumentsKey struct{}
+
+// WithLookupArguments returns a context passing args, along with the keys,
+// to the resolvers fetching federated objects from their keys in the queries
+// executed with it, eg. a tenant scope derived from the request:
+//   ctx = federation.WithLookupArguments(ctx, map[string]interface{}{"tenant": tenant})
+// A resolver receives the arguments that it declares next to Keys:
+//   schema.Object("Foo", Foo{}, schemabuilder.FetchObjectFromKeys(
+//     func(args struct{ Keys []*FooKeys; Tenant *string }) []*Foo { ... }))
+// Arguments that a resolver does not declare are not sent to its service.
+func WithLookupArguments(ctx context.Context, args map[string]interface{}) context.Context {
+	return context.WithValue(ctx, lookupArgumentsKey{}, args)
+}
+
+// lookupArguments returns the arguments of the federated lookup named
+// federatedName for keys, with the arguments of the context that the lookup
+// accepts.
+func lookupArguments(ctx context.Context, planner *Planner, federatedName string, keys []interface{}) map[string]interface{} {
+	args := map[string]interface{}{"keys": keys}
+	extra, ok := ctx.Value(lookupArgumentsKey{}).(map[string]interface{})
+	if !ok || len(extra) == 0 {
+		return args
+	}
+	field, ok := planner.schema.Schema.Query.(*graphql.Object).Fields[federationField]
+	if !ok {
+		return args
+	}
+	federation, ok := unwrapType(field.Type).(*graphql.Object)
+	if !ok {
+		return args
+	}
+	lookup, ok := federation.Fields[federatedName]
+	if !ok {
+		return args
+	}
+	for name, value := range extra {
+		if _, ok := lookup.Args[name]; ok && name != "keys" {
+			args[name] = value
+		}
+	}
+	return args
+}
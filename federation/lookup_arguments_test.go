@@ -0,0 +1,92 @@
+package federation
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorLookupArguments(t *testing.T) {
+	var mu sync.Mutex
+	var tenants []string
+
+	s2 := schemabuilder.NewSchemaWithName("schema2")
+	s2.Query().FieldFunc("s2root", func() string {
+		return "hello"
+	})
+	type FooKeys struct {
+		Name string
+	}
+	foo := s2.Object("Foo", Foo{}, schemabuilder.FetchObjectFromKeys(func(args struct {
+		Keys   []*FooKeys
+		Tenant *string
+	}) []*Foo {
+		mu.Lock()
+		defer mu.Unlock()
+		tenant := "<none>"
+		if args.Tenant != nil {
+			tenant = *args.Tenant
+		}
+		tenants = append(tenants, tenant)
+
+		foos := make([]*Foo, 0, len(args.Keys))
+		for _, key := range args.Keys {
+			foos = append(foos, &Foo{Name: key.Name})
+		}
+		return foos
+	}))
+	foo.FieldFunc("s2ok", func(in *Foo) int {
+		return len(in.Name)
+	})
+	s2.Query().FieldFunc("s2foo", func() *Foo {
+		return &Foo{Name: "jim"}
+	})
+
+	ctx := context.Background()
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": s2,
+	})
+	require.NoError(t, err)
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	require.NoError(t, err)
+
+	// The tenant is passed to schema2, which accepts it, but not to schema1,
+	// which does not.
+	scoped := WithLookupArguments(ctx, map[string]interface{}{"tenant": "acme"})
+	runAndValidateQueryResults(t, scoped, e, `{ s1fff { name s2ok } }`, `
+		{
+			"s1fff": [
+				{"name": "jimbo", "s2ok": 5},
+				{"name": "bob", "s2ok": 3}
+			]
+		}`)
+	runAndValidateQueryResults(t, scoped, e, `{ s2foo { s1hmm } }`, `{"s2foo": {"s1hmm": "jim!!!"}}`)
+	runAndValidateQueryResults(t, ctx, e, `{ s1f { s2ok } }`, `{"s1f": {"s2ok": 6}}`)
+	assert.Equal(t, []string{"acme", "<none>"}, tenants)
+
+	t.Run("fetch cache", func(t *testing.T) {
+		e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)}, WithFetchCache(time.Minute))
+		require.NoError(t, err)
+		tenants = nil
+
+		// Objects fetched with different lookup arguments are cached
+		// separately.
+		for _, tenant := range []string{"acme", "acme", "other"} {
+			scoped := WithLookupArguments(ctx, map[string]interface{}{"tenant": tenant})
+			runAndValidateQueryResults(t, scoped, e, `{ s1fff { name s2ok } }`, `
+				{
+					"s1fff": [
+						{"name": "jimbo", "s2ok": 5},
+						{"name": "bob", "s2ok": 3}
+					]
+				}`)
+		}
+		assert.Equal(t, []string{"acme", "other"}, tenants)
+	})
+}
@@ -0,0 +1,107 @@
+package federation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// LookupError is returned when fetching federated objects from a service by
+// their keys fails, eg. because the service's FetchObjectFromKeys or field
+// resolvers returned an error for the whole batch. It attributes the error to
+// the response paths of every object that depended on the lookup.
+type LookupError struct {
+	// Service is the service the objects were fetched from.
+	Service string
+	// Type is the type of the fetched objects.
+	Type string
+	// Paths are the response paths of the fetched objects, eg.
+	// ["s1fff", 0, "s1nest"]. Each element is a field alias or a list index.
+	Paths [][]interface{}
+	// Fields are the aliases of the fields that were fetched for every object.
+	Fields []string
+	// Err is the error returned by the service.
+	Err error
+}
+
+var _ graphql.MultiResponseError = &LookupError{}
+
+// maxLookupErrorPaths is the number of paths a LookupError's message names,
+// so that the message stays short for lookups of many objects.
+const maxLookupErrorPaths = 5
+
+func (e *LookupError) Error() string {
+	paths := make([]string, 0, maxLookupErrorPaths+1)
+	for i, path := range e.Paths {
+		if i == maxLookupErrorPaths {
+			paths = append(paths, fmt.Sprintf("%d more", len(e.Paths)-i))
+			break
+		}
+		paths = append(paths, formatResponsePath(path))
+	}
+	return fmt.Sprintf("fetching %s from %s for %s: %v", e.Type, e.Service, strings.Join(paths, ", "), e.Err)
+}
+
+// ResponseErrors returns an error for each of the paths of e, with the path,
+// to be written in the "errors" of a response.
+func (e *LookupError) ResponseErrors() []graphql.ResponseError {
+	message := fmt.Sprintf("fetching %s from %s: %v", e.Type, e.Service, e.Err)
+	responseErrors := make([]graphql.ResponseError, 0, len(e.Paths))
+	for _, path := range e.Paths {
+		responseErrors = append(responseErrors, graphql.ResponseError{Message: message, Path: path})
+	}
+	return responseErrors
+}
+
+// Unwrap returns the error returned by the service.
+func (e *LookupError) Unwrap() error {
+	return e.Err
+}
+
+// formatResponsePath formats path as a dotted string, eg. s1fff.0.s1nest.
+func formatResponsePath(path []interface{}) string {
+	parts := make([]string, 0, len(path))
+	for _, step := range path {
+		parts = append(parts, fmt.Sprint(step))
+	}
+	return strings.Join(parts, ".")
+}
+
+// rebaseLookupError prefixes the paths of a LookupError caused by a subplan
+// with the paths of the objects the subplan was dispatched for, so that they
+// are relative to the parent plan's results. Other errors are returned as is.
+func rebaseLookupError(err error, parents [][]interface{}) error {
+	lookupErr, ok := oops.Cause(err).(*LookupError)
+	if !ok {
+		return err
+	}
+	rebased := *lookupErr
+	rebased.Paths = rebasePaths(lookupErr.Paths, parents)
+	return &rebased
+}
+
+// newLookupError attributes err, returned when fetching the objects with keys
+// for plan p, to each of the objects. The selection aliased internalFieldName
+// carries keys, and is not reported as a field. The paths of the objects are their
+// indices in keys until the error is rebased by the parent plans.
+func newLookupError(p *Plan, internalFieldName string, keys []interface{}, err error) error {
+	paths := make([][]interface{}, 0, len(keys))
+	for i := range keys {
+		paths = append(paths, []interface{}{i})
+	}
+	var fields []string
+	for _, selection := range p.SelectionSet.Selections {
+		if selection.Alias != internalFieldName {
+			fields = append(fields, selection.Alias)
+		}
+	}
+	return &LookupError{
+		Service: p.Service,
+		Type:    p.Type,
+		Paths:   paths,
+		Fields:  fields,
+		Err:     err,
+	}
+}
@@ -0,0 +1,77 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorLookupError(t *testing.T) {
+	ctx := context.Background()
+	execs, err := makeExecutors(buildRequiresSchemas())
+	require.NoError(t, err)
+	// Only label requires the optional score key, which rank needs too.
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)}, WithRequiredFields("Item", "label", "score"))
+	require.NoError(t, err)
+
+	// Without the score, s2 fails to resolve rank for the whole batch of
+	// items.
+	_, _, err = e.Execute(ctx, graphql.MustParse(`{ items { name ranked: rank } }`, map[string]interface{}{}), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fetching Item from s2 for items.0, items.1: ")
+	assert.Contains(t, err.Error(), "missing score")
+
+	lookupErr, ok := oops.Cause(err).(*LookupError)
+	require.True(t, ok, "expected a LookupError, got %T", oops.Cause(err))
+	assert.Equal(t, "s2", lookupErr.Service)
+	assert.Equal(t, "Item", lookupErr.Type)
+	assert.Equal(t, [][]interface{}{{"items", 0}, {"items", 1}}, lookupErr.Paths)
+	assert.Equal(t, []string{"ranked"}, lookupErr.Fields)
+
+	// Responses have an error for each path.
+	assert.Equal(t, []graphql.ResponseError{
+		{Message: lookupErr.ResponseErrors()[0].Message, Path: []interface{}{"items", 0}},
+		{Message: lookupErr.ResponseErrors()[0].Message, Path: []interface{}{"items", 1}},
+	}, graphql.ResponseErrors(oops.Cause(err)))
+	assert.Contains(t, lookupErr.ResponseErrors()[0].Message, "fetching Item from s2: ")
+}
+
+func TestLookupErrorMessage(t *testing.T) {
+	err := &LookupError{
+		Service: "s2",
+		Type:    "Item",
+		Err:     errors.New("missing score"),
+	}
+	for i := 0; i < 8; i++ {
+		err.Paths = append(err.Paths, []interface{}{"items", i})
+	}
+	assert.Equal(t, "fetching Item from s2 for items.0, items.1, items.2, items.3, items.4, 3 more: missing score", err.Error())
+	assert.Len(t, err.ResponseErrors(), 8)
+}
+
+func TestRebaseLookupError(t *testing.T) {
+	err := &LookupError{
+		Service: "s3",
+		Type:    "Bar",
+		Paths:   [][]interface{}{{0, "bar"}, {1, "bar"}},
+	}
+	rebased := rebaseLookupError(oops.Wrapf(err, "run on service"), [][]interface{}{
+		{"foos", 0, "nested"},
+		{"foos", 2, "nested"},
+	})
+	lookupErr, ok := rebased.(*LookupError)
+	require.True(t, ok)
+	assert.Equal(t, [][]interface{}{
+		{"foos", 0, "nested", "bar"},
+		{"foos", 2, "nested", "bar"},
+	}, lookupErr.Paths)
+	assert.Equal(t, [][]interface{}{{0, "bar"}, {1, "bar"}}, err.Paths)
+
+	other := oops.Errorf("other")
+	assert.Equal(t, other, rebaseLookupError(other, nil))
+}
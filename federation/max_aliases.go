@@ -0,0 +1,45 @@
+package federation
+
+import (
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// WithMaxAliases rejects queries that select the same field more than n times
+// in one selection set under different aliases, so that a query cannot
+// multiply the load of an expensive field by aliasing it hundreds of times.
+// Queries are rejected when they are planned, before any subquery is sent.
+func WithMaxAliases(n int) ExecutorOption {
+	return func(e *Executor) {
+		e.maxAliases = n
+	}
+}
+
+// applyMaxAliases records the alias limit in planner.
+func (e *Executor) applyMaxAliases(planner *Planner) error {
+	planner.maxAliases = e.maxAliases
+	return nil
+}
+
+// validateAliases checks that no field is selected more than the maximum
+// number of times in any selection set of the flattened selectionSet.
+func (e *Planner) validateAliases(selectionSet *graphql.SelectionSet) error {
+	if e.maxAliases <= 0 || selectionSet == nil {
+		return nil
+	}
+	counts := make(map[string]int)
+	for _, selection := range selectionSet.Selections {
+		counts[selection.Name]++
+		if counts[selection.Name] > e.maxAliases {
+			return graphql.NewClientErrorAt(selection.Location, "field %s is selected more than the maximum of %d times", selection.Name, e.maxAliases)
+		}
+		if err := e.validateAliases(selection.SelectionSet); err != nil {
+			return err
+		}
+	}
+	for _, fragment := range selectionSet.Fragments {
+		if err := e.validateAliases(fragment.SelectionSet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package federation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorMaxAliases(t *testing.T) {
+	e, clients := createKitchenSinkExecutor(t, WithMaxAliases(10))
+	ctx := context.Background()
+
+	aliases := func(n int) string {
+		var selections []string
+		for i := 0; i < n; i++ {
+			selections = append(selections, fmt.Sprintf("bar%d: s2bar { id }", i))
+		}
+		return fmt.Sprintf(`{ s1f { %s } }`, strings.Join(selections, " "))
+	}
+
+	_, _, err := e.Execute(ctx, graphql.MustParse(aliases(10), nil), nil)
+	require.NoError(t, err)
+
+	clients["schema1"].reset()
+	clients["schema2"].reset()
+	_, _, err = e.Execute(ctx, graphql.MustParse(aliases(100), nil), nil)
+	require.Error(t, err)
+	assert.Equal(t, "field s2bar is selected more than the maximum of 10 times", oops.Cause(err).Error())
+	assert.Len(t, graphql.ErrorLocations(err), 1)
+	assert.Equal(t, 0, clients["schema1"].count)
+	assert.Equal(t, 0, clients["schema2"].count)
+
+	// Fields selected in different selection sets are counted separately.
+	runAndValidateQueryResults(t, ctx, e, `{ s1fff { a: s2bar { id } b: s2bar { id } } s1f { a: s2bar { id } } }`, `
+		{
+			"s1fff": [
+				{"a": {"id": 14}, "b": {"id": 14}},
+				{"a": {"id": 10}, "b": {"id": 10}}
+			],
+			"s1f": {"a": {"id": 16}}
+		}`)
+}
@@ -0,0 +1,58 @@
+package federation
+
+import (
+	"context"
+	"sync"
+
+	"github.com/samsarahq/go/oops"
+)
+
+// WithMaxKeys aborts execution of a query once it has sent more than n
+// federated keys in total to service, across all of its subqueries, so that
+// a pathological query cannot trigger millions of lookups on one service.
+// Keys are counted before they are deduplicated or served from the cache.
+func WithMaxKeys(service string, n int) ExecutorOption {
+	return func(e *Executor) {
+		if e.maxKeys == nil {
+			e.maxKeys = make(map[string]int)
+		}
+		e.maxKeys[service] = n
+	}
+}
+
+type keyCountsKey struct{}
+
+// keyCounts counts the keys a query sends to each service.
+type keyCounts struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// withKeyCounts returns a context counting the keys of a query, if the
+// executor limits them.
+func (e *Executor) withKeyCounts(ctx context.Context) context.Context {
+	if len(e.maxKeys) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, keyCountsKey{}, &keyCounts{counts: make(map[string]int)})
+}
+
+// countKeys adds n keys sent to service to the query's count, and fails if
+// the count exceeds the service's maximum.
+func (e *Executor) countKeys(ctx context.Context, service string, n int) error {
+	max, ok := e.maxKeys[service]
+	if !ok {
+		return nil
+	}
+	counts, ok := ctx.Value(keyCountsKey{}).(*keyCounts)
+	if !ok {
+		return nil
+	}
+	counts.mu.Lock()
+	defer counts.mu.Unlock()
+	counts.counts[service] += n
+	if count := counts.counts[service]; count > max {
+		return oops.Errorf("query sends %d keys to service %s, more than the maximum of %d", count, service, max)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package federation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorMaxKeys(t *testing.T) {
+	e, clients := createKitchenSinkExecutor(t, WithMaxKeys("schema2", 3))
+	ctx := context.Background()
+
+	// Keys are counted across all subqueries of a query.
+	runAndValidateQueryResults(t, ctx, e, `{ s1fff { s2ok } s1f { s2ok } }`, `
+		{
+			"s1fff": [{"s2ok": 5}, {"s2ok": 3}],
+			"s1f": {"s2ok": 6}
+		}`)
+
+	clients["schema2"].reset()
+	_, _, err := e.Execute(ctx, graphql.MustParse(`{ s1fff { s2ok } s1f { s2ok } s1both { ... on Foo { s2ok } } }`, nil), nil)
+	require.Error(t, err)
+	assert.Equal(t, "query sends 4 keys to service schema2, more than the maximum of 3", oops.Cause(err).Error())
+	assert.True(t, clients["schema2"].count < 3)
+
+	// The keys of every query are counted separately, and keys sent to
+	// other services are not limited.
+	runAndValidateQueryResults(t, ctx, e, `{ s1fff { s2bar { s1baz } } }`, `
+		{
+			"s1fff": [{"s2bar": {"s1baz": "14"}}, {"s2bar": {"s1baz": "10"}}]
+		}`)
+}
@@ -67,18 +67,28 @@ func (t *introspectionTypeRef) String() string {
 }
 
 type introspectionInputField struct {
-	Name string                `json:"name"`
-	Type *introspectionTypeRef `json:"type"`
+	Name              string                `json:"name"`
+	Type              *introspectionTypeRef `json:"type"`
+	IsDeprecated      bool                  `json:"isDeprecated"`
+	DeprecationReason string                `json:"deprecationReason"`
 }
 
 type introspectionField struct {
 	Name string                    `json:"name"`
 	Type *introspectionTypeRef     `json:"type"`
 	Args []introspectionInputField `json:"args"`
+	// TimeoutMs is the timeout of the field, as reported by
+	// introspection.FederationIntrospectionQuery.
+	TimeoutMs int64 `json:"timeoutMs,omitempty"`
+	// External is whether the field is external to the service, as reported
+	// by introspection.FederationIntrospectionQuery.
+	External bool `json:"external,omitempty"`
 }
 
 type introspectionEnumValue struct {
-	Name string `json:"name"`
+	Name              string `json:"name"`
+	IsDeprecated      bool   `json:"isDeprecated"`
+	DeprecationReason string `json:"deprecationReason"`
 }
 
 type introspectionType struct {
@@ -200,10 +210,18 @@ func mergeInputFields(a, b []introspectionInputField, mode MergeMode) ([]introsp
 		if err != nil {
 			return nil, fmt.Errorf("field %s has incompatible types %s and %s: %v", name, p[0].Type, p[1].Type, err)
 		}
-		merged = append(merged, introspectionInputField{
+		// Like enum values, a field is deprecated if any schema deprecates it.
+		field := introspectionInputField{
 			Name: name,
 			Type: m,
-		})
+		}
+		for _, other := range p {
+			if other.IsDeprecated && !field.IsDeprecated {
+				field.IsDeprecated = true
+				field.DeprecationReason = other.DeprecationReason
+			}
+		}
+		merged = append(merged, field)
 	}
 
 	return merged, nil
@@ -243,15 +261,27 @@ func mergeFields(a, b []introspectionField, mode MergeMode) ([]introspectionFiel
 		}
 
 		merged = append(merged, introspectionField{
-			Name: name,
-			Type: typ,
-			Args: args,
+			Name:      name,
+			Type:      typ,
+			Args:      args,
+			TimeoutMs: mergeTimeouts(p[0].TimeoutMs, p[1].TimeoutMs),
+			// Versions that do not resolve the field must not be sent it.
+			External: p[0].External || p[1].External,
 		})
 	}
 
 	return merged, nil
 }
 
+// mergeTimeouts returns the smaller of the timeouts a and b, ignoring timeouts
+// of 0, which do not limit fields.
+func mergeTimeouts(a, b int64) int64 {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
 func mergePossibleTypes(a, b []*introspectionTypeRef, mode MergeMode) ([]*introspectionTypeRef, error) {
 	types := make(map[string][]*introspectionTypeRef)
 	for _, a := range a {
@@ -306,7 +336,15 @@ func mergeEnumValues(a, b []introspectionEnumValue, mode MergeMode) ([]introspec
 			continue
 		}
 
-		merged = append(merged, p[0])
+		// A value is deprecated if any schema deprecates it, so that clients
+		// stop using it before it is removed.
+		value := p[0]
+		for _, other := range p[1:] {
+			if other.IsDeprecated && !value.IsDeprecated {
+				value = other
+			}
+		}
+		merged = append(merged, value)
 	}
 
 	return merged, nil
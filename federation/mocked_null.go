@@ -0,0 +1,18 @@
+package federation
+
+// WithMockedNullServices resolves every field of the named services as
+// null, without sending them any requests, to test the contribution of the
+// other services in isolation. Fields of other services nested in the fields
+// of mocked services are not fetched either.
+//
+// The schemas of mocked services are still introspected from their
+// executors, so they must still answer introspection queries. Unlike with
+// WithPartialTimeout, non-null fields of mocked services are null too.
+func WithMockedNullServices(services ...string) ExecutorOption {
+	return func(e *Executor) {
+		e.mockedNullServices = make(map[string]bool, len(services))
+		for _, service := range services {
+			e.mockedNullServices[service] = true
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package federation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutorMockedNullServices(t *testing.T) {
+	e, clients := createKitchenSinkExecutor(t, WithMockedNullServices("schema2"))
+	ctx := context.Background()
+
+	runAndValidateQueryResults(t, ctx, e, `
+		{
+			s2root
+			s1fff {
+				name
+				s1hmm
+				s2ok
+				s2bar { id s1baz }
+			}
+			s1both {
+				__typename
+				... on Foo { name s2ok }
+				... on Bar { id s1baz }
+			}
+		}`, `
+		{
+			"s2root": null,
+			"s1fff": [
+				{"name": "jimbo", "s1hmm": "jimbo!!!", "s2ok": null, "s2bar": null},
+				{"name": "bob", "s1hmm": "bob!!!", "s2ok": null, "s2bar": null}
+			],
+			"s1both": [
+				{"__typename": "Foo", "name": "this is the foo", "s2ok": null},
+				{"__typename": "Bar", "id": 1234, "s1baz": "1234"}
+			]
+		}`)
+	assert.Equal(t, 0, clients["schema2"].count)
+	assert.Equal(t, 1, clients["schema1"].count)
+
+	// Queries resolved entirely by a mocked service are resolved as null too.
+	runAndValidateQueryResults(t, ctx, e, `{ s2root }`, `{"s2root": null}`)
+	assert.Equal(t, 0, clients["schema2"].count)
+}
@@ -0,0 +1,104 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Widget struct {
+	Id   int64
+	Name string
+}
+
+func TestExecutorMultipleKeys(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	// origin resolves widgets, byName fetches them by name and byId by id.
+	origin := schemabuilder.NewSchemaWithName("origin")
+	origin.Query().FieldFunc("widget", func() *Widget {
+		return &Widget{Id: 1, Name: "one"}
+	})
+	origin.Query().FieldFunc("widgets", func() []*Widget {
+		return []*Widget{{Id: 2, Name: "two"}, {Id: 3, Name: "three"}}
+	})
+	origin.Object("Widget", Widget{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Widget }) []*Widget {
+		return args.Keys
+	}))
+
+	type WidgetNameKeys struct {
+		Name string
+	}
+	byName := schemabuilder.NewSchemaWithName("byname")
+	widget := byName.Object("Widget", Widget{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*WidgetNameKeys }) []*Widget {
+		widgets := make([]*Widget, 0, len(args.Keys))
+		for _, key := range args.Keys {
+			widgets = append(widgets, &Widget{Name: key.Name})
+		}
+		return widgets
+	}))
+	widget.FieldFunc("nameLabel", func(w *Widget) string {
+		return fmt.Sprintf("name %s, id %d", w.Name, w.Id)
+	})
+
+	type WidgetIdKeys struct {
+		Id int64
+	}
+	byId := schemabuilder.NewSchemaWithName("byid")
+	widget = byId.Object("Widget", Widget{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*WidgetIdKeys }) []*Widget {
+		widgets := make([]*Widget, 0, len(args.Keys))
+		for _, key := range args.Keys {
+			widgets = append(widgets, &Widget{Id: key.Id})
+		}
+		return widgets
+	}))
+	widget.FieldFunc("idLabel", func(w *Widget) string {
+		return fmt.Sprintf("name %q, id %d", w.Name, w.Id)
+	})
+
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"origin": origin,
+		"byname": byName,
+		"byid":   byId,
+	})
+	require.NoError(t, err)
+	clients := make(map[string]*recordingExecutorClient)
+	for name, exec := range execs {
+		clients[name] = &recordingExecutorClient{ExecutorClient: exec}
+		execs[name] = clients[name]
+	}
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	require.NoError(t, err)
+
+	runAndValidateQueryResults(t, ctx, e, `{
+		widget { nameLabel }
+		widgets { idLabel }
+		both: widget { nameLabel idLabel }
+	}`, `{
+		"widget": {"nameLabel": "name one, id 0"},
+		"widgets": [
+			{"idLabel": "name \"\", id 2"},
+			{"idLabel": "name \"\", id 3"}
+		],
+		"both": {"nameLabel": "name one, id 0", "idLabel": "name \"\", id 1"}
+	}`)
+
+	// Each service is only passed its own keys, whichever branch of the
+	// query reached the widgets.
+	assert.ElementsMatch(t, []interface{}{
+		map[string]interface{}{"name": "one"},
+		map[string]interface{}{"name": "one"},
+	}, clients["byname"].federatedKeys())
+	assert.ElementsMatch(t, []interface{}{
+		map[string]interface{}{"id": json.Number("2")},
+		map[string]interface{}{"id": json.Number("3")},
+		map[string]interface{}{"id": json.Number("1")},
+	}, clients["byid"].federatedKeys())
+	assert.Empty(t, clients["origin"].federatedKeys())
+}
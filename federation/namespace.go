@@ -0,0 +1,125 @@
+package federation
+
+import (
+	"strings"
+
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// namespaceSchema prefixes the names of the types in a service's schema with
+// prefix, so that they don't collide with unrelated types of the same name on
+// other services. Federated objects are shared between services and keep their
+// names, as do the root, federation and introspection types. namespaceSchema
+// returns the original names of the renamed types, by their new names.
+func namespaceSchema(schema *IntrospectionQueryResult, prefix string) map[string]string {
+	renamed := make(map[string]string)
+	for _, typ := range schema.Schema.Types {
+		if !isNamespaced(typ) {
+			continue
+		}
+		renamed[typ.Name] = prefix + typ.Name
+	}
+
+	renameRef := func(ref *introspectionTypeRef) {
+		for ; ref != nil; ref = ref.OfType {
+			if name, ok := renamed[ref.Name]; ok {
+				ref.Name = name
+			}
+		}
+	}
+	for i := range schema.Schema.Types {
+		typ := &schema.Schema.Types[i]
+		if name, ok := renamed[typ.Name]; ok {
+			typ.Name = name
+		}
+		for _, field := range typ.Fields {
+			renameRef(field.Type)
+			for _, arg := range field.Args {
+				renameRef(arg.Type)
+			}
+		}
+		for _, field := range typ.InputFields {
+			renameRef(field.Type)
+		}
+		for _, possibleType := range typ.PossibleTypes {
+			renameRef(possibleType)
+		}
+	}
+
+	originals := make(map[string]string, len(renamed))
+	for original, name := range renamed {
+		originals[name] = original
+	}
+	return originals
+}
+
+// isNamespaced returns whether typ is renamed by namespaceSchema.
+func isNamespaced(typ introspectionType) bool {
+	switch typ.Kind {
+	case "OBJECT", "UNION", "ENUM", "INPUT_OBJECT":
+	default:
+		return false
+	}
+	if strings.HasPrefix(typ.Name, "__") {
+		return false
+	}
+	switch typ.Name {
+	case "Query", "Mutation", "Federation":
+		return false
+	}
+	for _, field := range typ.Fields {
+		if field.Name == federationField {
+			return false
+		}
+	}
+	return true
+}
+
+// namespaceSelectionSet returns a copy of selectionSet that refers to the
+// namespaced types of a service by their original names.
+func namespaceSelectionSet(selectionSet *graphql.SelectionSet, originals map[string]string) *graphql.SelectionSet {
+	if selectionSet == nil {
+		return nil
+	}
+	translated := &graphql.SelectionSet{
+		Selections: make([]*graphql.Selection, 0, len(selectionSet.Selections)),
+		Fragments:  make([]*graphql.Fragment, 0, len(selectionSet.Fragments)),
+	}
+	for _, selection := range selectionSet.Selections {
+		selectionCopy := *selection
+		selectionCopy.SelectionSet = namespaceSelectionSet(selection.SelectionSet, originals)
+		translated.Selections = append(translated.Selections, &selectionCopy)
+	}
+	for _, fragment := range selectionSet.Fragments {
+		on := fragment.On
+		if original, ok := originals[on]; ok {
+			on = original
+		}
+		translated.Fragments = append(translated.Fragments, &graphql.Fragment{
+			On:           on,
+			SelectionSet: namespaceSelectionSet(fragment.SelectionSet, originals),
+		})
+	}
+	return translated
+}
+
+// namespaceTypenames renames the __typename of the objects in res returned by
+// a service to the namespaced names of their types.
+func namespaceTypenames(res interface{}, names map[string]string) {
+	switch res := res.(type) {
+	case []interface{}:
+		for _, elem := range res {
+			namespaceTypenames(elem, names)
+		}
+	case map[string]interface{}:
+		for k, v := range res {
+			if typename, ok := v.(string); ok && k == "__typename" {
+				if name, ok := names[typename]; ok {
+					res[k] = name
+				}
+				continue
+			}
+			namespaceTypenames(v, names)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package federation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildNamespacedSchemas() map[string]*schemabuilder.Schema {
+	type Device struct {
+		Id int64
+	}
+	type Config struct {
+		Retries int64
+	}
+	type Setting struct {
+		Key string
+	}
+	type ConfigOrSetting struct {
+		schemabuilder.Union
+		*Config
+		*Setting
+	}
+	s1 := schemabuilder.NewSchemaWithName("s1")
+	s1.Object("Device", Device{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Device }) []*Device {
+		return args.Keys
+	}))
+	s1.Object("Config", Config{})
+	s1.Query().FieldFunc("devices", func() []*Device {
+		return []*Device{{Id: 1}, {Id: 2}}
+	})
+	s1.Query().FieldFunc("s1config", func() *Config {
+		return &Config{Retries: 3}
+	})
+	s1.Query().FieldFunc("settings", func() []*ConfigOrSetting {
+		return []*ConfigOrSetting{{Config: &Config{Retries: 5}}, {Setting: &Setting{Key: "mode"}}}
+	})
+
+	type RemoteConfig struct {
+		Url string
+	}
+	s2 := schemabuilder.NewSchemaWithName("s2")
+	device := s2.Object("Device", Device{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Device }) []*Device {
+		return args.Keys
+	}))
+	s2.Object("Config", RemoteConfig{})
+	device.FieldFunc("config", func(d *Device) *RemoteConfig {
+		return &RemoteConfig{Url: "https://example.com/" + string(rune('0'+d.Id))}
+	})
+
+	return map[string]*schemabuilder.Schema{
+		"s1": s1,
+		"s2": s2,
+	}
+}
+
+func TestExecutorTypeNamespaces(t *testing.T) {
+	ctx := context.Background()
+	execs, err := makeExecutors(buildNamespacedSchemas())
+	require.NoError(t, err)
+
+	t.Run("without namespaces", func(t *testing.T) {
+		// The unrelated types are merged into one.
+		e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+		require.NoError(t, err)
+		ownership, err := e.Ownership()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"s1", "s2"}, ownership.Types["Config"])
+	})
+
+	syncer := NewIntrospectionSchemaSyncer(ctx, execs, nil)
+	syncer.TypeNamespaces = map[string]string{"s1": "S1", "s2": "S2"}
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: syncer})
+	require.NoError(t, err)
+
+	ownership, err := e.Ownership()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"s1"}, ownership.Types["S1Config"])
+	assert.Equal(t, []string{"s2"}, ownership.Types["S2Config"])
+	assert.Equal(t, []string{"s1", "s2"}, ownership.Types["Device"])
+	assert.NotContains(t, ownership.Types, "Config")
+
+	runAndValidateQueryResults(t, ctx, e, `{
+		s1config { __typename retries }
+		devices { id config { __typename url } }
+		settings {
+			__typename
+			... on S1Config { retries }
+			... on S1Setting { key }
+		}
+	}`, `
+		{
+			"s1config": {"__typename": "S1Config", "retries": 3},
+			"devices": [
+				{"id": 1, "config": {"__typename": "S2Config", "url": "https://example.com/1"}},
+				{"id": 2, "config": {"__typename": "S2Config", "url": "https://example.com/2"}}
+			],
+			"settings": [
+				{"__typename": "S1Config", "retries": 5},
+				{"__typename": "S1Setting", "key": "mode"}
+			]
+		}`)
+}
@@ -237,7 +237,7 @@ func (f *flattener) flatten(selectionSet *graphql.SelectionSet, typ graphql.Type
 			} else {
 				field, ok := typ.Fields[selection.Name]
 				if !ok {
-					return nil, fmt.Errorf("unknown field %s on typ %s", selection.Name, typ.Name)
+					return nil, graphql.NewClientErrorAt(selection.Location, "unknown field %s on typ %s", selection.Name, typ.Name)
 				}
 				fieldTyp = field.Type
 			}
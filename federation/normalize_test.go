@@ -11,7 +11,7 @@ import (
 
 func TestNormalize(t *testing.T) {
 	parse := func(q string) *graphql.SelectionSet {
-		return graphql.MustParse(q, map[string]interface{}{}).SelectionSet
+		return mustParse(q)
 	}
 
 	type User struct {
@@ -0,0 +1,60 @@
+package federation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// panickingExecutorClient panics on every request.
+type panickingExecutorClient struct{}
+
+func (panickingExecutorClient) Execute(ctx context.Context, request *QueryRequest) (*QueryResponse, error) {
+	panic("client bug")
+}
+
+func TestExecutorPanics(t *testing.T) {
+	ctx := context.Background()
+
+	s2 := buildTestSchema2()
+	s2.Query().FieldFunc("s2panic", func() string {
+		panic("resolver bug")
+	})
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": s2,
+	})
+	require.NoError(t, err)
+	// The syncer polls its own copy of the executors, which the test
+	// replaces below.
+	syncerExecs := make(map[string]ExecutorClient, len(execs))
+	for name, exec := range execs {
+		syncerExecs[name] = exec
+	}
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, syncerExecs, nil)})
+	require.NoError(t, err)
+
+	// A panicking resolver on a service fails the query with its path.
+	runAndValidateQueryError(t, ctx, e, `{ s1f { name } s2panic }`, "", "s2panic: graphql: panic: resolver bug")
+
+	// A panicking client fails the query instead of crashing the gateway.
+	e.Executors["schema2"] = panickingExecutorClient{}
+	_, _, err = e.Execute(ctx, graphql.MustParse(`{ s1fff { name s2ok } }`, map[string]interface{}{}), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "graphql: panic: client bug")
+	lookupErr, ok := oops.Cause(err).(*LookupError)
+	require.True(t, ok)
+	_, ok = oops.Cause(lookupErr.Err).(*graphql.PanicError)
+	assert.True(t, ok)
+
+	// A panicking client fails schema polls instead of crashing the gateway.
+	_, err = fetchSchema(ctx, panickingExecutorClient{}, nil)
+	require.Error(t, err)
+	_, ok = err.(*graphql.PanicError)
+	assert.True(t, ok)
+}
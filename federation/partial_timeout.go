@@ -0,0 +1,153 @@
+package federation
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// WithPartialTimeout resolves the fields of a subquery as null, rather than
+// failing the whole query, when its service does not respond within timeout.
+// The rest of the query, including the fields of other services, is still
+// returned, and the timeout is recorded in the context's PartialErrors.
+//
+// Subqueries that select non-null fields, which cannot be resolved as null,
+// and mutations still fail the query when they time out.
+func WithPartialTimeout(timeout time.Duration) ExecutorOption {
+	return func(e *Executor) {
+		e.partialTimeout = timeout
+	}
+}
+
+// PartialError is an error of a subquery whose fields were resolved as null
+// instead of failing the query.
+type PartialError struct {
+	// Service is the service the subquery was sent to.
+	Service string
+	// Paths are the response paths of the objects whose fields were resolved
+	// as null.
+	Paths [][]interface{}
+	// Err is the error of the subquery.
+	Err error
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("service %s: %v", e.Service, e.Err)
+}
+
+type partialErrorsKey struct{}
+
+// partialErrors collects the PartialErrors of a query.
+type partialErrors struct {
+	mu   sync.Mutex
+	errs []*PartialError
+}
+
+// WithPartialErrors returns a context that records the partial errors of the
+// queries executed with it, eg. subqueries that timed out with
+// WithPartialTimeout, which can be read with PartialErrors.
+func WithPartialErrors(ctx context.Context) context.Context {
+	return context.WithValue(ctx, partialErrorsKey{}, &partialErrors{})
+}
+
+// PartialErrors returns the partial errors recorded in a context created
+// with WithPartialErrors.
+func PartialErrors(ctx context.Context) []*PartialError {
+	collected, ok := ctx.Value(partialErrorsKey{}).(*partialErrors)
+	if !ok {
+		return nil
+	}
+	collected.mu.Lock()
+	defer collected.mu.Unlock()
+	return append([]*PartialError{}, collected.errs...)
+}
+
+func recordPartialError(ctx context.Context, err *PartialError) {
+	collected, ok := ctx.Value(partialErrorsKey{}).(*partialErrors)
+	if !ok {
+		return
+	}
+	collected.mu.Lock()
+	defer collected.mu.Unlock()
+	collected.errs = append(collected.errs, err)
+}
+
+// nullResults returns the results of p for each of keys, or for the root
+// object if keys is nil, with all of p's fields resolved as null. It returns
+// false if p selects non-null fields.
+func nullResults(planner *Planner, p *Plan, keys []interface{}) ([]interface{}, bool) {
+	if p.Kind != queryString {
+		return nil, false
+	}
+	typ, ok := planner.flattener.types[p.Type].(*graphql.Object)
+	if !ok {
+		return nil, false
+	}
+	for _, selection := range p.SelectionSet.Selections {
+		if selection.Name == "__typename" || selection.Name == federationField {
+			continue
+		}
+		field, ok := typ.Fields[selection.Name]
+		if !ok {
+			return nil, false
+		}
+		if _, ok := field.Type.(*graphql.NonNull); ok {
+			return nil, false
+		}
+	}
+
+	return nullObjects(p.Type, keys, p.SelectionSet), true
+}
+
+// nullObjects returns the results of selectionSet on typName for each of
+// keys, or for the root object if keys is nil, with all of the fields
+// resolved as null, whether or not they are non-null.
+func nullObjects(typName string, keys []interface{}, selectionSet *graphql.SelectionSet) []interface{} {
+	n := len(keys)
+	if keys == nil {
+		n = 1
+	}
+	res := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		obj := make(map[string]interface{}, len(selectionSet.Selections))
+		for _, selection := range selectionSet.Selections {
+			switch selection.Name {
+			case "__typename":
+				obj[selection.Alias] = typName
+			default:
+				// A null "_federation" leaves the object out of the
+				// subplans.
+				obj[selection.Alias] = nil
+			}
+		}
+		res = append(res, obj)
+	}
+	return res
+}
+
+// withPartialTimeout returns a function running p on its service with run,
+// which resolves the fields of p as null if the service does not respond
+// within timeout.
+func (e *Executor) withPartialTimeout(ctx context.Context, p *Plan, planner *Planner, timeout time.Duration, run func(ctx context.Context, keys []interface{}) ([]interface{}, interface{}, error)) func(keys []interface{}) ([]interface{}, interface{}, error) {
+	return func(keys []interface{}) ([]interface{}, interface{}, error) {
+		timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		res, metadata, err := run(timeoutCtx, keys)
+		if err == nil || ctx.Err() != nil || timeoutCtx.Err() != context.DeadlineExceeded {
+			return res, metadata, err
+		}
+		nulls, ok := nullResults(planner, p, keys)
+		if !ok {
+			return res, metadata, err
+		}
+		recordPartialError(ctx, &PartialError{
+			Service: p.Service,
+			Paths:   responsePathsFromContext(ctx),
+			Err:     err,
+		})
+		return nulls, nil, nil
+	}
+}
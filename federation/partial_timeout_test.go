@@ -0,0 +1,94 @@
+package federation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorPartialTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	// slow waits for the gateway to give up on the request.
+	slow := func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil
+		}
+	}
+	s2 := schemabuilder.NewSchemaWithName("schema2")
+	s2.Query().FieldFunc("s2root", func() string {
+		return "hello"
+	})
+	foo := s2.Object("Foo", Foo{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Foo }) []*Foo {
+		return args.Keys
+	}))
+	foo.FieldFunc("s2slow", func(ctx context.Context, in *Foo) (*int64, error) {
+		if err := slow(ctx); err != nil {
+			return nil, err
+		}
+		n := int64(len(in.Name))
+		return &n, nil
+	})
+	foo.FieldFunc("s2slowNonNull", func(ctx context.Context, in *Foo) (int64, error) {
+		if err := slow(ctx); err != nil {
+			return 0, err
+		}
+		return int64(len(in.Name)), nil
+	})
+
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": s2,
+	})
+	require.NoError(t, err)
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)}, WithPartialTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+
+	t.Run("slow fields are null", func(t *testing.T) {
+		ctx := WithPartialErrors(ctx)
+		res, _, err := e.Execute(ctx, graphql.MustParse(`{
+			s1fff { name s1hmm s2slow }
+			s2root
+		}`, map[string]interface{}{}), nil)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"s1fff": []interface{}{
+				map[string]interface{}{"name": "jimbo", "s1hmm": "jimbo!!!", "s2slow": nil},
+				map[string]interface{}{"name": "bob", "s1hmm": "bob!!!", "s2slow": nil},
+			},
+			"s2root": "hello",
+		}, res)
+
+		errs := PartialErrors(ctx)
+		require.Len(t, errs, 1)
+		assert.Equal(t, "schema2", errs[0].Service)
+		assert.Equal(t, [][]interface{}{{"s1fff", 0}, {"s1fff", 1}}, errs[0].Paths)
+		assert.Contains(t, errs[0].Error(), "context deadline exceeded")
+	})
+
+	t.Run("non-null fields fail the query", func(t *testing.T) {
+		ctx := WithPartialErrors(ctx)
+		_, _, err := e.Execute(ctx, graphql.MustParse(`{ s1fff { name s2slowNonNull } }`, map[string]interface{}{}), nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "context deadline exceeded")
+		assert.Empty(t, PartialErrors(ctx))
+	})
+
+	t.Run("fast fields are unaffected", func(t *testing.T) {
+		ctx := WithPartialErrors(ctx)
+		runAndValidateQueryResults(t, ctx, e, `{ s1f { name } s2root }`, `
+			{
+				"s1f": {"name": "jimbob"},
+				"s2root": "hello"
+			}`)
+		assert.Empty(t, PartialErrors(ctx))
+	})
+}
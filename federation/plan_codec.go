@@ -0,0 +1,248 @@
+package federation
+
+import (
+	"encoding/json"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// planFormatVersion is the version of the format written by MarshalPlan.
+const planFormatVersion = 2
+
+// serializedPlan is the portable representation of a Plan written by
+// MarshalPlan. Unlike the canonical representation used by Hash, it keeps
+// the order of selections and subplans.
+type serializedPlan struct {
+	Path         []PathStep             `json:"path,omitempty"`
+	Service      string                 `json:"service"`
+	Kind         string                 `json:"kind"`
+	Type         string                 `json:"type"`
+	SelectionSet *canonicalSelectionSet `json:"selectionSet,omitempty"`
+	After        []*serializedPlan      `json:"after,omitempty"`
+	Stage        int                    `json:"stage,omitempty"`
+}
+
+type serializedPlanFile struct {
+	Version int                    `json:"version"`
+	Plan    *serializedPlan        `json:"plan"`
+	Query   *canonicalSelectionSet `json:"query,omitempty"`
+}
+
+// MarshalPlan serializes plan, eg. to precompute the plans of a fixed set of
+// queries when deploying a gateway. The plan can be loaded with LoadPlan and
+// executed with ExecuteBatch, skipping planning entirely.
+func MarshalPlan(plan *Plan) ([]byte, error) {
+	file := &serializedPlanFile{
+		Version: planFormatVersion,
+		Plan:    encodePlan(plan),
+	}
+	if plan.query != nil {
+		file.Query = encodeSelectionSet(plan.query.SelectionSet)
+	}
+	b, err := json.Marshal(file)
+	if err != nil {
+		return nil, oops.Wrapf(err, "marshaling plan")
+	}
+	return b, nil
+}
+
+func encodePlan(p *Plan) *serializedPlan {
+	s := &serializedPlan{
+		Path:         p.Path,
+		Service:      p.Service,
+		Kind:         p.Kind,
+		Type:         p.Type,
+		SelectionSet: encodeSelectionSet(p.SelectionSet),
+		Stage:        p.Stage,
+	}
+	for _, subPlan := range p.After {
+		s.After = append(s.After, encodePlan(subPlan))
+	}
+	return s
+}
+
+// LoadPlan reconstructs a plan serialized with MarshalPlan. It fails if the
+// plan does not match the current merged schema, eg. because a service no
+// longer resolves a field the plan fetches from it, so that plans computed
+// against an old schema are replanned rather than executed.
+func (e *Executor) LoadPlan(data []byte) (*Plan, error) {
+	var file serializedPlanFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, oops.Wrapf(err, "unmarshaling plan")
+	}
+	if file.Version != planFormatVersion {
+		return nil, oops.Errorf("unsupported plan format version %d", file.Version)
+	}
+	if file.Plan == nil {
+		return nil, oops.Errorf("missing plan")
+	}
+	if file.Query == nil {
+		return nil, oops.Errorf("missing query")
+	}
+	plan := decodePlan(file.Plan)
+	plan.query = &graphql.Query{Kind: plan.Kind, SelectionSet: decodeSelectionSet(file.Query)}
+	plan.selectsUnions = selectsUnions(plan.query.SelectionSet)
+	if err := e.validatePlan(e.getPlanner(), plan); err != nil {
+		return nil, oops.Wrapf(err, "plan does not match the schema")
+	}
+	return plan, nil
+}
+
+func decodePlan(s *serializedPlan) *Plan {
+	p := &Plan{
+		Path:         s.Path,
+		Service:      s.Service,
+		Kind:         s.Kind,
+		Type:         s.Type,
+		SelectionSet: decodeSelectionSet(s.SelectionSet),
+		Stage:        s.Stage,
+	}
+	for _, subPlan := range s.After {
+		p.After = append(p.After, decodePlan(subPlan))
+	}
+	return p
+}
+
+func decodeSelectionSet(c *canonicalSelectionSet) *graphql.SelectionSet {
+	if c == nil {
+		return nil
+	}
+	selectionSet := &graphql.SelectionSet{}
+	for _, selection := range c.Selections {
+		args := selection.Args
+		if args == nil {
+			args = map[string]interface{}{}
+		}
+		selectionSet.Selections = append(selectionSet.Selections, &graphql.Selection{
+			Name:         selection.Name,
+			Alias:        selection.Alias,
+			UnparsedArgs: args,
+			SelectionSet: decodeSelectionSet(selection.SelectionSet),
+		})
+	}
+	for _, fragment := range c.Fragments {
+		selectionSet.Fragments = append(selectionSet.Fragments, &graphql.Fragment{
+			On:           fragment.On,
+			SelectionSet: decodeSelectionSet(fragment.SelectionSet),
+		})
+	}
+	return selectionSet
+}
+
+// validatePlan checks that the root plan p runs on the gateway and that every
+// subplan only fetches fields from services that resolve them, and that the
+// query of p matches the schema.
+func (e *Executor) validatePlan(planner *Planner, p *Plan) error {
+	if p.Service != gatewayCoordinatorServiceName {
+		return oops.Errorf("root plan runs on %s, expected the gateway", p.Service)
+	}
+	var root graphql.Type
+	switch p.Kind {
+	case queryString:
+		root = planner.schema.Schema.Query
+	case mutationString:
+		root = planner.schema.Schema.Mutation
+	default:
+		return oops.Errorf("unknown query kind %s", p.Kind)
+	}
+	rootObj, ok := root.(*graphql.Object)
+	if !ok || rootObj.Name != p.Type {
+		return oops.Errorf("unknown root type %s", p.Type)
+	}
+
+	for _, subPlan := range p.After {
+		if err := subPlan.Walk(func(subPlan *Plan) error {
+			return e.validateSubPlan(planner, rootObj, subPlan)
+		}); err != nil {
+			return err
+		}
+	}
+	if p.query != nil {
+		if _, err := planner.flattener.flatten(p.query.SelectionSet, root); err != nil {
+			return oops.Wrapf(err, "query")
+		}
+	}
+	return nil
+}
+
+func (e *Executor) validateSubPlan(planner *Planner, root *graphql.Object, p *Plan) error {
+	if _, ok := e.Executors[p.Service]; !ok && p.Service != introspectionServiceName {
+		return oops.Errorf("unknown service %s", p.Service)
+	}
+	var typ graphql.Type = root
+	if p.Type != root.Name {
+		var ok bool
+		if typ, ok = planner.flattener.types[p.Type].(*graphql.Object); !ok {
+			return oops.Errorf("unknown object type %s", p.Type)
+		}
+	}
+	return validatePlanSelections(planner, p.Service, typ, p.SelectionSet)
+}
+
+// validatePlanSelections checks that service resolves every field of
+// selectionSet on typ.
+func validatePlanSelections(planner *Planner, service string, typ graphql.Type, selectionSet *graphql.SelectionSet) error {
+	if selectionSet == nil {
+		return nil
+	}
+	switch typ := typ.(type) {
+	case *graphql.NonNull:
+		return validatePlanSelections(planner, service, typ.Type, selectionSet)
+	case *graphql.List:
+		return validatePlanSelections(planner, service, typ.Type, selectionSet)
+
+	case *graphql.Object:
+		for _, selection := range selectionSet.Selections {
+			if selection.Name == "__typename" {
+				continue
+			}
+			field, ok := typ.Fields[selection.Name]
+			if !ok {
+				return oops.Errorf("%s has no field %s", typ.Name, selection.Name)
+			}
+			if selection.Name == federationField {
+				// The keys of the object are fields of the object itself.
+				if err := validatePlanSelections(planner, service, typ, selection.SelectionSet); err != nil {
+					return oops.Wrapf(err, "keys of %s", typ.Name)
+				}
+				continue
+			}
+			if info := planner.schema.Fields[field]; info == nil || !info.Services[service] {
+				return oops.Errorf("service %s does not resolve %s.%s", service, typ.Name, selection.Name)
+			}
+			if err := validatePlanSelections(planner, service, field.Type, selection.SelectionSet); err != nil {
+				return err
+			}
+		}
+		for _, fragment := range selectionSet.Fragments {
+			if fragment.On != typ.Name {
+				return oops.Errorf("fragment on %s in %s", fragment.On, typ.Name)
+			}
+			if err := validatePlanSelections(planner, service, typ, fragment.SelectionSet); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *graphql.Union:
+		for _, selection := range selectionSet.Selections {
+			if selection.Name != "__typename" {
+				return oops.Errorf("%s has no field %s", typ.Name, selection.Name)
+			}
+		}
+		for _, fragment := range selectionSet.Fragments {
+			member, ok := typ.Types[fragment.On]
+			if !ok {
+				return oops.Errorf("%s is not a member of %s", fragment.On, typ.Name)
+			}
+			if err := validatePlanSelections(planner, service, member, fragment.SelectionSet); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return oops.Errorf("unexpected selections on %s", typ)
+	}
+}
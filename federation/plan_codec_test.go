@@ -0,0 +1,73 @@
+package federation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanCodec(t *testing.T) {
+	e, _ := createKitchenSinkExecutor(t)
+	ctx := context.Background()
+
+	query := `{
+		s1fff { name s1hmm s2ok s2bar { id s1baz } s1nest { name } }
+		s1both { ... on Foo { name s2ok } ... on Bar { id s1baz } }
+		s1echo(foo: "hi", required: {a: 1, b: 2})
+		s2root
+	}`
+	plan, err := e.Plan(graphql.MustParse(query, map[string]interface{}{}))
+	require.NoError(t, err)
+
+	data, err := MarshalPlan(plan)
+	require.NoError(t, err)
+	loaded, err := e.LoadPlan(data)
+	require.NoError(t, err)
+
+	// The loaded plan is the same plan, and marshals back to the same bytes.
+	expectedHash, err := plan.Hash()
+	require.NoError(t, err)
+	loadedHash, err := loaded.Hash()
+	require.NoError(t, err)
+	assert.Equal(t, expectedHash, loadedHash)
+	remarshaled, err := MarshalPlan(loaded)
+	require.NoError(t, err)
+	assert.Equal(t, string(data), string(remarshaled))
+
+	// Executing the loaded plan gives the same result as executing the query.
+	expected, _, err := e.Execute(ctx, graphql.MustParse(query, map[string]interface{}{}), nil)
+	require.NoError(t, err)
+	results, _, err := e.ExecuteBatch(ctx, []*Plan{loaded}, nil)
+	require.NoError(t, err)
+	expectedJSON, err := json.Marshal(expected)
+	require.NoError(t, err)
+	actualJSON, err := json.Marshal(results[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expectedJSON), string(actualJSON))
+
+	t.Run("skew", func(t *testing.T) {
+		for _, testCase := range []struct {
+			name, old, new, err string
+		}{
+			{"removed field", `"name":"s2ok"`, `"name":"s2gone"`, "Foo has no field s2gone"},
+			{"removed service", `"service":"schema2"`, `"service":"schema3"`, "unknown service schema3"},
+			{"moved field", `"service":"schema2"`, `"service":"schema1"`, "service schema1 does not resolve"},
+			{"removed type", `"type":"Bar"`, `"type":"Baz"`, "unknown object type Baz"},
+			{"unknown version", `{"version":2`, `{"version":3`, "unsupported plan format version 3"},
+			{"missing query", `,"query":`, `,"unknown":`, "missing query"},
+		} {
+			t.Run(testCase.name, func(t *testing.T) {
+				corrupted := bytes.Replace(data, []byte(testCase.old), []byte(testCase.new), -1)
+				require.NotEqual(t, string(data), string(corrupted))
+				_, err := e.LoadPlan(corrupted)
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), testCase.err)
+			})
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package federation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// canonicalPlan is the normalized structure of a Plan that is hashed. Its
+// selections, fragments and subplans are sorted, so that plans which only
+// differ in the iteration order of maps hash the same.
+type canonicalPlan struct {
+	Path         []PathStep             `json:"path,omitempty"`
+	Service      string                 `json:"service"`
+	Kind         string                 `json:"kind"`
+	Type         string                 `json:"type"`
+	SelectionSet *canonicalSelectionSet `json:"selectionSet,omitempty"`
+	After        []json.RawMessage      `json:"after,omitempty"`
+	Stage        int                    `json:"stage,omitempty"`
+}
+
+type canonicalSelectionSet struct {
+	Selections []*canonicalSelection `json:"selections,omitempty"`
+	Fragments  []*canonicalFragment  `json:"fragments,omitempty"`
+}
+
+type canonicalSelection struct {
+	Name         string                 `json:"name"`
+	Alias        string                 `json:"alias"`
+	Args         map[string]interface{} `json:"args,omitempty"`
+	SelectionSet *canonicalSelectionSet `json:"selectionSet,omitempty"`
+}
+
+type canonicalFragment struct {
+	On           string                 `json:"on"`
+	SelectionSet *canonicalSelectionSet `json:"selectionSet,omitempty"`
+}
+
+// Hash returns a hash of the plan that is stable across processes, eg. for
+// use as a cache key or in golden tests. Plans that resolve the same
+// selections on the same services in the same order hash the same, regardless
+// of the order in which their selections and subplans were built. A selection
+// without an alias hashes like one aliased to its own name.
+func (p *Plan) Hash() (string, error) {
+	b, err := p.canonicalJSON()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (p *Plan) canonicalJSON() ([]byte, error) {
+	c := &canonicalPlan{
+		Path:         p.Path,
+		Service:      p.Service,
+		Kind:         p.Kind,
+		Type:         p.Type,
+		SelectionSet: canonicalizeSelectionSet(p.SelectionSet),
+		After:        make([]json.RawMessage, 0, len(p.After)),
+		Stage:        p.Stage,
+	}
+	for _, subPlan := range p.After {
+		b, err := subPlan.canonicalJSON()
+		if err != nil {
+			return nil, err
+		}
+		c.After = append(c.After, b)
+	}
+	sort.Slice(c.After, func(i, j int) bool {
+		return string(c.After[i]) < string(c.After[j])
+	})
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, oops.Wrapf(err, "marshaling plan")
+	}
+	return b, nil
+}
+
+// canonicalizeSelectionSet encodes selectionSet with its selections and
+// fragments sorted.
+func canonicalizeSelectionSet(selectionSet *graphql.SelectionSet) *canonicalSelectionSet {
+	c := encodeSelectionSet(selectionSet)
+	sortSelectionSet(c)
+	return c
+}
+
+// encodeSelectionSet encodes selectionSet, keeping the order of its
+// selections and fragments.
+func encodeSelectionSet(selectionSet *graphql.SelectionSet) *canonicalSelectionSet {
+	if selectionSet == nil {
+		return nil
+	}
+	c := &canonicalSelectionSet{}
+	for _, selection := range selectionSet.Selections {
+		alias := selection.Alias
+		if alias == "" {
+			alias = selection.Name
+		}
+		var args map[string]interface{}
+		if len(selection.UnparsedArgs) > 0 {
+			args = selection.UnparsedArgs
+		}
+		c.Selections = append(c.Selections, &canonicalSelection{
+			Name:         selection.Name,
+			Alias:        alias,
+			Args:         args,
+			SelectionSet: encodeSelectionSet(selection.SelectionSet),
+		})
+	}
+	for _, fragment := range selectionSet.Fragments {
+		c.Fragments = append(c.Fragments, &canonicalFragment{
+			On:           fragment.On,
+			SelectionSet: encodeSelectionSet(fragment.SelectionSet),
+		})
+	}
+	return c
+}
+
+func sortSelectionSet(c *canonicalSelectionSet) {
+	if c == nil {
+		return
+	}
+	for _, selection := range c.Selections {
+		sortSelectionSet(selection.SelectionSet)
+	}
+	sort.SliceStable(c.Selections, func(i, j int) bool {
+		if c.Selections[i].Alias != c.Selections[j].Alias {
+			return c.Selections[i].Alias < c.Selections[j].Alias
+		}
+		return c.Selections[i].Name < c.Selections[j].Name
+	})
+	for _, fragment := range c.Fragments {
+		sortSelectionSet(fragment.SelectionSet)
+	}
+	sort.SliceStable(c.Fragments, func(i, j int) bool {
+		return c.Fragments[i].On < c.Fragments[j].On
+	})
+}
@@ -0,0 +1,113 @@
+package federation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/samsarahq/thunder/logger"
+)
+
+// WithPlanLogger makes the executor log the plan of every query that fails
+// to execute to l, including queries whose results fail to check or
+// post-process, and failed ResolveEntity calls. Logs include the request ID,
+// see WithRequestID, and the subquery that failed: its service, type, path
+// and the number of keys it was sent, along with a hash of the keys, which
+// can hold personal data, eg.
+//   federated query failed [error ... plan {...} request_id abc service schema2 type Foo path s1fff keys 2 keys_hash 5d41402abc4b2a76]
+// Queries that fail to plan are not logged, as they have no plan.
+func WithPlanLogger(l logger.Logger) ExecutorOption {
+	return func(e *Executor) {
+		e.planLogger = l
+	}
+}
+
+type failedStepKey struct{}
+
+// failedStep is the first subquery of a query that failed.
+type failedStep struct {
+	mu   sync.Mutex
+	plan *Plan
+	keys []interface{}
+}
+
+// withFailedStep returns a context recording the subquery that fails, if the
+// executor logs failed plans.
+func (e *Executor) withFailedStep(ctx context.Context) context.Context {
+	if e.planLogger == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, failedStepKey{}, &failedStep{})
+}
+
+// recordFailedStep records that the subquery p failed with keys, unless an
+// earlier subquery of the query failed already.
+func recordFailedStep(ctx context.Context, p *Plan, keys []interface{}) {
+	step, ok := ctx.Value(failedStepKey{}).(*failedStep)
+	if !ok {
+		return
+	}
+	step.mu.Lock()
+	defer step.mu.Unlock()
+	if step.plan == nil {
+		step.plan = p
+		step.keys = keys
+	}
+}
+
+// logFailedPlan logs plan, which failed with err, and the subquery recorded
+// in ctx.
+func (e *Executor) logFailedPlan(ctx context.Context, plan *Plan, err error) {
+	if e.planLogger == nil {
+		return
+	}
+	tags := []interface{}{"error", err.Error()}
+	if data, err := MarshalPlan(plan); err == nil {
+		tags = append(tags, "plan", string(data))
+	}
+	if id, ok := RequestIDFromContext(ctx); ok {
+		tags = append(tags, "request_id", id)
+	}
+	if step, ok := ctx.Value(failedStepKey{}).(*failedStep); ok {
+		step.mu.Lock()
+		defer step.mu.Unlock()
+		if step.plan != nil {
+			tags = append(tags, "service", step.plan.Service, "type", step.plan.Type, "path", formatPlanPath(step.plan.Path))
+			if step.keys != nil {
+				tags = append(tags, "keys", len(step.keys))
+				if hash, err := hashKeys(step.keys); err == nil {
+					tags = append(tags, "keys_hash", hash)
+				}
+			}
+		}
+	}
+	e.planLogger.Error("federated query failed", tags...)
+}
+
+// hashKeys returns a short hash of keys, which identifies them in logs without
+// logging their values.
+func hashKeys(keys []interface{}) (string, error) {
+	data, err := json.Marshal(keys)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// formatPlanPath formats the path of a subplan as a dotted string, eg.
+// s1both....Foo for the Foo members of s1both.
+func formatPlanPath(path []PathStep) string {
+	parts := make([]string, 0, len(path))
+	for _, step := range path {
+		if step.Kind == KindType {
+			parts = append(parts, "..."+step.Name)
+		} else {
+			parts = append(parts, step.Name)
+		}
+	}
+	return strings.Join(parts, ".")
+}
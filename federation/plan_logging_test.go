@@ -0,0 +1,120 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingLogger records the tags of the errors it logs.
+type recordingLogger struct {
+	mu     sync.Mutex
+	errors []map[string]interface{}
+}
+
+func (l *recordingLogger) Debug(msg string, tags ...interface{}) {}
+func (l *recordingLogger) Info(msg string, tags ...interface{})  {}
+func (l *recordingLogger) Warn(msg string, tags ...interface{})  {}
+
+func (l *recordingLogger) Error(msg string, tags ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := map[string]interface{}{"msg": msg}
+	for i := 0; i+1 < len(tags); i += 2 {
+		entry[tags[i].(string)] = tags[i+1]
+	}
+	l.errors = append(l.errors, entry)
+}
+
+func TestExecutorPlanLogger(t *testing.T) {
+	ctx := context.Background()
+
+	s2 := buildTestSchema2()
+	s2.Query().FieldFunc("s2fail", func() (string, error) {
+		return "", errors.New("s2fail failed")
+	})
+	s2.Object("Foo", Foo{}).FieldFunc("s2failFoo", func(in *Foo) (string, error) {
+		return "", errors.New("s2failFoo failed")
+	})
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": s2,
+	})
+	require.NoError(t, err)
+	l := &recordingLogger{}
+	var failResponses bool
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)}, WithPlanLogger(l),
+		WithResponseMiddleware(ResponseMiddlewareFunc(func(ctx context.Context, response *Response) error {
+			if failResponses {
+				return errors.New("middleware failed")
+			}
+			return nil
+		})))
+	require.NoError(t, err)
+
+	runAndValidateQueryResults(t, ctx, e, `{ s1fff { name s2ok } }`, `{
+		"s1fff": [{"name": "jimbo", "s2ok": 5}, {"name": "bob", "s2ok": 3}]
+	}`)
+	assert.Empty(t, l.errors)
+
+	query := graphql.MustParse(`{ s1fff { name s2failFoo } }`, map[string]interface{}{})
+	_, _, err = e.Execute(WithRequestID(ctx, "req-1"), query, nil)
+	require.Error(t, err)
+	plan, err := e.Plan(query)
+	require.NoError(t, err)
+	data, err := MarshalPlan(plan)
+	require.NoError(t, err)
+	require.Len(t, l.errors, 1)
+	assert.Contains(t, l.errors[0]["error"], "s2failFoo failed")
+	delete(l.errors[0], "error")
+	// The keys are logged as a hash rather than as they are.
+	keysHash, err := hashKeys([]interface{}{map[string]interface{}{"name": "jimbo"}, map[string]interface{}{"name": "bob"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"msg":        "federated query failed",
+		"plan":       string(data),
+		"request_id": "req-1",
+		"service":    "schema2",
+		"type":       "Foo",
+		"path":       "s1fff",
+		"keys":       2,
+		"keys_hash":  keysHash,
+	}, l.errors[0])
+
+	// Queries resolved by a single service log the root subquery, without
+	// keys.
+	l.errors = nil
+	query = graphql.MustParse(`{ s2fail }`, map[string]interface{}{})
+	_, _, err = e.Execute(ctx, query, nil)
+	require.Error(t, err)
+	require.Len(t, l.errors, 1)
+	assert.Contains(t, l.errors[0]["error"], "s2fail failed")
+	assert.Equal(t, "schema2", l.errors[0]["service"])
+	assert.Equal(t, "Query", l.errors[0]["type"])
+	assert.Equal(t, "", l.errors[0]["path"])
+	assert.NotContains(t, l.errors[0], "keys")
+
+	// Queries whose results fail to post-process are logged too.
+	l.errors = nil
+	failResponses = true
+	_, _, err = e.Execute(ctx, graphql.MustParse(`{ s1fff { name s2ok } }`, map[string]interface{}{}), nil)
+	require.Error(t, err)
+	require.Len(t, l.errors, 1)
+	assert.Contains(t, l.errors[0]["error"], "middleware failed")
+	failResponses = false
+
+	// And so are failed entity lookups.
+	l.errors = nil
+	_, err = e.ResolveEntity(ctx, "Foo", map[string]interface{}{"name": "jimbo"}, mustParse(`{ s2failFoo }`), nil)
+	require.Error(t, err)
+	require.Len(t, l.errors, 1)
+	assert.Contains(t, l.errors[0]["error"], "s2failFoo failed")
+	assert.Equal(t, "schema2", l.errors[0]["service"])
+	assert.Equal(t, 1, l.errors[0]["keys"])
+}
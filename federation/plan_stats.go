@@ -0,0 +1,70 @@
+package federation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PlanStats summarizes the requests that executing a plan sends to services,
+// eg. for dashboards or to reject expensive queries before they run.
+type PlanStats struct {
+	// SubPlans is the number of subqueries sent to services.
+	SubPlans int `json:"subPlans"`
+	// HopDepth is the number of requests along the longest chain of
+	// subqueries that each wait on the previous one, counting the wait of a
+	// subquery on the earlier stages of its parent's subqueries.
+	HopDepth int `json:"hopDepth"`
+	// Calls is the estimated number of requests sent to each service. Every
+	// subquery is sent once, with the keys of all of its parent's objects.
+	Calls map[string]int `json:"calls"`
+}
+
+// Stats returns the statistics of the plan p returned by Executor.Plan.
+func (p *Plan) Stats() PlanStats {
+	stats := PlanStats{Calls: make(map[string]int)}
+	p.Walk(func(subPlan *Plan) error {
+		// The root plan is resolved on the gateway itself.
+		if subPlan.Service != gatewayCoordinatorServiceName {
+			stats.SubPlans++
+			stats.Calls[subPlan.Service]++
+		}
+		return nil
+	})
+	stats.HopDepth = len(p.hopChain())
+	return stats
+}
+
+// hopChain returns the longest chain of sequential subqueries that executing
+// p sends, as counted by PlanStats.HopDepth, WithMaxHops and
+// QueryCost.Hops.
+func (p *Plan) hopChain() []string {
+	// The root plan is resolved on the gateway itself.
+	if p.Service == gatewayCoordinatorServiceName {
+		return longestHopChain(p.After, nil)
+	}
+	return longestHopChain([]*Plan{p}, nil)
+}
+
+// longestHopChain returns the longest chain of sequential subqueries among
+// plans, the subplans of a plan nested on path, describing each hop by its
+// service and the path of its objects in the query. Subplans of a stage wait
+// on all the subqueries of the earlier stages, so their chains follow the
+// longest chain of those.
+func longestHopChain(plans []*Plan, path []string) []string {
+	var chain []string
+	for _, stage := range subPlanStages(plans) {
+		var longest []string
+		for _, p := range stage {
+			subPath := subPlanPath(path, p)
+			hop := p.Service
+			if len(subPath) > 0 {
+				hop = fmt.Sprintf("%s (%s)", p.Service, strings.Join(subPath, "."))
+			}
+			if hops := append([]string{hop}, longestHopChain(p.After, subPath)...); len(hops) > len(longest) {
+				longest = hops
+			}
+		}
+		chain = append(chain, longest...)
+	}
+	return chain
+}
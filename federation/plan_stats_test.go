@@ -0,0 +1,50 @@
+package federation
+
+import (
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanStats(t *testing.T) {
+	e, _ := createKitchenSinkExecutor(t)
+
+	testCases := []struct {
+		Name  string
+		Query string
+		Stats PlanStats
+	}{
+		{
+			Name:  "single service",
+			Query: `{ s1fff { name s1hmm } }`,
+			Stats: PlanStats{SubPlans: 1, HopDepth: 1, Calls: map[string]int{"schema1": 1}},
+		},
+		{
+			Name: "kitchen sink",
+			// s1fff -> s2bar -> s1baz hops from schema1 to schema2 and back.
+			Query: `{
+				s1fff {
+					name
+					s2ok
+					s2bar { id s1baz }
+					s1nest { s2ok2 }
+				}
+				s1both {
+					... on Foo { name s2ok }
+					... on Bar { id s1baz }
+				}
+				s2root
+			}`,
+			Stats: PlanStats{SubPlans: 6, HopDepth: 3, Calls: map[string]int{"schema1": 2, "schema2": 4}},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			plan, err := e.Plan(graphql.MustParse(testCase.Query, map[string]interface{}{}))
+			require.NoError(t, err)
+			assert.Equal(t, testCase.Stats, plan.Stats())
+		})
+	}
+}
@@ -8,16 +8,21 @@ import (
 	
 	"github.com/samsarahq/go/oops"
 	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/introspection"
 )
 
 const queryString string = "query"
 const mutationString string = "mutation"
 const gatewayCoordinatorServiceName string = "gateway-coordinator-service"
 
+// StepKind is the kind of a PathStep.
 type StepKind int
 
 const (
+	// KindType steps only match objects of the named concrete type, eg.
+	// when the subplan is nested in a fragment on a union.
 	KindType StepKind = iota
+	// KindField steps descend into the field with the named alias.
 	KindField
 )
 
@@ -44,7 +49,12 @@ type PathStep struct {
 	Name string   // Name of the selection or type this path is nested on
 }
 
-// Plan breaks the query down into subqueries that can be resolved by a single graphql server
+// Plan breaks the query down into subqueries that can be resolved by a single graphql server.
+//
+// A Plan is a tree: the root plan runs on the gateway itself, and every node in
+// After depends on the results of its parent. A node's Path, relative to its
+// parent's results, locates the objects whose federated keys are sent to the
+// node's Service along with its SelectionSet.
 type Plan struct {
 	Path         []PathStep            // Pathstep defines what the steps this subplan is nested on
 	Service      string                // Service that resolves this path step
@@ -52,6 +62,48 @@ type Plan struct {
 	Type         string                // Type is the name of the object type each subplan is nested on
 	SelectionSet *graphql.SelectionSet // Selections that will be resolved in this part of the plan
 	After        []*Plan               // Subplans from nested queries on this path
+	// Stage orders the subplans of a parent plan. A subplan only runs once the
+	// subplans of earlier stages have merged their results into the parent's,
+	// so that its keys can include fields fetched by those subplans.
+	Stage int
+
+	// query is the flattened query of a root or entity plan, used to
+	// post-process its result.
+	query *graphql.Query
+	// selectsUnions is whether query selects fields of unions, whose members
+	// checkTypenames checks.
+	selectsUnions bool
+}
+
+// Walk calls f for p and each of its subplans, parents before children. If f
+// returns an error, Walk stops and returns that error.
+func (p *Plan) Walk(f func(*Plan) error) error {
+	if err := f(p); err != nil {
+		return err
+	}
+	for _, subPlan := range p.After {
+		if err := subPlan.Walk(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// singleService returns the subplan of a root plan when the whole query is
+// resolved by that one service, without any federated joins. Such a subplan
+// can be sent as is, and its result needs no stitching.
+func (p *Plan) singleService() (*Plan, bool) {
+	if p.Service != gatewayCoordinatorServiceName || len(p.After) != 1 || len(p.After[0].After) != 0 {
+		return nil, false
+	}
+	// Selections resolved on the gateway itself, other than the bookkeeping
+	// for the subplan, still need the full executor.
+	for _, selection := range p.SelectionSet.Selections {
+		if selection.Name != federationField {
+			return nil, false
+		}
+	}
+	return p.After[0], true
 }
 
 // Planner is responsible for taking a query created a plan that will be used by the executor.
@@ -63,6 +115,37 @@ type Planner struct {
 	// flattener knows how to combine all the fragments on a query into a singel query.
 	flattener       *flattener
 	serviceSelector ServiceSelector
+	// requires maps fields to the sibling fields they need to be resolved.
+	requires map[*graphql.Field][]string
+	// provides maps fields to the services that can resolve some fields of
+	// the returned object inline, and those fields.
+	provides map[*graphql.Field]map[string]map[string]bool
+	// introspectionClient answers introspection queries about the merged
+	// schema on the gateway itself.
+	introspectionClient ExecutorClient
+	// namespaces maps services to the original names of their namespaced
+	// types, by their names in the merged schema.
+	namespaces map[string]map[string]string
+	// rootFieldOwners maps root fields shared by several services to the
+	// service that resolves them.
+	rootFieldOwners map[*graphql.Field]string
+	// fallbackService resolves the fields that no other service resolves.
+	fallbackService string
+	// virtualFields maps the fields computed at the gateway to their
+	// resolvers.
+	virtualFields map[*graphql.Field]*VirtualFieldResolver
+	// maxAliases limits the number of times a field can be selected in one
+	// selection set. A value of 0 means there is no limit.
+	maxAliases int
+	// internalFieldName is the alias under which "_federation" fields are
+	// selected.
+	internalFieldName string
+	// commonUnionFields selects the fields common to all members of unions
+	// on the unions themselves.
+	commonUnionFields bool
+	// fieldTimeouts fetches fields with timeouts in subqueries of their own,
+	// see WithFieldTimeouts.
+	fieldTimeouts bool
 }
 
 // ServiceSelector is an optional field which can be used to override the <type,field> to service mapping,
@@ -77,9 +160,16 @@ func NewPlanner(types *SchemaWithFederationInfo, optionalServiceSelector Service
 	// The planner is aware of the merged schema and what executors
 	// know about what fields
 	planner := &Planner{
-		schema:          types,
-		flattener:       flattener,
-		serviceSelector: optionalServiceSelector,
+		schema:            types,
+		flattener:         flattener,
+		serviceSelector:   optionalServiceSelector,
+		internalFieldName: federationField,
+		introspectionClient: &DirectExecutorClient{
+			Client: &Server{
+				schema:        introspection.BareIntrospectionSchema(types.Schema),
+				localExecutor: graphql.NewExecutor(graphql.NewImmediateGoroutineScheduler()),
+			},
+		},
 	}
 	return planner, err
 }
@@ -153,11 +243,15 @@ func (e *Planner) selectService(
 		customService = e.serviceSelector(typeName, selection.Name)
 	}
 	if customService == "" {
-		if fieldInfo.Services[currentService] {
+		if currentService == gatewayCoordinatorServiceName {
+			return e.selectRootService(typeName, selection, field, fieldInfo)
+		}
+		claimed := e.claimedElsewhere(fieldInfo)
+		if fieldInfo.Services[currentService] && !(claimed && currentService == e.fallbackService) {
 			return currentService, nil
 		}
 		for service, hasField := range fieldInfo.Services {
-			if hasField {
+			if hasField && !(claimed && service == e.fallbackService) {
 				return service, nil
 			}
 		}
@@ -169,7 +263,7 @@ func (e *Planner) selectService(
 	return customService, nil
 }
 
-func (e *Planner) planObject(typ *graphql.Object, selectionSet *graphql.SelectionSet, service string) (*Plan, error) {
+func (e *Planner) planObject(typ *graphql.Object, selectionSet *graphql.SelectionSet, service string, provided map[string]bool) (*Plan, error) {
 	p := &Plan{
 		Type:         typ.Name,
 		Service:      service,
@@ -180,13 +274,22 @@ func (e *Planner) planObject(typ *graphql.Object, selectionSet *graphql.Selectio
 
 	var localSelections []*graphql.Selection
 	selectionsByService := make(map[string][]*graphql.Selection)
+	// timedSelections are the selections of fields with timeouts fetched in
+	// subqueries of their own, and timedServices their services.
+	var timedSelections []*graphql.Selection
+	timedServices := make(map[*graphql.Selection]string)
 
 	// Flattened queries should not have any fragments
 	if len(selectionSet.Fragments) > 0 {
 		return nil, errors.New("selectionSet has fragments, expected flattened query")
 	}
 
-	for _, selection := range selectionSet.Selections {
+	// Virtual fields are computed at the gateway, so the fields they require
+	// are planned in their place.
+	selections := selectionSet.Selections
+	virtualRequired := make(map[string]bool)
+	for i := 0; i < len(selections); i++ {
+		selection := selections[i]
 		ok, err := graphql.ShouldIncludeNode(selection.Directives)
 		if err != nil {
 			return nil, oops.Wrapf(err, "applying directive")
@@ -207,6 +310,23 @@ func (e *Planner) planObject(typ *graphql.Object, selectionSet *graphql.Selectio
 		}
 		fieldInfo := e.schema.Fields[field]
 
+		if resolver, ok := e.virtualFields[field]; ok {
+			for _, required := range virtualSelections(resolver) {
+				if !virtualRequired[required.Alias] {
+					virtualRequired[required.Alias] = true
+					// Copy rather than append to the query's selections.
+					selections = append(selections[:len(selections):len(selections)], required)
+				}
+			}
+			continue
+		}
+
+		// Fields provided by the parent field's service stay local.
+		if provided[selection.Name] {
+			localSelections = append(localSelections, selection)
+			continue
+		}
+
 		targetService, err := e.selectService(
 			typ.Name,
 			service,
@@ -216,6 +336,11 @@ func (e *Planner) planObject(typ *graphql.Object, selectionSet *graphql.Selectio
 		if err != nil {
 			return nil, oops.Wrapf(err, "selecting service")
 		}
+		if e.isolatesTimeout(typ, field, targetService, service) {
+			timedSelections = append(timedSelections, selection)
+			timedServices[selection] = targetService
+			continue
+		}
 		if targetService == service {
 			localSelections = append(localSelections, selection)
 		} else {
@@ -229,7 +354,7 @@ func (e *Planner) planObject(typ *graphql.Object, selectionSet *graphql.Selectio
 		var childPlan *Plan
 		if selection.SelectionSet != nil {
 			var err error
-			childPlan, err = e.plan(field.Type, selection.SelectionSet, service)
+			childPlan, err = e.planProvided(field.Type, selection.SelectionSet, service, e.provides[field][service])
 			if err != nil {
 				return nil, fmt.Errorf("planning for %s: %v", selection.Name, err)
 			}
@@ -267,7 +392,20 @@ func (e *Planner) planObject(typ *graphql.Object, selectionSet *graphql.Selectio
 	}
 	sort.Strings(otherServices)
 
-	// Create a plan for all selections that can be resolved in other graphql queries
+	// Required fields that the current service cannot resolve are fetched
+	// from the services that resolve them in a first stage, and the
+	// selections that need them run in a second stage.
+	var remoteRequires map[string][]string
+	if service != gatewayCoordinatorServiceName {
+		var err error
+		if remoteRequires, err = e.planRemoteRequires(typ, service, otherServices, selectionsByService); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create a plan for all selections that can be resolved in other graphql queries.
+	// Each service gets a single subplan with all of its selections, so that
+	// it looks up the objects from their keys only once per stage.
 	for _, other := range otherServices {
 		selections := selectionsByService[other]
 		needKey = true
@@ -276,7 +414,47 @@ func (e *Planner) planObject(typ *graphql.Object, selectionSet *graphql.Selectio
 		if err != nil {
 			return nil, fmt.Errorf("planning for %s: %v", other, err)
 		}
+		if names, ok := remoteRequires[other]; ok {
+			addFederationSelections(subPlan, e.internalFieldName, names)
+		}
+		if e.needsRemoteRequires(typ, service, selections) {
+			subPlan.Stage = 1
+		}
+
+		p.After = append(p.After, subPlan)
+	}
+	// Fields with timeouts are fetched in subqueries of their own, so that
+	// their timeouts do not affect other fields, see WithFieldTimeouts.
+	timedKeys := make(map[string]bool)
+	for _, selection := range timedSelections {
+		needKey = true
+		other := timedServices[selection]
+		timedKeys[other] = true
+
+		// The field stays local in its subquery rather than being isolated again.
+		subPlan, err := e.planProvided(typ, &graphql.SelectionSet{Selections: []*graphql.Selection{selection}}, other, map[string]bool{selection.Name: true})
+		if err != nil {
+			return nil, fmt.Errorf("planning for %s: %v", other, err)
+		}
+		p.After = append(p.After, subPlan)
+	}
 
+	var providers []string
+	for provider := range remoteRequires {
+		if len(selectionsByService[provider]) == 0 {
+			providers = append(providers, provider)
+		}
+	}
+	sort.Strings(providers)
+	for _, provider := range providers {
+		needKey = true
+		subPlan := &Plan{
+			Type:         typ.Name,
+			Service:      provider,
+			SelectionSet: &graphql.SelectionSet{},
+			Kind:         queryString,
+		}
+		addFederationSelections(subPlan, e.internalFieldName, remoteRequires[provider])
 		p.After = append(p.After, subPlan)
 	}
 
@@ -285,10 +463,10 @@ func (e *Planner) planObject(typ *graphql.Object, selectionSet *graphql.Selectio
 	if needKey {
 		hasKey := false
 		for _, selection := range p.SelectionSet.Selections {
-			if selection.Name == federationField && selection.Alias == federationField {
+			if selection.Name == federationField && selection.Alias == e.internalFieldName {
 				hasKey = true
-			} else if selection.Name == federationField || selection.Alias == federationField {
-				return nil, fmt.Errorf("Both the selection name and alias have to be _federation")
+			} else if selection.Name == federationField || selection.Alias == e.internalFieldName {
+				return nil, fmt.Errorf("The selection name has to be _federation and the alias %s", e.internalFieldName)
 			}
 		}
 		if !hasKey {
@@ -300,7 +478,7 @@ func (e *Planner) planObject(typ *graphql.Object, selectionSet *graphql.Selectio
 			selections := make([]*graphql.Selection, 0, len(typ.Fields))
 			for name, field := range typ.Fields {
 				for service := range field.FederatedKey {
-					if len(selectionsByService[service]) > 0 {
+					if _, ok := remoteRequires[service]; ok || len(selectionsByService[service]) > 0 || timedKeys[service] {
 						selections = append(selections, &graphql.Selection{
 							Name:         name,
 							Alias:        name,
@@ -311,9 +489,38 @@ func (e *Planner) planObject(typ *graphql.Object, selectionSet *graphql.Selectio
 				}
 			}
 
+			// Fetch the fields required by selections on other services
+			// along with the keys, so they are passed to those services too.
+			if service != gatewayCoordinatorServiceName {
+				for _, other := range otherServices {
+					for _, selection := range selectionsByService[other] {
+						for _, name := range e.requires[typ.Fields[selection.Name]] {
+							if info := e.schema.Fields[typ.Fields[name]]; info == nil || !info.Services[service] {
+								// Fetched from another service by planRemoteRequires.
+								continue
+							}
+							selected := false
+							for _, keySelection := range selections {
+								if keySelection.Name == name {
+									selected = true
+									break
+								}
+							}
+							if !selected {
+								selections = append(selections, &graphql.Selection{
+									Name:         name,
+									Alias:        name,
+									UnparsedArgs: map[string]interface{}{},
+								})
+							}
+						}
+					}
+				}
+			}
+
 			federatedSelection := &graphql.Selection{
 				Name:         federationField,
-				Alias:        federationField,
+				Alias:        e.internalFieldName,
 				UnparsedArgs: map[string]interface{}{},
 				SelectionSet: &graphql.SelectionSet{
 					Selections: selections,
@@ -391,19 +598,29 @@ func (e *Planner) planUnion(typ *graphql.Union, selectionSet *graphql.SelectionS
 		}
 	}
 
+	if e.commonUnionFields {
+		hoistCommonFields(typ, plan)
+	}
+
 	return plan, nil
 }
 
 func (e *Planner) plan(typIface graphql.Type, selectionSet *graphql.SelectionSet, service string) (*Plan, error) {
+	return e.planProvided(typIface, selectionSet, service, nil)
+}
+
+// planProvided plans like plan, resolving the provided fields of objects on
+// service.
+func (e *Planner) planProvided(typIface graphql.Type, selectionSet *graphql.SelectionSet, service string, provided map[string]bool) (*Plan, error) {
 	switch typ := typIface.(type) {
 	case *graphql.NonNull:
-		return e.plan(typ.Type, selectionSet, service)
+		return e.planProvided(typ.Type, selectionSet, service, provided)
 
 	case *graphql.List:
-		return e.plan(typ.Type, selectionSet, service)
+		return e.planProvided(typ.Type, selectionSet, service, provided)
 
 	case *graphql.Object:
-		return e.planObject(typ, selectionSet, service)
+		return e.planObject(typ, selectionSet, service, provided)
 
 	case *graphql.Union:
 		return e.planUnion(typ, selectionSet, service)
@@ -441,6 +658,15 @@ func (e *Planner) planRoot(query *graphql.Query) (*Plan, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := e.validateArguments(schema, flattened); err != nil {
+		return nil, err
+	}
+	if err := e.validateAliases(flattened); err != nil {
+		return nil, err
+	}
+	if err := e.validateInternalAliases(flattened); err != nil {
+		return nil, err
+	}
 
 	p, err := e.plan(schema, flattened, gatewayCoordinatorServiceName)
 	if err != nil {
@@ -459,5 +685,77 @@ func (e *Planner) planRoot(query *graphql.Query) (*Plan, error) {
 	}
 
 	reversePaths(p)
+	p.query = &graphql.Query{Kind: query.Kind, SelectionSet: flattened}
+	p.selectsUnions = selectsUnions(flattened)
 	return p, nil
 }
+
+// planEntity plans fetching selectionSet on the federated object typeName. The
+// plan starts at a service that can look up the object by its federated keys,
+// preferring the service that can resolve the most selections itself.
+func (e *Planner) planEntity(typeName string, selectionSet *graphql.SelectionSet) (*Plan, error) {
+	obj, ok := e.flattener.types[typeName].(*graphql.Object)
+	if !ok {
+		return nil, oops.Errorf("unknown object type %s", typeName)
+	}
+	federation, ok := obj.Fields[federationField]
+	if !ok {
+		return nil, oops.Errorf("object %s is not federated", typeName)
+	}
+	var services []string
+	for service := range e.schema.Fields[federation].Services {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	flattened, err := e.flattener.flatten(selectionSet, obj)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.validateArguments(obj, flattened); err != nil {
+		return nil, err
+	}
+	if err := e.validateAliases(flattened); err != nil {
+		return nil, err
+	}
+	if err := e.validateInternalAliases(flattened); err != nil {
+		return nil, err
+	}
+
+	var service string
+	most := -1
+	for _, candidate := range services {
+		count := 0
+		for _, selection := range flattened.Selections {
+			field, ok := obj.Fields[selection.Name]
+			if ok && e.schema.Fields[field].Services[candidate] {
+				count++
+			}
+		}
+		if count > most {
+			service, most = candidate, count
+		}
+	}
+
+	p, err := e.plan(obj, flattened, service)
+	if err != nil {
+		return nil, err
+	}
+	reversePaths(p)
+	p.query = &graphql.Query{Kind: queryString, SelectionSet: flattened}
+	p.selectsUnions = selectsUnions(flattened)
+	return p, nil
+}
+
+// resultType returns the type of the result of p, a plan returned by
+// planRoot or planEntity: the query or mutation type, or the entity's type.
+func (e *Planner) resultType(p *Plan) graphql.Type {
+	switch {
+	case p.Service != gatewayCoordinatorServiceName:
+		return e.flattener.types[p.Type]
+	case p.Kind == mutationString:
+		return e.schema.Schema.Mutation
+	default:
+		return e.schema.Schema.Query
+	}
+}
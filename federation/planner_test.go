@@ -1,6 +1,8 @@
 package federation
 
 import (
+	"context"
+	"strings"
 	"testing"
 
 	"github.com/samsarahq/thunder/graphql"
@@ -10,7 +12,24 @@ import (
 )
 
 func mustParse(s string) *graphql.SelectionSet {
-	return graphql.MustParse(s, map[string]interface{}{}).SelectionSet
+	return withoutLocations(graphql.MustParse(s, map[string]interface{}{}).SelectionSet)
+}
+
+// withoutLocations clears the source locations of the selections in
+// selectionSet, so that it compares equal to selection sets parsed from other
+// sources.
+func withoutLocations(selectionSet *graphql.SelectionSet) *graphql.SelectionSet {
+	if selectionSet == nil {
+		return nil
+	}
+	for _, selection := range selectionSet.Selections {
+		selection.Location = nil
+		withoutLocations(selection.SelectionSet)
+	}
+	for _, fragment := range selectionSet.Fragments {
+		withoutLocations(fragment.SelectionSet)
+	}
+	return selectionSet
 }
 
 func setupExecutor(t *testing.T) (*Planner, error) {
@@ -35,8 +54,9 @@ func setupExecutor(t *testing.T) (*Planner, error) {
 
 	f, err := newFlattener(merged.Schema)
 	return &Planner{
-		flattener: f,
-		schema:    merged,
+		flattener:         f,
+		schema:            merged,
+		internalFieldName: federationField,
 	}, nil
 }
 
@@ -332,10 +352,202 @@ func TestPlanner(t *testing.T) {
 
 	for _, testCase := range testCases {
 		t.Run(testCase.Name, func(t *testing.T) {
-			plan, err := e.planRoot(graphql.MustParse(testCase.Input, map[string]interface{}{}))
+			query := graphql.MustParse(testCase.Input, map[string]interface{}{})
+			withoutLocations(query.SelectionSet)
+			plan, err := e.planRoot(query)
 			require.NoError(t, err)
 			assert.Equal(t, testCase.Output, plan.After)
 		})
 	}
 
 }
+
+func TestPlanKitchenSink(t *testing.T) {
+	e, _ := createKitchenSinkExecutor(t)
+
+	plan, err := e.Plan(graphql.MustParse(`
+		{
+			s1fff {
+				name
+				s1hmm
+				s2ok
+				s2bar {
+					id
+					s1baz
+				}
+				s1nest {
+					s2ok2
+				}
+			}
+			s1both {
+				__typename
+				... on Foo {
+					name
+					s2ok
+				}
+				... on Bar {
+					id
+					s1baz
+				}
+			}
+			s2root
+		}`, map[string]interface{}{}))
+	require.NoError(t, err)
+
+	type node struct {
+		Service string
+		Type    string
+		Path    string
+		Fields  []string
+	}
+	var nodes []node
+	require.NoError(t, plan.Walk(func(p *Plan) error {
+		var path []string
+		for _, step := range p.Path {
+			if step.Kind == KindType {
+				path = append(path, "..."+step.Name)
+			} else {
+				path = append(path, step.Name)
+			}
+		}
+		var fields []string
+		for _, selection := range p.SelectionSet.Selections {
+			fields = append(fields, selection.Alias)
+		}
+		nodes = append(nodes, node{Service: p.Service, Type: p.Type, Path: strings.Join(path, "."), Fields: fields})
+		return nil
+	}))
+
+	// Subplans are listed after the plan they depend on.
+	assert.Equal(t, []node{
+		{Service: gatewayCoordinatorServiceName, Type: "Query", Path: "", Fields: []string{"_federation"}},
+		{Service: "schema1", Type: "Query", Path: "", Fields: []string{"s1both", "s1fff"}},
+		{Service: "schema2", Type: "Foo", Path: "s1both....Foo", Fields: []string{"s2ok"}},
+		{Service: "schema2", Type: "Foo", Path: "s1fff.s1nest", Fields: []string{"s2ok2"}},
+		{Service: "schema2", Type: "Foo", Path: "s1fff", Fields: []string{"s2bar", "s2ok"}},
+		{Service: "schema1", Type: "Bar", Path: "s2bar", Fields: []string{"s1baz"}},
+		{Service: "schema2", Type: "Query", Path: "", Fields: []string{"s2root"}},
+	}, nodes)
+
+	// The lookup of s1baz depends on the schema2 plan that fetches s2bar.
+	require.Len(t, plan.After, 2)
+	require.Len(t, plan.After[0].After, 3)
+	assert.Equal(t, "s1baz", plan.After[0].After[2].After[0].SelectionSet.Selections[0].Name)
+}
+
+func TestPlanSingleService(t *testing.T) {
+	e, _ := createKitchenSinkExecutor(t)
+
+	testCases := []struct {
+		Name    string
+		Query   string
+		Service string
+	}{
+		{
+			Name:    "fields from one service",
+			Query:   `{ s1fff { name s1hmm s1nest { name } } }`,
+			Service: "schema1",
+		},
+		{
+			Name:    "root field from the other service",
+			Query:   `{ s2root }`,
+			Service: "schema2",
+		},
+		{
+			Name:  "root fields from two services",
+			Query: `{ s1f { name } s2root }`,
+		},
+		{
+			Name:  "nested fields from another service",
+			Query: `{ s1fff { name s2ok } }`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			plan, err := e.Plan(graphql.MustParse(testCase.Query, map[string]interface{}{}))
+			require.NoError(t, err)
+			subPlan, ok := plan.singleService()
+			if testCase.Service == "" {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, testCase.Service, subPlan.Service)
+		})
+	}
+}
+
+func TestPlanHash(t *testing.T) {
+	e, _ := createKitchenSinkExecutor(t)
+
+	hash := func(query string) string {
+		plan, err := e.Plan(graphql.MustParse(query, map[string]interface{}{}))
+		require.NoError(t, err)
+		h, err := plan.Hash()
+		require.NoError(t, err)
+		return h
+	}
+
+	query := `{ s1fff { name s1hmm s2ok s2bar { id } } s2root }`
+	h := hash(query)
+	assert.Len(t, h, 64)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, h, hash(query), "hash should be stable across plans")
+	}
+
+	// The order of selections and the presence of aliases that match the
+	// field name don't change the plan.
+	assert.Equal(t, h, hash(`{ s2root s1fff { s2bar { id } s2ok s1hmm name } }`))
+	assert.Equal(t, h, hash(`{ s1fff { name: name s1hmm s2ok s2bar { id } } s2root }`))
+
+	// Different selections, aliases or arguments change the plan.
+	assert.NotEqual(t, h, hash(`{ s1fff { name s1hmm s2ok } s2root }`))
+	assert.NotEqual(t, h, hash(`{ s1fff { n: name s1hmm s2ok s2bar { id } } s2root }`))
+	assert.NotEqual(t, hash(`{ s1echo(foo: "a", pair: {a: 1, b: 2}) }`), hash(`{ s1echo(foo: "b", pair: {a: 1, b: 2}) }`))
+
+	// Reordering the subplans of a plan doesn't change its hash.
+	plan, err := e.Plan(graphql.MustParse(query, map[string]interface{}{}))
+	require.NoError(t, err)
+	require.True(t, len(plan.After) > 1)
+	plan.After[0], plan.After[len(plan.After)-1] = plan.After[len(plan.After)-1], plan.After[0]
+	reordered, err := plan.Hash()
+	require.NoError(t, err)
+	assert.Equal(t, h, reordered)
+}
+
+func TestPlanKeepsFieldsLocal(t *testing.T) {
+	// schema3 resolves s2ok2 too, so selections on objects of schema3 do
+	// not need to hop to schema2 for it.
+	s3 := schemabuilder.NewSchemaWithName("schema3")
+	foo := s3.Object("Foo", Foo{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Foo }) []*Foo {
+		return args.Keys
+	}))
+	foo.FieldFunc("s2ok2", func(in *Foo) int {
+		return len(in.Name)
+	})
+	foo.FieldFunc("s3nest", func(in *Foo) *Foo {
+		return &Foo{Name: in.Name + "?"}
+	})
+
+	ctx := context.Background()
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": buildTestSchema2(),
+		"schema3": s3,
+	})
+	require.NoError(t, err)
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	require.NoError(t, err)
+
+	plan, err := e.Plan(graphql.MustParse(`{ s1f { s3nest { name s2ok2 } } }`, map[string]interface{}{}))
+	require.NoError(t, err)
+	require.Len(t, plan.After, 1)
+	root := plan.After[0]
+	require.Len(t, root.After, 1)
+	assert.Equal(t, "schema3", root.After[0].Service)
+	assert.Empty(t, root.After[0].After)
+
+	runAndValidateQueryResults(t, ctx, e, `{ s1f { s3nest { name s2ok2 } } }`, `{
+		"s1f": {"s3nest": {"name": "jimbob?", "s2ok2": 7}}
+	}`)
+}
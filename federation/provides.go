@@ -0,0 +1,95 @@
+package federation
+
+import (
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// providedFields are the fields of the object returned by a field that a
+// service can resolve inline.
+type providedFields struct {
+	service  string
+	typeName string
+	field    string
+	provides []string
+}
+
+// WithProvidedFields declares that when service resolves the field on the
+// object typeName, it can also resolve the fields provides of the returned
+// object, even though another service owns them. The planner then resolves
+// those fields on service below the field instead of dispatching another
+// subquery to the owning service.
+//
+// Provided fields are typically declared external to service with
+// WithExternalField, so they are only fetched from service when it provides
+// them:
+//   WithExternalField("s1", "Product", "name"),
+//   WithProvidedFields("s1", "Order", "product", "name"),
+func WithProvidedFields(service, typeName, field string, provides ...string) ExecutorOption {
+	return func(e *Executor) {
+		e.providedFields = append(e.providedFields, providedFields{
+			service:  service,
+			typeName: typeName,
+			field:    field,
+			provides: provides,
+		})
+	}
+}
+
+// applyProvidedFields records the provided fields in planner, checking that the
+// declaring service resolves both the field and the fields it provides. It must
+// run before external fields are removed from their services.
+func (e *Executor) applyProvidedFields(planner *Planner) error {
+	for _, provided := range e.providedFields {
+		obj, ok := planner.flattener.types[provided.typeName].(*graphql.Object)
+		if !ok {
+			return oops.Errorf("provided fields of %s.%s: unknown object type %s", provided.typeName, provided.field, provided.typeName)
+		}
+		field, ok := obj.Fields[provided.field]
+		if !ok {
+			return oops.Errorf("provided fields of %s.%s: unknown field", provided.typeName, provided.field)
+		}
+		if info := planner.schema.Fields[field]; info == nil || !info.Services[provided.service] {
+			return oops.Errorf("provided fields of %s.%s: service %s does not resolve the field", provided.typeName, provided.field, provided.service)
+		}
+		returned, ok := unwrapType(field.Type).(*graphql.Object)
+		if !ok {
+			return oops.Errorf("provided fields of %s.%s: field does not return an object", provided.typeName, provided.field)
+		}
+
+		if planner.provides == nil {
+			planner.provides = make(map[*graphql.Field]map[string]map[string]bool)
+		}
+		if planner.provides[field] == nil {
+			planner.provides[field] = make(map[string]map[string]bool)
+		}
+		if planner.provides[field][provided.service] == nil {
+			planner.provides[field][provided.service] = make(map[string]bool)
+		}
+		for _, name := range provided.provides {
+			providedField, ok := returned.Fields[name]
+			if !ok {
+				return oops.Errorf("provided fields of %s.%s: unknown field %s on %s", provided.typeName, provided.field, name, returned.Name)
+			}
+			if info := planner.schema.Fields[providedField]; info == nil || !info.Services[provided.service] {
+				return oops.Errorf("provided fields of %s.%s: service %s does not resolve %s.%s", provided.typeName, provided.field, provided.service, returned.Name, name)
+			}
+			planner.provides[field][provided.service][name] = true
+		}
+	}
+	return nil
+}
+
+// unwrapType returns the named type wrapped by lists and non-nulls in typ.
+func unwrapType(typ graphql.Type) graphql.Type {
+	for {
+		switch inner := typ.(type) {
+		case *graphql.List:
+			typ = inner.Type
+		case *graphql.NonNull:
+			typ = inner.Type
+		default:
+			return typ
+		}
+	}
+}
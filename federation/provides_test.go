@@ -0,0 +1,115 @@
+package federation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildProvidesSchemas() map[string]*schemabuilder.Schema {
+	type Product struct {
+		Id   int64
+		Name string
+	}
+	type Order struct {
+		Number int64
+	}
+	s1 := schemabuilder.NewSchemaWithName("s1")
+	product := s1.Object("Product", Product{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Product }) []*Product {
+		return args.Keys
+	}))
+	product.Key("id")
+	order := s1.Object("Order", Order{})
+	order.FieldFunc("product", func(o *Order) *Product {
+		return &Product{Id: o.Number * 10, Name: "cached product"}
+	})
+	s1.Query().FieldFunc("orders", func() []*Order {
+		return []*Order{{Number: 1}, {Number: 2}}
+	})
+
+	type ProductKeys struct {
+		Id int64
+	}
+	s2 := schemabuilder.NewSchemaWithName("s2")
+	catalogProduct := s2.Object("Product", Product{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*ProductKeys }) []*Product {
+		products := make([]*Product, 0, len(args.Keys))
+		for _, key := range args.Keys {
+			products = append(products, &Product{Id: key.Id, Name: "catalog product"})
+		}
+		return products
+	}))
+	catalogProduct.Key("id")
+	catalogProduct.FieldFunc("price", func(p *Product) int64 {
+		return p.Id + 1
+	})
+
+	return map[string]*schemabuilder.Schema{
+		"s1": s1,
+		"s2": s2,
+	}
+}
+
+func TestExecutorProvidedFields(t *testing.T) {
+	ctx := context.Background()
+	newExecutor := func(opts ...ExecutorOption) (*Executor, *countingExecutorClient, error) {
+		e, clients, err := newTestExecutor(t, buildProvidesSchemas(), opts...)
+		return e, clients["s2"], err
+	}
+
+	e, s2Client, err := newExecutor(
+		WithExternalField("s1", "Product", "name"),
+		WithProvidedFields("s1", "Order", "product", "name"),
+	)
+	require.NoError(t, err)
+
+	// The provided field is resolved by s1 without a hop to s2.
+	s2Client.reset()
+	runAndValidateQueryResults(t, ctx, e, `{ orders { product { id name } } }`, `
+		{
+			"orders": [
+				{"product": {"__key": 10, "id": 10, "name": "cached product"}},
+				{"product": {"__key": 20, "id": 20, "name": "cached product"}}
+			]
+		}`)
+	assert.Equal(t, 0, s2Client.count)
+
+	// Fields that aren't provided are still fetched from s2.
+	s2Client.reset()
+	runAndValidateQueryResults(t, ctx, e, `{ orders { product { name price } } }`, `
+		{
+			"orders": [
+				{"product": {"__key": 10, "name": "cached product", "price": 11}},
+				{"product": {"__key": 20, "name": "cached product", "price": 21}}
+			]
+		}`)
+	assert.Equal(t, 1, s2Client.count)
+
+	t.Run("without the declaration", func(t *testing.T) {
+		e, s2Client, err := newExecutor(WithExternalField("s1", "Product", "name"))
+		require.NoError(t, err)
+		s2Client.reset()
+		runAndValidateQueryResults(t, ctx, e, `{ orders { product { name } } }`, `
+			{
+				"orders": [
+					{"product": {"__key": 10, "name": "catalog product"}},
+					{"product": {"__key": 20, "name": "catalog product"}}
+				]
+			}`)
+		assert.Equal(t, 1, s2Client.count)
+	})
+
+	t.Run("field not resolved by the service", func(t *testing.T) {
+		_, _, err := newExecutor(WithProvidedFields("s1", "Product", "price", "name"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "service s1 does not resolve the field")
+	})
+
+	t.Run("provided field not resolved by the service", func(t *testing.T) {
+		_, _, err := newExecutor(WithProvidedFields("s1", "Order", "product", "price"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "service s1 does not resolve Product.price")
+	})
+}
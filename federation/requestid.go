@@ -0,0 +1,66 @@
+package federation
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// requestIDHeader is the gRPC metadata key that carries the request ID to
+// federated servers.
+const requestIDHeader = "x-request-id"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the request ID id. Every request
+// the executor sends to federated servers while executing a query with this
+// context includes the ID, so that their logs can be correlated.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx. On federated
+// servers, the ID of the gateway request is available in resolvers.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// WithRequestIDGenerator makes the executor generate a request ID for queries
+// whose context does not already carry one.
+func WithRequestIDGenerator(generate func() string) ExecutorOption {
+	return func(e *Executor) {
+		e.generateRequestID = generate
+	}
+}
+
+// withGeneratedRequestID returns ctx with a generated request ID, unless it
+// already carries one.
+func (e *Executor) withGeneratedRequestID(ctx context.Context) context.Context {
+	if _, ok := RequestIDFromContext(ctx); ok || e.generateRequestID == nil {
+		return ctx
+	}
+	return WithRequestID(ctx, e.generateRequestID())
+}
+
+// outgoingRequestIDContext adds the request ID carried by ctx to the outgoing
+// gRPC metadata.
+func outgoingRequestIDContext(ctx context.Context) context.Context {
+	if id, ok := RequestIDFromContext(ctx); ok {
+		return metadata.AppendToOutgoingContext(ctx, requestIDHeader, id)
+	}
+	return ctx
+}
+
+// incomingRequestIDContext makes the request ID sent by the gateway in the
+// incoming gRPC metadata available with RequestIDFromContext.
+func incomingRequestIDContext(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	if ids := md.Get(requestIDHeader); len(ids) > 0 {
+		return WithRequestID(ctx, ids[0])
+	}
+	return ctx
+}
@@ -0,0 +1,95 @@
+package federation
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/samsarahq/thunder/thunderpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+// requestIDRecordingClient records the request IDs of the requests sent to the
+// wrapped client.
+type requestIDRecordingClient struct {
+	ExecutorClient
+	mu  sync.Mutex
+	ids []string
+}
+
+func (c *requestIDRecordingClient) Execute(ctx context.Context, request *QueryRequest) (*QueryResponse, error) {
+	c.mu.Lock()
+	c.ids = append(c.ids, request.RequestID)
+	c.mu.Unlock()
+	return c.ExecutorClient.Execute(ctx, request)
+}
+
+func TestExecutorRequestID(t *testing.T) {
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": buildTestSchema2(),
+	})
+	require.NoError(t, err)
+	clients := make(map[string]*requestIDRecordingClient)
+	for name, exec := range execs {
+		clients[name] = &requestIDRecordingClient{ExecutorClient: exec}
+		execs[name] = clients[name]
+	}
+
+	ctx := context.Background()
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)},
+		WithRequestIDGenerator(func() string { return "generated" }))
+	require.NoError(t, err)
+
+	query := graphql.MustParse(`{ s1fff { name s2bar { s1baz } } }`, map[string]interface{}{})
+
+	testCases := []struct {
+		Name string
+		Ctx  context.Context
+		ID   string
+	}{
+		{
+			Name: "id from context",
+			Ctx:  WithRequestID(ctx, "abc"),
+			ID:   "abc",
+		},
+		{
+			Name: "generated id",
+			Ctx:  ctx,
+			ID:   "generated",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			for _, client := range clients {
+				client.ids = nil
+			}
+			_, _, err := e.Execute(testCase.Ctx, query, nil)
+			require.NoError(t, err)
+			assert.Equal(t, []string{testCase.ID, testCase.ID}, clients["schema1"].ids)
+			assert.Equal(t, []string{testCase.ID}, clients["schema2"].ids)
+		})
+	}
+}
+
+func TestServerRequestID(t *testing.T) {
+	var id string
+	s := schemabuilder.NewSchemaWithName("s1")
+	s.Query().FieldFunc("requestId", func(ctx context.Context) string {
+		id, _ = RequestIDFromContext(ctx)
+		return id
+	})
+	srv, err := NewServer(s.MustBuild())
+	require.NoError(t, err)
+
+	marshaled, err := MarshalQuery(graphql.MustParse(`{ requestId }`, map[string]interface{}{}))
+	require.NoError(t, err)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(requestIDHeader, "abc"))
+	_, err = srv.Execute(ctx, &thunderpb.ExecuteRequest{Query: marshaled})
+	require.NoError(t, err)
+	assert.Equal(t, "abc", id)
+}
@@ -0,0 +1,217 @@
+package federation
+
+import (
+	"sort"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// requiredFields are the sibling fields that a field needs to be resolved.
+type requiredFields struct {
+	typeName string
+	field    string
+	requires []string
+}
+
+// WithRequiredFields declares that resolving the field on the object typeName
+// requires the sibling fields requires, eg. because they are inputs to a
+// computation. When the field is fetched from another service, the planner
+// fetches the required fields along with the object's keys and passes them to
+// that service's FetchObjectFromKeys function.
+//
+// Every service that resolves the field must accept the required fields as
+// optional keys, ie. as nullable fields of its keys struct:
+//   schema.Object("Foo", Foo{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*struct {
+//       Name  string
+//       Score *int64
+//   } }) []*Foo { ... }))
+//
+// The required fields may come from several services. Required fields that
+// the service the object comes from cannot resolve are first fetched from
+// services that resolve them, and the field is then fetched with all of its
+// required fields as keys.
+func WithRequiredFields(typeName, field string, requires ...string) ExecutorOption {
+	return func(e *Executor) {
+		e.requiredFields = append(e.requiredFields, requiredFields{
+			typeName: typeName,
+			field:    field,
+			requires: requires,
+		})
+	}
+}
+
+// applyRequiredFields records the required fields in planner, checking that
+// every service resolving a field with requirements accepts them as keys, and
+// that every optional key is required by a field.
+func (e *Executor) applyRequiredFields(planner *Planner) error {
+	for _, required := range e.requiredFields {
+		obj, ok := planner.flattener.types[required.typeName].(*graphql.Object)
+		if !ok {
+			return oops.Errorf("required fields of %s.%s: unknown object type %s", required.typeName, required.field, required.typeName)
+		}
+		field, ok := obj.Fields[required.field]
+		if !ok {
+			return oops.Errorf("required fields of %s.%s: unknown field", required.typeName, required.field)
+		}
+		info, ok := planner.schema.Fields[field]
+		if !ok {
+			return oops.Errorf("required fields of %s.%s: no services resolve the field", required.typeName, required.field)
+		}
+
+		for _, name := range required.requires {
+			requiredField, ok := obj.Fields[name]
+			if !ok {
+				return oops.Errorf("required fields of %s.%s: unknown field %s", required.typeName, required.field, name)
+			}
+			requiredInfo := planner.schema.Fields[requiredField]
+			for service, ok := range info.Services {
+				if !ok || requiredField.FederatedKey[service] {
+					continue
+				}
+				if requiredInfo == nil || !requiredInfo.OptionalKey[service] {
+					return oops.Errorf("required fields of %s.%s: service %s does not accept %s as a key", required.typeName, required.field, service, name)
+				}
+			}
+		}
+
+		if planner.requires == nil {
+			planner.requires = make(map[*graphql.Field][]string)
+		}
+		planner.requires[field] = append(planner.requires[field], required.requires...)
+	}
+
+	// Optional keys are only sent when a field requires them, so a nullable
+	// key that no field requires would silently never be sent.
+	for _, typ := range planner.flattener.types {
+		obj, ok := typ.(*graphql.Object)
+		if !ok {
+			continue
+		}
+		required := make(map[string]bool)
+		for _, field := range obj.Fields {
+			for _, name := range planner.requires[field] {
+				required[name] = true
+			}
+		}
+		for _, name := range sortedFieldNames(obj) {
+			info := planner.schema.Fields[obj.Fields[name]]
+			if info == nil || required[name] {
+				continue
+			}
+			for service, ok := range info.OptionalKey {
+				if ok {
+					return oops.Errorf("service %s accepts %s.%s as a key, which other services do not resolve: make it non-null, or declare a field requiring it with WithRequiredFields", service, obj.Name, name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// needsRemoteRequires returns whether any of selections on typ requires a
+// field that service cannot resolve.
+func (e *Planner) needsRemoteRequires(typ *graphql.Object, service string, selections []*graphql.Selection) bool {
+	for _, selection := range selections {
+		for _, name := range e.requires[typ.Fields[selection.Name]] {
+			if info := e.schema.Fields[typ.Fields[name]]; info == nil || !info.Services[service] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// planRemoteRequires picks the services that fetch the fields required by
+// selectionsByService which service cannot resolve itself, returning those
+// fields by service. A field is preferably fetched from a service that the
+// object is already dispatched to.
+func (e *Planner) planRemoteRequires(typ *graphql.Object, service string, otherServices []string, selectionsByService map[string][]*graphql.Selection) (map[string][]string, error) {
+	var remoteRequires map[string][]string
+	for _, other := range otherServices {
+		for _, selection := range selectionsByService[other] {
+			for _, name := range e.requires[typ.Fields[selection.Name]] {
+				info := e.schema.Fields[typ.Fields[name]]
+				if info != nil && info.Services[service] {
+					continue
+				}
+
+				var candidates []string
+				if info != nil {
+					for candidate, ok := range info.Services {
+						if ok && candidate != other {
+							candidates = append(candidates, candidate)
+						}
+					}
+				}
+				if len(candidates) == 0 {
+					return nil, oops.Errorf("%s.%s requires %s, which neither service %s nor another service can resolve", typ.Name, selection.Name, name, service)
+				}
+				sort.Strings(candidates)
+				provider := candidates[0]
+				for _, candidate := range candidates {
+					if len(selectionsByService[candidate]) > 0 {
+						provider = candidate
+						break
+					}
+				}
+				if e.needsRemoteRequires(typ, service, selectionsByService[provider]) {
+					return nil, oops.Errorf("%s.%s requires %s from service %s, which itself requires fields from other services", typ.Name, selection.Name, name, provider)
+				}
+
+				if remoteRequires == nil {
+					remoteRequires = make(map[string][]string)
+				}
+				fetched := false
+				for _, existing := range remoteRequires[provider] {
+					if existing == name {
+						fetched = true
+						break
+					}
+				}
+				if !fetched {
+					remoteRequires[provider] = append(remoteRequires[provider], name)
+				}
+			}
+		}
+	}
+	return remoteRequires, nil
+}
+
+// addFederationSelections adds the fields names to the "_federation"
+// selection of p, aliased internalFieldName, so that they are merged into the keys of the objects p
+// fetches.
+func addFederationSelections(p *Plan, internalFieldName string, names []string) {
+	var federatedSelection *graphql.Selection
+	for _, selection := range p.SelectionSet.Selections {
+		if selection.Name == federationField && selection.Alias == internalFieldName {
+			federatedSelection = selection
+			break
+		}
+	}
+	if federatedSelection == nil {
+		federatedSelection = &graphql.Selection{
+			Name:         federationField,
+			Alias:        internalFieldName,
+			UnparsedArgs: map[string]interface{}{},
+			SelectionSet: &graphql.SelectionSet{},
+		}
+		p.SelectionSet.Selections = append(p.SelectionSet.Selections, federatedSelection)
+	}
+	for _, name := range names {
+		selected := false
+		for _, selection := range federatedSelection.SelectionSet.Selections {
+			if selection.Name == name {
+				selected = true
+				break
+			}
+		}
+		if !selected {
+			federatedSelection.SelectionSet.Selections = append(federatedSelection.SelectionSet.Selections, &graphql.Selection{
+				Name:         name,
+				Alias:        name,
+				UnparsedArgs: map[string]interface{}{},
+			})
+		}
+	}
+}
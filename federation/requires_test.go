@@ -0,0 +1,271 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildRequiresSchemas() map[string]*schemabuilder.Schema {
+	type Item struct {
+		Name string
+	}
+	s1 := schemabuilder.NewSchemaWithName("s1")
+	item := s1.Object("Item", Item{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Item }) []*Item {
+		return args.Keys
+	}))
+	item.FieldFunc("score", func(i *Item) int64 {
+		return int64(len(i.Name)) * 10
+	})
+	s1.Query().FieldFunc("items", func() []*Item {
+		return []*Item{{Name: "a"}, {Name: "bcd"}}
+	})
+
+	type RankedItem struct {
+		Name  string
+		score *int64
+	}
+	type RankedItemKeys struct {
+		Name  string
+		Score *int64
+	}
+	s2 := schemabuilder.NewSchemaWithName("s2")
+	rankedItem := s2.Object("Item", RankedItem{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*RankedItemKeys }) []*RankedItem {
+		items := make([]*RankedItem, 0, len(args.Keys))
+		for _, key := range args.Keys {
+			items = append(items, &RankedItem{Name: key.Name, score: key.Score})
+		}
+		return items
+	}))
+	rankedItem.FieldFunc("rank", func(i *RankedItem) (int64, error) {
+		if i.score == nil {
+			return 0, errors.New("missing score")
+		}
+		return *i.score + 1, nil
+	})
+	rankedItem.FieldFunc("label", func(i *RankedItem) string {
+		return "item " + i.Name
+	})
+
+	return map[string]*schemabuilder.Schema{
+		"s1": s1,
+		"s2": s2,
+	}
+}
+
+func TestExecutorRequiredFields(t *testing.T) {
+	ctx := context.Background()
+	newExecutor := func(opts ...ExecutorOption) (*Executor, error) {
+		e, _, err := newTestExecutor(t, buildRequiresSchemas(), opts...)
+		return e, err
+	}
+
+	e, err := newExecutor(WithRequiredFields("Item", "rank", "score"))
+	require.NoError(t, err)
+
+	runAndValidateQueryResults(t, ctx, e, `{ items { name rank } }`, `
+		{
+			"items": [
+				{"name": "a", "rank": 11},
+				{"name": "bcd", "rank": 31}
+			]
+		}`)
+
+	// Required fields are only fetched for the fields that need them.
+	keysFetchedFromS1 := func(query string) []string {
+		plan, err := e.Plan(graphql.MustParse(query, map[string]interface{}{}))
+		require.NoError(t, err)
+		var keys []string
+		for _, selection := range plan.After[0].SelectionSet.Selections[0].SelectionSet.Selections {
+			if selection.Name == federationField {
+				for _, key := range selection.SelectionSet.Selections {
+					keys = append(keys, key.Name)
+				}
+			}
+		}
+		return keys
+	}
+	assert.ElementsMatch(t, []string{"name", "score"}, keysFetchedFromS1(`{ items { rank } }`))
+	assert.ElementsMatch(t, []string{"name"}, keysFetchedFromS1(`{ items { label } }`))
+
+	t.Run("without the declaration", func(t *testing.T) {
+		// The nullable key that s1 does not resolve is not silently ignored.
+		_, err := newExecutor()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "service s2 accepts Item.score as a key, which other services do not resolve")
+	})
+
+	t.Run("required field is not a key", func(t *testing.T) {
+		_, err := newExecutor(WithRequiredFields("Item", "rank", "label"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "service s2 does not accept label as a key")
+	})
+}
+
+// buildMultiSourceRequiresSchemas builds three services, where s3 resolves
+// Item.combined from the item's name and score, resolved by s1, and its
+// barId, resolved by s2.
+func buildMultiSourceRequiresSchemas() map[string]*schemabuilder.Schema {
+	schemas := buildRequiresSchemas()
+	delete(schemas, "s2")
+
+	type BarItem struct {
+		Name string
+	}
+	s2 := schemabuilder.NewSchemaWithName("s2")
+	barItem := s2.Object("Item", BarItem{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*BarItem }) []*BarItem {
+		return args.Keys
+	}))
+	barItem.FieldFunc("barId", func(i *BarItem) int64 {
+		return int64(len(i.Name)) + 100
+	})
+	schemas["s2"] = s2
+
+	type CombinedItem struct {
+		Name  string
+		score *int64
+		barId *int64
+	}
+	type CombinedItemKeys struct {
+		Name  string
+		Score *int64
+		BarId *int64
+	}
+	s3 := schemabuilder.NewSchemaWithName("s3")
+	combinedItem := s3.Object("Item", CombinedItem{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*CombinedItemKeys }) []*CombinedItem {
+		items := make([]*CombinedItem, 0, len(args.Keys))
+		for _, key := range args.Keys {
+			items = append(items, &CombinedItem{Name: key.Name, score: key.Score, barId: key.BarId})
+		}
+		return items
+	}))
+	combinedItem.FieldFunc("combined", func(i *CombinedItem) (string, error) {
+		if i.score == nil || i.barId == nil {
+			return "", errors.New("missing keys")
+		}
+		return fmt.Sprintf("%s:%d:%d", i.Name, *i.score, *i.barId), nil
+	})
+	schemas["s3"] = s3
+
+	return schemas
+}
+
+func TestExecutorMultiSourceRequiredFields(t *testing.T) {
+	ctx := context.Background()
+	e, clients, err := newTestExecutor(t, buildMultiSourceRequiresSchemas(),
+		WithRequiredFields("Item", "combined", "score", "barId"))
+	require.NoError(t, err)
+
+	runAndValidateQueryResults(t, ctx, e, `{ items { name combined } }`, `
+		{
+			"items": [
+				{"name": "a", "combined": "a:10:101"},
+				{"name": "bcd", "combined": "bcd:30:103"}
+			]
+		}`)
+	// s2 fetches barId for the items, and s3 is then called once with all
+	// the keys.
+	assert.Equal(t, 1, clients["s1"].count)
+	assert.Equal(t, 1, clients["s2"].count)
+	assert.Equal(t, 1, clients["s3"].count)
+
+	plan, err := e.Plan(graphql.MustParse(`{ items { name combined } }`, map[string]interface{}{}))
+	require.NoError(t, err)
+	require.Len(t, plan.After, 1)
+	stages := make(map[string]int)
+	keysBySubPlan := make(map[string][]string)
+	for _, subPlan := range plan.After[0].After {
+		stages[subPlan.Service] = subPlan.Stage
+		for _, selection := range subPlan.SelectionSet.Selections {
+			if selection.Name == federationField {
+				for _, key := range selection.SelectionSet.Selections {
+					keysBySubPlan[subPlan.Service] = append(keysBySubPlan[subPlan.Service], key.Name)
+				}
+			}
+		}
+	}
+	assert.Equal(t, map[string]int{"s2": 0, "s3": 1}, stages)
+	assert.Equal(t, []string{"barId"}, keysBySubPlan["s2"])
+
+	// s3 waits on s2, so the stages add a hop.
+	assert.Equal(t, []string{"s1", "s2 (items)", "s3 (items)"}, plan.hopChain())
+	assert.Equal(t, 3, plan.Stats().HopDepth)
+	cost, err := e.EstimateCost(graphql.MustParse(`{ items { name combined } }`, map[string]interface{}{}))
+	require.NoError(t, err)
+	assert.Equal(t, 3, cost.Hops)
+	limited, _, err := newTestExecutor(t, buildMultiSourceRequiresSchemas(),
+		WithRequiredFields("Item", "combined", "score", "barId"), WithMaxHops(2))
+	require.NoError(t, err)
+	_, err = limited.Plan(graphql.MustParse(`{ items { name combined } }`, map[string]interface{}{}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "query requires 3 sequential hops, more than the maximum of 2: s1 -> s2 (items) -> s3 (items)")
+
+	// Fields of the providing service can be selected alongside.
+	runAndValidateQueryResults(t, ctx, e, `{ items { barId combined } }`, `
+		{
+			"items": [
+				{"barId": 101, "combined": "a:10:101"},
+				{"barId": 103, "combined": "bcd:30:103"}
+			]
+		}`)
+}
+
+// TestExecutorRequiredFieldsPopulateParent checks that the object passed to
+// the resolvers of a service is populated with the required fields fetched
+// from another service, and not just with its key.
+func TestExecutorRequiredFieldsPopulateParent(t *testing.T) {
+	ctx := context.Background()
+
+	s1 := buildTestSchema1()
+	s1.Object("Foo", Foo{}).FieldFunc("s1len", func(f *Foo) int64 {
+		return int64(len(f.Name))
+	})
+
+	type FullFoo struct {
+		Name string
+		hmm  *string
+		len  *int64
+	}
+	type FullFooKeys struct {
+		Name  string
+		S1hmm *string
+		S1len *int64
+	}
+	s2 := schemabuilder.NewSchemaWithName("schema2")
+	s2.Query().FieldFunc("s2root", func() string {
+		return "hello"
+	})
+	foo := s2.Object("Foo", FullFoo{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*FullFooKeys }) []*FullFoo {
+		foos := make([]*FullFoo, 0, len(args.Keys))
+		for _, key := range args.Keys {
+			foos = append(foos, &FullFoo{Name: key.Name, hmm: key.S1hmm, len: key.S1len})
+		}
+		return foos
+	}))
+	foo.FieldFunc("s2summary", func(f *FullFoo) (string, error) {
+		if f.hmm == nil || f.len == nil {
+			return "", errors.New("parent is missing fields")
+		}
+		return fmt.Sprintf("%s %s %d", f.Name, *f.hmm, *f.len), nil
+	})
+
+	e, _, err := newTestExecutor(t, map[string]*schemabuilder.Schema{
+		"schema1": s1,
+		"schema2": s2,
+	}, WithRequiredFields("Foo", "s2summary", "s1hmm", "s1len"))
+	require.NoError(t, err)
+
+	runAndValidateQueryResults(t, ctx, e, `{ s1fff { s2summary } }`, `
+		{
+			"s1fff": [
+				{"s2summary": "jimbo jimbo!!! 5"},
+				{"s2summary": "bob bob!!! 3"}
+			]
+		}`)
+}
@@ -0,0 +1,127 @@
+package federation
+
+import (
+	"context"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// Response is the result of a query executed by an Executor, passed to its
+// ResponseMiddleware before it is returned.
+type Response struct {
+	// Query is the executed query.
+	Query *graphql.Query
+	// Type is the type of the result in the merged schema: the root type of
+	// the query, or the type of the object fetched by ResolveEntity.
+	Type graphql.Type
+	// Result is the result of the query. Middleware can modify it in place or
+	// replace it.
+	Result interface{}
+}
+
+// ResponseMiddleware post-processes the results of queries, eg. to redact
+// fields or add computed values.
+type ResponseMiddleware interface {
+	ProcessResponse(ctx context.Context, response *Response) error
+}
+
+// ResponseMiddlewareFunc is a ResponseMiddleware implemented by a function.
+type ResponseMiddlewareFunc func(ctx context.Context, response *Response) error
+
+// ProcessResponse calls f(ctx, response).
+func (f ResponseMiddlewareFunc) ProcessResponse(ctx context.Context, response *Response) error {
+	return f(ctx, response)
+}
+
+// WithResponseMiddleware runs middlewares, in order, on the result of every
+// query run with Execute, and every object fetched with ResolveEntity, before
+// it is returned.
+func WithResponseMiddleware(middlewares ...ResponseMiddleware) ExecutorOption {
+	return func(e *Executor) {
+		e.responseMiddlewares = append(e.responseMiddlewares, middlewares...)
+	}
+}
+
+// processResponse runs the executor's response middlewares on res.
+func (e *Executor) processResponse(ctx context.Context, typ graphql.Type, query *graphql.Query, res interface{}) (interface{}, error) {
+	if len(e.responseMiddlewares) == 0 {
+		return res, nil
+	}
+	return e.runResponseMiddlewares(ctx, &Response{
+		Query:  query,
+		Type:   typ,
+		Result: res,
+	})
+}
+
+// runResponseMiddlewares runs the executor's response middlewares on
+// response, returning its processed result.
+func (e *Executor) runResponseMiddlewares(ctx context.Context, response *Response) (interface{}, error) {
+	for _, middleware := range e.responseMiddlewares {
+		if err := middleware.ProcessResponse(ctx, response); err != nil {
+			return nil, oops.Wrapf(err, "processing response")
+		}
+	}
+	return response.Result, nil
+}
+
+// WalkFields calls f for every selected field of every object in the result,
+// however deeply nested, with the object's type and the object itself. The
+// value of the field is obj[selection.Alias]. Objects of union types are
+// matched with their fragments using __typename.
+func (r *Response) WalkFields(f func(typ *graphql.Object, selection *graphql.Selection, obj map[string]interface{})) {
+	walkFields(r.Type, r.Query.SelectionSet, r.Result, f)
+}
+
+func walkFields(typ graphql.Type, selectionSet *graphql.SelectionSet, value interface{}, f func(typ *graphql.Object, selection *graphql.Selection, obj map[string]interface{})) {
+	if selectionSet == nil || value == nil {
+		return
+	}
+	switch typ := typ.(type) {
+	case *graphql.NonNull:
+		walkFields(typ.Type, selectionSet, value, f)
+
+	case *graphql.List:
+		list, ok := value.([]interface{})
+		if !ok {
+			return
+		}
+		for _, elem := range list {
+			walkFields(typ.Type, selectionSet, elem, f)
+		}
+
+	case *graphql.Object:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for _, selection := range selectionSet.Selections {
+			field, ok := typ.Fields[selection.Name]
+			if !ok {
+				continue
+			}
+			f(typ, selection, obj)
+			walkFields(field.Type, selection.SelectionSet, obj[selection.Alias], f)
+		}
+		for _, fragment := range selectionSet.Fragments {
+			walkFields(typ, fragment.SelectionSet, obj, f)
+		}
+
+	case *graphql.Union:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		typename, _ := obj["__typename"].(string)
+		member, ok := typ.Types[typename]
+		if !ok {
+			return
+		}
+		for _, fragment := range selectionSet.Fragments {
+			if fragment.On == typename {
+				walkFields(member, fragment.SelectionSet, obj, f)
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package federation
+
+import (
+	"context"
+
+	"github.com/samsarahq/thunder/graphql"
+)
+
+type responsePathsKey struct{}
+
+// withResponsePaths returns ctx carrying the response paths, in the query
+// executed by the gateway, of the results of the plan executed with it.
+func withResponsePaths(ctx context.Context, paths [][]interface{}) context.Context {
+	return context.WithValue(ctx, responsePathsKey{}, paths)
+}
+
+// responsePathsFromContext returns the response paths carried by ctx.
+func responsePathsFromContext(ctx context.Context) [][]interface{} {
+	paths, _ := ctx.Value(responsePathsKey{}).([][]interface{})
+	return paths
+}
+
+// rebasePaths replaces the first step of each of paths, the index of a result
+// of the parent plan, with the path of that result in parents. Paths of
+// unknown results are dropped.
+func rebasePaths(paths, parents [][]interface{}) [][]interface{} {
+	rebased := make([][]interface{}, 0, len(paths))
+	for _, path := range paths {
+		if len(path) == 0 {
+			continue
+		}
+		idx, ok := path[0].(int)
+		if !ok || idx >= len(parents) {
+			continue
+		}
+		rebased = append(rebased, append(append([]interface{}{}, parents[idx]...), path[1:]...))
+	}
+	return rebased
+}
+
+// withKeyPathMapper returns ctx in which graphql.PathFromContext, on a
+// service fetching the objects with the response paths keyPaths from their
+// keys, translates the paths of the service's fields, eg.
+// ["_federation", "schema2_Foo", 1, "name"] where "_federation" is the
+// alias internalFieldName, into paths in the query executed
+// by the gateway. The translation only applies to services that run in the
+// gateway's process and receive its context, eg. with DirectExecutorClient.
+func withKeyPathMapper(ctx context.Context, internalFieldName string, keyPaths [][]interface{}) context.Context {
+	if keyPaths == nil {
+		return ctx
+	}
+	return graphql.WithPathMapper(ctx, func(path []interface{}) []interface{} {
+		if len(path) < 3 || path[0] != internalFieldName {
+			return path
+		}
+		idx, ok := path[2].(int)
+		if !ok || idx >= len(keyPaths) {
+			return path
+		}
+		return append(append([]interface{}{}, keyPaths[idx]...), path[3:]...)
+	})
+}
@@ -0,0 +1,58 @@
+package federation
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorPathFromContext(t *testing.T) {
+	ctx := context.Background()
+
+	type Item struct {
+		Name string
+	}
+	s1 := schemabuilder.NewSchemaWithName("s1")
+	s1.Object("Item", Item{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Item }) []*Item {
+		return args.Keys
+	}))
+	type Box struct {
+		Items []*Item
+	}
+	s1.Object("Box", Box{})
+	s1.Query().FieldFunc("box", func(ctx context.Context) *Box {
+		return &Box{Items: []*Item{{Name: "a"}, {Name: "b"}}}
+	})
+	s1.Query().FieldFunc("s1path", func(ctx context.Context) string {
+		return fmt.Sprint(graphql.PathFromContext(ctx))
+	})
+
+	s2 := schemabuilder.NewSchemaWithName("s2")
+	item := s2.Object("Item", Item{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Item }) []*Item {
+		return args.Keys
+	}))
+	item.FieldFunc("path", func(ctx context.Context, i *Item) string {
+		return fmt.Sprint(graphql.PathFromContext(ctx))
+	})
+
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{"s1": s1, "s2": s2})
+	require.NoError(t, err)
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)})
+	require.NoError(t, err)
+
+	// Resolvers on federated services see their paths in the gateway's query.
+	runAndValidateQueryResults(t, ctx, e, `{ s1path box { items { name p: path } } }`, `
+		{
+			"s1path": "[s1path]",
+			"box": {
+				"items": [
+					{"name": "a", "p": "[box items 0 p]"},
+					{"name": "b", "p": "[box items 1 p]"}
+				]
+			}
+		}`)
+}
@@ -0,0 +1,115 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorResponseMiddleware(t *testing.T) {
+	ctx := context.Background()
+
+	type maskKey struct{}
+	masker := ResponseMiddlewareFunc(func(ctx context.Context, response *Response) error {
+		mask, _ := ctx.Value(maskKey{}).(string)
+		response.WalkFields(func(typ *graphql.Object, selection *graphql.Selection, obj map[string]interface{}) {
+			if typ.Name == "Foo" && selection.Name == "name" {
+				obj[selection.Alias] = mask
+			}
+		})
+		return nil
+	})
+	counter := ResponseMiddlewareFunc(func(ctx context.Context, response *Response) error {
+		result, ok := response.Result.(map[string]interface{})
+		if !ok {
+			return errors.New("unexpected result")
+		}
+		if foos, ok := result["s1fff"].([]interface{}); ok {
+			result["count"] = json.Number(strconv.Itoa(len(foos)))
+		}
+		return nil
+	})
+	e, _ := createKitchenSinkExecutor(t, WithResponseMiddleware(masker, counter))
+	ctx = context.WithValue(ctx, maskKey{}, "REDACTED")
+
+	runAndValidateQueryResults(t, ctx, e, `{
+		s1fff { name alias: name s2ok s1nest { name } }
+		s1both {
+			... on Foo { name }
+			... on Bar { id }
+		}
+	}`, `
+		{
+			"s1fff": [
+				{"name": "REDACTED", "alias": "REDACTED", "s2ok": 5, "s1nest": {"name": "REDACTED"}},
+				{"name": "REDACTED", "alias": "REDACTED", "s2ok": 3, "s1nest": {"name": "REDACTED"}}
+			],
+			"s1both": [
+				{"__typename": "Foo", "name": "REDACTED"},
+				{"__typename": "Bar", "id": 1234}
+			],
+			"count": 2
+		}`)
+
+	// Single-service queries are processed too.
+	runAndValidateQueryResults(t, ctx, e, `{ s1f { name } }`, `{"s1f": {"name": "REDACTED"}}`)
+
+	t.Run("entities", func(t *testing.T) {
+		dropper := ResponseMiddlewareFunc(func(ctx context.Context, response *Response) error {
+			response.WalkFields(func(typ *graphql.Object, selection *graphql.Selection, obj map[string]interface{}) {
+				if typ.Name == "Bar" && selection.Name == "s1baz" {
+					delete(obj, selection.Alias)
+				}
+			})
+			return nil
+		})
+		e, _ := createKitchenSinkExecutor(t, WithResponseMiddleware(masker, dropper))
+		res, err := e.ResolveEntity(ctx, "Foo", map[string]interface{}{"name": "jimbo"}, mustParse(`{
+			name
+			s2ok
+			s2bar { id s1baz }
+		}`), nil)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"name":  "REDACTED",
+			"s2ok":  json.Number("5"),
+			"s2bar": map[string]interface{}{"id": json.Number("14")},
+		}, res)
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		plan, err := e.Plan(graphql.MustParse(`{ s1fff { name s2ok } }`, map[string]interface{}{}))
+		require.NoError(t, err)
+		data, err := MarshalPlan(plan)
+		require.NoError(t, err)
+		loaded, err := e.LoadPlan(data)
+		require.NoError(t, err)
+
+		results, _, err := e.ExecuteBatch(ctx, []*Plan{plan, loaded}, nil)
+		require.NoError(t, err)
+		for _, res := range results {
+			out, err := json.Marshal(res)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{
+				"s1fff": [
+					{"name": "REDACTED", "s2ok": 5},
+					{"name": "REDACTED", "s2ok": 3}
+				],
+				"count": 2
+			}`, string(out))
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		e, _ := createKitchenSinkExecutor(t, WithResponseMiddleware(ResponseMiddlewareFunc(func(ctx context.Context, response *Response) error {
+			return errors.New("access denied")
+		})))
+		runAndValidateQueryError(t, ctx, e, `{ s1f { name } }`, "", "access denied")
+	})
+}
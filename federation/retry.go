@@ -0,0 +1,174 @@
+package federation
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// RetryExecutorClient is an ExecutorClient that retries failed requests.
+// Only requests that select idempotent fields exclusively are retried, so that
+// mutations are never run more than once by accident, and only after transient
+// errors.
+type RetryExecutorClient struct {
+	Client ExecutorClient
+	// Schema is the schema of the service that Client sends requests to. It is
+	// used to determine whether the fields of a request are idempotent.
+	Schema *graphql.Schema
+	// MaxAttempts is the maximum number of times a request is sent.
+	MaxAttempts int
+	// Classifier classifies the errors returned by Client. Only transient
+	// errors are retried. If nil, DefaultErrorClassifier is used.
+	Classifier ErrorClassifier
+	// BaseDelay is how long to wait before the first retry. The delay doubles
+	// with every further retry, up to MaxDelay, and is jittered so that
+	// clients failing together do not retry together. If 0,
+	// DefaultRetryBaseDelay is used.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between retries. If 0, DefaultRetryMaxDelay is
+	// used.
+	MaxDelay time.Duration
+}
+
+const (
+	// DefaultRetryBaseDelay is the delay before the first retry of a
+	// RetryExecutorClient without a BaseDelay.
+	DefaultRetryBaseDelay = 50 * time.Millisecond
+	// DefaultRetryMaxDelay caps the delay between retries of a
+	// RetryExecutorClient without a MaxDelay.
+	DefaultRetryMaxDelay = 2 * time.Second
+)
+
+// Execute sends the request to the wrapped client, retrying on failure if all
+// fields in the request are idempotent.
+func (c *RetryExecutorClient) Execute(ctx context.Context, request *QueryRequest) (*QueryResponse, error) {
+	attempts := 1
+	if isIdempotentQuery(c.Schema, request.Query) && c.MaxAttempts > 1 {
+		attempts = c.MaxAttempts
+	}
+
+	classifier := c.Classifier
+	if classifier == nil {
+		classifier = DefaultErrorClassifier
+	}
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		var resp *QueryResponse
+		resp, err = c.Client.Execute(ctx, request)
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, oops.Wrapf(err, "executing after %d attempts", i+1)
+		}
+		if category := classifier.Classify(err); category != ErrorCategoryTransient {
+			return nil, oops.Wrapf(err, "executing after %d attempts: %s error", i+1, category)
+		}
+		if i+1 == attempts {
+			break
+		}
+
+		timer := time.NewTimer(c.backoff(i))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, oops.Wrapf(err, "executing after %d attempts: %s", i+1, ctx.Err())
+		case <-timer.C:
+		}
+	}
+	return nil, oops.Wrapf(err, "executing after %d attempts", attempts)
+}
+
+// backoff returns how long to wait after the given failed attempt, counting
+// from 0: a random duration between half and all of BaseDelay doubled once per
+// earlier attempt, capped at MaxDelay.
+func (c *RetryExecutorClient) backoff(attempt int) time.Duration {
+	base, max := c.BaseDelay, c.MaxDelay
+	if base <= 0 {
+		base = DefaultRetryBaseDelay
+	}
+	if max <= 0 {
+		max = DefaultRetryMaxDelay
+	}
+
+	delay := base
+	for i := 0; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// isIdempotentQuery returns whether all fields selected by query are marked
+// as idempotent in schema.
+func isIdempotentQuery(schema *graphql.Schema, query *graphql.Query) bool {
+	if schema == nil || query == nil {
+		return false
+	}
+	switch query.Kind {
+	case queryString:
+		return isIdempotentSelectionSet(schema.Query, query.SelectionSet)
+	case mutationString:
+		return isIdempotentSelectionSet(schema.Mutation, query.SelectionSet)
+	default:
+		return false
+	}
+}
+
+func isIdempotentSelectionSet(typ graphql.Type, selectionSet *graphql.SelectionSet) bool {
+	if selectionSet == nil {
+		return true
+	}
+
+	switch typ := typ.(type) {
+	case *graphql.NonNull:
+		return isIdempotentSelectionSet(typ.Type, selectionSet)
+	case *graphql.List:
+		return isIdempotentSelectionSet(typ.Type, selectionSet)
+	case *graphql.Object:
+		for _, selection := range selectionSet.Selections {
+			// Introspection fields never have side effects.
+			if strings.HasPrefix(selection.Name, "__") {
+				continue
+			}
+			field, ok := typ.Fields[selection.Name]
+			if !ok || !field.Idempotent {
+				return false
+			}
+			if !isIdempotentSelectionSet(field.Type, selection.SelectionSet) {
+				return false
+			}
+		}
+		for _, fragment := range selectionSet.Fragments {
+			if !isIdempotentSelectionSet(typ, fragment.SelectionSet) {
+				return false
+			}
+		}
+		return true
+	case *graphql.Union:
+		for _, selection := range selectionSet.Selections {
+			if selection.Name != "__typename" {
+				return false
+			}
+		}
+		for _, fragment := range selectionSet.Fragments {
+			obj, ok := typ.Types[fragment.On]
+			if !ok {
+				return false
+			}
+			if !isIdempotentSelectionSet(obj, fragment.SelectionSet) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,192 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyExecutorClient fails the first failures requests it receives with err,
+// or a connection error if err is nil.
+type flakyExecutorClient struct {
+	ExecutorClient
+	failures int
+	attempts int
+	err      error
+}
+
+func (c *flakyExecutorClient) Execute(ctx context.Context, request *QueryRequest) (*QueryResponse, error) {
+	c.attempts++
+	if c.attempts <= c.failures {
+		if c.err != nil {
+			return nil, c.err
+		}
+		return nil, errors.New("connection reset")
+	}
+	return c.ExecutorClient.Execute(ctx, request)
+}
+
+func TestRetryExecutorClient(t *testing.T) {
+	s := schemabuilder.NewSchemaWithName("s1")
+	s.Query().FieldFunc("count", func() int64 { return 1 })
+	s.Mutation().FieldFunc("increment", func() int64 { return 2 })
+	s.Mutation().FieldFunc("reset", func() int64 { return 0 }, schemabuilder.Idempotent)
+	schema := s.MustBuild()
+	srv, err := NewServer(schema)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		Name     string
+		Query    string
+		Attempts int
+		Error    bool
+	}{
+		{
+			Name:     "query fields are retried",
+			Query:    `{ count }`,
+			Attempts: 3,
+		},
+		{
+			Name:     "mutation fields are not retried",
+			Query:    `mutation { increment }`,
+			Attempts: 1,
+			Error:    true,
+		},
+		{
+			Name:     "mutation fields marked idempotent are retried",
+			Query:    `mutation { reset }`,
+			Attempts: 3,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			flaky := &flakyExecutorClient{ExecutorClient: &DirectExecutorClient{Client: srv}, failures: 2}
+			client := &RetryExecutorClient{Client: flaky, Schema: schema, MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+			_, err := client.Execute(context.Background(), &QueryRequest{
+				Query: graphql.MustParse(testCase.Query, map[string]interface{}{}),
+			})
+			if testCase.Error {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, testCase.Attempts, flaky.attempts)
+		})
+	}
+}
+
+func TestRetryExecutorClientErrorClassifier(t *testing.T) {
+	s := schemabuilder.NewSchemaWithName("s1")
+	s.Query().FieldFunc("count", func() int64 { return 1 })
+	schema := s.MustBuild()
+	srv, err := NewServer(schema)
+	require.NoError(t, err)
+
+	// Only overloaded errors are worth retrying on this service.
+	classifier := ErrorClassifierFunc(func(err error) ErrorCategory {
+		if strings.Contains(err.Error(), "server overloaded") {
+			return ErrorCategoryTransient
+		}
+		return DefaultErrorClassifier.Classify(err)
+	})
+
+	testCases := []struct {
+		Name       string
+		Err        error
+		Classifier ErrorClassifier
+		Attempts   int
+		Error      string
+	}{
+		{
+			Name:       "custom transient error",
+			Err:        errors.New("server overloaded"),
+			Classifier: classifier,
+			Attempts:   3,
+		},
+		{
+			Name:     "client errors are not retried",
+			Err:      graphql.NewClientError("unknown field"),
+			Attempts: 1,
+			Error:    "client error",
+		},
+		{
+			Name:     "deadline errors are retried",
+			Err:      oops.Wrapf(context.DeadlineExceeded, "calling s1"),
+			Attempts: 3,
+		},
+		{
+			Name: "permanent errors are not retried",
+			Err:  errors.New("schema mismatch"),
+			Classifier: ErrorClassifierFunc(func(err error) ErrorCategory {
+				return ErrorCategoryPermanent
+			}),
+			Attempts: 1,
+			Error:    "permanent error",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			flaky := &flakyExecutorClient{ExecutorClient: &DirectExecutorClient{Client: srv}, failures: 2, err: testCase.Err}
+			client := &RetryExecutorClient{Client: flaky, Schema: schema, MaxAttempts: 3, BaseDelay: time.Millisecond, Classifier: testCase.Classifier}
+
+			_, err := client.Execute(context.Background(), &QueryRequest{
+				Query: graphql.MustParse(`{ count }`, map[string]interface{}{}),
+			})
+			if testCase.Error != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), testCase.Error)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, testCase.Attempts, flaky.attempts)
+		})
+	}
+}
+
+func TestRetryExecutorClientBackoff(t *testing.T) {
+	client := &RetryExecutorClient{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	for attempt, max := range []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+		50 * time.Millisecond,
+		50 * time.Millisecond,
+	} {
+		for i := 0; i < 100; i++ {
+			delay := client.backoff(attempt)
+			assert.True(t, delay >= max/2 && delay <= max, "attempt %d: delay %v not in [%v, %v]", attempt, delay, max/2, max)
+		}
+	}
+}
+
+func TestRetryExecutorClientBackoffContext(t *testing.T) {
+	s := schemabuilder.NewSchemaWithName("s1")
+	s.Query().FieldFunc("count", func() int64 { return 1 })
+	schema := s.MustBuild()
+	srv, err := NewServer(schema)
+	require.NoError(t, err)
+
+	flaky := &flakyExecutorClient{ExecutorClient: &DirectExecutorClient{Client: srv}, failures: 2}
+	client := &RetryExecutorClient{Client: flaky, Schema: schema, MaxAttempts: 3, BaseDelay: time.Minute}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = client.Execute(ctx, &QueryRequest{
+		Query: graphql.MustParse(`{ count }`, map[string]interface{}{}),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "context deadline exceeded")
+	assert.Equal(t, 1, flaky.attempts)
+	assert.True(t, time.Since(start) < time.Second, "waited out the backoff after the context expired")
+}
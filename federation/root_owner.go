@@ -0,0 +1,87 @@
+package federation
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// rootFieldOwner is the service that resolves a root field shared by
+// several services.
+type rootFieldOwner struct {
+	typeName string
+	field    string
+	service  string
+}
+
+// WithRootFieldOwner makes service resolve the root field of typeName, Query
+// or Mutation, when several services expose a root field with the same name.
+// Queries for a shared root field without a configured owner fail, rather than
+// picking one of the services arbitrarily.
+func WithRootFieldOwner(typeName, field, service string) ExecutorOption {
+	return func(e *Executor) {
+		e.rootFieldOwners = append(e.rootFieldOwners, rootFieldOwner{
+			typeName: typeName,
+			field:    field,
+			service:  service,
+		})
+	}
+}
+
+// applyRootFieldOwners records the owners of root fields in planner, checking
+// that every owner resolves its field.
+func (e *Executor) applyRootFieldOwners(planner *Planner) error {
+	for _, owner := range e.rootFieldOwners {
+		var root graphql.Type
+		switch owner.typeName {
+		case "Query":
+			root = planner.schema.Schema.Query
+		case "Mutation":
+			root = planner.schema.Schema.Mutation
+		default:
+			return oops.Errorf("owner of %s.%s: %s is not a root type", owner.typeName, owner.field, owner.typeName)
+		}
+		obj, ok := root.(*graphql.Object)
+		if !ok {
+			return oops.Errorf("owner of %s.%s: unknown root type", owner.typeName, owner.field)
+		}
+		field, ok := obj.Fields[owner.field]
+		if !ok {
+			return oops.Errorf("owner of %s.%s: unknown field", owner.typeName, owner.field)
+		}
+		if info := planner.schema.Fields[field]; info == nil || !info.Services[owner.service] {
+			return oops.Errorf("owner of %s.%s: service %s does not resolve the field", owner.typeName, owner.field, owner.service)
+		}
+
+		if planner.rootFieldOwners == nil {
+			planner.rootFieldOwners = make(map[*graphql.Field]string)
+		}
+		planner.rootFieldOwners[field] = owner.service
+	}
+	return nil
+}
+
+// selectRootService returns the service that resolves the root field, which
+// must be unambiguous.
+func (e *Planner) selectRootService(typeName string, selection *graphql.Selection, field *graphql.Field, fieldInfo *FieldInfo) (string, error) {
+	if owner, ok := e.rootFieldOwners[field]; ok {
+		return owner, nil
+	}
+	claimed := e.claimedElsewhere(fieldInfo)
+	var services []string
+	for service, hasField := range fieldInfo.Services {
+		if hasField && !(claimed && service == e.fallbackService) {
+			services = append(services, service)
+		}
+	}
+	switch len(services) {
+	case 0:
+		return "", oops.Errorf("Field is not on multiple services")
+	case 1:
+		return services[0], nil
+	}
+	sort.Strings(services)
+	return "", oops.Errorf("root field %s.%s is resolved by services %s; configure its owner with WithRootFieldOwner", typeName, selection.Name, strings.Join(services, ", "))
+}
@@ -0,0 +1,69 @@
+package federation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorRootFieldOwner(t *testing.T) {
+	ctx := context.Background()
+	newExecutor := func(opts ...ExecutorOption) (*Executor, map[string]*countingExecutorClient, error) {
+		s1 := schemabuilder.NewSchemaWithName("s1")
+		s1.Query().FieldFunc("version", func() string { return "s1-v1" })
+		s1.Query().FieldFunc("s1only", func() string { return "s1" })
+		s2 := schemabuilder.NewSchemaWithName("s2")
+		s2.Query().FieldFunc("version", func() string { return "s2-v7" })
+		return newTestExecutor(t, map[string]*schemabuilder.Schema{"s1": s1, "s2": s2}, opts...)
+	}
+
+	t.Run("configured owner", func(t *testing.T) {
+		e, clients, err := newExecutor(WithRootFieldOwner("Query", "version", "s2"))
+		require.NoError(t, err)
+		runAndValidateQueryResults(t, ctx, e, `{ version }`, `{"version": "s2-v7"}`)
+		assert.Equal(t, 0, clients["s1"].count)
+		assert.Equal(t, 1, clients["s2"].count)
+
+		// Fields only resolved by one service are unaffected.
+		runAndValidateQueryResults(t, ctx, e, `{ version s1only }`, `{"version": "s2-v7", "s1only": "s1"}`)
+	})
+
+	t.Run("ambiguous without an owner", func(t *testing.T) {
+		e, _, err := newExecutor()
+		require.NoError(t, err)
+		runAndValidateQueryError(t, ctx, e, `{ version }`, "", "root field Query.version is resolved by services s1, s2; configure its owner with WithRootFieldOwner")
+		runAndValidateQueryResults(t, ctx, e, `{ s1only }`, `{"s1only": "s1"}`)
+	})
+
+	testCases := []struct {
+		Name  string
+		Opt   ExecutorOption
+		Error string
+	}{
+		{
+			Name:  "owner does not resolve the field",
+			Opt:   WithRootFieldOwner("Query", "s1only", "s2"),
+			Error: "owner of Query.s1only: service s2 does not resolve the field",
+		},
+		{
+			Name:  "unknown field",
+			Opt:   WithRootFieldOwner("Query", "missing", "s1"),
+			Error: "owner of Query.missing: unknown field",
+		},
+		{
+			Name:  "not a root type",
+			Opt:   WithRootFieldOwner("Foo", "version", "s1"),
+			Error: "owner of Foo.version: Foo is not a root type",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			_, _, err := newExecutor(testCase.Opt)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), testCase.Error)
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/samsarahq/go/oops"
 	"github.com/samsarahq/thunder/graphql"
@@ -22,6 +23,17 @@ type FieldInfo struct {
 	// field. If a service has multiple versions, all versions
 	// must be able to resolve the field.
 	Services map[string]bool
+	// OptionalKey is the set of services that accept this field, which not
+	// every service resolves, as a nullable federated key. Optional keys are
+	// only sent to a service when a field selected on it requires them, see
+	// WithRequiredFields.
+	OptionalKey map[string]bool
+	// Timeouts are the timeouts of the field on the services that limit how
+	// long resolving it may take, see schemabuilder.Timeout.
+	Timeouts map[string]time.Duration
+	// External are the services that declare the field external, see
+	// schemabuilder.Object.External.
+	External map[string]bool
 }
 
 // SchemaWithFederationInfo holds a graphql.Schema along with
@@ -32,6 +44,63 @@ type SchemaWithFederationInfo struct {
 	Fields map[*graphql.Field]*FieldInfo
 }
 
+// Ownership describes which services can resolve each object type and field
+// in a federated schema.
+type Ownership struct {
+	// Types maps object type names to the sorted services that define them.
+	Types map[string][]string `json:"types"`
+	// Fields maps object type names and field names to the sorted services
+	// that can resolve each field.
+	Fields map[string]map[string][]string `json:"fields"`
+}
+
+// Ownership returns the services that can resolve each object type and field
+// in the schema.
+func (s *SchemaWithFederationInfo) Ownership() (*Ownership, error) {
+	types := make(map[graphql.Type]string)
+	if err := CollectTypes(s.Schema.Query, types); err != nil {
+		return nil, err
+	}
+	if err := CollectTypes(s.Schema.Mutation, types); err != nil {
+		return nil, err
+	}
+
+	ownership := &Ownership{
+		Types:  make(map[string][]string),
+		Fields: make(map[string]map[string][]string),
+	}
+	for typ := range types {
+		obj, ok := typ.(*graphql.Object)
+		if !ok {
+			continue
+		}
+		typeServices := make(map[string]bool)
+		fields := make(map[string][]string, len(obj.Fields))
+		for name, field := range obj.Fields {
+			info, ok := s.Fields[field]
+			if !ok {
+				continue
+			}
+			services := make([]string, 0, len(info.Services))
+			for service := range info.Services {
+				services = append(services, service)
+				typeServices[service] = true
+			}
+			sort.Strings(services)
+			fields[name] = services
+		}
+
+		services := make([]string, 0, len(typeServices))
+		for service := range typeServices {
+			services = append(services, service)
+		}
+		sort.Strings(services)
+		ownership.Types[obj.Name] = services
+		ownership.Fields[obj.Name] = fields
+	}
+	return ownership, nil
+}
+
 func getRootType(typ *introspectionTypeRef) *introspectionTypeRef {
 	if typ.OfType == nil {
 		return typ
@@ -75,6 +144,71 @@ func validateFederationKeys(serviceNames []string, serviceSchemasByName map[stri
 	return nil
 }
 
+// validateKeyTypes checks that the federated keys of objects that services
+// fetch with FetchObjectFromKeys have the same named types, eg. the same
+// scalar, as the fields of the objects on the services that send the keys.
+// Services that disagree, eg. on whether an ID is an int64 or a string,
+// would otherwise only fail at runtime. Fields shared by services are
+// checked when merging their schemas.
+func validateKeyTypes(serviceNames []string, serviceSchemasByName map[string]*IntrospectionQueryResult) error {
+	// fieldTypes maps objects to their fields to the named type of the field
+	// on each service.
+	fieldTypes := make(map[string]map[string]map[string]string)
+	for _, service := range serviceNames {
+		for _, typ := range serviceSchemasByName[service].Schema.Types {
+			if typ.Kind != "OBJECT" {
+				continue
+			}
+			for _, field := range typ.Fields {
+				if fieldTypes[typ.Name] == nil {
+					fieldTypes[typ.Name] = make(map[string]map[string]string)
+				}
+				if fieldTypes[typ.Name][field.Name] == nil {
+					fieldTypes[typ.Name][field.Name] = make(map[string]string)
+				}
+				fieldTypes[typ.Name][field.Name][service] = getRootType(field.Type).Name
+			}
+		}
+	}
+
+	for _, service := range serviceNames {
+		inputs := make(map[string]introspectionType)
+		var lookups []introspectionField
+		for _, typ := range serviceSchemasByName[service].Schema.Types {
+			switch {
+			case typ.Kind == "INPUT_OBJECT":
+				inputs[typ.Name] = typ
+			case typ.Name == "Federation":
+				lookups = typ.Fields
+			}
+		}
+
+		for _, lookup := range lookups {
+			names := strings.SplitN(lookup.Name, "_", 2)
+			if len(names) != 2 {
+				continue
+			}
+			objName := names[1]
+			for _, arg := range lookup.Args {
+				if arg.Name != "keys" {
+					continue
+				}
+				for _, key := range inputs[getRootType(arg.Type).Name].InputFields {
+					keyType := getRootType(key.Type).Name
+					for _, other := range serviceNames {
+						fieldType, ok := fieldTypes[objName][key.Name][other]
+						if ok && fieldType != keyType {
+							return oops.Errorf("key %s.%s has type %s on service %s but field %s.%s has type %s on service %s",
+								objName, key.Name, keyType, service, objName, key.Name, fieldType, other)
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // validateFederatedObjects validates that if a object is federated, it is federated on all the schemas
 func validateFederatedObjects(serviceNames []string, serviceSchemasByName map[string]*IntrospectionQueryResult, objName string) error {
 	// Check if it is federated on one service. It is federated if there is a field
@@ -204,6 +338,10 @@ func ConvertVersionedSchemas(schemas serviceSchemas) (*SchemaWithFederationInfo,
 		serviceSchemas = append(serviceSchemas, serviceSchema)
 	}
 
+	if err := validateKeyTypes(serviceNames, serviceSchemasByName); err != nil {
+		return nil, err
+	}
+
 	// Finds the union of all the schemas from different executor services
 	merged, err := mergeSchemaSlice(serviceSchemas, Union)
 	if err != nil {
@@ -221,6 +359,7 @@ func ConvertVersionedSchemas(schemas serviceSchemas) (*SchemaWithFederationInfo,
 	}
 
 	fieldInfos := make(map[*graphql.Field]*FieldInfo)
+	optionalKeys := make(map[*graphql.Field]map[string]bool)
 	for _, service := range serviceNames {
 		for _, typ := range serviceSchemasByName[service].Schema.Types {
 			// For federated fields parse the arguments to figure out which
@@ -241,7 +380,11 @@ func ConvertVersionedSchemas(schemas serviceSchemas) (*SchemaWithFederationInfo,
 					}
 
 					for _, arg := range field.Args {
-
+						// Other arguments are passed from the gateway's
+						// context, see WithLookupArguments.
+						if arg.Name != "keys" {
+							continue
+						}
 						rootType := getRootType(arg.Type)
 
 						inputType, ok := types[rootType.Name].(*graphql.InputObject)
@@ -250,9 +393,16 @@ func ConvertVersionedSchemas(schemas serviceSchemas) (*SchemaWithFederationInfo,
 						}
 
 						// Check that all the input fields are on the federated object
-						for fName := range inputType.InputFields {
+						optional := make(map[string]bool)
+						for fName, fType := range inputType.InputFields {
 							if err := validateFederationKeys(serviceNames, serviceSchemasByName, obj, fName); err != nil {
-								return nil, err
+								// Nullable keys that not every service resolves are
+								// optional keys, which must be required by a field
+								// declared with WithRequiredFields.
+								if _, ok := fType.(*graphql.NonNull); ok {
+									return nil, err
+								}
+								optional[fName] = true
 							}
 
 							if _, ok := obj.Fields[fName]; !ok {
@@ -266,6 +416,13 @@ func ConvertVersionedSchemas(schemas serviceSchemas) (*SchemaWithFederationInfo,
 							if _, ok := inputType.InputFields[fName]; !ok {
 								continue
 							}
+							if optional[fName] {
+								if optionalKeys[f] == nil {
+									optionalKeys[f] = make(map[string]bool)
+								}
+								optionalKeys[f][service] = true
+								continue
+							}
 							if f.FederatedKey == nil {
 								f.FederatedKey = make(map[string]bool, len(serviceNames))
 							}
@@ -289,11 +446,29 @@ func ConvertVersionedSchemas(schemas serviceSchemas) (*SchemaWithFederationInfo,
 						fieldInfos[f] = info
 					}
 					info.Services[service] = true
+					if field.TimeoutMs > 0 {
+						if info.Timeouts == nil {
+							info.Timeouts = make(map[string]time.Duration)
+						}
+						info.Timeouts[service] = time.Duration(field.TimeoutMs) * time.Millisecond
+					}
+					if field.External {
+						if info.External == nil {
+							info.External = make(map[string]bool)
+						}
+						info.External[service] = true
+					}
 				}
 			}
 		}
 	}
 
+	for f, services := range optionalKeys {
+		if info, ok := fieldInfos[f]; ok {
+			info.OptionalKey = services
+		}
+	}
+
 	err = validateFieldsReturningFederatedObject(serviceNames, serviceSchemasByName, types, fieldInfos)
 	if err != nil {
 		return nil, oops.Wrapf(err, "Field funcs can not shadow objects")
@@ -401,6 +576,21 @@ func parseInputFields(source []introspectionInputField, all map[string]graphql.T
 	return fields, nil
 }
 
+// parseInputDeprecations maps the deprecated fields of source to the reasons
+// they are deprecated.
+func parseInputDeprecations(source []introspectionInputField) map[string]string {
+	var deprecations map[string]string
+	for _, field := range source {
+		if field.IsDeprecated {
+			if deprecations == nil {
+				deprecations = make(map[string]string)
+			}
+			deprecations[field.Name] = field.DeprecationReason
+		}
+	}
+	return deprecations
+}
+
 // parseSchema takes the introspected schema, validates the types,
 // and maps every field to the graphql types
 func parseSchema(schema *IntrospectionQueryResult) (map[string]graphql.Type, error) {
@@ -460,8 +650,9 @@ func parseSchema(schema *IntrospectionQueryResult) (map[string]graphql.Type, err
 				}
 
 				fields[field.Name] = &graphql.Field{
-					Args: parsed,
-					Type: fieldTyp,
+					Args:            parsed,
+					Type:            fieldTyp,
+					ArgDeprecations: parseInputDeprecations(field.Args),
 				}
 			}
 
@@ -473,7 +664,9 @@ func parseSchema(schema *IntrospectionQueryResult) (map[string]graphql.Type, err
 				return nil, fmt.Errorf("typ %s: %v", typ.Name, err)
 			}
 
-			all[typ.Name].(*graphql.InputObject).InputFields = parsed
+			inputObject := all[typ.Name].(*graphql.InputObject)
+			inputObject.InputFields = parsed
+			inputObject.Deprecations = parseInputDeprecations(typ.InputFields)
 
 		case "UNION":
 			types := make(map[string]*graphql.Object)
@@ -494,14 +687,22 @@ func parseSchema(schema *IntrospectionQueryResult) (map[string]graphql.Type, err
 			// XXX: introspection relies on the EnumValues map.
 			reverseMap := make(map[interface{}]string)
 			values := make([]string, 0, len(typ.EnumValues))
+			var deprecations map[string]string
 			for _, value := range typ.EnumValues {
 				values = append(values, value.Name)
 				reverseMap[value.Name] = value.Name
+				if value.IsDeprecated {
+					if deprecations == nil {
+						deprecations = make(map[string]string)
+					}
+					deprecations[value.Name] = value.DeprecationReason
+				}
 			}
 
 			enum := all[typ.Name].(*graphql.Enum)
 			enum.Values = values
 			enum.ReverseMap = reverseMap
+			enum.Deprecations = deprecations
 
 		case "SCALAR":
 			// pass
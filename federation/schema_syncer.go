@@ -3,11 +3,18 @@ package federation
 import (
 	"context"
 	"encoding/json"
+	"sync"
 
 	"github.com/samsarahq/go/oops"
 	"github.com/samsarahq/thunder/graphql/introspection"
+	"golang.org/x/sync/errgroup"
 )
 
+// introspectionServiceName is the service that resolves the introspection
+// fields __schema and __type. The gateway answers these itself, describing the
+// merged schema.
+const introspectionServiceName = "introspection"
+
 // SchemaSyncer has a function that checks if the schema has changed,
 // and if so updates the planner in the federated executor
 type SchemaSyncer interface {
@@ -16,6 +23,13 @@ type SchemaSyncer interface {
 type IntrospectionSchemaSyncer struct {
 	executors     map[string]ExecutorClient
 	queryMetadata interface{}
+
+	// TypeNamespaces maps services to a prefix for the names of their types,
+	// so that unrelated types with the same name on different services don't
+	// collide in the merged schema. Federated objects are shared between
+	// services and are never namespaced. The executor translates the names
+	// back when it dispatches queries to the services.
+	TypeNamespaces map[string]string
 }
 
 // Creates a schema syncer that periodically runs an introspection query agaisnt all the federated servers to check for updates.
@@ -29,18 +43,35 @@ func NewIntrospectionSchemaSyncer(ctx context.Context, executors map[string]Exec
 
 func (s *IntrospectionSchemaSyncer) FetchPlanner(ctx context.Context) (*Planner, error) {
 	schemas := make(map[string]*IntrospectionQueryResult)
+	namespaces := make(map[string]map[string]string)
+	// Services are introspected concurrently, as fetching the schemas of
+	// large graphs one by one is slow.
+	var mu sync.Mutex
+	g, gCtx := errgroup.WithContext(ctx)
 	for server, client := range s.executors {
-		resp, err := fetchSchema(ctx, client, s.queryMetadata)
-		if err != nil {
-			return nil, oops.Wrapf(err, "fetching schema %s", server)
-		}
-		schema := resp.Result
-		var iq IntrospectionQueryResult
-		if err := json.Unmarshal(schema, &iq); err != nil {
-			return nil, oops.Wrapf(err, "unmarshaling schema %s", server)
-		}
+		server, client := server, client
+		g.Go(func() error {
+			resp, err := fetchSchema(gCtx, client, s.queryMetadata)
+			if err != nil {
+				return oops.Wrapf(err, "fetching schema %s", server)
+			}
+			schema := resp.Result
+			var iq IntrospectionQueryResult
+			if err := json.Unmarshal(schema, &iq); err != nil {
+				return oops.Wrapf(err, "unmarshaling schema %s", server)
+			}
 
-		schemas[server] = &iq
+			mu.Lock()
+			defer mu.Unlock()
+			if prefix, ok := s.TypeNamespaces[server]; ok {
+				namespaces[server] = namespaceSchema(&iq, prefix)
+			}
+			schemas[server] = &iq
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
 	}
 
 	types, err := convertSchema(schemas)
@@ -59,11 +90,16 @@ func (s *IntrospectionSchemaSyncer) FetchPlanner(ctx context.Context) (*Planner,
 		return nil, oops.Wrapf(err, "unmarshaling introspection schema")
 	}
 
-	schemas["introspection"] = &iq
+	schemas[introspectionServiceName] = &iq
 	types, err = convertSchema(schemas)
 	if err != nil {
 		return nil, oops.Wrapf(err, "converting schemas error")
 	}
 
-	return NewPlanner(types, nil)
+	planner, err := NewPlanner(types, nil)
+	if err != nil {
+		return nil, err
+	}
+	planner.namespaces = namespaces
+	return planner, nil
 }
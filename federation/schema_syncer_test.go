@@ -6,12 +6,16 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
 	"github.com/samsarahq/thunder/graphql/introspection"
 	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/samsarahq/thunder/thunderpb"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -698,3 +702,88 @@ func TestAddingFederatedKey(t *testing.T) {
 	})
 	runAndValidateQueryResults(t, ctx, e, query, expectedOutput)
 }
+
+// barrierExecutorClient blocks requests until all clients sharing the barrier
+// have received one.
+type barrierExecutorClient struct {
+	ExecutorClient
+	barrier *sync.WaitGroup
+}
+
+func (c *barrierExecutorClient) Execute(ctx context.Context, request *QueryRequest) (*QueryResponse, error) {
+	c.barrier.Done()
+	done := make(chan struct{})
+	go func() {
+		c.barrier.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return c.ExecutorClient.Execute(ctx, request)
+	case <-time.After(5 * time.Second):
+		return nil, oops.Errorf("services were not introspected concurrently")
+	}
+}
+
+func TestIntrospectionSchemaSyncerConcurrentIntrospection(t *testing.T) {
+	ctx := context.Background()
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": buildTestSchema2(),
+	})
+	require.NoError(t, err)
+
+	var barrier sync.WaitGroup
+	barrier.Add(len(execs))
+	for name, exec := range execs {
+		execs[name] = &barrierExecutorClient{ExecutorClient: exec, barrier: &barrier}
+	}
+	_, err = NewIntrospectionSchemaSyncer(ctx, execs, nil).FetchPlanner(ctx)
+	require.NoError(t, err)
+}
+
+// failingExecutorRunner fails to execute any query.
+type failingExecutorRunner struct{}
+
+func (failingExecutorRunner) Execute(ctx context.Context, typ graphql.Type, source interface{}, query *graphql.Query) (interface{}, error) {
+	return nil, oops.Errorf("unavailable")
+}
+
+func TestServerCachesIntrospection(t *testing.T) {
+	ctx := context.Background()
+	server, err := NewServer(buildTestSchema1().MustBuild())
+	require.NoError(t, err)
+
+	query, err := MarshalQuery(graphql.MustParse(introspection.IntrospectionQuery, map[string]interface{}{}))
+	require.NoError(t, err)
+	// The cached response does not depend on the context of the request
+	// that computes it.
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	first, err := server.Execute(canceled, &thunderpb.ExecuteRequest{Query: query})
+	require.NoError(t, err)
+	second, err := server.Execute(ctx, &thunderpb.ExecuteRequest{Query: query})
+	require.NoError(t, err)
+	assert.True(t, first == second, "expected the cached introspection response")
+
+	// Failures are not cached.
+	server, err = NewServer(buildTestSchema1().MustBuild())
+	require.NoError(t, err)
+	localExecutor := server.localExecutor
+	server.localExecutor = failingExecutorRunner{}
+	_, err = server.Execute(ctx, &thunderpb.ExecuteRequest{Query: query})
+	require.Error(t, err)
+	server.localExecutor = localExecutor
+	_, err = server.Execute(ctx, &thunderpb.ExecuteRequest{Query: query})
+	require.NoError(t, err)
+
+	// Other queries are executed every time.
+	query, err = MarshalQuery(graphql.MustParse(`{ __schema { types { name } } }`, map[string]interface{}{}))
+	require.NoError(t, err)
+	first, err = server.Execute(ctx, &thunderpb.ExecuteRequest{Query: query})
+	require.NoError(t, err)
+	second, err = server.Execute(ctx, &thunderpb.ExecuteRequest{Query: query})
+	require.NoError(t, err)
+	assert.False(t, first == second)
+	assert.Equal(t, first.Result, second.Result)
+}
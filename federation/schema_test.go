@@ -6,6 +6,7 @@ import (
 	"sort"
 	"testing"
 
+	"github.com/samsarahq/go/oops"
 	"github.com/samsarahq/go/snapshotter"
 	"github.com/samsarahq/thunder/graphql"
 	"github.com/samsarahq/thunder/graphql/introspection"
@@ -200,6 +201,35 @@ func TestIncompatibleInputsConflictingTypes(t *testing.T) {
 	assertSchemaUnionError(t, s1, s2, "can't merge type Query: merging fields: field f has incompatible arguments: field foo has incompatible types string! and int32!: types must be identical")
 }
 
+// TestIncompatibleFederationKeyTypes tests that services must agree on the
+// types of the keys they send to each other.
+func TestIncompatibleFederationKeyTypes(t *testing.T) {
+	type Bar struct {
+		Id int64
+	}
+	s1 := schemabuilder.NewSchemaWithName("schema1")
+	s1.Object("Bar", Bar{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*Bar }) []*Bar {
+		return args.Keys
+	}))
+	s1.Query().FieldFunc("bar", func() *Bar { return &Bar{Id: 1} })
+
+	type StringBar struct {
+		Id string
+	}
+	s2 := schemabuilder.NewSchemaWithName("schema2")
+	s2.Object("Bar", StringBar{}, schemabuilder.FetchObjectFromKeys(func(args struct{ Keys []*StringBar }) []*StringBar {
+		return args.Keys
+	}))
+	s2.Query().FieldFunc("s2bar", func() *StringBar { return &StringBar{Id: "1"} })
+
+	_, err := convertSchema(extractSchemas(t, map[string]*schemabuilder.Schema{
+		"schema1": s1,
+		"schema2": s2,
+	}))
+	require.Error(t, err)
+	assert.Equal(t, "key Bar.id has type int64 on service schema1 but field Bar.id has type string on service schema2", oops.Cause(err).Error())
+}
+
 // TestMergeNonNilNonNilField tests that a non-nil field combined with a non-nil
 // field is non-nil in the combined schema.
 func TestMergeNonNilNonNilField(t *testing.T) {
@@ -824,3 +854,17 @@ func TestSchemaIntersectionInputFields(t *testing.T) {
 	})
 	assertSchemaIntersectionEq(t, s1, s2, s1)
 }
+
+func TestOwnership(t *testing.T) {
+	e, _ := createKitchenSinkExecutor(t)
+	ownership, err := e.Ownership()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"schema1", "schema2"}, ownership.Types["Foo"])
+	assert.Equal(t, []string{"schema1"}, ownership.Fields["Foo"]["s1hmm"])
+	assert.Equal(t, []string{"schema2"}, ownership.Fields["Foo"]["s2ok"])
+	assert.Equal(t, []string{"schema1", "schema2"}, ownership.Fields["Foo"]["name"])
+	assert.Equal(t, []string{"schema1"}, ownership.Fields["Query"]["s1fff"])
+	assert.Equal(t, []string{"schema2"}, ownership.Fields["Query"]["s2root"])
+	assert.Equal(t, []string{"schema1"}, ownership.Fields["Mutation"]["s1addFoo"])
+}
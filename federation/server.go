@@ -4,13 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/gogo/protobuf/proto"
 	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/batch"
 	"github.com/samsarahq/thunder/graphql"
 	"github.com/samsarahq/thunder/graphql/introspection"
 	"github.com/samsarahq/thunder/reactive"
 	"github.com/samsarahq/thunder/thunderpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 type GrpcExecutorClient struct {
@@ -22,13 +27,14 @@ func (c *GrpcExecutorClient) Execute(ctx context.Context, req *QueryRequest) (*Q
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.Client.Execute(ctx, &thunderpb.ExecuteRequest{
+	var trailer metadata.MD
+	resp, err := c.Client.Execute(outgoingRequestIDContext(ctx), &thunderpb.ExecuteRequest{
 		Query: marshaled,
-	})
+	}, grpc.Trailer(&trailer))
 	if err != nil {
-		return nil, err
+		return nil, withErrorLocations(err, trailer)
 	}
-	return &QueryResponse{Result: resp.Result}, nil
+	return newQueryResponse(resp)
 }
 
 
@@ -38,6 +44,21 @@ type DirectExecutorClient struct {
 }
 
 func (c *DirectExecutorClient) Execute(ctx context.Context, request *QueryRequest) (*QueryResponse, error) {
+	// Servers in the same process execute a copy of the query directly,
+	// rather than its protobuf encoding. Introspection queries still go
+	// through Execute, which caches their response.
+	if server, ok := c.Client.(*Server); ok && !isIntrospectionQuery(request.Query) {
+		query, err := copyQuery(request.Query)
+		if err != nil {
+			return nil, oops.Wrapf(err, "copying query")
+		}
+		resp, err := executeQuery(incomingRequestIDContext(ctx), query, server.schema, server.localExecutor)
+		if err != nil {
+			return nil, oops.Wrapf(err, "executing query")
+		}
+		return newQueryResponse(resp)
+	}
+
 	// marshal query into a protobuf
 	marshaled, err := MarshalQuery(request.Query)
 	if err != nil {
@@ -50,7 +71,7 @@ func (c *DirectExecutorClient) Execute(ctx context.Context, request *QueryReques
 	if err != nil {
 		return nil, oops.Wrapf(err, "executing query")
 	}
-	return &QueryResponse{Result: resp.Result}, nil
+	return newQueryResponse(resp)
 }
 
 // Server must implement thunderpb.ExecutorServer.
@@ -59,15 +80,70 @@ var _ thunderpb.ExecutorServer = &Server{}
 type Server struct {
 	schema        *graphql.Schema
 	localExecutor graphql.ExecutorRunner
+
+	// introspection caches the responses to the executors' introspection
+	// queries, by query, which are the same for as long as the schema is.
+	// Failures are not cached, so that the next introspection query tries
+	// again.
+	introspectionMu sync.Mutex
+	introspection   map[string]*thunderpb.ExecuteResponse
+
+	// apolloFederation exposes the Apollo Federation subgraph contract.
+	apolloFederation bool
+	// debounce is the debounce window of the batches of queries, see
+	// WithDebounce.
+	debounce time.Duration
 }
 
-func NewServer(schema *graphql.Schema) (*Server, error) {
-	introspection.AddIntrospectionToSchema(schema)
+func NewServer(schema *graphql.Schema, opts ...ServerOption) (*Server, error) {
 	localExecutor := graphql.NewExecutor(graphql.NewImmediateGoroutineScheduler())
-	return &Server{
+	s := &Server{
 		schema:        schema,
 		localExecutor: localExecutor,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.debounce > 0 {
+		s.localExecutor = &debouncedExecutor{ExecutorRunner: s.localExecutor, window: s.debounce}
+	}
+	if s.apolloFederation {
+		if err := addApolloFederation(schema); err != nil {
+			return nil, err
+		}
+	}
+	introspection.AddIntrospectionToSchema(schema)
+	return s, nil
+}
+
+// WithScheduler makes the server execute queries with scheduler rather than
+// graphql.NewImmediateGoroutineScheduler(), eg. graphql.NewSequentialScheduler()
+// to debug resolvers.
+func WithScheduler(scheduler graphql.WorkScheduler) ServerOption {
+	return func(s *Server) {
+		s.localExecutor = graphql.NewExecutor(scheduler)
+	}
+}
+
+// WithDebounce makes the batches of the server's queries wait for at least
+// window after their latest invocation, see batch.WithDebounce, so that
+// subqueries that fan out to many objects at once are resolved in fewer,
+// larger batches.
+func WithDebounce(window time.Duration) ServerOption {
+	return func(s *Server) {
+		s.debounce = window
+	}
+}
+
+// debouncedExecutor executes queries in a context with the debounce window
+// of batches set, see batch.WithDebounce.
+type debouncedExecutor struct {
+	graphql.ExecutorRunner
+	window time.Duration
+}
+
+func (e *debouncedExecutor) Execute(ctx context.Context, typ graphql.Type, source interface{}, query *graphql.Query) (interface{}, error) {
+	return e.ExecutorRunner.Execute(batch.WithDebounce(ctx, e.window), typ, source, query)
 }
 
 // ExecuteRequest unmarshals the protobuf query and executes it on the server
@@ -76,7 +152,12 @@ func ExecuteRequest(ctx context.Context, req *thunderpb.ExecuteRequest, gqlSchem
 	if err != nil {
 		return nil, oops.Wrapf(err, "unmarshaling query")
 	}
+	return executeQuery(ctx, query, gqlSchema, localExecutor)
+}
 
+// executeQuery executes query on the server, preparing its selection set in
+// place.
+func executeQuery(ctx context.Context, query *graphql.Query, gqlSchema *graphql.Schema, localExecutor graphql.ExecutorRunner) (*thunderpb.ExecuteResponse, error) {
 	var schema graphql.Type
 	switch query.Kind {
 	case "query":
@@ -91,6 +172,8 @@ func ExecuteRequest(ctx context.Context, req *thunderpb.ExecuteRequest, gqlSchem
 		return nil, err
 	}
 
+	ctx, marshalExtensions := withResponseExtensions(ctx)
+
 	// We're using `reactive.NewRerunner` to ensure that the reactive cache is set up correctly,
 	// but we won't actually wait for the query to rerun if invalidated.
 	done := make(chan struct{})
@@ -112,31 +195,127 @@ func ExecuteRequest(ctx context.Context, req *thunderpb.ExecuteRequest, gqlSchem
 		if err != nil {
 			return nil, oops.Wrapf(err, "unmarshalling json query response")
 		}
+		extensions, err := marshalExtensions()
+		if err != nil {
+			return nil, oops.Wrapf(err, "marshaling extensions")
+		}
 
 		return &thunderpb.ExecuteResponse{
-			Result: bytes,
+			Result:     bytes,
+			Extensions: extensions,
 		}, nil
 	}, time.Hour, false)
+	defer rerunner.Stop()
+
+	// The rerunner does not run the query at all if ctx is canceled before
+	// it starts, eg. when another subquery of the gateway's query fails.
+	select {
+	case <-done:
+		return queryResponse, queryError
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *Server) Execute(ctx context.Context, req *thunderpb.ExecuteRequest) (*thunderpb.ExecuteResponse, error) {
+	if source, ok := introspectionRequestSource(req); ok {
+		return s.executeIntrospection(source, req)
+	}
+	resp, err := ExecuteRequest(incomingRequestIDContext(ctx), req, s.schema, s.localExecutor)
+	if err != nil {
+		SetErrorLocations(ctx, err)
+		return nil, err
+	}
+	return resp, nil
+}
 
-	<-done
+// executeIntrospection returns the cached response to req, the introspection
+// query source, executing it if no response is cached yet. The response is
+// shared by all callers, so it is computed without the context of the
+// request that happens to execute it.
+func (s *Server) executeIntrospection(source string, req *thunderpb.ExecuteRequest) (*thunderpb.ExecuteResponse, error) {
+	s.introspectionMu.Lock()
+	defer s.introspectionMu.Unlock()
+	if resp, ok := s.introspection[source]; ok {
+		return resp, nil
+	}
+	resp, err := ExecuteRequest(context.Background(), req, s.schema, s.localExecutor)
+	if err != nil {
+		return nil, err
+	}
+	if s.introspection == nil {
+		s.introspection = make(map[string]*thunderpb.ExecuteResponse)
+	}
+	s.introspection[source] = resp
+	return resp, nil
+}
 
-	rerunner.Stop()
-	return queryResponse, queryError
+// isIntrospectionQuery returns whether query could be the introspection query
+// sent by executors to fetch the schema of a server.
+func isIntrospectionQuery(query *graphql.Query) bool {
+	return query.SelectionSet != nil && len(query.SelectionSet.Selections) == 1 && query.SelectionSet.Selections[0].Name == "__schema"
 }
 
-func (s *Server) Execute(ctx context.Context, req *thunderpb.ExecuteRequest) (*thunderpb.ExecuteResponse, error) {
-	return ExecuteRequest(ctx, req, s.schema, s.localExecutor)
+var (
+	marshaledIntrospectionQueriesOnce sync.Once
+	// marshaledIntrospectionQueries are the introspectionQueries sent by
+	// executors, by source.
+	marshaledIntrospectionQueries map[string]*thunderpb.Query
+)
+
+// isIntrospectionRequest returns whether req is one of the introspection
+// queries sent by executors to fetch the schema of a server.
+func isIntrospectionRequest(req *thunderpb.ExecuteRequest) bool {
+	_, ok := introspectionRequestSource(req)
+	return ok
+}
+
+// introspectionRequestSource returns the source of the introspection query
+// that req is, if it is one of the queries sent by executors to fetch the
+// schema of a server.
+func introspectionRequestSource(req *thunderpb.ExecuteRequest) (string, bool) {
+	selectionSet := req.Query.GetSelectionSet()
+	if selectionSet == nil || len(selectionSet.Selections) != 1 || selectionSet.Selections[0].Name != "__schema" {
+		return "", false
+	}
+	marshaledIntrospectionQueriesOnce.Do(func() {
+		marshaledIntrospectionQueries = make(map[string]*thunderpb.Query, len(introspectionQueries))
+		for _, source := range introspectionQueries {
+			query, err := graphql.Parse(source, map[string]interface{}{})
+			if err != nil {
+				continue
+			}
+			if marshaled, err := MarshalQuery(query); err == nil {
+				marshaledIntrospectionQueries[source] = marshaled
+			}
+		}
+	})
+	for source, query := range marshaledIntrospectionQueries {
+		if proto.Equal(req.Query, query) {
+			return source, true
+		}
+	}
+	return "", false
 }
 
-// marshalPbSelections gets a selection set and marshals it into the protobuf format
+// marshalPbSelections gets a selection set and marshals it into the protobuf
+// format, without the locations of the selections, eg. to compare selection
+// sets regardless of where they are in the source of their queries.
 func marshalPbSelections(selectionSet *graphql.SelectionSet) (*thunderpb.SelectionSet, error) {
+	return marshalPbSelectionSet(selectionSet, false)
+}
+
+// marshalPbSelectionSet marshals selectionSet into the protobuf format, with
+// the locations of the selections if withLocations is set, so that servers
+// can return the locations of their errors.
+func marshalPbSelectionSet(selectionSet *graphql.SelectionSet, withLocations bool) (*thunderpb.SelectionSet, error) {
 	if selectionSet == nil {
 		return nil, nil
 	}
 
 	selections := make([]*thunderpb.Selection, 0, len(selectionSet.Selections))
 	for _, selection := range selectionSet.Selections {
-		children, err := marshalPbSelections(selection.SelectionSet)
+		children, err := marshalPbSelectionSet(selection.SelectionSet, withLocations)
 		if err != nil {
 			return nil, oops.Wrapf(err, "marshaling selections")
 		}
@@ -150,17 +329,22 @@ func marshalPbSelections(selectionSet *graphql.SelectionSet) (*thunderpb.Selecti
 			}
 		}
 
-		selections = append(selections, &thunderpb.Selection{
+		pbSelection := &thunderpb.Selection{
 			Name:         selection.Name,
 			Alias:        selection.Alias,
 			SelectionSet: children,
 			Arguments:    args,
-		})
+		}
+		if withLocations && selection.Location != nil {
+			pbSelection.Line = int32(selection.Location.Line)
+			pbSelection.Column = int32(selection.Location.Column)
+		}
+		selections = append(selections, pbSelection)
 	}
 
 	fragments := make([]*thunderpb.Fragment, 0, len(selectionSet.Fragments))
 	for _, fragment := range selectionSet.Fragments {
-		selections, err := marshalPbSelections(fragment.SelectionSet)
+		selections, err := marshalPbSelectionSet(fragment.SelectionSet, withLocations)
 		if err != nil {
 			return nil, oops.Wrapf(err, "marshaling fragments")
 		}
@@ -196,11 +380,16 @@ func unmarshalPbSelectionSet(selectionSet *thunderpb.SelectionSet) (*graphql.Sel
 			}
 		}
 
+		var location *graphql.Location
+		if selection.Line != 0 {
+			location = &graphql.Location{Line: int(selection.Line), Column: int(selection.Column)}
+		}
 		selections = append(selections, &graphql.Selection{
 			Name:         selection.Name,
 			Alias:        selection.Alias,
 			SelectionSet: children,
 			UnparsedArgs: args,
+			Location:     location,
 		})
 	}
 
@@ -222,9 +411,119 @@ func unmarshalPbSelectionSet(selectionSet *thunderpb.SelectionSet) (*graphql.Sel
 	}, nil
 }
 
+// copyQuery copies query as if it were marshaled into a protobuf and back,
+// without encoding it, so that preparing the copy leaves query untouched.
+func copyQuery(query *graphql.Query) (*graphql.Query, error) {
+	selectionSet, err := copySelectionSet(query.SelectionSet)
+	if err != nil {
+		return nil, err
+	}
+	return &graphql.Query{
+		Name:         query.Name,
+		Kind:         query.Kind,
+		SelectionSet: selectionSet,
+	}, nil
+}
+
+// copySelectionSet copies the fields of selectionSet that MarshalQuery
+// encodes.
+func copySelectionSet(selectionSet *graphql.SelectionSet) (*graphql.SelectionSet, error) {
+	if selectionSet == nil {
+		return nil, nil
+	}
+
+	selections := make([]*graphql.Selection, 0, len(selectionSet.Selections))
+	for _, selection := range selectionSet.Selections {
+		children, err := copySelectionSet(selection.SelectionSet)
+		if err != nil {
+			return nil, err
+		}
+
+		var args map[string]interface{}
+		if selection.UnparsedArgs != nil {
+			copied, err := copyJSONValue(selection.UnparsedArgs)
+			if err != nil {
+				return nil, oops.Wrapf(err, "copying args")
+			}
+			args = copied.(map[string]interface{})
+		}
+
+		selections = append(selections, &graphql.Selection{
+			Name:         selection.Name,
+			Alias:        selection.Alias,
+			SelectionSet: children,
+			UnparsedArgs: args,
+			Location:     selection.Location,
+		})
+	}
+
+	fragments := make([]*graphql.Fragment, 0, len(selectionSet.Fragments))
+	for _, fragment := range selectionSet.Fragments {
+		selections, err := copySelectionSet(fragment.SelectionSet)
+		if err != nil {
+			return nil, err
+		}
+		fragments = append(fragments, &graphql.Fragment{
+			On:           fragment.On,
+			SelectionSet: selections,
+		})
+	}
+
+	return &graphql.SelectionSet{
+		Selections: selections,
+		Fragments:  fragments,
+	}, nil
+}
+
+// copyJSONValue copies value as if it were marshaled to JSON and back, eg.
+// converting numbers to float64. Values of other types than the ones
+// unmarshaled from JSON are marshaled to JSON.
+func copyJSONValue(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case nil, bool, string, float64:
+		return value, nil
+	case json.Number:
+		return value.Float64()
+	case int:
+		return float64(value), nil
+	case int64:
+		return float64(value), nil
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			c, err := copyJSONValue(v)
+			if err != nil {
+				return nil, err
+			}
+			copied[k] = c
+		}
+		return copied, nil
+	case []interface{}:
+		copied := make([]interface{}, len(value))
+		for i, v := range value {
+			c, err := copyJSONValue(v)
+			if err != nil {
+				return nil, err
+			}
+			copied[i] = c
+		}
+		return copied, nil
+	default:
+		bytes, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		var copied interface{}
+		if err := json.Unmarshal(bytes, &copied); err != nil {
+			return nil, err
+		}
+		return copied, nil
+	}
+}
+
 // marshalQuery marshals a graphql query type into a protobuf
 func MarshalQuery(query *graphql.Query) (*thunderpb.Query, error) {
-	selectionSet, err := marshalPbSelections(query.SelectionSet)
+	selectionSet, err := marshalPbSelectionSet(query.SelectionSet, true)
 	if err != nil {
 		return nil, oops.Wrapf(err, "marshaling query")
 	}
@@ -0,0 +1,111 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/thunderpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// protobufExecutorServer hides the *Server it wraps from DirectExecutorClient,
+// which then sends it the protobuf encoding of queries.
+type protobufExecutorServer struct {
+	thunderpb.ExecutorServer
+}
+
+func TestDirectExecutorClient(t *testing.T) {
+	ctx := context.Background()
+	server, err := NewServer(buildTestSchema2().MustBuild())
+	require.NoError(t, err)
+
+	query := graphql.MustParse(`{
+		_federation {
+			Foo(keys: $keys) { name s2ok s2score(weight: $weight) }
+		}
+	}`, map[string]interface{}{
+		// Values decoded with json.Decoder.UseNumber, as keys are.
+		"keys":   []interface{}{map[string]interface{}{"name": "jimbo"}},
+		"weight": json.Number("3"),
+	})
+	query.SelectionSet.Selections[0].SelectionSet.Selections[0].Name = "schema2_Foo"
+	before, err := MarshalQuery(query)
+	require.NoError(t, err)
+
+	direct, err := (&DirectExecutorClient{Client: server}).Execute(ctx, &QueryRequest{Query: query})
+	require.NoError(t, err)
+	encoded, err := (&DirectExecutorClient{Client: protobufExecutorServer{server}}).Execute(ctx, &QueryRequest{Query: query})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"_federation": {"Foo": [{"name": "jimbo", "s2ok": 5, "s2score": 15}]}}`, string(direct.Result))
+	assert.JSONEq(t, string(encoded.Result), string(direct.Result))
+
+	// Executing the query directly leaves it untouched.
+	after, err := MarshalQuery(query)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+	assert.Nil(t, query.SelectionSet.Selections[0].SelectionSet.Selections[0].Args)
+}
+
+func BenchmarkDirectExecutorClient(b *testing.B) {
+	ctx := context.Background()
+	server, err := NewServer(buildTestSchema1().MustBuild())
+	require.NoError(b, err)
+	query := graphql.MustParse(`{
+		s1fff { name s1hmm s1nest { name s1enum } }
+		s1echo(foo: "echo", required: {a: 1, b: 2})
+	}`, map[string]interface{}{})
+
+	for _, c := range []struct {
+		name   string
+		client *DirectExecutorClient
+	}{
+		{"direct", &DirectExecutorClient{Client: server}},
+		{"protobuf", &DirectExecutorClient{Client: protobufExecutorServer{server}}},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := c.client.Execute(ctx, &QueryRequest{Query: query}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestGrpcExecutorClientErrorLocations(t *testing.T) {
+	ctx := context.Background()
+	server, err := NewServer(buildTestSchema2().MustBuild())
+	require.NoError(t, err)
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	thunderpb.RegisterExecutorServer(grpcServer, server)
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.DialContext(ctx, "bufconn", grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return listener.Dial()
+	}))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := &GrpcExecutorClient{Client: thunderpb.NewExecutorClient(conn)}
+
+	_, err = client.Execute(ctx, &QueryRequest{Query: graphql.MustParse(`{
+		s2root
+		missing
+	}`, map[string]interface{}{})})
+	require.Error(t, err)
+	assert.Equal(t, `unknown field "missing"`, err.Error())
+	assert.Equal(t, []graphql.Location{{Line: 3, Column: 3}}, graphql.ErrorLocations(err))
+
+	// Errors without locations are returned as they are.
+	_, err = client.Execute(ctx, &QueryRequest{Query: graphql.MustParse(`{ s2root }`, map[string]interface{}{})})
+	require.NoError(t, err)
+}
@@ -0,0 +1,138 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// StreamingQueryResponse is a QueryResponse whose result is read from a
+// stream as it arrives from the service.
+type StreamingQueryResponse struct {
+	// Result is the JSON result of the query, which the executor closes once
+	// it has decoded it.
+	Result io.ReadCloser
+	// Metadata is an optional custom field which can be used to receive
+	// metadata such as query duration along with the response.
+	Metadata interface{}
+	// Extensions are the extensions of the GraphQL response, see
+	// QueryResponse.
+	Extensions map[string]interface{}
+}
+
+// StreamingExecutorClient is an ExecutorClient that can stream the results
+// of queries. The executor decodes streamed results as they arrive, rather
+// than buffering the whole response first, which reduces the peak memory of
+// queries that fetch thousands of objects from a service.
+//
+// The executor calls ExecuteStream rather than Execute for clients that
+// implement it. Wrappers of an ExecutorClient, such as RetryExecutorClient,
+// hide the streaming support of the clients they wrap.
+type StreamingExecutorClient interface {
+	ExecutorClient
+	ExecuteStream(ctx context.Context, request *QueryRequest) (*StreamingQueryResponse, error)
+}
+
+func safeExecuteStream(ctx context.Context, client StreamingExecutorClient, request *QueryRequest) (response *StreamingQueryResponse, err error) {
+	defer func() {
+		if panicErr := recover(); panicErr != nil {
+			response, err = nil, graphql.NewPanicError(panicErr)
+		}
+	}()
+	return client.ExecuteStream(ctx, request)
+}
+
+// executeStream executes request on service with client and decodes its
+// streamed result. The list of objects at listPath in the result, if any, is
+// decoded one object at a time, see decodeStreamed. It finishes the request's
+// span once the result is decoded.
+func (e *Executor) executeStream(ctx, spanCtx context.Context, service string, client StreamingExecutorClient, request *QueryRequest, listPath []string, finishSpan func(error)) (interface{}, interface{}, error) {
+	response, err := safeExecuteStream(spanCtx, client, request)
+	if err != nil {
+		finishSpan(err)
+		return nil, nil, oops.Wrapf(err, "execute remotely")
+	}
+	defer response.Result.Close()
+
+	d := json.NewDecoder(response.Result)
+	d.UseNumber()
+	res, err := decodeStreamed(d, listPath)
+	finishSpan(err)
+	if err != nil {
+		return nil, nil, oops.Wrapf(err, "unmarshal res")
+	}
+	recordExtensions(ctx, service, response.Extensions)
+	return res, response.Metadata, nil
+}
+
+// decodeStreamed decodes the next JSON value of d. The list at path in the
+// value, eg. the objects in {"_federation": {"schema2_Foo": [...]}}, is
+// decoded element by element, so that the decoder only buffers one object of
+// a long list at a time rather than the whole response. Values outside of
+// path are decoded whole.
+func decodeStreamed(d *json.Decoder, path []string) (interface{}, error) {
+	if path == nil {
+		var value interface{}
+		if err := d.Decode(&value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+
+	tok, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok == nil {
+		return nil, nil
+	}
+	if len(path) == 0 {
+		if tok != json.Delim('[') {
+			return nil, oops.Errorf("expected a list, got %v", tok)
+		}
+		list := []interface{}{}
+		for d.More() {
+			var elem interface{}
+			if err := d.Decode(&elem); err != nil {
+				return nil, err
+			}
+			list = append(list, elem)
+		}
+		if _, err := d.Token(); err != nil {
+			return nil, err
+		}
+		return list, nil
+	}
+
+	if tok != json.Delim('{') {
+		return nil, oops.Errorf("expected an object, got %v", tok)
+	}
+	obj := make(map[string]interface{})
+	for d.More() {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, oops.Errorf("expected a key, got %v", tok)
+		}
+		var value interface{}
+		if key == path[0] {
+			value, err = decodeStreamed(d, path[1:])
+		} else {
+			err = d.Decode(&value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = value
+	}
+	if _, err := d.Token(); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
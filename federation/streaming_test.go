@@ -0,0 +1,112 @@
+package federation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamingExecutorClient streams the results of an ExecutorClient, and
+// counts the streams that were closed.
+type streamingExecutorClient struct {
+	ExecutorClient
+
+	mu     sync.Mutex
+	closed int
+}
+
+func (c *streamingExecutorClient) Execute(ctx context.Context, request *QueryRequest) (*QueryResponse, error) {
+	return nil, errors.New("expected ExecuteStream")
+}
+
+func (c *streamingExecutorClient) ExecuteStream(ctx context.Context, request *QueryRequest) (*StreamingQueryResponse, error) {
+	response, err := c.ExecutorClient.Execute(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return &StreamingQueryResponse{
+		Result:     &closeCounter{Reader: bytes.NewReader(response.Result), client: c},
+		Metadata:   response.Metadata,
+		Extensions: response.Extensions,
+	}, nil
+}
+
+type closeCounter struct {
+	*bytes.Reader
+	client *streamingExecutorClient
+}
+
+func (c *closeCounter) Close() error {
+	c.client.mu.Lock()
+	defer c.client.mu.Unlock()
+	c.client.closed++
+	return nil
+}
+
+func TestExecutorStreamingClient(t *testing.T) {
+	ctx := context.Background()
+	newExecutor := func(opts ...ExecutorOption) (*Executor, *streamingExecutorClient) {
+		e, _ := createKitchenSinkExecutor(t, opts...)
+		// Stream the responses of schema2 once its schema is introspected.
+		streaming := &streamingExecutorClient{ExecutorClient: e.Executors["schema2"]}
+		e.Executors["schema2"] = streaming
+		return e, streaming
+	}
+
+	e, streaming := newExecutor()
+	runAndValidateQueryResults(t, ctx, e, `{ s2root s1fff { name s2ok } }`, `
+		{
+			"s2root": "hello",
+			"s1fff": [
+				{"name": "jimbo", "s2ok": 5},
+				{"name": "bob", "s2ok": 3}
+			]
+		}`)
+	assert.Equal(t, 2, streaming.closed)
+
+	t.Run("max response size", func(t *testing.T) {
+		e, streaming := newExecutor(WithMaxResponseSize(10))
+		_, _, err := e.Execute(ctx, graphql.MustParse(`{ s2root }`, nil), nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "exceeds the maximum of 10 bytes")
+		assert.Equal(t, 1, streaming.closed)
+	})
+}
+
+func TestDecodeStreamed(t *testing.T) {
+	path := []string{"_federation", "schema2_Foo"}
+	for _, testCase := range []struct {
+		name, input string
+	}{
+		{"objects", `{"_federation": {"schema2_Foo": [{"name": "jimbo", "n": 5}, null, {"name": "bob", "nested": [1, {"a": 2}]}]}}`},
+		{"other keys", `{"other": [1, 2], "_federation": {"schema2_Bar": {"id": 1}, "schema2_Foo": []}}`},
+		{"null list", `{"_federation": {"schema2_Foo": null}}`},
+		{"null", `null`},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			d := json.NewDecoder(strings.NewReader(testCase.input))
+			d.UseNumber()
+			streamed, err := decodeStreamed(d, path)
+			require.NoError(t, err)
+
+			d = json.NewDecoder(strings.NewReader(testCase.input))
+			d.UseNumber()
+			var expected interface{}
+			require.NoError(t, d.Decode(&expected))
+			assert.Equal(t, expected, streamed)
+		})
+	}
+
+	_, err := decodeStreamed(json.NewDecoder(strings.NewReader(`{"_federation": {"schema2_Foo": {"name": "jimbo"}}}`)), path)
+	require.Error(t, err)
+	assert.Equal(t, "expected a list, got {", oops.Cause(err).Error())
+}
@@ -0,0 +1,78 @@
+package federation
+
+import (
+	"sort"
+
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// ServiceTopology describes the part of a federated schema that one service
+// resolves.
+type ServiceTopology struct {
+	// Fields maps object type names to the sorted fields the service
+	// resolves on them.
+	Fields map[string][]string `json:"fields"`
+	// Keys maps the names of the object types the service can fetch from
+	// their federated keys to the sorted key fields it accepts.
+	Keys map[string][]string `json:"keys"`
+}
+
+// Topology returns, for every service, the fields it resolves and the
+// federated objects it can fetch from their keys. The bookkeeping used to
+// dispatch federated objects, ie. the _federation fields and the Federation
+// object, and the gateway's own introspection are left out.
+func (s *SchemaWithFederationInfo) Topology() (map[string]*ServiceTopology, error) {
+	types := make(map[graphql.Type]string)
+	if err := CollectTypes(s.Schema.Query, types); err != nil {
+		return nil, err
+	}
+	if err := CollectTypes(s.Schema.Mutation, types); err != nil {
+		return nil, err
+	}
+
+	topology := make(map[string]*ServiceTopology)
+	serviceTopology := func(service string) *ServiceTopology {
+		if _, ok := topology[service]; !ok {
+			topology[service] = &ServiceTopology{
+				Fields: make(map[string][]string),
+				Keys:   make(map[string][]string),
+			}
+		}
+		return topology[service]
+	}
+	for typ := range types {
+		obj, ok := typ.(*graphql.Object)
+		if !ok || obj.Name == "Federation" {
+			continue
+		}
+		for name, field := range obj.Fields {
+			if name == federationField {
+				continue
+			}
+			if info, ok := s.Fields[field]; ok {
+				for service, ok := range info.Services {
+					if ok && service != introspectionServiceName {
+						t := serviceTopology(service)
+						t.Fields[obj.Name] = append(t.Fields[obj.Name], name)
+					}
+				}
+			}
+			for service, ok := range field.FederatedKey {
+				if ok {
+					t := serviceTopology(service)
+					t.Keys[obj.Name] = append(t.Keys[obj.Name], name)
+				}
+			}
+		}
+	}
+
+	for _, t := range topology {
+		for _, fields := range t.Fields {
+			sort.Strings(fields)
+		}
+		for _, keys := range t.Keys {
+			sort.Strings(keys)
+		}
+	}
+	return topology, nil
+}
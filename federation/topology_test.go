@@ -0,0 +1,38 @@
+package federation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorTopology(t *testing.T) {
+	e, _ := createKitchenSinkExecutor(t)
+	topology, err := e.Topology()
+	require.NoError(t, err)
+	require.Len(t, topology, 2)
+
+	ownsPrefixed := func(service, typeName, prefix string) {
+		var prefixed []string
+		for _, field := range topology[service].Fields[typeName] {
+			if strings.HasPrefix(field, "s1") || strings.HasPrefix(field, "s2") {
+				prefixed = append(prefixed, field)
+			}
+		}
+		assert.NotEmpty(t, prefixed)
+		for _, field := range prefixed {
+			assert.True(t, strings.HasPrefix(field, prefix), "%s resolves %s.%s", service, typeName, field)
+		}
+	}
+	ownsPrefixed("schema1", "Foo", "s1")
+	ownsPrefixed("schema2", "Foo", "s2")
+	assert.Equal(t, []string{"name", "s1enum", "s1hmm", "s1nest"}, topology["schema1"].Fields["Foo"])
+	assert.Equal(t, []string{"s1both", "s1echo", "s1f", "s1fff"}, topology["schema1"].Fields["Query"])
+	assert.Equal(t, []string{"s2root"}, topology["schema2"].Fields["Query"])
+
+	// Both services fetch the objects of the other from their keys.
+	assert.Equal(t, []string{"id"}, topology["schema1"].Keys["Bar"])
+	assert.Equal(t, []string{"name"}, topology["schema2"].Keys["Foo"])
+}
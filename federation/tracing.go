@@ -0,0 +1,92 @@
+package federation
+
+import (
+	"context"
+	"strings"
+)
+
+// Span tags set by the executor on the spans of requests to services.
+const (
+	SpanTagService   = "federation.service"
+	SpanTagPath      = "federation.path"
+	SpanTagKeys      = "federation.keys"
+	SpanTagRequestID = "federation.request_id"
+	SpanTagError     = "error"
+)
+
+// executeSpanName is the operation name of the spans of requests to services.
+const executeSpanName = "federation.execute"
+
+// Tracer starts spans for the requests the executor sends to services. It
+// lets the executor emit spans to a tracing library, such as OpenTracing or
+// Datadog, without depending on it.
+type Tracer interface {
+	// StartSpan starts a span named operationName as a child of the span
+	// carried by ctx, if any, and returns a context carrying the new span.
+	StartSpan(ctx context.Context, operationName string) (Span, context.Context)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	SetTag(key string, value interface{})
+	Finish()
+}
+
+// WithTracer makes the executor start a span with tracer for every request it
+// sends to a service, as a child of the span carried by the query's context.
+// The spans are tagged with the service, the path of the fields the request
+// resolves, the number of keys fetched, the request ID, if any (see
+// WithRequestID), and whether the request failed.
+func WithTracer(tracer Tracer) ExecutorOption {
+	return func(e *Executor) {
+		e.tracer = tracer
+	}
+}
+
+type planPathKey struct{}
+
+// withPlanPath returns a context carrying the path of the fields that the
+// subplans executed with it are nested on.
+func withPlanPath(ctx context.Context, path []string) context.Context {
+	return context.WithValue(ctx, planPathKey{}, path)
+}
+
+// planPathFromContext returns the path carried by ctx.
+func planPathFromContext(ctx context.Context) []string {
+	path, _ := ctx.Value(planPathKey{}).([]string)
+	return path
+}
+
+// subPlanPath returns the path of the fields that subPlan is nested on, given
+// the path of its parent.
+func subPlanPath(parent []string, subPlan *Plan) []string {
+	path := append([]string{}, parent...)
+	for _, step := range subPlan.Path {
+		if step.Kind == KindField {
+			path = append(path, step.Name)
+		}
+	}
+	return path
+}
+
+// startExecuteSpan starts the span of a request to service, if the executor
+// has a tracer. The returned function finishes the span with the request's
+// error.
+func (e *Executor) startExecuteSpan(ctx context.Context, service string, keys []interface{}) (context.Context, func(err error)) {
+	if e.tracer == nil {
+		return ctx, func(error) {}
+	}
+	span, ctx := e.tracer.StartSpan(ctx, executeSpanName)
+	span.SetTag(SpanTagService, service)
+	span.SetTag(SpanTagPath, strings.Join(planPathFromContext(ctx), "."))
+	span.SetTag(SpanTagKeys, len(keys))
+	if id, ok := RequestIDFromContext(ctx); ok {
+		span.SetTag(SpanTagRequestID, id)
+	}
+	return ctx, func(err error) {
+		if err != nil {
+			span.SetTag(SpanTagError, true)
+		}
+		span.Finish()
+	}
+}
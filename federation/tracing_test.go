@@ -0,0 +1,104 @@
+package federation
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSpan struct {
+	name     string
+	parent   *fakeSpan
+	tags     map[string]interface{}
+	finished bool
+}
+
+func (s *fakeSpan) SetTag(key string, value interface{}) {
+	s.tags[key] = value
+}
+
+func (s *fakeSpan) Finish() {
+	s.finished = true
+}
+
+type fakeSpanKey struct{}
+
+type fakeTracer struct {
+	mu    sync.Mutex
+	spans []*fakeSpan
+}
+
+func (t *fakeTracer) StartSpan(ctx context.Context, operationName string) (Span, context.Context) {
+	parent, _ := ctx.Value(fakeSpanKey{}).(*fakeSpan)
+	span := &fakeSpan{name: operationName, parent: parent, tags: map[string]interface{}{}}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans = append(t.spans, span)
+	return span, context.WithValue(ctx, fakeSpanKey{}, span)
+}
+
+func TestExecutorTracer(t *testing.T) {
+	tracer := &fakeTracer{}
+	e, _ := createKitchenSinkExecutor(t, WithTracer(tracer))
+
+	root := &fakeSpan{name: "request"}
+	ctx := context.WithValue(context.Background(), fakeSpanKey{}, root)
+	_, _, err := e.Execute(WithRequestID(ctx, "req-1"), graphql.MustParse(`{ s1fff { name s2ok s1nest { name s2ok } } }`, map[string]interface{}{}), nil)
+	require.NoError(t, err)
+	// Spans are tagged with the request ID.
+	require.NotEmpty(t, tracer.spans)
+	for _, s := range tracer.spans {
+		assert.Equal(t, "req-1", s.tags[SpanTagRequestID])
+	}
+
+	tracer.spans = nil
+	_, _, err = e.Execute(ctx, graphql.MustParse(`{ s1fff { name s2ok s1nest { name s2ok } } }`, map[string]interface{}{}), nil)
+	require.NoError(t, err)
+
+	type span struct {
+		Service  string
+		Path     string
+		Keys     int
+		Error    bool
+		Finished bool
+		IsChild  bool
+	}
+	var spans []span
+	for _, s := range tracer.spans {
+		assert.Equal(t, "federation.execute", s.name)
+		assert.NotContains(t, s.tags, SpanTagRequestID)
+		_, failed := s.tags[SpanTagError]
+		spans = append(spans, span{
+			Service:  s.tags[SpanTagService].(string),
+			Path:     s.tags[SpanTagPath].(string),
+			Keys:     s.tags[SpanTagKeys].(int),
+			Error:    failed,
+			Finished: s.finished,
+			IsChild:  s.parent == root,
+		})
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Path < spans[j].Path })
+	assert.Equal(t, []span{
+		{Service: "schema1", Path: "", Keys: 0, Finished: true, IsChild: true},
+		{Service: "schema2", Path: "s1fff", Keys: 2, Finished: true, IsChild: true},
+		{Service: "schema2", Path: "s1fff.s1nest", Keys: 2, Finished: true, IsChild: true},
+	}, spans)
+
+	t.Run("failed request", func(t *testing.T) {
+		tracer.spans = nil
+		e.Executors["schema2"] = panickingExecutorClient{}
+		_, _, err := e.Execute(ctx, graphql.MustParse(`{ s1fff { s2ok } }`, map[string]interface{}{}), nil)
+		require.Error(t, err)
+		require.Len(t, tracer.spans, 2)
+		for _, s := range tracer.spans {
+			_, failed := s.tags[SpanTagError]
+			assert.Equal(t, s.tags[SpanTagService] == "schema2", failed)
+			assert.True(t, s.finished)
+		}
+	})
+}
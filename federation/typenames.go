@@ -0,0 +1,119 @@
+package federation
+
+import (
+	"fmt"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// WithStrictTypenames fails queries in which a service returns a member of a
+// union or interface whose __typename the merged schema does not know, eg.
+// because the service was deployed with a new member before the gateway
+// refreshed its schema.
+//
+// By default, such members are resolved leniently: the fields selected in
+// fragments on the members of the union are null, since none of the fragments
+// apply to them.
+func WithStrictTypenames() ExecutorOption {
+	return func(e *Executor) {
+		e.strictTypenames = true
+	}
+}
+
+// checkTypenames checks the __typename of the union members in res, the
+// result of the root plan p, against the merged schema.
+func (e *Executor) checkTypenames(planner *Planner, p *Plan, res interface{}) error {
+	if !p.selectsUnions {
+		return nil
+	}
+	return e.checkValueTypenames(planner.resultType(p), p.query.SelectionSet, res, nil)
+}
+
+// selectsUnions returns whether the flattened selectionSet selects fields of
+// unions, whose selection sets have a fragment for each of their members.
+func selectsUnions(selectionSet *graphql.SelectionSet) bool {
+	if selectionSet == nil {
+		return false
+	}
+	if len(selectionSet.Fragments) > 0 {
+		return true
+	}
+	for _, selection := range selectionSet.Selections {
+		if selectsUnions(selection.SelectionSet) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkValueTypenames checks the __typename of the union members in value,
+// found at path in the result, a result of the flattened selectionSet on typ.
+func (e *Executor) checkValueTypenames(typ graphql.Type, selectionSet *graphql.SelectionSet, value interface{}, path []string) error {
+	if value == nil || selectionSet == nil {
+		return nil
+	}
+
+	switch typ := typ.(type) {
+	case *graphql.NonNull:
+		return e.checkValueTypenames(typ.Type, selectionSet, value, path)
+
+	case *graphql.List:
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i, elem := range list {
+			if err := e.checkValueTypenames(typ.Type, selectionSet, elem, append(path, fmt.Sprint(i))); err != nil {
+				return err
+			}
+		}
+
+	case *graphql.Object:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for _, selection := range selectionSet.Selections {
+			field, ok := typ.Fields[selection.Name]
+			if !ok || selection.SelectionSet == nil {
+				continue
+			}
+			if err := e.checkValueTypenames(field.Type, selection.SelectionSet, obj[selection.Alias], append(path, selection.Alias)); err != nil {
+				return err
+			}
+		}
+
+	case *graphql.Union:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		typename, _ := obj["__typename"].(string)
+		if member, ok := typ.Types[typename]; ok {
+			for _, fragment := range selectionSet.Fragments {
+				if fragment.On == typename {
+					return e.checkValueTypenames(member, fragment.SelectionSet, obj, path)
+				}
+			}
+			return nil
+		}
+		if e.strictTypenames {
+			return oops.Errorf("%s: unknown __typename %q for %s", formatPath(path), typename, typ.Name)
+		}
+		// None of the fragments apply to the member, so the service resolved
+		// none of their fields, except for those it selected on the union.
+		for _, fragment := range selectionSet.Fragments {
+			for _, selection := range fragment.SelectionSet.Selections {
+				if _, ok := obj[selection.Alias]; ok {
+					continue
+				}
+				if ok, err := graphql.ShouldIncludeNode(selection.Directives); err != nil || !ok {
+					continue
+				}
+				obj[selection.Alias] = nil
+			}
+		}
+	}
+	return nil
+}
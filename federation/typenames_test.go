@@ -0,0 +1,121 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unknownTypenameExecutorClient replaces the Bar in the results of s1both
+// with a member of a type that the merged schema does not know.
+type unknownTypenameExecutorClient struct {
+	ExecutorClient
+}
+
+func (c *unknownTypenameExecutorClient) Execute(ctx context.Context, request *QueryRequest) (*QueryResponse, error) {
+	response, err := c.ExecutorClient.Execute(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		return nil, err
+	}
+	both, ok := result["s1both"].([]interface{})
+	if !ok {
+		return response, nil
+	}
+	both[1] = map[string]interface{}{"__typename": "Baz"}
+	response.Result, err = json.Marshal(result)
+	return response, err
+}
+
+func TestExecutorStrictTypenames(t *testing.T) {
+	ctx := context.Background()
+	newExecutor := func(opts ...ExecutorOption) *Executor {
+		e, _ := createKitchenSinkExecutor(t, opts...)
+		e.Executors["schema1"] = &unknownTypenameExecutorClient{ExecutorClient: e.Executors["schema1"]}
+		return e
+	}
+
+	queries := []struct {
+		Name   string
+		Query  string
+		Output string
+	}{
+		{
+			Name: "multiple services",
+			Query: `{
+				s1both {
+					__typename
+					... on Foo { name s2ok }
+					... on Bar { id s1baz }
+				}
+			}`,
+			Output: `{
+				"s1both": [
+					{"__typename": "Foo", "name": "this is the foo", "s2ok": 15},
+					{"__typename": "Baz", "name": null, "s2ok": null, "id": null, "s1baz": null}
+				]
+			}`,
+		},
+		{
+			Name:  "single service",
+			Query: `{ s1both { ... on Bar { id } } }`,
+			Output: `{
+				"s1both": [
+					{"__typename": "Foo"},
+					{"__typename": "Baz", "id": null}
+				]
+			}`,
+		},
+	}
+
+	t.Run("lenient", func(t *testing.T) {
+		e := newExecutor()
+		for _, query := range queries {
+			t.Run(query.Name, func(t *testing.T) {
+				runAndValidateQueryResults(t, ctx, e, query.Query, query.Output)
+			})
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		e := newExecutor(WithStrictTypenames())
+		for _, query := range queries {
+			t.Run(query.Name, func(t *testing.T) {
+				_, _, err := e.Execute(ctx, graphql.MustParse(query.Query, nil), nil)
+				require.Error(t, err)
+				assert.Equal(t, `s1both.1: unknown __typename "Baz" for FooOrBar`, oops.Cause(err).Error())
+			})
+		}
+	})
+}
+
+func TestPlanSelectsUnions(t *testing.T) {
+	e, _ := createKitchenSinkExecutor(t)
+
+	for query, selectsUnions := range map[string]bool{
+		`{ s1fff { name s2ok } }`:                          false,
+		`{ s1fff { s1nest { name } } }`:                    false,
+		`{ s1both { ... on Foo { name } } }`:               true,
+		`{ s1fff { s2bar { id } } s1both { __typename } }`: true,
+	} {
+		plan, err := e.Plan(graphql.MustParse(query, nil))
+		require.NoError(t, err)
+		assert.Equal(t, selectsUnions, plan.selectsUnions, query)
+
+		// Loaded plans skip checking the typenames of queries without unions
+		// too.
+		data, err := MarshalPlan(plan)
+		require.NoError(t, err)
+		loaded, err := e.LoadPlan(data)
+		require.NoError(t, err)
+		assert.Equal(t, selectsUnions, loaded.selectsUnions, query)
+	}
+}
@@ -0,0 +1,93 @@
+package federation
+
+import (
+	"reflect"
+
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// WithCommonUnionFields makes the planner select the fields that every member
+// of a union or interface resolves on the same service once on the union
+// itself, rather than once in the fragment of every member, so that services
+// resolve them for every element without branching on its type. Services must
+// accept fields common to all members selected on the union, as servers built
+// with the graphql package do.
+func WithCommonUnionFields() ExecutorOption {
+	return func(e *Executor) {
+		e.commonUnionFields = true
+	}
+}
+
+// applyCommonUnionFields records whether planner selects common fields on
+// unions.
+func (e *Executor) applyCommonUnionFields(planner *Planner) error {
+	planner.commonUnionFields = e.commonUnionFields
+	return nil
+}
+
+// hoistCommonFields moves the fields without subselections that the fragments
+// of plan, one for every member of typ, all select locally with the same
+// arguments out of the fragments and onto the union.
+func hoistCommonFields(typ *graphql.Union, plan *Plan) {
+	fragments := plan.SelectionSet.Fragments
+	if len(typ.Types) < 2 || len(fragments) != len(typ.Types) {
+		return
+	}
+
+	common := make(map[string]bool)
+	for _, candidate := range fragments[0].SelectionSet.Selections {
+		if candidate.Name == "__typename" || candidate.SelectionSet != nil {
+			continue
+		}
+		if isCommonSelection(typ, fragments, candidate) {
+			common[candidate.Alias] = true
+			plan.SelectionSet.Selections = append(plan.SelectionSet.Selections, candidate)
+		}
+	}
+	if len(common) == 0 {
+		return
+	}
+
+	for _, fragment := range fragments {
+		selections := make([]*graphql.Selection, 0, len(fragment.SelectionSet.Selections)-len(common))
+		for _, selection := range fragment.SelectionSet.Selections {
+			if !common[selection.Alias] {
+				selections = append(selections, selection)
+			}
+		}
+		fragment.SelectionSet = &graphql.SelectionSet{
+			Selections: selections,
+			Fragments:  fragment.SelectionSet.Fragments,
+		}
+	}
+}
+
+// isCommonSelection reports whether every fragment selects candidate, a field
+// of the same type on every member of typ.
+func isCommonSelection(typ *graphql.Union, fragments []*graphql.Fragment, candidate *graphql.Selection) bool {
+	var fieldType string
+	for _, fragment := range fragments {
+		field, ok := typ.Types[fragment.On].Fields[candidate.Name]
+		if !ok {
+			return false
+		}
+		if fieldType != "" && field.Type.String() != fieldType {
+			return false
+		}
+		fieldType = field.Type.String()
+
+		found := false
+		for _, selection := range fragment.SelectionSet.Selections {
+			if selection.Alias != candidate.Alias {
+				continue
+			}
+			found = selection.Name == candidate.Name && selection.SelectionSet == nil &&
+				reflect.DeepEqual(selection.UnparsedArgs, candidate.UnparsedArgs)
+			break
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
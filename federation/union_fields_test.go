@@ -0,0 +1,69 @@
+package federation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorCommonUnionFields(t *testing.T) {
+	ctx := context.Background()
+	s2 := buildTestSchema2()
+	s2.Object("Foo", Foo{}).FieldFunc("s2label", func(in *Foo) string {
+		return "foo " + in.Name
+	})
+	s2.Object("Bar", Bar{}).FieldFunc("s2label", func(in *Bar) string {
+		return "bar"
+	})
+	s2.InterfaceUnion("Node", (*node)(nil), &Foo{}, &Bar{})
+	s2.Query().FieldFunc("nodes", func() []node {
+		return []node{&Foo{Name: "jimbo"}, &Bar{Id: 12}}
+	})
+	execs, err := makeExecutors(map[string]*schemabuilder.Schema{
+		"schema1": buildTestSchema1(),
+		"schema2": s2,
+	})
+	require.NoError(t, err)
+	e, err := NewExecutor(ctx, execs, &SchemaSyncerConfig{SchemaSyncer: NewIntrospectionSchemaSyncer(ctx, execs, nil)}, WithCommonUnionFields())
+	require.NoError(t, err)
+
+	query := `
+		{
+			nodes {
+				__typename
+				s2label
+				... on Foo { name s1hmm }
+				... on Bar { id s1baz }
+			}
+		}`
+
+	plan, err := e.Plan(graphql.MustParse(query, nil))
+	require.NoError(t, err)
+	require.Len(t, plan.After, 1)
+	nodes := plan.After[0].SelectionSet.Selections[0]
+	require.Equal(t, "nodes", nodes.Name)
+
+	// s2label is selected once on the interface, and not by the fragments.
+	var names []string
+	for _, selection := range nodes.SelectionSet.Selections {
+		names = append(names, selection.Name)
+	}
+	assert.Contains(t, names, "s2label")
+	for _, fragment := range nodes.SelectionSet.Fragments {
+		for _, selection := range fragment.SelectionSet.Selections {
+			assert.NotEqual(t, "s2label", selection.Name, fragment.On)
+		}
+	}
+
+	runAndValidateQueryResults(t, ctx, e, query, `
+		{
+			"nodes": [
+				{"__typename": "Foo", "s2label": "foo jimbo", "name": "jimbo", "s1hmm": "jimbo!!!"},
+				{"__typename": "Bar", "s2label": "bar", "id": 12, "s1baz": "12"}
+			]
+		}`)
+}
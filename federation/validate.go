@@ -0,0 +1,158 @@
+package federation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// WithResultValidation makes the executor check the merged result of every
+// query against the merged schema before returning it: every field in the
+// result must be selected by the query and known to the schema, every
+// selected field must be present, non-null fields must not be null and
+// values must have the shape of their types. A result that fails the check
+// fails the query.
+//
+// Validation walks the entire result, so it is meant for debugging, eg. to
+// catch planner or merge bugs in staging, rather than for production.
+func WithResultValidation() ExecutorOption {
+	return func(e *Executor) {
+		e.validateResults = true
+	}
+}
+
+// validateResult checks res against the root type of query, if the executor
+// validates results.
+func (e *Executor) validateResult(typ graphql.Type, query *graphql.Query, res interface{}) error {
+	if !e.validateResults {
+		return nil
+	}
+	if err := validateValue(typ, query.SelectionSet, res, nil); err != nil {
+		return oops.Wrapf(err, "invalid result")
+	}
+	return nil
+}
+
+// validateValue checks that value, found at path in the result, is a valid
+// result of selectionSet on typ.
+func validateValue(typ graphql.Type, selectionSet *graphql.SelectionSet, value interface{}, path []string) error {
+	if nonNull, ok := typ.(*graphql.NonNull); ok {
+		if value == nil {
+			return fmt.Errorf("%s: null for non-null type %s", formatPath(path), nonNull.Type)
+		}
+		return validateValue(nonNull.Type, selectionSet, value, path)
+	}
+	if value == nil {
+		return nil
+	}
+
+	switch typ := typ.(type) {
+	case *graphql.Scalar, *graphql.Enum:
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return fmt.Errorf("%s: expected a %s, got %T", formatPath(path), typ, value)
+		}
+		return nil
+
+	case *graphql.List:
+		list, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected a list, got %T", formatPath(path), value)
+		}
+		for i, elem := range list {
+			if err := validateValue(typ.Type, selectionSet, elem, append(path, fmt.Sprint(i))); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *graphql.Object:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected a %s object, got %T", formatPath(path), typ.Name, value)
+		}
+		return validateObject(typ, typ.Name, selectionSet, obj, path)
+
+	case *graphql.Union:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected a %s object, got %T", formatPath(path), typ.Name, value)
+		}
+		typename, _ := obj["__typename"].(string)
+		member, ok := typ.Types[typename]
+		if !ok {
+			return fmt.Errorf("%s: %q is not a member of union %s", formatPath(path), typename, typ.Name)
+		}
+		return validateObject(member, typename, selectionSet, obj, path)
+
+	default:
+		return fmt.Errorf("%s: unexpected type %s", formatPath(path), typ)
+	}
+}
+
+// validateObject checks that obj, an object of type typ, has exactly the
+// fields selected by selectionSet and that their values are valid.
+func validateObject(typ *graphql.Object, typename string, selectionSet *graphql.SelectionSet, obj map[string]interface{}, path []string) error {
+	selected := make(map[string]bool)
+	var check func(selectionSet *graphql.SelectionSet, required bool) error
+	check = func(selectionSet *graphql.SelectionSet, required bool) error {
+		if selectionSet == nil {
+			return nil
+		}
+		for _, selection := range selectionSet.Selections {
+			selected[selection.Alias] = true
+			// Skipped selections are not in the result.
+			value, ok := obj[selection.Alias]
+			if !ok {
+				if required && len(selection.Directives) == 0 {
+					return fmt.Errorf("%s: missing field %s", formatPath(path), selection.Alias)
+				}
+				continue
+			}
+			if selection.Name == "__typename" {
+				if value != typename {
+					return fmt.Errorf("%s: __typename %v, expected %s", formatPath(path), value, typename)
+				}
+				continue
+			}
+			field, ok := typ.Fields[selection.Name]
+			if !ok {
+				return fmt.Errorf("%s: unknown field %s on %s", formatPath(path), selection.Name, typ.Name)
+			}
+			if err := validateValue(field.Type, selection.SelectionSet, value, append(path, selection.Alias)); err != nil {
+				return err
+			}
+		}
+		for _, fragment := range selectionSet.Fragments {
+			if fragment.On != "" && fragment.On != typename {
+				continue
+			}
+			if err := check(fragment.SelectionSet, required && len(fragment.Directives) == 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := check(selectionSet, true); err != nil {
+		return err
+	}
+
+	// The executor adds __key to objects with keys and __typename to the
+	// members of unions.
+	for alias, value := range obj {
+		if selected[alias] || alias == keyField || (alias == "__typename" && value == typename) {
+			continue
+		}
+		return fmt.Errorf("%s: unexpected field %s on %s", formatPath(path), alias, typ.Name)
+	}
+	return nil
+}
+
+func formatPath(path []string) string {
+	if len(path) == 0 {
+		return "root"
+	}
+	return strings.Join(path, ".")
+}
@@ -0,0 +1,100 @@
+package federation
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// corruptingExecutorClient replaces old with new in the responses of Client.
+type corruptingExecutorClient struct {
+	ExecutorClient
+	old, new string
+}
+
+func (c *corruptingExecutorClient) Execute(ctx context.Context, request *QueryRequest) (*QueryResponse, error) {
+	resp, err := c.ExecutorClient.Execute(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return &QueryResponse{Result: bytes.Replace(resp.Result, []byte(c.old), []byte(c.new), -1)}, nil
+}
+
+func TestExecutorResultValidation(t *testing.T) {
+	ctx := context.Background()
+	e, clients := createKitchenSinkExecutor(t, WithResultValidation())
+
+	runAndValidateQueryResults(t, ctx, e, `{
+		s1fff { name s2ok s1nest { s1enum s2bar { id s1baz } } }
+		s1both {
+			... on Foo { name s2ok }
+			... on Bar { id s1baz }
+		}
+		s2root
+	}`, `
+		{
+			"s1fff": [
+				{"name": "jimbo", "s2ok": 5, "s1nest": {"s1enum": "one", "s2bar": {"id": 14, "s1baz": "14"}}},
+				{"name": "bob", "s2ok": 3, "s1nest": {"s1enum": "one", "s2bar": {"id": 10, "s1baz": "10"}}}
+			],
+			"s1both": [
+				{"__typename": "Foo", "name": "this is the foo", "s2ok": 15},
+				{"__typename": "Bar", "id": 1234, "s1baz": "1234"}
+			],
+			"s2root": "hello"
+		}`)
+
+	testCases := []struct {
+		Name  string
+		Old   string
+		New   string
+		Error string
+	}{
+		{
+			Name:  "null for non-null field",
+			Old:   `"s2ok":5`,
+			New:   `"s2ok":null`,
+			Error: "s1fff.0.s2ok: null for non-null type int",
+		},
+		{
+			Name:  "unexpected field",
+			Old:   `"s2ok":5`,
+			New:   `"s2ok":5,"extra":true`,
+			Error: "s1fff.0: unexpected field extra on Foo",
+		},
+		{
+			Name:  "missing field",
+			Old:   `"s2ok":`,
+			New:   `"s2okay":`,
+			Error: "s1fff.0: missing field s2ok",
+		},
+		{
+			Name:  "wrong shape",
+			Old:   `"s2ok":5`,
+			New:   `"s2ok":[5]`,
+			Error: "s1fff.0.s2ok: expected a int, got []interface {}",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			e.Executors["schema2"] = &corruptingExecutorClient{ExecutorClient: clients["schema2"], old: testCase.Old, new: testCase.New}
+			defer func() { e.Executors["schema2"] = clients["schema2"] }()
+
+			_, _, err := e.Execute(ctx, graphql.MustParse(`{ s1fff { name s2ok } }`, map[string]interface{}{}), nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "invalid result")
+			assert.Contains(t, err.Error(), testCase.Error)
+		})
+	}
+
+	t.Run("without validation", func(t *testing.T) {
+		e, clients := createKitchenSinkExecutor(t)
+		e.Executors["schema2"] = &corruptingExecutorClient{ExecutorClient: clients["schema2"], old: `"s2ok":5`, new: `"s2ok":null`}
+		_, _, err := e.Execute(ctx, graphql.MustParse(`{ s1fff { name s2ok } }`, map[string]interface{}{}), nil)
+		assert.NoError(t, err)
+	})
+}
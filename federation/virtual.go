@@ -0,0 +1,186 @@
+package federation
+
+import (
+	"context"
+
+	"github.com/samsarahq/go/oops"
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// virtualFieldAliasPrefix prefixes the aliases of the fields fetched for
+// virtual fields, so they don't collide with the fields of the query.
+const virtualFieldAliasPrefix = "_virtual_"
+
+// VirtualFieldResolver computes a field at the gateway, see
+// Executor.AddVirtualField.
+type VirtualFieldResolver struct {
+	// Type is the type of the field in the merged schema.
+	Type graphql.Type
+	// Requires are the fields of the object that the field is computed
+	// from. They must be scalar or enum fields without arguments.
+	Requires []string
+	// Resolve computes the field from object, which holds the required
+	// fields by name.
+	Resolve func(ctx context.Context, object map[string]interface{}) (interface{}, error)
+}
+
+// virtualField is a field computed at the gateway.
+type virtualField struct {
+	typeName string
+	field    string
+	resolver *VirtualFieldResolver
+}
+
+// AddVirtualField adds the field fieldName to the object typeName of the
+// merged schema, which no service resolves. Instead, whenever the field is
+// selected the gateway fetches the fields resolver.Requires from the services
+// that resolve them and computes the field with resolver.Resolve once they are
+// all resolved. For example, a display name can be computed from the fields
+// of two services:
+//   e.AddVirtualField("User", "displayName", &federation.VirtualFieldResolver{
+//       Type:     &graphql.Scalar{Type: "string"},
+//       Requires: []string{"firstName", "lastName"},
+//       Resolve: func(ctx context.Context, user map[string]interface{}) (interface{}, error) {
+//           return fmt.Sprintf("%s %s", user["firstName"], user["lastName"]), nil
+//       },
+//   })
+//
+// Virtual fields should be added right after creating the executor, before it
+// executes queries. They are added to the schemas fetched later on as well.
+func (e *Executor) AddVirtualField(typeName, fieldName string, resolver *VirtualFieldResolver) error {
+	virtual := virtualField{
+		typeName: typeName,
+		field:    fieldName,
+		resolver: resolver,
+	}
+	if err := applyVirtualField(e.getPlanner(), virtual); err != nil {
+		return oops.Wrapf(err, "invalid virtual field")
+	}
+	e.virtualFieldsMu.Lock()
+	defer e.virtualFieldsMu.Unlock()
+	e.virtualFields = append(e.virtualFields, virtual)
+	return nil
+}
+
+// applyVirtualFields adds the executor's virtual fields to planner's schema.
+func (e *Executor) applyVirtualFields(planner *Planner) error {
+	e.virtualFieldsMu.Lock()
+	defer e.virtualFieldsMu.Unlock()
+	for _, virtual := range e.virtualFields {
+		if err := applyVirtualField(planner, virtual); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyVirtualField adds virtual to the merged schema of planner, checking
+// that the fields it requires exist.
+func applyVirtualField(planner *Planner, virtual virtualField) error {
+	if virtual.resolver == nil || virtual.resolver.Type == nil || virtual.resolver.Resolve == nil {
+		return oops.Errorf("virtual field %s.%s: missing type or resolver", virtual.typeName, virtual.field)
+	}
+	if virtual.typeName == "Query" || virtual.typeName == "Mutation" {
+		return oops.Errorf("virtual field %s.%s: virtual fields cannot be added to root types", virtual.typeName, virtual.field)
+	}
+	obj, ok := planner.flattener.types[virtual.typeName].(*graphql.Object)
+	if !ok {
+		return oops.Errorf("virtual field %s.%s: unknown object type %s", virtual.typeName, virtual.field, virtual.typeName)
+	}
+	if _, ok := obj.Fields[virtual.field]; ok {
+		return oops.Errorf("virtual field %s.%s: field already exists", virtual.typeName, virtual.field)
+	}
+	for _, name := range virtual.resolver.Requires {
+		required, ok := obj.Fields[name]
+		if !ok {
+			return oops.Errorf("virtual field %s.%s: unknown field %s", virtual.typeName, virtual.field, name)
+		}
+		if _, ok := planner.virtualFields[required]; ok {
+			return oops.Errorf("virtual field %s.%s: requires the virtual field %s", virtual.typeName, virtual.field, name)
+		}
+		switch unwrapType(required.Type).(type) {
+		case *graphql.Scalar, *graphql.Enum:
+		default:
+			return oops.Errorf("virtual field %s.%s: required field %s is not a scalar", virtual.typeName, virtual.field, name)
+		}
+		if len(required.Args) > 0 {
+			return oops.Errorf("virtual field %s.%s: required field %s has arguments", virtual.typeName, virtual.field, name)
+		}
+	}
+
+	field := &graphql.Field{
+		Type: virtual.resolver.Type,
+		Args: map[string]graphql.Type{},
+	}
+	obj.Fields[virtual.field] = field
+	if planner.virtualFields == nil {
+		planner.virtualFields = make(map[*graphql.Field]*VirtualFieldResolver)
+	}
+	planner.virtualFields[field] = virtual.resolver
+	return nil
+}
+
+// virtualSelections returns the selections fetching the fields required by
+// the virtual field resolver.
+func virtualSelections(resolver *VirtualFieldResolver) []*graphql.Selection {
+	selections := make([]*graphql.Selection, 0, len(resolver.Requires))
+	for _, name := range resolver.Requires {
+		selections = append(selections, &graphql.Selection{
+			Name:         name,
+			Alias:        virtualFieldAliasPrefix + name,
+			UnparsedArgs: map[string]interface{}{},
+		})
+	}
+	return selections
+}
+
+// resolveVirtualFields computes the virtual fields selected by query in res,
+// and removes the fields fetched to compute them.
+func (e *Executor) resolveVirtualFields(ctx context.Context, planner *Planner, typ graphql.Type, query *graphql.Query, res interface{}) error {
+	if len(planner.virtualFields) == 0 {
+		return nil
+	}
+	response := &Response{
+		Query:  query,
+		Type:   typ,
+		Result: res,
+	}
+
+	type pending struct {
+		typ       *graphql.Object
+		selection *graphql.Selection
+		obj       map[string]interface{}
+		resolver  *VirtualFieldResolver
+	}
+	var virtuals []pending
+	required := make(map[string]bool)
+	response.WalkFields(func(typ *graphql.Object, selection *graphql.Selection, obj map[string]interface{}) {
+		resolver, ok := planner.virtualFields[typ.Fields[selection.Name]]
+		if !ok {
+			return
+		}
+		if include, err := graphql.ShouldIncludeNode(selection.Directives); err != nil || !include {
+			return
+		}
+		virtuals = append(virtuals, pending{typ: typ, selection: selection, obj: obj, resolver: resolver})
+		for _, name := range resolver.Requires {
+			required[name] = true
+		}
+	})
+
+	for _, virtual := range virtuals {
+		object := make(map[string]interface{}, len(virtual.resolver.Requires))
+		for _, name := range virtual.resolver.Requires {
+			object[name] = virtual.obj[virtualFieldAliasPrefix+name]
+		}
+		value, err := virtual.resolver.Resolve(ctx, object)
+		if err != nil {
+			return oops.Wrapf(err, "resolving virtual field %s.%s", virtual.typ.Name, virtual.selection.Name)
+		}
+		virtual.obj[virtual.selection.Alias] = value
+	}
+	for name := range required {
+		deleteKey(res, virtualFieldAliasPrefix+name)
+	}
+	return nil
+}
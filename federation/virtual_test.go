@@ -0,0 +1,111 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorVirtualField(t *testing.T) {
+	ctx := context.Background()
+	e, clients := createKitchenSinkExecutor(t)
+	require.NoError(t, e.AddVirtualField("Foo", "displayName", &VirtualFieldResolver{
+		Type:     &graphql.NonNull{Type: &graphql.Scalar{Type: "string"}},
+		Requires: []string{"name", "s1hmm"},
+		Resolve: func(ctx context.Context, foo map[string]interface{}) (interface{}, error) {
+			return fmt.Sprintf("%s (%s)", foo["name"], foo["s1hmm"]), nil
+		},
+	}))
+	require.NoError(t, e.AddVirtualField("Foo", "summary", &VirtualFieldResolver{
+		Type:     &graphql.Scalar{Type: "string"},
+		Requires: []string{"name", "s2ok"},
+		Resolve: func(ctx context.Context, foo map[string]interface{}) (interface{}, error) {
+			return fmt.Sprintf("%s:%v", foo["name"], foo["s2ok"]), nil
+		},
+	}))
+
+	t.Run("computed from one service", func(t *testing.T) {
+		runAndValidateQueryResults(t, ctx, e, `{ s1fff { displayName } }`, `
+			{
+				"s1fff": [
+					{"displayName": "jimbo (jimbo!!!)"},
+					{"displayName": "bob (bob!!!)"}
+				]
+			}`)
+	})
+
+	t.Run("computed from several services", func(t *testing.T) {
+		clients["schema2"].count = 0
+		runAndValidateQueryResults(t, ctx, e, `{
+			s1fff { name d: displayName summary }
+			s1both { ... on Foo { summary } }
+		}`, `
+			{
+				"s1fff": [
+					{"name": "jimbo", "d": "jimbo (jimbo!!!)", "summary": "jimbo:5"},
+					{"name": "bob", "d": "bob (bob!!!)", "summary": "bob:3"}
+				],
+				"s1both": [
+					{"__typename": "Foo", "summary": "this is the foo:15"},
+					{"__typename": "Bar"}
+				]
+			}`)
+		// One subquery for the Foos of each field.
+		assert.Equal(t, 2, clients["schema2"].count)
+	})
+
+	t.Run("skipped", func(t *testing.T) {
+		runAndValidateQueryResults(t, ctx, e, `{ s1fff { name displayName @skip(if: true) } }`, `
+			{
+				"s1fff": [
+					{"name": "jimbo"},
+					{"name": "bob"}
+				]
+			}`)
+	})
+
+	t.Run("resolver error", func(t *testing.T) {
+		require.NoError(t, e.AddVirtualField("Foo", "broken", &VirtualFieldResolver{
+			Type:     &graphql.Scalar{Type: "string"},
+			Requires: []string{"name"},
+			Resolve: func(ctx context.Context, foo map[string]interface{}) (interface{}, error) {
+				return nil, errors.New("broken")
+			},
+		}))
+		_, _, err := e.Execute(ctx, graphql.MustParse(`{ s1f { broken } }`, map[string]interface{}{}), nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "broken")
+		assert.Contains(t, err.Error(), "resolving virtual field Foo.broken")
+	})
+}
+
+func TestExecutorVirtualFieldInvalid(t *testing.T) {
+	e, _ := createKitchenSinkExecutor(t)
+	resolve := func(ctx context.Context, object map[string]interface{}) (interface{}, error) {
+		return nil, nil
+	}
+	str := &graphql.Scalar{Type: "string"}
+
+	for _, c := range []struct {
+		typeName, field string
+		requires        []string
+		err             string
+	}{
+		{"Baz", "x", nil, "unknown object type Baz"},
+		{"Query", "x", nil, "cannot be added to root types"},
+		{"Foo", "name", nil, "field already exists"},
+		{"Foo", "x", []string{"missing"}, "unknown field missing"},
+		{"Foo", "x", []string{"s2bar"}, "required field s2bar is not a scalar"},
+		{"Foo", "x", []string{"s2score"}, "required field s2score has arguments"},
+	} {
+		err := e.AddVirtualField(c.typeName, c.field, &VirtualFieldResolver{Type: str, Requires: c.requires, Resolve: resolve})
+		if assert.Error(t, err, c.err) {
+			assert.Contains(t, err.Error(), c.err)
+		}
+	}
+}
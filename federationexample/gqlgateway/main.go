@@ -34,13 +34,13 @@ func (c *GatewayExecutorClient) Execute(ctx context.Context, req *graphql.Query)
 }
 
 type Gateway struct {
-	Executor *federation.Executor
+	Executor federation.GraphqlExecutor
 }
 
 // Server must implement thunderpb.ExecutorServer.
 var _ thunderpb.ExecutorServer = &Gateway{}
 
-func NewGateway(e *federation.Executor) (*Gateway, error) {
+func NewGateway(e federation.GraphqlExecutor) (*Gateway, error) {
 	return &Gateway{
 		Executor: e,
 	}, nil
@@ -52,8 +52,14 @@ func (g *Gateway) Execute(ctx context.Context, req *thunderpb.ExecuteRequest) (*
 	if err != nil {
 		return nil, err
 	}
+	// Collect the extensions returned by the services, to return them
+	// under extensions.services.<service>.
+	ctx = federation.WithExtensions(ctx)
 	res,_, err := g.Executor.Execute(ctx, query, nil)
 	if err != nil {
+		// Send the locations of the error, eg. of an unknown field, for the
+		// server to return them.
+		federation.SetErrorLocations(ctx, err)
 		return nil, err
 	}
 
@@ -65,6 +71,12 @@ func (g *Gateway) Execute(ctx context.Context, req *thunderpb.ExecuteRequest) (*
 	resp := &thunderpb.ExecuteResponse{
 		Result: bytes,
 	}
+	if extensions := federation.Extensions(ctx); extensions != nil {
+		resp.Extensions, err = json.Marshal(extensions)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return resp, nil
 
 }
@@ -87,7 +99,13 @@ func (c *DirectExecutorClient) Execute(ctx context.Context, request *federation.
 	if err != nil {
 		return nil, oops.Wrapf(err, "executing query")
 	}
-	return &federation.QueryResponse{Result: resp.Result}, nil
+	response := &federation.QueryResponse{Result: resp.Result}
+	if len(resp.Extensions) > 0 {
+		if err := json.Unmarshal(resp.Extensions, &response.Extensions); err != nil {
+			return nil, oops.Wrapf(err, "unmarshaling extensions")
+		}
+	}
+	return response, nil
 }
 
 
@@ -29,17 +29,20 @@ type httpPostBody struct {
 }
 
 type httpResponse struct {
-	Data   interface{} `json:"data"`
-	Errors []string    `json:"errors"`
+	Data       interface{}             `json:"data"`
+	Errors     []graphql.ResponseError `json:"errors"`
+	Extensions map[string]interface{}  `json:"extensions,omitempty"`
 }
 
 func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var extensions map[string]interface{}
 	writeResponse := func(value interface{}, err error) {
 		response := httpResponse{}
 		if err != nil {
-			response.Errors = []string{err.Error()}
+			response.Errors = []graphql.ResponseError{graphql.NewResponseError(err)}
 		} else {
 			response.Data = value
+			response.Extensions = extensions
 		}
 
 		responseJSON, err := json.Marshal(response)
@@ -91,6 +94,7 @@ func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if err := json.Unmarshal(result.Result, &res); err != nil {
 			writeResponse(nil, err)
 		}
+		extensions = result.Extensions
 	}
 	writeResponse(res, nil)
 
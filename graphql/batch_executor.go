@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 
 	"github.com/samsarahq/thunder/reactive"
@@ -180,7 +181,23 @@ func executeWorkUnit(unit *WorkUnit) []*WorkUnit {
 }
 
 func executeBatchWorkUnit(unit *WorkUnit) []*WorkUnit {
-	results, err := SafeExecuteBatchResolver(unit.Ctx, unit.field, unit.sources, unit.selection.Args, unit.selection.SelectionSet)
+	err := checkCostBudget(unit.Ctx)
+	var results []interface{}
+	if err == nil {
+		ctx, cancel := withFieldTimeout(unit.Ctx, unit.field)
+		results, err = SafeExecuteBatchResolver(ctx, unit.field, unit.sources, unit.selection.Args, unit.selection.SelectionSet)
+		err = fieldTimeoutError(unit.Ctx, ctx, unit.field, unit.selection, err)
+		cancel()
+	}
+	if err == nil {
+		err = chargeCost(unit.Ctx, unit.selection, results...)
+	}
+	if value, ok := errorAsData(unit.Ctx, unit.field, err); ok {
+		for _, dest := range unit.destinations {
+			dest.Fill(value)
+		}
+		return nil
+	}
 	if err != nil {
 		for _, dest := range unit.destinations {
 			dest.Fail(err)
@@ -199,6 +216,7 @@ func executeBatchWorkUnit(unit *WorkUnit) []*WorkUnit {
 
 func executeNonExpensiveWorkUnit(unit *WorkUnit) []*WorkUnit {
 	results := make([]interface{}, 0, len(unit.sources))
+	destinations := make([]*outputNode, 0, len(unit.sources))
 	for idx, src := range unit.sources {
 		ctx := unit.Ctx
 
@@ -207,15 +225,21 @@ func executeNonExpensiveWorkUnit(unit *WorkUnit) []*WorkUnit {
 		if unit.objectName != "Mutation" {
 			ctx = context.WithValue(unit.Ctx, nonExpensive{}, struct{}{})
 		}
-		fieldResult, err := SafeExecuteResolver(ctx, unit.field, src, unit.selection.Args, unit.selection.SelectionSet)
+		ctx = withPath(ctx, unit.destinations[idx].pathTracker)
+		fieldResult, err := safeExecuteResolverWithCost(ctx, unit.field, src, unit.selection)
+		if value, ok := errorAsData(ctx, unit.field, err); ok {
+			unit.destinations[idx].Fill(value)
+			continue
+		}
 		if err != nil {
 			// Fail the unit and exit.
 			unit.destinations[idx].Fail(err)
 			return nil
 		}
 		results = append(results, fieldResult)
+		destinations = append(destinations, unit.destinations[idx])
 	}
-	unitChildren, err := resolveBatch(unit.Ctx, results, unit.field.Type, unit.selection.SelectionSet, unit.destinations)
+	unitChildren, err := resolveBatch(unit.Ctx, results, unit.field.Type, unit.selection.SelectionSet, destinations)
 	if err != nil {
 		for _, dest := range unit.destinations {
 			dest.Fail(err)
@@ -262,7 +286,11 @@ func getWorkCacheKey(src interface{}, field *Field, selection *Selection) resolv
 
 // executeNonBatchWorkUnit resolves a non-batch field in our graphql response graph.
 func executeNonBatchWorkUnit(ctx context.Context, src interface{}, dest *outputNode, unit *WorkUnit) []*WorkUnit {
-	fieldResult, err := SafeExecuteResolver(ctx, unit.field, src, unit.selection.Args, unit.selection.SelectionSet)
+	fieldResult, err := safeExecuteResolverWithCost(withPath(ctx, dest.pathTracker), unit.field, src, unit.selection)
+	if value, ok := errorAsData(ctx, unit.field, err); ok {
+		dest.Fill(value)
+		return nil
+	}
 	if err != nil {
 		dest.Fail(err)
 		return nil
@@ -275,6 +303,24 @@ func executeNonBatchWorkUnit(ctx context.Context, src interface{}, dest *outputN
 	return subFieldWorkUnits
 }
 
+// safeExecuteResolverWithCost resolves a field, charging its result to the
+// cost budget of ctx. The resolver isn't run once the budget is exceeded.
+func safeExecuteResolverWithCost(ctx context.Context, field *Field, src interface{}, selection *Selection) (interface{}, error) {
+	if err := checkCostBudget(ctx); err != nil {
+		return nil, err
+	}
+	timeoutCtx, cancel := withFieldTimeout(ctx, field)
+	defer cancel()
+	result, err := SafeExecuteResolver(timeoutCtx, field, src, selection.Args, selection.SelectionSet)
+	if err != nil {
+		return nil, fieldTimeoutError(ctx, timeoutCtx, field, selection, err)
+	}
+	if err := chargeCost(ctx, selection, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 // resolveBatch traverses the provided sources and fills in result data and
 // returns new work units that are required to resolve the rest of the
 // query result.
@@ -350,7 +396,21 @@ func resolveListBatch(ctx context.Context, sources []interface{}, typ *List, sel
 			destinations[idx].Fill(make([]interface{}, 0))
 			continue
 		}
+		if slice.Kind() == reflect.Map && slice.IsNil() {
+			destinations[idx].Fill(nil)
+			continue
+		}
 		respList := make([]interface{}, slice.Len())
+		if slice.Kind() == reflect.Map {
+			for i, entry := range mapEntries(slice) {
+				writer := newOutputNode(destinations[idx], strconv.Itoa(i))
+				respList[i] = writer
+				flattenedResps = append(flattenedResps, writer)
+				flattenedSources = append(flattenedSources, entry)
+			}
+			destinations[idx].Fill(respList)
+			continue
+		}
 		for i := 0; i < slice.Len(); i++ {
 			writer := newOutputNode(destinations[idx], strconv.Itoa(i))
 			respList[i] = writer
@@ -362,12 +422,39 @@ func resolveListBatch(ctx context.Context, sources []interface{}, typ *List, sel
 	return resolveBatch(ctx, flattenedSources, typ.Type, selectionSet, flattenedResps)
 }
 
+// mapEntries returns the entries of a map with string keys, sorted by key.
+func mapEntries(m reflect.Value) []MapEntry {
+	entries := make([]MapEntry, 0, m.Len())
+	iter := m.MapRange()
+	for iter.Next() {
+		entries = append(entries, MapEntry{Key: iter.Key().String(), Value: iter.Value().Interface()})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
 // Traverses the Union type and resolves or creates work units to resolve
 // all of the sub-objects for all the provided sources.
 func resolveUnionBatch(ctx context.Context, sources []interface{}, typ *Union, selectionSet *SelectionSet, destinations []*outputNode) ([]*WorkUnit, error) {
 	sourcesByType := make(map[string][]interface{}, len(typ.Types))
 	destinationsByType := make(map[string][]*outputNode, len(typ.Types))
 	for idx, src := range sources {
+		if typ.MemberOf != nil {
+			srcType, member, err := typ.MemberOf(src)
+			if err != nil {
+				return nil, err
+			}
+			if srcType == "" {
+				destinations[idx].Fill(nil)
+				continue
+			}
+			sourcesByType[srcType] = append(sourcesByType[srcType], member)
+			destinationsByType[srcType] = append(destinationsByType[srcType], destinations[idx])
+			continue
+		}
+
 		union := reflect.ValueOf(src)
 		if !union.IsValid() || (union.Kind() == reflect.Ptr && union.IsNil()) {
 			// Don't create a destination for any nil Unions types
@@ -396,10 +483,12 @@ func resolveUnionBatch(ctx context.Context, sources []interface{}, typ *Union, s
 	var workUnits []*WorkUnit
 	for srcType, sources := range sourcesByType {
 		gqlType := typ.Types[srcType]
+		hasFragment := false
 		for _, fragment := range selectionSet.Fragments {
 			if fragment.On != srcType {
 				continue
 			}
+			hasFragment = true
 			units, err := resolveObjectBatch(ctx, sources, gqlType, fragment.SelectionSet, destinationsByType[srcType])
 			if err != nil {
 				return nil, err
@@ -407,6 +496,15 @@ func resolveUnionBatch(ctx context.Context, sources []interface{}, typ *Union, s
 			workUnits = append(workUnits, units...)
 		}
 
+		// Without a fragment on the concrete type, only the union's own
+		// selections (ie. __typename) are resolved.
+		if !hasFragment {
+			units, err := resolveObjectBatch(ctx, sources, gqlType, &SelectionSet{Selections: selectionSet.Selections}, destinationsByType[srcType])
+			if err != nil {
+				return nil, err
+			}
+			workUnits = append(workUnits, units...)
+		}
 	}
 	return workUnits, nil
 }
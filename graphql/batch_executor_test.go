@@ -442,3 +442,64 @@ func (q *counterGoroutineScheduler) runEnqueue(resolver graphql.UnitResolver, un
 		}(unit)
 	}
 }
+
+func TestSequentialScheduler(t *testing.T) {
+	type Object struct {
+		Key string
+	}
+
+	var calls []string
+	builder := schemabuilder.NewSchema()
+	builder.Query().FieldFunc("objects", func(ctx context.Context) []*Object {
+		calls = append(calls, "objects")
+		return []*Object{{Key: "a"}, {Key: "b"}}
+	})
+	obj := builder.Object("Object", Object{})
+	obj.FieldFunc("first", func(ctx context.Context, o *Object) string {
+		calls = append(calls, "first "+o.Key)
+		return o.Key
+	})
+	obj.FieldFunc("second", func(ctx context.Context, o *Object) *Object {
+		calls = append(calls, "second "+o.Key)
+		return &Object{Key: o.Key + o.Key}
+	})
+	obj.BatchFieldFunc("batched", func(ctx context.Context, o map[batch.Index]*Object) (map[batch.Index]string, error) {
+		calls = append(calls, "batched")
+		res := make(map[batch.Index]string, len(o))
+		for i, o := range o {
+			res[i] = o.Key
+		}
+		return res, nil
+	})
+	schema, err := builder.Build()
+	require.NoError(t, err)
+
+	q := graphql.MustParse(`{
+		objects {
+			second { first }
+			batched
+			first
+		}
+	}`, nil)
+	require.NoError(t, graphql.PrepareQuery(context.Background(), schema.Query, q.SelectionSet))
+
+	e := graphql.NewExecutor(graphql.NewSequentialScheduler())
+	for i := 0; i < 10; i++ {
+		calls = nil
+		res, err := e.Execute(context.Background(), schema.Query, nil, q)
+		require.NoError(t, err)
+		assert.Equal(t, internal.ParseJSON(`{
+			"objects": [
+				{"second": {"first": "aa"}, "batched": "a", "first": "a"},
+				{"second": {"first": "bb"}, "batched": "b", "first": "b"}
+			]
+		}`), internal.AsJSON(res))
+		assert.Equal(t, []string{
+			"objects",
+			"second a", "second b",
+			"first aa", "first bb",
+			"batched",
+			"first a", "first b",
+		}, calls)
+	}
+}
@@ -33,3 +33,22 @@ func (r *immediateGoroutineSchedulerRunner) runEnqueue(resolver UnitResolver, un
 		}(unit)
 	}
 }
+
+// NewSequentialScheduler creates a new batch execution scheduler that executes
+// all Units one at a time on the goroutine running the query, depth-first and
+// in the order of the query's selections. It is meant for debugging resolvers:
+// executions are deterministic and can be stepped through without goroutines
+// interleaving. Batch fields are still resolved in a single call for all of
+// their sources. See HTTPHandlerWithScheduler, WithScheduler and the
+// federation package's WithScheduler.
+func NewSequentialScheduler() WorkScheduler {
+	return &sequentialScheduler{}
+}
+
+type sequentialScheduler struct{}
+
+func (q *sequentialScheduler) Run(resolver UnitResolver, initialUnits ...*WorkUnit) {
+	for _, unit := range initialUnits {
+		q.Run(resolver, resolver(unit)...)
+	}
+}
@@ -0,0 +1,94 @@
+package graphql
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+)
+
+// CostBudgetExceededCode is the code of the error returned when a query
+// exceeds its cost budget.
+const CostBudgetExceededCode = "COST_BUDGET_EXCEEDED"
+
+// CostFunc computes the cost of resolving selection to result.
+type CostFunc func(selection *Selection, result interface{}) int64
+
+// DefaultCostFunc charges 1 for every resolved field, plus 1 for every
+// element of a returned list.
+func DefaultCostFunc(selection *Selection, result interface{}) int64 {
+	cost := int64(1)
+	value := reflect.ValueOf(result)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		cost += int64(value.Len())
+	}
+	return cost
+}
+
+// costBudget accumulates the cost of the resolvers run for a request.
+type costBudget struct {
+	limit    int64
+	spent    int64
+	costFunc CostFunc
+}
+
+type costBudgetKey struct{}
+
+// WithCostBudget returns a context that limits the total cost of the
+// resolvers executed with it to limit. As each resolver returns, its cost, as
+// computed by costFunc, is added to the budget; once the budget is exceeded
+// the field fails and no further resolvers run, aborting the query. If
+// costFunc is nil, DefaultCostFunc is used.
+func WithCostBudget(ctx context.Context, limit int64, costFunc CostFunc) context.Context {
+	if costFunc == nil {
+		costFunc = DefaultCostFunc
+	}
+	return context.WithValue(ctx, costBudgetKey{}, &costBudget{limit: limit, costFunc: costFunc})
+}
+
+// CostSpent returns the cost accumulated so far in the budget of ctx, or 0 if
+// ctx has no budget.
+func CostSpent(ctx context.Context) int64 {
+	budget, ok := ctx.Value(costBudgetKey{}).(*costBudget)
+	if !ok {
+		return 0
+	}
+	return atomic.LoadInt64(&budget.spent)
+}
+
+// checkCostBudget returns an error if the budget of ctx is already exceeded.
+func checkCostBudget(ctx context.Context) error {
+	budget, ok := ctx.Value(costBudgetKey{}).(*costBudget)
+	if !ok {
+		return nil
+	}
+	if atomic.LoadInt64(&budget.spent) > budget.limit {
+		return budget.exceeded()
+	}
+	return nil
+}
+
+// chargeCost adds the cost of resolving selection to results to the budget of
+// ctx, returning an error if the budget is exceeded.
+func chargeCost(ctx context.Context, selection *Selection, results ...interface{}) error {
+	budget, ok := ctx.Value(costBudgetKey{}).(*costBudget)
+	// Key fields, which have no selection, are free.
+	if !ok || selection.Name == "" {
+		return nil
+	}
+	var cost int64
+	for _, result := range results {
+		cost += budget.costFunc(selection, result)
+	}
+	if atomic.AddInt64(&budget.spent, cost) > budget.limit {
+		return budget.exceeded()
+	}
+	return nil
+}
+
+func (b *costBudget) exceeded() error {
+	return NewCodedError(CostBudgetExceededCode, "query exceeded its cost budget of %d", b.limit)
+}
@@ -0,0 +1,79 @@
+package graphql_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCostBudget(t *testing.T) {
+	type Object struct {
+		Key string
+	}
+	var valueCalls int64
+	builder := schemabuilder.NewSchema()
+	builder.Query().FieldFunc("objects", func() []*Object {
+		return []*Object{{Key: "a"}, {Key: "b"}, {Key: "c"}}
+	})
+	obj := builder.Object("object", Object{})
+	obj.FieldFunc("value", func(o *Object) string {
+		atomic.AddInt64(&valueCalls, 1)
+		return o.Key
+	})
+	schema := builder.MustBuild()
+
+	run := func(ctx context.Context) (interface{}, error) {
+		q := graphql.MustParse(`{ objects { key value } }`, nil)
+		require.NoError(t, graphql.PrepareQuery(ctx, schema.Query, q.SelectionSet))
+		e := graphql.NewExecutor(graphql.NewImmediateGoroutineScheduler())
+		return e.Execute(ctx, schema.Query, nil, q)
+	}
+
+	t.Run("within budget", func(t *testing.T) {
+		ctx := graphql.WithCostBudget(context.Background(), 100, nil)
+		_, err := run(ctx)
+		require.NoError(t, err)
+		// objects costs 1 plus 1 per element, and every key and value 1.
+		assert.Equal(t, int64(10), graphql.CostSpent(ctx))
+	})
+
+	t.Run("exceeded", func(t *testing.T) {
+		atomic.StoreInt64(&valueCalls, 0)
+		ctx := graphql.WithCostBudget(context.Background(), 5, nil)
+		_, err := run(ctx)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "query exceeded its cost budget of 5")
+		assert.Equal(t, graphql.CostBudgetExceededCode, graphql.ErrorCode(err))
+		// Execution stops once the budget is exceeded.
+		assert.True(t, atomic.LoadInt64(&valueCalls) < 3)
+	})
+
+	t.Run("custom cost function", func(t *testing.T) {
+		costFunc := func(selection *graphql.Selection, result interface{}) int64 {
+			if selection.Name == "value" {
+				return 10
+			}
+			return 0
+		}
+		ctx := graphql.WithCostBudget(context.Background(), 25, costFunc)
+		_, err := run(ctx)
+		require.Error(t, err)
+		assert.Equal(t, graphql.CostBudgetExceededCode, graphql.ErrorCode(err))
+		assert.Equal(t, int64(30), graphql.CostSpent(ctx))
+
+		ctx = graphql.WithCostBudget(context.Background(), 30, costFunc)
+		_, err = run(ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("no budget", func(t *testing.T) {
+		_, err := run(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), graphql.CostSpent(context.Background()))
+	})
+}
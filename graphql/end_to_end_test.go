@@ -3,11 +3,15 @@ package graphql_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/samsarahq/thunder/batch"
 	"github.com/samsarahq/thunder/concurrencylimiter"
 	"github.com/samsarahq/thunder/graphql"
 	"github.com/samsarahq/thunder/graphql/schemabuilder"
@@ -380,6 +384,178 @@ func TestArgumentOptionality(t *testing.T) {
 		}`, filledVariables, `{"mandatory": 5}`)
 }
 
+type dateTime struct {
+	time.Time
+}
+
+// TestCustomScalar tests that scalars registered with RegisterScalar are
+// parsed using their custom parser for both literal and variable inputs.
+func TestCustomScalar(t *testing.T) {
+	err := schemabuilder.RegisterScalar(reflect.TypeOf(dateTime{}), "DateTime", func(value interface{}, dest reflect.Value) error {
+		asString, ok := value.(string)
+		if !ok {
+			return errors.New("not a string")
+		}
+		t, err := time.Parse(time.RFC3339, asString)
+		if err != nil {
+			return err
+		}
+		dest.Set(reflect.ValueOf(dateTime{Time: t}))
+		return nil
+	})
+	assert.NoError(t, err)
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("year", func(args struct{ At dateTime }) int64 {
+		return int64(args.At.Year())
+	})
+	query.FieldFunc("echo", func(args struct{ At dateTime }) dateTime {
+		return args.At
+	})
+	_ = schema.Mutation()
+	builtSchema := schema.MustBuild()
+
+	verifyArgumentOption(t, builtSchema.Query, `
+		{
+			year(at: "2019-03-04T05:06:07Z")
+		}`, map[string]interface{}{}, `{"year": 2019}`)
+
+	verifyArgumentOption(t, builtSchema.Query, `
+		query getYear($at: DateTime!) {
+			year(at: $at)
+			echo(at: $at)
+		}`, map[string]interface{}{"at": "2020-01-02T03:04:05Z"}, `{"year": 2020, "echo": "2020-01-02T03:04:05Z"}`)
+
+	q := graphql.MustParse(`{ year(at: "yesterday") }`, nil)
+	err = graphql.PrepareQuery(context.Background(), builtSchema.Query, q.SelectionSet)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `error parsing args for "year": at:`)
+	}
+}
+
+type concurrentScalar struct {
+	Value string
+}
+
+// TestRegisterScalarConcurrently tests that scalars can be registered while
+// other schemas are being built.
+func TestRegisterScalarConcurrently(t *testing.T) {
+	parse := func(value interface{}, dest reflect.Value) error {
+		return errors.New("unsupported")
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, schemabuilder.RegisterScalar(reflect.TypeOf(concurrentScalar{}), "ConcurrentScalar", parse))
+		}()
+		go func() {
+			defer wg.Done()
+			schema := schemabuilder.NewSchema()
+			schema.Query().FieldFunc("echo", func(args struct{ At time.Time }) time.Time {
+				return args.At
+			})
+			_ = schema.Mutation()
+			_, err := schema.Build()
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+}
+
+// TestFieldCacheTTL tests that cached fields are only resolved once per parent
+// key, arguments and CacheKey, and that cached sources are removed from
+// batches. Expiry and eviction are tested in schemabuilder.
+func TestFieldCacheTTL(t *testing.T) {
+	type User struct {
+		Id int64
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	var users []*User
+	query.FieldFunc("users", func() []*User {
+		return users
+	})
+
+	var greetings []int64
+	var scoreBatches [][]int64
+	user := schema.Object("User", User{})
+	user.Key("id")
+	user.FieldFunc("greeting", func(u *User, args struct{ Prefix string }) string {
+		greetings = append(greetings, u.Id)
+		return fmt.Sprintf("%s %d", args.Prefix, u.Id)
+	}, schemabuilder.CacheTTL(time.Minute))
+	user.BatchFieldFunc("score", func(ctx context.Context, in map[batch.Index]*User) (map[batch.Index]int64, error) {
+		var ids []int64
+		out := make(map[batch.Index]int64, len(in))
+		for i, u := range in {
+			ids = append(ids, u.Id)
+			out[i] = u.Id * 10
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		scoreBatches = append(scoreBatches, ids)
+		return out, nil
+	}, schemabuilder.CacheTTL(time.Minute))
+	type viewerKey struct{}
+	var viewerGreetings []string
+	user.FieldFunc("viewerGreeting", func(ctx context.Context, u *User) string {
+		viewer := ctx.Value(viewerKey{}).(string)
+		viewerGreetings = append(viewerGreetings, viewer)
+		return fmt.Sprintf("hi %d from %s", u.Id, viewer)
+	}, schemabuilder.CacheTTL(time.Minute), schemabuilder.CacheKey(func(ctx context.Context) (interface{}, error) {
+		return ctx.Value(viewerKey{}), nil
+	}))
+
+	builtSchema := schema.MustBuild()
+
+	runAs := func(viewer string, queryString string) interface{} {
+		ctx := context.WithValue(context.Background(), viewerKey{}, viewer)
+		q := graphql.MustParse(queryString, nil)
+		if err := graphql.PrepareQuery(ctx, builtSchema.Query, q.SelectionSet); err != nil {
+			t.Fatal(err)
+		}
+		e := testgraphql.NewExecutorWrapper(t)
+		result, err := e.Execute(ctx, builtSchema.Query, nil, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return internal.AsJSON(result)
+	}
+	run := func(queryString string) interface{} {
+		return runAs("alice", queryString)
+	}
+
+	users = []*User{{Id: 1}}
+	assert.Equal(t, internal.ParseJSON(`{"users": [{"__key": 1, "greeting": "hi 1", "score": 10}]}`),
+		run(`{ users { greeting(prefix: "hi") score } }`))
+	assert.Equal(t, []int64{1}, greetings)
+	assert.Equal(t, [][]int64{{1}}, scoreBatches)
+
+	// Cached results are reused, and only uncached users are batched.
+	users = []*User{{Id: 1}, {Id: 2}}
+	assert.Equal(t, internal.ParseJSON(`{"users": [{"__key": 1, "greeting": "hi 1", "score": 10}, {"__key": 2, "greeting": "hi 2", "score": 20}]}`),
+		run(`{ users { greeting(prefix: "hi") score } }`))
+	assert.Equal(t, []int64{1, 2}, greetings)
+	assert.Equal(t, [][]int64{{1}, {2}}, scoreBatches)
+
+	// Different arguments are cached separately.
+	assert.Equal(t, internal.ParseJSON(`{"users": [{"__key": 1, "greeting": "bye 1"}, {"__key": 2, "greeting": "bye 2"}]}`),
+		run(`{ users { greeting(prefix: "bye") } }`))
+	assert.Equal(t, []int64{1, 2, 1, 2}, greetings)
+
+	// Results keyed with CacheKey are cached for every viewer separately.
+	users = []*User{{Id: 1}}
+	assert.Equal(t, internal.ParseJSON(`{"users": [{"__key": 1, "viewerGreeting": "hi 1 from alice"}]}`),
+		runAs("alice", `{ users { viewerGreeting } }`))
+	assert.Equal(t, internal.ParseJSON(`{"users": [{"__key": 1, "viewerGreeting": "hi 1 from bob"}]}`),
+		runAs("bob", `{ users { viewerGreeting } }`))
+	runAs("alice", `{ users { viewerGreeting } }`)
+	assert.Equal(t, []string{"alice", "bob"}, viewerGreetings)
+}
+
 // TestConcurrencyLimiterDeadlock tests that the executor does not cause a
 // concurrency limit deadlock by holding on to tokens after a resolver finishes
 // running.
@@ -447,3 +623,102 @@ func TestConcurrencyLimiterDeadlock(t *testing.T) {
 	wg.Wait()
 	defer rerunner.Stop()
 }
+
+type mapEntity map[string]interface{}
+
+func TestMapObject(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("entities", func() []mapEntity {
+		return []mapEntity{
+			{"display_name": "alice", "age": int64(30)},
+			{"display_name": "bob", "parent": mapEntity{"display_name": "alice"}},
+		}
+	})
+	query.FieldFunc("broken", func() mapEntity {
+		return mapEntity{"display_name": 5}
+	})
+
+	entity := schema.Object("Entity", mapEntity{})
+	entity.MapField("name", "display_name", "")
+	entity.MapField("age", "age", (*int64)(nil))
+	entity.MapField("parent", "parent", (*mapEntity)(nil))
+	entity.FieldFunc("shout", func(e mapEntity) string {
+		name, _ := e["display_name"].(string)
+		return strings.ToUpper(name)
+	})
+
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`{ entities { name age shout parent { name } } }`, nil)
+	if err := graphql.PrepareQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	e := testgraphql.NewExecutorWrapper(t)
+	result, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, internal.ParseJSON(`{
+		"entities": [
+			{"name": "alice", "age": 30, "shout": "ALICE", "parent": null},
+			{"name": "bob", "age": null, "shout": "BOB", "parent": {"name": "alice"}}
+		]
+	}`), internal.AsJSON(result))
+
+	q = graphql.MustParse(`{ broken { name } }`, nil)
+	if err := graphql.PrepareQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	_, err = e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err == nil || !strings.Contains(err.Error(), "map key display_name has type int, expected string") {
+		t.Errorf("expected type error, got %v", err)
+	}
+}
+
+func TestMapValuedField(t *testing.T) {
+	type Item struct {
+		Name string
+	}
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("items", func() map[string]*Item {
+		return map[string]*Item{
+			"b": {Name: "bravo"},
+			"a": {Name: "alpha"},
+			"c": nil,
+		}
+	})
+	query.FieldFunc("none", func() map[string]*Item {
+		return nil
+	})
+	schema.Object("Item", Item{})
+
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`{ items { key value { name } } none { key } }`, nil)
+	if err := graphql.PrepareQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	e := testgraphql.NewExecutorWrapper(t)
+	result, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, internal.ParseJSON(`{
+		"items": [
+			{"key": "a", "value": {"name": "alpha"}},
+			{"key": "b", "value": {"name": "bravo"}},
+			{"key": "c", "value": null}
+		],
+		"none": null
+	}`), internal.AsJSON(result))
+
+	scalars := schemabuilder.NewSchema()
+	scalars.Query().FieldFunc("counts", func() map[string]int64 {
+		return nil
+	})
+	if _, err := scalars.Build(); err == nil || !strings.Contains(err.Error(), "map values should be objects or unions") {
+		t.Errorf("expected map value error, got %v", err)
+	}
+}
@@ -1,7 +1,10 @@
 package graphql
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"runtime"
 
 	"github.com/gorilla/websocket"
 )
@@ -14,6 +17,9 @@ type SanitizedError interface {
 type SafeError struct {
 	inner   error
 	message string
+	// location, if set, is the location in the source of the query that
+	// caused the error.
+	location *Location
 }
 
 type ClientError SafeError
@@ -47,6 +53,61 @@ func NewClientError(format string, a ...interface{}) error {
 	return ClientError{message: fmt.Sprintf(format, a...)}
 }
 
+// NewClientErrorAt returns a client error caused by the part of the query at
+// location, eg. the location of an unknown field, which clients can
+// highlight. The location is returned by ErrorLocations.
+func NewClientErrorAt(location *Location, format string, a ...interface{}) error {
+	return ClientError{message: fmt.Sprintf(format, a...), location: location}
+}
+
+// ErrorLocations returns the locations in the source of the query of the
+// first ClientError wrapped by err, in the format of the "locations" of
+// GraphQL errors, or nil if the error has no location.
+func ErrorLocations(err error) []Location {
+	var client ClientError
+	if errors.As(err, &client) && client.location != nil {
+		return []Location{*client.location}
+	}
+	return nil
+}
+
+// ResponseError is an error as written in the "errors" of a response.
+type ResponseError struct {
+	Message string `json:"message"`
+	// Locations are the locations in the source of the query of the error, if
+	// any, see ErrorLocations.
+	Locations []Location `json:"locations,omitempty"`
+	// Path is the response path of the field the error belongs to, if any,
+	// eg. ["users", 3, "name"]. Each element is a field alias or a list index.
+	Path []interface{} `json:"path,omitempty"`
+}
+
+// NewResponseError returns err as written in the "errors" of a response.
+func NewResponseError(err error) ResponseError {
+	return ResponseError{Message: err.Error(), Locations: ErrorLocations(err)}
+}
+
+// MultiResponseError is implemented by errors that are written as several
+// errors in the "errors" of a response, eg. one for each of the fields that
+// an error failed.
+type MultiResponseError interface {
+	error
+	ResponseErrors() []ResponseError
+}
+
+// ResponseErrors returns err as written in the "errors" of a response: the
+// ResponseErrors of the first MultiResponseError wrapped by err, if any, or
+// else the single NewResponseError(err).
+func ResponseErrors(err error) []ResponseError {
+	var multi MultiResponseError
+	if errors.As(err, &multi) {
+		if responseErrors := multi.ResponseErrors(); len(responseErrors) > 0 {
+			return responseErrors
+		}
+	}
+	return []ResponseError{NewResponseError(err)}
+}
+
 func NewSafeError(format string, a ...interface{}) error {
 	return SafeError{message: fmt.Sprintf(format, a...)}
 }
@@ -57,6 +118,100 @@ func WrapAsSafeError(err error, format string, a ...interface{}) error {
 	return SafeError{inner: err, message: fmt.Sprintf(format, a...)}
 }
 
+// CodedError is an error with a machine-readable code, such as NOT_FOUND or
+// UNAUTHENTICATED, that clients and proxies can act on.
+type CodedError struct {
+	SafeError
+	code string
+}
+
+// ErrorCode returns the code of the error.
+func (e CodedError) ErrorCode() string {
+	return e.code
+}
+
+// NewCodedError returns a safe error with the given code.
+func NewCodedError(code string, format string, a ...interface{}) error {
+	return CodedError{SafeError: SafeError{message: fmt.Sprintf(format, a...)}, code: code}
+}
+
+// ErrorCode returns the code of the first CodedError wrapped by err, or "" if
+// there is none.
+func ErrorCode(err error) string {
+	var coded CodedError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+	return ""
+}
+
+// PanicError is the error of a resolver that panicked. The executor recovers
+// the panic and fails the field with a PanicError, so that a panicking resolver
+// fails its query instead of crashing the server.
+type PanicError struct {
+	// Value is the value the resolver panicked with.
+	Value interface{}
+	// Stack is the stack trace of the resolver's goroutine when it panicked.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("graphql: panic: %v\n%s", e.Value, e.Stack)
+}
+
+// NewPanicError returns a PanicError for a value recovered from a panic, with
+// the stack of the current goroutine.
+func NewPanicError(value interface{}) *PanicError {
+	const size = 64 << 10
+	buf := make([]byte, size)
+	buf = buf[:runtime.Stack(buf, false)]
+	return &PanicError{Value: value, Stack: buf}
+}
+
+// ErrorValue is an error that a field can resolve to as data. With
+// WithErrorsAsData, a nullable field whose resolver fails with an ErrorValue
+// resolves to the error's value instead of failing the query, eg. for clients
+// that select a union of a result and typed error objects.
+type ErrorValue interface {
+	error
+	// ErrorValue returns the value of the field, as it is written in the
+	// response, eg. map[string]interface{}{"__typename": "NotFound"}.
+	ErrorValue() interface{}
+}
+
+type errorsAsDataKey struct{}
+
+// WithErrorsAsData returns a context in which nullable fields whose resolvers
+// fail with an ErrorValue resolve to the error's value, see ErrorValue.
+func WithErrorsAsData(ctx context.Context) context.Context {
+	return context.WithValue(ctx, errorsAsDataKey{}, true)
+}
+
+// errorAsData returns the value field resolves to when its resolver fails
+// with err, if err is an ErrorValue that can be returned as data, or a
+// FieldTimeoutError, which resolves nullable fields to null if ctx records
+// FieldErrors.
+func errorAsData(ctx context.Context, field *Field, err error) (interface{}, bool) {
+	if err == nil {
+		return nil, false
+	}
+	if _, ok := field.Type.(*NonNull); ok {
+		return nil, false
+	}
+	var timeout *FieldTimeoutError
+	if errors.As(err, &timeout) {
+		return nil, RecordFieldError(ctx, err)
+	}
+	if enabled, _ := ctx.Value(errorsAsDataKey{}).(bool); !enabled {
+		return nil, false
+	}
+	var value ErrorValue
+	if !errors.As(err, &value) {
+		return nil, false
+	}
+	return value.ErrorValue(), true
+}
+
 // SanitizeError returns a sanitized error message for an error.
 func SanitizeError(err error) string {
 	if sanitized, ok := err.(SanitizedError); ok {
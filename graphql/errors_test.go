@@ -1,11 +1,16 @@
 package graphql_test
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"testing"
 
+	"github.com/samsarahq/thunder/batch"
 	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type Wrapper interface {
@@ -26,3 +31,136 @@ func TestWrapAsSafeError(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, sourceErr, wrapperError.Unwrap())
 }
+
+func TestErrorCode(t *testing.T) {
+	err := graphql.NewCodedError("NOT_FOUND", "no user %d", 5)
+	assert.Equal(t, "no user 5", err.Error())
+	assert.Equal(t, "no user 5", graphql.SanitizeError(err))
+	assert.Equal(t, "NOT_FOUND", graphql.ErrorCode(err))
+	assert.Equal(t, "NOT_FOUND", graphql.ErrorCode(fmt.Errorf("resolving user: %w", err)))
+	assert.Equal(t, "", graphql.ErrorCode(errors.New("no code")))
+}
+
+type notFoundError struct {
+	id int64
+}
+
+func (e notFoundError) Error() string {
+	return fmt.Sprintf("no user %d", e.id)
+}
+
+func (e notFoundError) ErrorValue() interface{} {
+	return map[string]interface{}{"__typename": "NotFound", "id": e.id}
+}
+
+func TestErrorsAsData(t *testing.T) {
+	type User struct {
+		Id int64
+	}
+	builder := schemabuilder.NewSchema()
+	query := builder.Query()
+	query.FieldFunc("user", func(args struct{ Id int64 }) (*User, error) {
+		if args.Id != 1 {
+			return nil, notFoundError{id: args.Id}
+		}
+		return &User{Id: 1}, nil
+	})
+	query.FieldFunc("requiredUser", func(args struct{ Id int64 }) (User, error) {
+		return User{}, notFoundError{id: args.Id}
+	})
+	query.FieldFunc("broken", func() (*User, error) {
+		return nil, errors.New("broken")
+	})
+	query.FieldFunc("users", func() []*User {
+		return []*User{{Id: 1}, {Id: 2}}
+	})
+	user := builder.Object("User", User{})
+	user.FieldFunc("friend", func(ctx context.Context, u *User) (*User, error) {
+		if u.Id == 2 {
+			return nil, fmt.Errorf("loading friend: %w", notFoundError{id: 3})
+		}
+		return &User{Id: 2}, nil
+	})
+	user.BatchFieldFunc("manager", func(ctx context.Context, users map[batch.Index]*User) (map[batch.Index]*User, error) {
+		return nil, notFoundError{id: 4}
+	})
+	schema := builder.MustBuild()
+
+	run := func(ctx context.Context, q string) (interface{}, error) {
+		query := graphql.MustParse(q, nil)
+		require.NoError(t, graphql.PrepareQuery(ctx, schema.Query, query.SelectionSet))
+		e := graphql.NewExecutor(graphql.NewImmediateGoroutineScheduler())
+		return e.Execute(ctx, schema.Query, nil, query)
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		ctx := graphql.WithErrorsAsData(context.Background())
+		res, err := run(ctx, `{
+			found: user(id: 1) { id }
+			missing: user(id: 2) { id }
+			users { id friend { id } manager { id } }
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"found":   map[string]interface{}{"id": int64(1)},
+			"missing": map[string]interface{}{"__typename": "NotFound", "id": int64(2)},
+			"users": []interface{}{
+				map[string]interface{}{
+					"id":      int64(1),
+					"friend":  map[string]interface{}{"id": int64(2)},
+					"manager": map[string]interface{}{"__typename": "NotFound", "id": int64(4)},
+				},
+				map[string]interface{}{
+					"id":      int64(2),
+					"friend":  map[string]interface{}{"__typename": "NotFound", "id": int64(3)},
+					"manager": map[string]interface{}{"__typename": "NotFound", "id": int64(4)},
+				},
+			},
+		}, res)
+
+		// Other errors, and errors of non-null fields, still fail the query.
+		_, err = run(ctx, `{ broken { id } }`)
+		assert.EqualError(t, err, "broken: broken")
+		_, err = run(ctx, `{ requiredUser(id: 5) { id } }`)
+		assert.EqualError(t, err, "requiredUser: no user 5")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		_, err := run(context.Background(), `{ missing: user(id: 2) { id } }`)
+		assert.EqualError(t, err, "missing: no user 2")
+	})
+}
+
+func TestErrorLocations(t *testing.T) {
+	type User struct {
+		Id int64
+	}
+	builder := schemabuilder.NewSchema()
+	builder.Query().FieldFunc("user", func(args struct{ Id int64 }) *User {
+		return &User{Id: args.Id}
+	})
+	builder.Object("User", User{})
+	schema := builder.MustBuild()
+
+	prepare := func(q string) error {
+		query := graphql.MustParse(q, nil)
+		return graphql.PrepareQuery(context.Background(), schema.Query, query.SelectionSet)
+	}
+
+	err := prepare(`{
+		user(id: 1) {
+			id
+			name
+		}
+	}`)
+	require.Error(t, err)
+	assert.Equal(t, `unknown field "name"`, err.Error())
+	assert.Equal(t, []graphql.Location{{Line: 4, Column: 4}}, graphql.ErrorLocations(err))
+
+	err = prepare(`{ user(id: "one") { id } }`)
+	require.Error(t, err)
+	assert.Equal(t, []graphql.Location{{Line: 1, Column: 3}}, graphql.ErrorLocations(err))
+
+	assert.Nil(t, graphql.ErrorLocations(graphql.NewClientError("no location")))
+	assert.Nil(t, graphql.ErrorLocations(errors.New("not a client error")))
+}
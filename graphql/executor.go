@@ -3,9 +3,7 @@ package graphql
 import (
 	"bytes"
 	"context"
-	"fmt"
 	"reflect"
-	"runtime"
 )
 
 type pathError struct {
@@ -104,6 +102,23 @@ func unwrap(v interface{}) interface{} {
 	return i.Interface()
 }
 
+// isCommonField reports whether all members of typ have a field name of the
+// same type.
+func isCommonField(typ *Union, name string) bool {
+	var fieldType string
+	for _, member := range typ.Types {
+		field, ok := member.Fields[name]
+		if !ok {
+			return false
+		}
+		if fieldType != "" && field.Type.String() != fieldType {
+			return false
+		}
+		fieldType = field.Type.String()
+	}
+	return len(typ.Types) > 0
+}
+
 // PrepareQuery checks that the given selectionSet matches the schema typ, and
 // parses the args in selectionSet
 func PrepareQuery(ctx context.Context, typ Type, selectionSet *SelectionSet) error {
@@ -123,16 +138,9 @@ func PrepareQuery(ctx context.Context, typ Type, selectionSet *SelectionSet) err
 			return NewClientError("object field must have selections")
 		}
 
-		for _, fragment := range selectionSet.Fragments {
-			for typString, graphqlTyp := range typ.Types {
-				if fragment.On != typString {
-					continue
-				}
-				if err := PrepareQuery(ctx, graphqlTyp, fragment.SelectionSet); err != nil {
-					return err
-				}
-			}
-		}
+		// Fields other than __typename can be selected on the union itself
+		// if all of its members have them, and are resolved by each member.
+		var common []*Selection
 		for _, selection := range selectionSet.Selections {
 			if selection.Name == "__typename" {
 				if !isNilArgs(selection.UnparsedArgs) {
@@ -141,12 +149,47 @@ func PrepareQuery(ctx context.Context, typ Type, selectionSet *SelectionSet) err
 				if selection.SelectionSet != nil {
 					return NewClientError(`scalar field "__typename" must have no selection`)
 				}
+				continue
+			}
+			if !isCommonField(typ, selection.Name) {
+				return NewClientErrorAt(selection.Location, `unknown field "%s"`, selection.Name)
+			}
+			common = append(common, selection)
+		}
+		if len(common) > 0 {
+			// Resolve the common fields with a fragment on every member.
+			for typString := range typ.Types {
+				hasFragment := false
 				for _, fragment := range selectionSet.Fragments {
-					fragment.SelectionSet.Selections = append(fragment.SelectionSet.Selections, selection)
+					if fragment.On == typString {
+						hasFragment = true
+						break
+					}
+				}
+				if !hasFragment {
+					selectionSet.Fragments = append(selectionSet.Fragments, &Fragment{On: typString, SelectionSet: &SelectionSet{}})
 				}
+			}
+		}
+
+		for _, fragment := range selectionSet.Fragments {
+			graphqlTyp, ok := typ.Types[fragment.On]
+			if !ok {
 				continue
 			}
-			return NewClientError(`unknown field "%s"`, selection.Name)
+			for _, selection := range selectionSet.Selections {
+				if selection.Name != "__typename" {
+					// Every member parses the arguments of its own field.
+					copy := *selection
+					copy.parsed = false
+					copy.Args = nil
+					selection = &copy
+				}
+				fragment.SelectionSet.Selections = append(fragment.SelectionSet.Selections, selection)
+			}
+			if err := PrepareQuery(ctx, graphqlTyp, fragment.SelectionSet); err != nil {
+				return err
+			}
 		}
 		return nil
 	case *Object:
@@ -166,7 +209,7 @@ func PrepareQuery(ctx context.Context, typ Type, selectionSet *SelectionSet) err
 
 			field, ok := typ.Fields[selection.Name]
 			if !ok {
-				return NewClientError(`unknown field "%s"`, selection.Name)
+				return NewClientErrorAt(selection.Location, `unknown field "%s"`, selection.Name)
 			}
 
 			// Only parse args once for a given selection.
@@ -174,7 +217,7 @@ func PrepareQuery(ctx context.Context, typ Type, selectionSet *SelectionSet) err
 				selection.parsed = true
 				parsed, err := field.ParseArguments(selection.UnparsedArgs)
 				if err != nil {
-					return NewClientError(`error parsing args for "%s": %s`, selection.Name, err)
+					return NewClientErrorAt(selection.Location, `error parsing args for "%s": %s`, selection.Name, err)
 				}
 				selection.Args = parsed
 			}
@@ -206,10 +249,7 @@ func PrepareQuery(ctx context.Context, typ Type, selectionSet *SelectionSet) err
 func SafeExecuteBatchResolver(ctx context.Context, field *Field, sources []interface{}, args interface{}, selectionSet *SelectionSet) (results []interface{}, err error) {
 	defer func() {
 		if panicErr := recover(); panicErr != nil {
-			const size = 64 << 10
-			buf := make([]byte, size)
-			buf = buf[:runtime.Stack(buf, false)]
-			results, err = nil, fmt.Errorf("graphql: panic: %v\n%s", panicErr, buf)
+			results, err = nil, NewPanicError(panicErr)
 		}
 	}()
 	return field.BatchResolver(ctx, sources, args, selectionSet)
@@ -218,10 +258,7 @@ func SafeExecuteBatchResolver(ctx context.Context, field *Field, sources []inter
 func SafeExecuteResolver(ctx context.Context, field *Field, source, args interface{}, selectionSet *SelectionSet) (result interface{}, err error) {
 	defer func() {
 		if panicErr := recover(); panicErr != nil {
-			const size = 64 << 10
-			buf := make([]byte, size)
-			buf = buf[:runtime.Stack(buf, false)]
-			result, err = nil, fmt.Errorf("graphql: panic: %v\n%s", panicErr, buf)
+			result, err = nil, NewPanicError(panicErr)
 		}
 	}()
 	return field.Resolve(ctx, source, args, selectionSet)
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
@@ -363,6 +364,58 @@ func TestPanic(t *testing.T) {
 	}
 }
 
+func TestPanicInNestedField(t *testing.T) {
+	type Item struct {
+		Id int64
+	}
+	var mu sync.Mutex
+	var resolved []int64
+
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("items", func() []*Item {
+		return []*Item{{Id: 1}, {Id: 2}, {Id: 3}}
+	})
+	schema.Query().FieldFunc("sibling", func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		resolved = append(resolved, 0)
+		return "ok"
+	})
+	item := schema.Object("Item", Item{})
+	item.FieldFunc("detail", func(i *Item) string {
+		if i.Id == 2 {
+			panic("bad item")
+		}
+		return "detail"
+	})
+	item.FieldFunc("name", func(i *Item) string {
+		mu.Lock()
+		defer mu.Unlock()
+		resolved = append(resolved, i.Id)
+		return "name"
+	})
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`{ items { detail name } sibling }`, nil)
+	if err := graphql.PrepareQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.NewExecutor(graphql.NewImmediateGoroutineScheduler())
+	_, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	require.Error(t, err)
+
+	// The panic fails the field at its path with a PanicError.
+	var panicErr *graphql.PanicError
+	require.True(t, errors.As(err, &panicErr))
+	assert.Equal(t, "bad item", panicErr.Value)
+	assert.Contains(t, string(panicErr.Stack), "executor_test.go")
+	assert.True(t, strings.HasPrefix(err.Error(), "items.1.detail: graphql: panic: bad item"), err.Error())
+
+	// Sibling fields still resolve.
+	assert.ElementsMatch(t, []int64{0, 1, 2, 3}, resolved)
+}
+
 func TestSelectionType(t *testing.T) {
 	query := makeQuery(nil)
 
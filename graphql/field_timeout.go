@@ -0,0 +1,91 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FieldTimeoutError is the error of a field whose resolver did not return
+// within the field's Timeout. Nullable fields that time out resolve to null
+// instead of failing the query if the context records FieldErrors, see
+// WithFieldErrors, and fail the query otherwise, so that timeouts are never
+// silent. The HTTP and websocket handlers record them and write them to the
+// response's errors.
+type FieldTimeoutError struct {
+	// Path is the response path of the field, see PathFromContext, which is
+	// empty for batch resolvers.
+	Path []interface{}
+	// Name is the name of the field.
+	Name    string
+	Timeout time.Duration
+}
+
+func (e *FieldTimeoutError) Error() string {
+	return fmt.Sprintf("resolving %s timed out after %v", e.Name, e.Timeout)
+}
+
+func (e *FieldTimeoutError) SanitizedError() string {
+	return e.Error()
+}
+
+// withFieldTimeout returns a context for resolving field that expires after
+// the field's Timeout, if it has one.
+func withFieldTimeout(ctx context.Context, field *Field) (context.Context, context.CancelFunc) {
+	if field.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, field.Timeout)
+}
+
+// fieldTimeoutError returns a FieldTimeoutError in place of err if the
+// resolver of field failed because its timeoutCtx, derived from ctx with
+// withFieldTimeout, expired.
+func fieldTimeoutError(ctx, timeoutCtx context.Context, field *Field, selection *Selection, err error) error {
+	if err == nil || field.Timeout <= 0 || ctx.Err() != nil || timeoutCtx.Err() != context.DeadlineExceeded {
+		return err
+	}
+	return &FieldTimeoutError{Path: PathFromContext(ctx), Name: selection.Name, Timeout: field.Timeout}
+}
+
+type fieldErrorsKey struct{}
+
+type fieldErrors struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+// WithFieldErrors returns a context that records the errors of the fields
+// that resolved to null instead of failing the query, eg. FieldTimeoutErrors,
+// which can be read with FieldErrors.
+func WithFieldErrors(ctx context.Context) context.Context {
+	return context.WithValue(ctx, fieldErrorsKey{}, &fieldErrors{})
+}
+
+// FieldErrors returns the field errors recorded in a context created with
+// WithFieldErrors.
+func FieldErrors(ctx context.Context) []error {
+	collected, ok := ctx.Value(fieldErrorsKey{}).(*fieldErrors)
+	if !ok {
+		return nil
+	}
+	collected.mu.Lock()
+	defer collected.mu.Unlock()
+	return append([]error{}, collected.errs...)
+}
+
+// RecordFieldError records err, the error of a field that resolves to null
+// instead of failing the query, in the field errors of ctx. It returns false
+// if ctx was not created with WithFieldErrors, in which case the field should
+// fail the query instead.
+func RecordFieldError(ctx context.Context, err error) bool {
+	collected, ok := ctx.Value(fieldErrorsKey{}).(*fieldErrors)
+	if !ok {
+		return false
+	}
+	collected.mu.Lock()
+	defer collected.mu.Unlock()
+	collected.errs = append(collected.errs, err)
+	return true
+}
@@ -0,0 +1,103 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/samsarahq/thunder/batch"
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldTimeout(t *testing.T) {
+	type User struct {
+		Id int64
+	}
+
+	slow := func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	}
+
+	builder := schemabuilder.NewSchema()
+	query := builder.Query()
+	query.FieldFunc("users", func() []*User {
+		return []*User{{Id: 1}, {Id: 2}}
+	})
+	query.FieldFunc("fast", func(ctx context.Context) (string, error) {
+		return "fast", nil
+	}, schemabuilder.Timeout(time.Second))
+	query.FieldFunc("slowRequired", func(ctx context.Context) (string, error) {
+		return "slow", slow(ctx)
+	}, schemabuilder.Timeout(10*time.Millisecond))
+	user := builder.Object("User", User{})
+	user.FieldFunc("profile", func(ctx context.Context, u *User) (*string, error) {
+		if u.Id == 1 {
+			profile := "quick"
+			return &profile, nil
+		}
+		return nil, slow(ctx)
+	}, schemabuilder.Timeout(10*time.Millisecond))
+	user.BatchFieldFunc("score", func(ctx context.Context, users map[batch.Index]*User) (map[batch.Index]*int64, error) {
+		return nil, slow(ctx)
+	}, schemabuilder.Timeout(10*time.Millisecond))
+	user.FieldFunc("broken", func(ctx context.Context, u *User) (*string, error) {
+		return nil, errors.New("broken")
+	})
+	schema := builder.MustBuild()
+
+	run := func(ctx context.Context, q string) (interface{}, error) {
+		query := graphql.MustParse(q, nil)
+		require.NoError(t, graphql.PrepareQuery(ctx, schema.Query, query.SelectionSet))
+		e := graphql.NewExecutor(graphql.NewImmediateGoroutineScheduler())
+		return e.Execute(ctx, schema.Query, nil, query)
+	}
+
+	ctx := graphql.WithFieldErrors(context.Background())
+	res, err := run(ctx, `{ fast users { id profile score } }`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"fast": "fast",
+		"users": []interface{}{
+			map[string]interface{}{"id": int64(1), "profile": "quick", "score": nil},
+			map[string]interface{}{"id": int64(2), "profile": nil, "score": nil},
+		},
+	}, res)
+
+	var paths [][]interface{}
+	for _, err := range graphql.FieldErrors(ctx) {
+		var timeout *graphql.FieldTimeoutError
+		require.True(t, errors.As(err, &timeout))
+		assert.Equal(t, 10*time.Millisecond, timeout.Timeout)
+		paths = append(paths, timeout.Path)
+	}
+	assert.ElementsMatch(t, [][]interface{}{{"users", 1, "profile"}, nil}, paths)
+
+	t.Run("non-null field", func(t *testing.T) {
+		_, err := run(context.Background(), `{ slowRequired }`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "resolving slowRequired timed out after 10ms")
+	})
+
+	t.Run("without field errors", func(t *testing.T) {
+		// Timeouts fail the query unless they are recorded, rather than
+		// resolving to null silently.
+		_, err := run(context.Background(), `{ users { profile } }`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "resolving profile timed out after 10ms")
+	})
+
+	t.Run("other errors", func(t *testing.T) {
+		_, err := run(context.Background(), `{ users { broken } }`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "broken")
+	})
+}
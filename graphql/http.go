@@ -15,6 +15,12 @@ func HTTPHandler(schema *Schema, middlewares ...MiddlewareFunc) http.Handler {
 	return HTTPHandlerWithExecutor(schema, (NewExecutor(NewImmediateGoroutineScheduler())), middlewares...)
 }
 
+// HTTPHandlerWithScheduler is like HTTPHandler, but executes queries with
+// scheduler, eg. NewSequentialScheduler() to debug resolvers.
+func HTTPHandlerWithScheduler(schema *Schema, scheduler WorkScheduler, middlewares ...MiddlewareFunc) http.Handler {
+	return HTTPHandlerWithExecutor(schema, NewExecutor(scheduler), middlewares...)
+}
+
 func HTTPHandlerWithExecutor(schema *Schema, executor ExecutorRunner, middlewares ...MiddlewareFunc) http.Handler {
 	return &httpHandler{
 		schema:      schema,
@@ -23,29 +29,95 @@ func HTTPHandlerWithExecutor(schema *Schema, executor ExecutorRunner, middleware
 	}
 }
 
+// HTTPHandlerWithErrorStatuses is like HTTPHandlerWithExecutor, but responds
+// with the HTTP status in statuses for the code of the error, if any, as
+// returned by ErrorCode. Responses for errors without a status in statuses use
+// 200, as usual for GraphQL over HTTP.
+//
+// For example, to respond with 404 and 401 to errors created with
+// NewCodedError("NOT_FOUND", ...) and NewCodedError("UNAUTHENTICATED", ...):
+//   HTTPHandlerWithErrorStatuses(schema, executor, map[string]int{
+//       "NOT_FOUND":       http.StatusNotFound,
+//       "UNAUTHENTICATED": http.StatusUnauthorized,
+//   })
+func HTTPHandlerWithErrorStatuses(schema *Schema, executor ExecutorRunner, statuses map[string]int, middlewares ...MiddlewareFunc) http.Handler {
+	return &httpHandler{
+		schema:        schema,
+		middlewares:   middlewares,
+		executor:      executor,
+		errorStatuses: statuses,
+	}
+}
+
+// HTTPHandlerWithResponseErrors is like HTTPHandlerWithErrorStatuses, but
+// writes errors as objects with a "message", and the "locations" of the error
+// in the query and its response "path", if any, see ResponseErrors, rather
+// than as strings. statuses
+// may be nil.
+func HTTPHandlerWithResponseErrors(schema *Schema, executor ExecutorRunner, statuses map[string]int, middlewares ...MiddlewareFunc) http.Handler {
+	return &httpHandler{
+		schema:         schema,
+		middlewares:    middlewares,
+		executor:       executor,
+		errorStatuses:  statuses,
+		responseErrors: true,
+	}
+}
+
 type httpHandler struct {
 	schema      *Schema
 	middlewares []MiddlewareFunc
 	executor    ExecutorRunner
+	// errorStatuses maps error codes to the HTTP status of responses with
+	// that error.
+	errorStatuses map[string]int
+	// responseErrors writes errors as ResponseErrors instead of strings.
+	responseErrors bool
 }
 
 type httpPostBody struct {
-	Query     string                 `json:"query"`
-	Variables map[string]interface{} `json:"variables"`
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
 }
 
 type httpResponse struct {
-	Data   interface{} `json:"data"`
-	Errors []string    `json:"errors"`
+	Data interface{} `json:"data"`
+	// Errors are strings, or ResponseErrors for handlers created with
+	// HTTPHandlerWithResponseErrors.
+	Errors interface{} `json:"errors"`
+}
+
+// responseErrorsOf returns errs as written in the "errors" of a response.
+func (h *httpHandler) responseErrorsOf(errs []error) interface{} {
+	if h.responseErrors {
+		var responseErrors []ResponseError
+		for _, err := range errs {
+			responseErrors = append(responseErrors, ResponseErrors(err)...)
+		}
+		return responseErrors
+	}
+	var messages []string
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	return messages
 }
 
 func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	writeResponse := func(value interface{}, err error) {
+	// writeResponse writes value, or err if the query failed, along with the
+	// errors of the fields that resolved to null, see FieldErrors.
+	writeResponse := func(value interface{}, err error, fieldErrors ...error) {
 		response := httpResponse{}
+		status := http.StatusOK
 		if err != nil {
-			response.Errors = []string{err.Error()}
+			response.Errors = h.responseErrorsOf([]error{err})
+			if errorStatus, ok := h.errorStatuses[ErrorCode(err)]; ok {
+				status = errorStatus
+			}
 		} else {
 			response.Data = value
+			response.Errors = h.responseErrorsOf(fieldErrors)
 		}
 
 		responseJSON, err := json.Marshal(response)
@@ -56,6 +128,7 @@ func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if w.Header().Get("Content-Type") == "" {
 			w.Header().Set("Content-Type", "application/json")
 		}
+		w.WriteHeader(status)
 		w.Write(responseJSON)
 	}
 
@@ -75,7 +148,7 @@ func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query, err := Parse(params.Query, params.Variables)
+	query, err := ParseOperation(params.Query, params.Variables, params.OperationName)
 	if err != nil {
 		writeResponse(nil, err)
 		return
@@ -98,6 +171,7 @@ func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		defer wg.Done()
 
 		ctx = batch.WithBatching(ctx)
+		ctx = WithFieldErrors(ctx)
 
 		var middlewares []MiddlewareFunc
 		middlewares = append(middlewares, h.middlewares...)
@@ -124,7 +198,7 @@ func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return nil, err
 		}
 
-		writeResponse(current, nil)
+		writeResponse(current, nil, FieldErrors(ctx)...)
 		return nil, nil
 	}, DefaultMinRerunInterval, false)
 
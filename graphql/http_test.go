@@ -1,13 +1,16 @@
 package graphql_test
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/kylelemons/godebug/pretty"
 
+	"github.com/samsarahq/thunder/batch"
 	"github.com/samsarahq/thunder/graphql"
 	"github.com/samsarahq/thunder/graphql/schemabuilder"
 )
@@ -113,3 +116,155 @@ func TestHTTPContentType(t *testing.T) {
 		t.Errorf("expected response to match, but received %s", diff)
 	}
 }
+
+func TestHTTPErrorStatuses(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("user", func(args struct{ Id int64 }) (int64, error) {
+		switch args.Id {
+		case 1:
+			return 0, graphql.NewCodedError("NOT_FOUND", "no user %d", args.Id)
+		case 2:
+			return 0, graphql.NewCodedError("UNAUTHENTICATED", "not logged in")
+		case 3:
+			return 0, graphql.NewCodedError("CONFLICT", "busy")
+		default:
+			return args.Id, nil
+		}
+	})
+	builtSchema := schema.MustBuild()
+
+	statuses := map[string]int{
+		"NOT_FOUND":       http.StatusNotFound,
+		"UNAUTHENTICATED": http.StatusUnauthorized,
+	}
+	handler := graphql.HTTPHandlerWithErrorStatuses(builtSchema, graphql.NewExecutor(graphql.NewImmediateGoroutineScheduler()), statuses)
+	defaultHandler := graphql.HTTPHandler(builtSchema)
+
+	testCases := []struct {
+		name    string
+		handler http.Handler
+		query   string
+		status  int
+	}{
+		{"not found", handler, `{ user(id: 1) }`, http.StatusNotFound},
+		{"unauthenticated", handler, `{ user(id: 2) }`, http.StatusUnauthorized},
+		{"unmapped code", handler, `{ user(id: 3) }`, http.StatusOK},
+		{"uncoded error", handler, `{ missing }`, http.StatusOK},
+		{"success", handler, `{ user(id: 4) }`, http.StatusOK},
+		{"no mapping", defaultHandler, `{ user(id: 1) }`, http.StatusOK},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "`+testCase.query+`"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			testCase.handler.ServeHTTP(rr, req)
+			if rr.Code != testCase.status {
+				t.Errorf("expected %d, but received %d: %s", testCase.status, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHTTPFieldTimeout(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("fast", func() string {
+		return "fast"
+	})
+	query.FieldFunc("slow", func(ctx context.Context) (*string, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, schemabuilder.Timeout(10*time.Millisecond))
+	builtSchema := schema.MustBuild()
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ fast slow }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	graphql.HTTPHandler(builtSchema).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200, but received %d", rr.Code)
+	}
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"fast":"fast","slow":null},"errors":["resolving slow timed out after 10ms"]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestHTTPResponseErrors(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("mirror", func(args struct{ Value int64 }) int64 {
+		return args.Value * -1
+	})
+	builtSchema := schema.MustBuild()
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ mirror(value: 1) missing }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	graphql.HTTPHandlerWithResponseErrors(builtSchema, graphql.NewExecutor(graphql.NewImmediateGoroutineScheduler()), nil).ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"unknown field \"missing\"","locations":[{"line":1,"column":20}]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+// countingScheduler counts the queries it runs.
+type countingScheduler struct {
+	graphql.WorkScheduler
+	runs int
+}
+
+func (s *countingScheduler) Run(resolver graphql.UnitResolver, initialUnits ...*graphql.WorkUnit) {
+	s.runs++
+	s.WorkScheduler.Run(resolver, initialUnits...)
+}
+
+func TestHTTPScheduler(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("mirror", func(args struct{ Value int64 }) int64 {
+		return args.Value * -1
+	})
+	scheduler := &countingScheduler{WorkScheduler: graphql.NewSequentialScheduler()}
+	handler := graphql.HTTPHandlerWithScheduler(schema.MustBuild(), scheduler)
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ mirror(value: 1) }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Body.String(), "{\"data\":{\"mirror\":-1},\"errors\":null}"); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+	if scheduler.runs != 1 {
+		t.Errorf("expected the scheduler to run the query once, but ran %d", scheduler.runs)
+	}
+}
+
+func TestHTTPDebounce(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("debounce", func(ctx context.Context) int64 {
+		return int64(batch.Debounce(ctx) / time.Millisecond)
+	})
+	handler := graphql.HTTPHandler(schema.MustBuild(), graphql.DebounceMiddleware(20*time.Millisecond))
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ debounce }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Body.String(), "{\"data\":{\"debounce\":20},\"errors\":null}"); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
@@ -0,0 +1,224 @@
+package introspection
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// CompareSchemas reports the differences between the expected and actual
+// schemas, eg. a hand-written contract parsed with ParseSchema and the schema
+// built by a server, so that tests can catch the server drifting from its
+// contract:
+//   expected, err := introspection.ParseSchema(contract)
+//   ...
+//   if diffs := introspection.CompareSchemas(expected, schema.MustBuild()); len(diffs) > 0 {
+//     t.Errorf("schema does not match contract:\n%s", strings.Join(diffs, "\n"))
+//   }
+//
+// It compares the types, fields, arguments, enum values, union members and
+// deprecations of the schemas, ignoring introspection, and returns nil if
+// they match.
+func CompareSchemas(expected, actual *graphql.Schema) []string {
+	c := &schemaComparison{}
+	c.compareRoot("query", expected.Query, actual.Query)
+	c.compareRoot("mutation", expected.Mutation, actual.Mutation)
+
+	expectedTypes, actualTypes := schemaTypes(expected), schemaTypes(actual)
+	for _, name := range unionOfKeys(expectedTypes, actualTypes) {
+		expectedType, ok := expectedTypes[name]
+		if !ok {
+			c.addf("unexpected type %s", name)
+			continue
+		}
+		actualType, ok := actualTypes[name]
+		if !ok {
+			c.addf("missing type %s", name)
+			continue
+		}
+		c.compareTypes(name, expectedType, actualType)
+	}
+	return c.diffs
+}
+
+type schemaComparison struct {
+	diffs []string
+}
+
+func (c *schemaComparison) addf(format string, args ...interface{}) {
+	c.diffs = append(c.diffs, fmt.Sprintf(format, args...))
+}
+
+func (c *schemaComparison) compareRoot(operation string, expected, actual graphql.Type) {
+	expectedName, actualName := typeName(expected), typeName(actual)
+	if expectedName != actualName {
+		c.addf("%s type is %s, expected %s", operation, actualName, expectedName)
+	}
+}
+
+func typeName(typ graphql.Type) string {
+	if typ == nil {
+		return ""
+	}
+	return typ.String()
+}
+
+func typeKind(typ graphql.Type) string {
+	switch typ.(type) {
+	case *graphql.Object:
+		return "object"
+	case *graphql.InputObject:
+		return "input object"
+	case *graphql.Scalar:
+		return "scalar"
+	case *graphql.Enum:
+		return "enum"
+	case *graphql.Union:
+		return "union"
+	default:
+		return fmt.Sprintf("%T", typ)
+	}
+}
+
+func (c *schemaComparison) compareTypes(name string, expected, actual graphql.Type) {
+	if typeKind(expected) != typeKind(actual) {
+		c.addf("type %s is a %s, expected a %s", name, typeKind(actual), typeKind(expected))
+		return
+	}
+
+	switch expected := expected.(type) {
+	case *graphql.Object:
+		actual := actual.(*graphql.Object)
+		if expected.Description != actual.Description {
+			c.addf("type %s has description %q, expected %q", name, actual.Description, expected.Description)
+		}
+		expectedFields, actualFields := visibleFields(expected), visibleFields(actual)
+		for _, field := range unionOfKeys(expectedFields, actualFields) {
+			expectedField, ok := expectedFields[field]
+			if !ok {
+				c.addf("unexpected field %s.%s", name, field)
+				continue
+			}
+			actualField, ok := actualFields[field]
+			if !ok {
+				c.addf("missing field %s.%s", name, field)
+				continue
+			}
+			c.compareTypeRefs(fmt.Sprintf("field %s.%s", name, field), expectedField.Type, actualField.Type)
+			c.compareInputValues(fmt.Sprintf("%s.%s argument", name, field),
+				expectedField.Args, actualField.Args, expectedField.ArgDeprecations, actualField.ArgDeprecations)
+		}
+
+	case *graphql.InputObject:
+		actual := actual.(*graphql.InputObject)
+		if expected.OneOf != actual.OneOf {
+			c.addf("input %s has oneOf %v, expected %v", name, actual.OneOf, expected.OneOf)
+		}
+		c.compareInputValues(fmt.Sprintf("%s input field", name),
+			expected.InputFields, actual.InputFields, expected.Deprecations, actual.Deprecations)
+
+	case *graphql.Enum:
+		actual := actual.(*graphql.Enum)
+		expectedValues, actualValues := enumValueSet(expected), enumValueSet(actual)
+		for _, value := range unionOfKeys(expectedValues, actualValues) {
+			if !expectedValues[value] {
+				c.addf("unexpected enum value %s.%s", name, value)
+				continue
+			}
+			if !actualValues[value] {
+				c.addf("missing enum value %s.%s", name, value)
+				continue
+			}
+			c.compareDeprecations(fmt.Sprintf("enum value %s.%s", name, value), value, expected.Deprecations, actual.Deprecations)
+		}
+
+	case *graphql.Union:
+		actual := actual.(*graphql.Union)
+		if expected.Description != actual.Description {
+			c.addf("union %s has description %q, expected %q", name, actual.Description, expected.Description)
+		}
+		for _, member := range unionOfKeys(expected.Types, actual.Types) {
+			if _, ok := expected.Types[member]; !ok {
+				c.addf("unexpected member %s of union %s", member, name)
+			} else if _, ok := actual.Types[member]; !ok {
+				c.addf("missing member %s of union %s", member, name)
+			}
+		}
+	}
+}
+
+// compareInputValues compares the types and deprecations of arguments or
+// input fields.
+func (c *schemaComparison) compareInputValues(prefix string, expected, actual map[string]graphql.Type, expectedDeprecations, actualDeprecations map[string]string) {
+	for _, name := range unionOfKeys(expected, actual) {
+		expectedType, ok := expected[name]
+		if !ok {
+			c.addf("unexpected %s %s", prefix, name)
+			continue
+		}
+		actualType, ok := actual[name]
+		if !ok {
+			c.addf("missing %s %s", prefix, name)
+			continue
+		}
+		c.compareTypeRefs(fmt.Sprintf("%s %s", prefix, name), expectedType, actualType)
+		c.compareDeprecations(fmt.Sprintf("%s %s", prefix, name), name, expectedDeprecations, actualDeprecations)
+	}
+}
+
+func (c *schemaComparison) compareTypeRefs(what string, expected, actual graphql.Type) {
+	if expected.String() != actual.String() {
+		c.addf("%s has type %s, expected %s", what, actual, expected)
+	}
+}
+
+func (c *schemaComparison) compareDeprecations(what, name string, expected, actual map[string]string) {
+	expectedReason, expectedDeprecated := expected[name]
+	actualReason, actualDeprecated := actual[name]
+	switch {
+	case expectedDeprecated && !actualDeprecated:
+		c.addf("%s is not deprecated, expected it to be", what)
+	case !expectedDeprecated && actualDeprecated:
+		c.addf("%s is deprecated, expected it not to be", what)
+	case expectedReason != actualReason:
+		c.addf("%s is deprecated with reason %q, expected %q", what, actualReason, expectedReason)
+	}
+}
+
+// visibleFields returns the fields of object other than introspection fields.
+func visibleFields(object *graphql.Object) map[string]*graphql.Field {
+	fields := make(map[string]*graphql.Field, len(object.Fields))
+	for name, field := range object.Fields {
+		if !isIntrospectionName(name) {
+			fields[name] = field
+		}
+	}
+	return fields
+}
+
+func enumValueSet(enum *graphql.Enum) map[string]bool {
+	values := make(map[string]bool, len(enum.Values))
+	for _, value := range enum.Values {
+		values[value] = true
+	}
+	return values
+}
+
+// unionOfKeys returns the sorted keys of the maps a and b, which must have
+// string keys.
+func unionOfKeys(a, b interface{}) []string {
+	set := make(map[string]bool)
+	for _, m := range []interface{}{a, b} {
+		for _, key := range reflect.ValueOf(m).MapKeys() {
+			set[key.String()] = true
+		}
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
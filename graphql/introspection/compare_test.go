@@ -0,0 +1,96 @@
+package introspection_test
+
+import (
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql/introspection"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchemaRoundTrip(t *testing.T) {
+	builtSchema := buildPrintedSchema()
+	parsed, err := introspection.ParseSchema(introspection.PrintSchema(builtSchema))
+	require.NoError(t, err)
+	assert.Empty(t, introspection.CompareSchemas(parsed, builtSchema))
+	assert.Equal(t, introspection.PrintSchema(builtSchema), introspection.PrintSchema(parsed))
+}
+
+func TestCompareSchemas(t *testing.T) {
+	contract, err := introspection.ParseSchema(`
+		# The contract of the schema built by buildPrintedSchema.
+		schema {
+			query: Query
+			mutation: Mutation
+		}
+
+		type Query {
+			search: [printedSearchable!]!
+			users(filter: printedFilter_InputObject, first: int64, role: printedRole): [User!]!
+			count: int64!
+		}
+
+		type Mutation {
+			rename(name: string!): User
+		}
+
+		"""
+		A user of the app.
+		Users have a name.
+		"""
+		type User {
+			name: string!
+		}
+
+		type Pet { species: string! }
+		type Cat { name: string! }
+
+		union printedSearchable = | User | Cat
+
+		input printedFilter_InputObject {
+			name: string @deprecated(reason: "use nickname")
+			nickname: string @deprecated
+		}
+
+		enum printedRole {
+			admin
+			member
+			guest @deprecated(reason: "use admin")
+			owner
+		}
+
+		scalar int64
+		scalar string
+	`)
+	require.NoError(t, err)
+
+	// Pet is unreachable in the contract, as the union lists Cat instead.
+	assert.Equal(t, []string{
+		"missing type Cat",
+		"unexpected type Pet",
+		"missing field Query.count",
+		"Query.users argument first has type int64!, expected int64",
+		"Query.users argument role is deprecated, expected it not to be",
+		"printedFilter_InputObject input field name is not deprecated, expected it to be",
+		"enum value printedRole.guest is deprecated with reason \"use member\", expected \"use admin\"",
+		"missing enum value printedRole.owner",
+		"missing member Cat of union printedSearchable",
+		"unexpected member Pet of union printedSearchable",
+	}, introspection.CompareSchemas(contract, buildPrintedSchema()))
+}
+
+func TestParseSchemaErrors(t *testing.T) {
+	for _, sdl := range []string{
+		`type Query { foo: Missing }`,
+		`type Query { foo: string }`,
+		`type Query implements Node { id: ID }`,
+		`type Query { foo(bar: Int = 1): Int }`,
+		`type Query { foo: Int } type Query { bar: Int }`,
+		`type Query { foo: Int } union U = Int`,
+		`type Query { foo: Int`,
+		`type Query { foo: "Int" }`,
+	} {
+		_, err := introspection.ParseSchema(sdl)
+		assert.Error(t, err, sdl)
+	}
+}
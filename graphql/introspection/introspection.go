@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/samsarahq/thunder/graphql"
 	"github.com/samsarahq/thunder/graphql/schemabuilder"
@@ -41,10 +42,12 @@ const (
 )
 
 type InputValue struct {
-	Name         string
-	Description  string
-	Type         Type
-	DefaultValue *string
+	Name              string
+	Description       string
+	Type              Type
+	DefaultValue      *string
+	IsDeprecated      bool
+	DeprecationReason string
 }
 
 func (s *introspection) registerInputValue(schema *schemabuilder.Schema) {
@@ -199,15 +202,27 @@ func (s *introspection) registerType(schema *schemabuilder.Schema) {
 		}
 	})
 
+	object.FieldFunc("isOneOf", func(t Type) *bool {
+		switch t := t.Inner.(type) {
+		case *graphql.InputObject:
+			return &t.OneOf
+		default:
+			return nil
+		}
+	})
+
 	object.FieldFunc("inputFields", func(t Type) []InputValue {
 		var fields []InputValue
 
 		switch t := t.Inner.(type) {
 		case *graphql.InputObject:
 			for name, f := range t.InputFields {
+				reason, deprecated := t.Deprecations[name]
 				fields = append(fields, InputValue{
-					Name: name,
-					Type: Type{Inner: f},
+					Name:              name,
+					Type:              Type{Inner: f},
+					IsDeprecated:      deprecated,
+					DeprecationReason: reason,
 				})
 			}
 		}
@@ -226,17 +241,22 @@ func (s *introspection) registerType(schema *schemabuilder.Schema) {
 			for name, f := range t.Fields {
 				var args []InputValue
 				for name, a := range f.Args {
+					reason, deprecated := f.ArgDeprecations[name]
 					args = append(args, InputValue{
-						Name: name,
-						Type: Type{Inner: a},
+						Name:              name,
+						Type:              Type{Inner: a},
+						IsDeprecated:      deprecated,
+						DeprecationReason: reason,
 					})
 				}
 				sort.Slice(args, func(i, j int) bool { return args[i].Name < args[j].Name })
 
 				fields = append(fields, field{
-					Name: name,
-					Type: Type{Inner: f.Type},
-					Args: args,
+					Name:      name,
+					Type:      Type{Inner: f.Type},
+					Args:      args,
+					TimeoutMs: timeoutMs(f.Timeout),
+					External:  f.FederationExternal,
 				})
 			}
 		}
@@ -265,8 +285,12 @@ func (s *introspection) registerType(schema *schemabuilder.Schema) {
 			var enumVals []EnumValue
 			for k, v := range t.ReverseMap {
 				val := fmt.Sprintf("%v", k)
+				reason, deprecated := t.Deprecations[v]
+				if deprecated && (args.IncludeDeprecated == nil || !*args.IncludeDeprecated) {
+					continue
+				}
 				enumVals = append(enumVals,
-					EnumValue{Name: v, Description: val, IsDeprecated: false, DeprecationReason: ""})
+					EnumValue{Name: v, Description: val, IsDeprecated: deprecated, DeprecationReason: reason})
 			}
 			sort.Slice(enumVals, func(i, j int) bool { return enumVals[i].Name < enumVals[j].Name })
 			return enumVals
@@ -282,6 +306,23 @@ type field struct {
 	Type              Type
 	IsDeprecated      bool
 	DeprecationReason string
+	// TimeoutMs is the timeout of the field in milliseconds, see
+	// schemabuilder.Timeout, or 0. It is not part of the GraphQL spec, and is
+	// only selected by FederationIntrospectionQuery.
+	TimeoutMs int64
+	// External is whether another service owns the field, see
+	// schemabuilder.Object.External. Like TimeoutMs, it is only selected by
+	// FederationIntrospectionQuery.
+	External bool
+}
+
+// timeoutMs returns timeout in milliseconds, rounded up so that positive
+// timeouts stay positive.
+func timeoutMs(timeout time.Duration) int64 {
+	if timeout <= 0 {
+		return 0
+	}
+	return int64((timeout + time.Millisecond - 1) / time.Millisecond)
 }
 
 func (s *introspection) registerField(schema *schemabuilder.Schema) {
@@ -1,5 +1,7 @@
 package introspection
 
+import "strings"
+
 // Copied from https://github.com/graphql/graphiql/blob/master/src/utility/introspectionQueries.js
 const IntrospectionQuery = `
 query IntrospectionQuery {
@@ -56,6 +58,8 @@ fragment InputValue on __InputValue {
 	description
 	type { ...TypeRef }
 	defaultValue
+	isDeprecated
+	deprecationReason
 }
 fragment TypeRef on __Type {
 	kind
@@ -89,3 +93,31 @@ fragment TypeRef on __Type {
 		}
 	}
 }`
+
+
+// LegacyIntrospectionQuery is IntrospectionQuery without the deprecation of
+// arguments and input fields, for servers whose __InputValue predates
+// isDeprecated and deprecationReason.
+var LegacyIntrospectionQuery = strings.Replace(IntrospectionQuery, `
+	defaultValue
+	isDeprecated
+	deprecationReason
+`, `
+	defaultValue
+`, 1)
+
+// FederationIntrospectionQuery is IntrospectionQuery with the extensions of
+// __Field that federation executors read from thunder servers: the timeouts
+// of fields, see schemabuilder.Timeout, and whether they are external, see
+// schemabuilder.Object.External.
+var FederationIntrospectionQuery = strings.Replace(IntrospectionQuery, `
+		isDeprecated
+		deprecationReason
+	}
+	inputFields {`, `
+		isDeprecated
+		deprecationReason
+		timeoutMs
+		external
+	}
+	inputFields {`, 1)
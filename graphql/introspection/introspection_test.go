@@ -1,12 +1,16 @@
 package introspection_test
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/samsarahq/go/snapshotter"
+	"github.com/samsarahq/thunder/graphql"
 	"github.com/samsarahq/thunder/graphql/introspection"
 	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -111,6 +115,218 @@ func TestComputeSchemaJSON(t *testing.T) {
 	snap.Snapshot("schema", actual)
 }
 
+func TestEnumValueDeprecation(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Enum(enumType(0), map[string]enumType{
+		"old": enumType(1),
+		"new": enumType(2),
+	})
+	schema.DeprecateEnumValue(enumType(0), "old", "use new")
+	schema.Query().FieldFunc("enum", func() enumType { return enumType(2) })
+	builtSchema := schema.MustBuild()
+	introspection.AddIntrospectionToSchema(builtSchema)
+
+	query := graphql.MustParse(`{
+		__type(name: "enumType") {
+			enumValues { name isDeprecated }
+			all: enumValues(includeDeprecated: true) { name isDeprecated deprecationReason }
+		}
+	}`, nil)
+	require.NoError(t, graphql.PrepareQuery(context.Background(), builtSchema.Query, query.SelectionSet))
+	e := graphql.NewExecutor(graphql.NewImmediateGoroutineScheduler())
+	res, err := e.Execute(context.Background(), builtSchema.Query, nil, query)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"__type": map[string]interface{}{
+			"enumValues": []interface{}{
+				map[string]interface{}{"name": "new", "isDeprecated": false},
+			},
+			"all": []interface{}{
+				map[string]interface{}{"name": "new", "isDeprecated": false, "deprecationReason": ""},
+				map[string]interface{}{"name": "old", "isDeprecated": true, "deprecationReason": "use new"},
+			},
+		},
+	}, res)
+
+	assert.Panics(t, func() { schema.DeprecateEnumValue(enumType(0), "missing", "") })
+}
+
+type searchFilter struct {
+	Name     string
+	Nickname *string
+}
+
+func TestArgumentDeprecation(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.DeprecateInputField(searchFilter{}, "nickname", "use name")
+	schema.Query().FieldFunc("search", func(args struct {
+		Query  *string
+		Q      *string
+		Filter *searchFilter
+	}) string {
+		return ""
+	}, schemabuilder.DeprecatedArg("q", "use query"))
+	builtSchema := schema.MustBuild()
+	introspection.AddIntrospectionToSchema(builtSchema)
+
+	query := graphql.MustParse(`{
+		query: __type(name: "Query") {
+			fields { args { name isDeprecated deprecationReason } }
+		}
+		filter: __type(name: "searchFilter_InputObject") {
+			inputFields { name isDeprecated deprecationReason }
+		}
+	}`, nil)
+	require.NoError(t, graphql.PrepareQuery(context.Background(), builtSchema.Query, query.SelectionSet))
+	e := graphql.NewExecutor(graphql.NewImmediateGoroutineScheduler())
+	res, err := e.Execute(context.Background(), builtSchema.Query, nil, query)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"query": map[string]interface{}{
+			"fields": []interface{}{
+				map[string]interface{}{
+					"args": []interface{}{
+						map[string]interface{}{"name": "filter", "isDeprecated": false, "deprecationReason": ""},
+						map[string]interface{}{"name": "q", "isDeprecated": true, "deprecationReason": "use query"},
+						map[string]interface{}{"name": "query", "isDeprecated": false, "deprecationReason": ""},
+					},
+				},
+			},
+		},
+		"filter": map[string]interface{}{
+			"inputFields": []interface{}{
+				map[string]interface{}{"name": "name", "isDeprecated": false, "deprecationReason": ""},
+				map[string]interface{}{"name": "nickname", "isDeprecated": true, "deprecationReason": "use name"},
+			},
+		},
+	}, res)
+
+	unknownArg := schemabuilder.NewSchema()
+	unknownArg.Query().FieldFunc("search", func(args struct{ Query string }) string {
+		return ""
+	}, schemabuilder.DeprecatedArg("q", ""))
+	_, err = unknownArg.Build()
+	assert.Error(t, err)
+
+	unknownField := schemabuilder.NewSchema()
+	unknownField.DeprecateInputField(searchFilter{}, "missing", "")
+	unknownField.Query().FieldFunc("search", func(args struct{ Filter searchFilter }) string {
+		return ""
+	})
+	_, err = unknownField.Build()
+	assert.Error(t, err)
+}
+
+func TestFieldTimeout(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("slow", func() string { return "" }, schemabuilder.Timeout(1500*time.Microsecond))
+	schema.Query().FieldFunc("fast", func() string { return "" })
+	builtSchema := schema.MustBuild()
+	introspection.AddIntrospectionToSchema(builtSchema)
+
+	query := graphql.MustParse(`{
+		__type(name: "Query") {
+			fields { name timeoutMs }
+		}
+	}`, nil)
+	require.NoError(t, graphql.PrepareQuery(context.Background(), builtSchema.Query, query.SelectionSet))
+	e := graphql.NewExecutor(graphql.NewImmediateGoroutineScheduler())
+	res, err := e.Execute(context.Background(), builtSchema.Query, nil, query)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"__type": map[string]interface{}{
+			"fields": []interface{}{
+				map[string]interface{}{"name": "fast", "timeoutMs": int64(0)},
+				map[string]interface{}{"name": "slow", "timeoutMs": int64(2)},
+			},
+		},
+	}, res)
+
+	// The federation introspection query selects the timeouts.
+	federationQuery := graphql.MustParse(introspection.FederationIntrospectionQuery, nil)
+	require.NoError(t, graphql.PrepareQuery(context.Background(), builtSchema.Query, federationQuery.SelectionSet))
+	res, err = e.Execute(context.Background(), builtSchema.Query, nil, federationQuery)
+	require.NoError(t, err)
+	data, err := json.Marshal(res)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"timeoutMs":2`)
+}
+
+func TestFieldExternal(t *testing.T) {
+	type User struct {
+		Id   int64
+		Name string
+	}
+	schema := schemabuilder.NewSchema()
+	schema.Object("User", User{}).External("id")
+	schema.Query().FieldFunc("user", func() *User { return &User{} })
+	builtSchema := schema.MustBuild()
+	introspection.AddIntrospectionToSchema(builtSchema)
+
+	query := graphql.MustParse(`{
+		__type(name: "User") {
+			fields { name external }
+		}
+	}`, nil)
+	require.NoError(t, graphql.PrepareQuery(context.Background(), builtSchema.Query, query.SelectionSet))
+	e := graphql.NewExecutor(graphql.NewImmediateGoroutineScheduler())
+	res, err := e.Execute(context.Background(), builtSchema.Query, nil, query)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"__type": map[string]interface{}{
+			"fields": []interface{}{
+				map[string]interface{}{"name": "id", "external": true},
+				map[string]interface{}{"name": "name", "external": false},
+			},
+		},
+	}, res)
+}
+
+type userBy struct {
+	Id    *int64
+	Email *string
+}
+
+func TestOneOfInput(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.OneOfInput(userBy{})
+	schema.Query().FieldFunc("user", func(args struct {
+		By     userBy
+		Filter *searchFilter
+	}) string {
+		return ""
+	})
+	builtSchema := schema.MustBuild()
+	introspection.AddIntrospectionToSchema(builtSchema)
+
+	query := graphql.MustParse(`{
+		by: __type(name: "userBy_InputObject") { isOneOf }
+		filter: __type(name: "searchFilter_InputObject") { isOneOf }
+		query: __type(name: "Query") { isOneOf }
+	}`, nil)
+	require.NoError(t, graphql.PrepareQuery(context.Background(), builtSchema.Query, query.SelectionSet))
+	e := graphql.NewExecutor(graphql.NewImmediateGoroutineScheduler())
+	res, err := e.Execute(context.Background(), builtSchema.Query, nil, query)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"by":     map[string]interface{}{"isOneOf": true},
+		"filter": map[string]interface{}{"isOneOf": false},
+		"query":  map[string]interface{}{"isOneOf": (*bool)(nil)},
+	}, res)
+
+	printed := introspection.PrintSchema(builtSchema)
+	assert.Contains(t, printed, "input userBy_InputObject @oneOf {")
+	assert.Contains(t, printed, "input searchFilter_InputObject {")
+
+	parsed, err := introspection.ParseSchema(printed)
+	require.NoError(t, err)
+	assert.Empty(t, introspection.CompareSchemas(parsed, builtSchema))
+	assert.True(t, parsed.Query.(*graphql.Object).Fields["user"].Args["by"].(*graphql.NonNull).Type.(*graphql.InputObject).OneOf)
+
+	parsed.Query.(*graphql.Object).Fields["user"].Args["by"].(*graphql.NonNull).Type.(*graphql.InputObject).OneOf = false
+	assert.Equal(t, []string{"input userBy_InputObject has oneOf true, expected false"}, introspection.CompareSchemas(parsed, builtSchema))
+}
+
 // Uuid is a stub version of a "Text Marshalable" type.
 type Uuid struct{}
 
@@ -0,0 +1,785 @@
+package introspection
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// ParseSchema parses a schema written in the GraphQL schema definition
+// language (SDL), such as the output of PrintSchema, into a *graphql.Schema
+// that can be compared to other schemas with CompareSchemas.
+//
+// The fields of the parsed schema have no resolvers, so it cannot be executed.
+// Directive definitions and directives other than @deprecated are ignored, and
+// interfaces, type extensions and default values are not supported.
+func ParseSchema(sdl string) (*graphql.Schema, error) {
+	p := &sdlParser{
+		lexer: &sdlLexer{src: sdl},
+		types: make(map[string]graphql.Type),
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if err := p.parseDocument(); err != nil {
+		return nil, err
+	}
+	if err := p.resolve(); err != nil {
+		return nil, err
+	}
+
+	schema := &graphql.Schema{}
+	if typ, ok := p.types[p.query].(*graphql.Object); ok {
+		schema.Query = typ
+	} else {
+		return nil, fmt.Errorf("missing query type %s", p.query)
+	}
+	if typ, ok := p.types[p.mutation].(*graphql.Object); ok {
+		schema.Mutation = typ
+	} else {
+		schema.Mutation = &graphql.Object{Name: p.mutation, Fields: make(map[string]*graphql.Field)}
+	}
+	return schema, nil
+}
+
+const (
+	tokenEOF = iota
+	tokenPunctuator
+	tokenName
+	tokenString
+	tokenNumber
+)
+
+type sdlToken struct {
+	kind  int
+	value string
+	pos   int
+}
+
+// sdlLexer splits SDL into tokens, skipping whitespace, commas and comments.
+type sdlLexer struct {
+	src string
+	pos int
+}
+
+func (l *sdlLexer) next() (sdlToken, error) {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == '#' {
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		} else if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			l.pos++
+		} else {
+			break
+		}
+	}
+	if l.pos >= len(l.src) {
+		return sdlToken{kind: tokenEOF, pos: l.pos}, nil
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], `"""`):
+		return l.blockString()
+
+	case c == '"':
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] != '"' {
+			if l.src[l.pos] == '\\' {
+				l.pos++
+			}
+			l.pos++
+		}
+		if l.pos >= len(l.src) {
+			return sdlToken{}, fmt.Errorf("unterminated string at %d", start)
+		}
+		l.pos++
+		value, err := strconv.Unquote(l.src[start:l.pos])
+		if err != nil {
+			return sdlToken{}, fmt.Errorf("bad string at %d: %v", start, err)
+		}
+		return sdlToken{kind: tokenString, value: value, pos: start}, nil
+
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.pos += 3
+		return sdlToken{kind: tokenPunctuator, value: "...", pos: start}, nil
+
+	case strings.IndexByte("!$&()[]{}:=@|", c) >= 0:
+		l.pos++
+		return sdlToken{kind: tokenPunctuator, value: string(c), pos: start}, nil
+
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		for l.pos < len(l.src) && isNameChar(l.src[l.pos]) {
+			l.pos++
+		}
+		return sdlToken{kind: tokenName, value: l.src[start:l.pos], pos: start}, nil
+
+	case c == '-' || c >= '0' && c <= '9':
+		l.pos++
+		for l.pos < len(l.src) && (isNameChar(l.src[l.pos]) || l.src[l.pos] == '.' || l.src[l.pos] == '-' || l.src[l.pos] == '+') {
+			l.pos++
+		}
+		return sdlToken{kind: tokenNumber, value: l.src[start:l.pos], pos: start}, nil
+	}
+	return sdlToken{}, fmt.Errorf("unexpected character %q at %d", c, start)
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// blockString lexes a """block string""", removing its common indentation
+// and leading and trailing blank lines.
+func (l *sdlLexer) blockString() (sdlToken, error) {
+	start := l.pos
+	l.pos += 3
+	end := -1
+	for i := l.pos; i+3 <= len(l.src); i++ {
+		if l.src[i] == '\\' && strings.HasPrefix(l.src[i+1:], `"""`) {
+			i += 3
+			continue
+		}
+		if strings.HasPrefix(l.src[i:], `"""`) {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return sdlToken{}, fmt.Errorf("unterminated block string at %d", start)
+	}
+	raw := strings.Replace(l.src[l.pos:end], `\"""`, `"""`, -1)
+	l.pos = end + 3
+
+	lines := strings.Split(strings.Replace(raw, "\r\n", "\n", -1), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+		if n := len(line) - len(trimmed); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= indent {
+				lines[i] = lines[i][indent:]
+			} else {
+				lines[i] = strings.TrimLeft(lines[i], " \t")
+			}
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return sdlToken{kind: tokenString, value: strings.Join(lines, "\n"), pos: start}, nil
+}
+
+// sdlParser parses SDL into graphql types. Types may be referenced before
+// they are defined, so references are first parsed as typeRefs and resolved
+// once all types are defined.
+type sdlParser struct {
+	lexer *sdlLexer
+	token sdlToken
+
+	types    map[string]graphql.Type
+	query    string
+	mutation string
+
+	// pending are the fields, arguments and input fields whose types are
+	// resolved once all types are defined.
+	pending []func() error
+}
+
+// typeRef is a reference to a named type, wrapped in lists and non-nulls.
+type typeRef struct {
+	name    string
+	list    *typeRef
+	nonNull bool
+}
+
+func (p *sdlParser) next() error {
+	token, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.token = token
+	return nil
+}
+
+func (p *sdlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("at %d: %s", p.token.pos, fmt.Sprintf(format, args...))
+}
+
+// peek reports whether the current token is the punctuator or keyword value.
+func (p *sdlParser) peek(value string) bool {
+	return (p.token.kind == tokenPunctuator || p.token.kind == tokenName) && p.token.value == value
+}
+
+func (p *sdlParser) expect(value string) error {
+	if !p.peek(value) {
+		return p.errorf("expected %q, got %q", value, p.token.value)
+	}
+	return p.next()
+}
+
+func (p *sdlParser) name() (string, error) {
+	if p.token.kind != tokenName {
+		return "", p.errorf("expected a name, got %q", p.token.value)
+	}
+	name := p.token.value
+	return name, p.next()
+}
+
+// description parses an optional description.
+func (p *sdlParser) description() (string, error) {
+	if p.token.kind != tokenString {
+		return "", nil
+	}
+	description := p.token.value
+	return description, p.next()
+}
+
+func (p *sdlParser) parseDocument() error {
+	p.query, p.mutation = "Query", "Mutation"
+	for p.token.kind != tokenEOF {
+		description, err := p.description()
+		if err != nil {
+			return err
+		}
+		keyword, err := p.name()
+		if err != nil {
+			return err
+		}
+		switch keyword {
+		case "schema":
+			err = p.parseSchemaDefinition()
+		case "scalar":
+			err = p.parseScalar()
+		case "type":
+			err = p.parseObject(description)
+		case "input":
+			err = p.parseInputObject()
+		case "enum":
+			err = p.parseEnum()
+		case "union":
+			err = p.parseUnion(description)
+		case "directive":
+			err = p.parseDirectiveDefinition()
+		default:
+			return p.errorf("unsupported definition %q", keyword)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *sdlParser) define(name string, typ graphql.Type) error {
+	if _, ok := p.types[name]; ok {
+		return p.errorf("duplicate type %s", name)
+	}
+	p.types[name] = typ
+	return nil
+}
+
+func (p *sdlParser) parseSchemaDefinition() error {
+	if _, _, err := p.directives(); err != nil {
+		return err
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for !p.peek("}") {
+		operation, err := p.name()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(":"); err != nil {
+			return err
+		}
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		switch operation {
+		case "query":
+			p.query = name
+		case "mutation":
+			p.mutation = name
+		default:
+			return p.errorf("unsupported operation %s", operation)
+		}
+	}
+	return p.next()
+}
+
+func (p *sdlParser) parseScalar() error {
+	name, err := p.name()
+	if err != nil {
+		return err
+	}
+	if _, _, err := p.directives(); err != nil {
+		return err
+	}
+	return p.define(name, &graphql.Scalar{Type: name})
+}
+
+func (p *sdlParser) parseObject(description string) error {
+	name, err := p.name()
+	if err != nil {
+		return err
+	}
+	if p.peek("implements") {
+		return p.errorf("interfaces are not supported")
+	}
+	if _, _, err := p.directives(); err != nil {
+		return err
+	}
+	object := &graphql.Object{
+		Name:        name,
+		Description: description,
+		Fields:      make(map[string]*graphql.Field),
+	}
+	if err := p.define(name, object); err != nil {
+		return err
+	}
+
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for !p.peek("}") {
+		if _, err := p.description(); err != nil {
+			return err
+		}
+		fieldName, err := p.name()
+		if err != nil {
+			return err
+		}
+		if _, ok := object.Fields[fieldName]; ok {
+			return p.errorf("duplicate field %s.%s", name, fieldName)
+		}
+		field := &graphql.Field{Args: make(map[string]graphql.Type)}
+		object.Fields[fieldName] = field
+
+		if p.peek("(") {
+			args, deprecations, err := p.inputValues("(", ")")
+			if err != nil {
+				return err
+			}
+			for arg, ref := range args {
+				arg, ref := arg, ref
+				p.pending = append(p.pending, func() error {
+					typ, err := p.lookup(ref)
+					if err != nil {
+						return fmt.Errorf("%s.%s argument %s: %v", name, fieldName, arg, err)
+					}
+					field.Args[arg] = typ
+					return nil
+				})
+			}
+			field.ArgDeprecations = deprecations
+		}
+		if err := p.expect(":"); err != nil {
+			return err
+		}
+		ref, err := p.typeRef()
+		if err != nil {
+			return err
+		}
+		if _, _, err := p.directives(); err != nil {
+			return err
+		}
+		p.pending = append(p.pending, func() error {
+			typ, err := p.lookup(ref)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %v", name, fieldName, err)
+			}
+			field.Type = typ
+			return nil
+		})
+	}
+	return p.next()
+}
+
+func (p *sdlParser) parseInputObject() error {
+	name, err := p.name()
+	if err != nil {
+		return err
+	}
+	var oneOf bool
+	for p.peek("@") {
+		directive, _, err := p.directive()
+		if err != nil {
+			return err
+		}
+		if directive == "oneOf" {
+			oneOf = true
+		}
+	}
+	object := &graphql.InputObject{
+		Name:        name,
+		InputFields: make(map[string]graphql.Type),
+		OneOf:       oneOf,
+	}
+	if err := p.define(name, object); err != nil {
+		return err
+	}
+
+	fields, deprecations, err := p.inputValues("{", "}")
+	if err != nil {
+		return err
+	}
+	for field, ref := range fields {
+		field, ref := field, ref
+		p.pending = append(p.pending, func() error {
+			typ, err := p.lookup(ref)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %v", name, field, err)
+			}
+			object.InputFields[field] = typ
+			return nil
+		})
+	}
+	object.Deprecations = deprecations
+	return nil
+}
+
+// inputValues parses arguments or input fields between open and close,
+// returning their types and the reasons of the deprecated ones.
+func (p *sdlParser) inputValues(open, close string) (map[string]*typeRef, map[string]string, error) {
+	if err := p.expect(open); err != nil {
+		return nil, nil, err
+	}
+	values := make(map[string]*typeRef)
+	var deprecations map[string]string
+	for !p.peek(close) {
+		if _, err := p.description(); err != nil {
+			return nil, nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, ok := values[name]; ok {
+			return nil, nil, p.errorf("duplicate input value %s", name)
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, nil, err
+		}
+		ref, err := p.typeRef()
+		if err != nil {
+			return nil, nil, err
+		}
+		if p.peek("=") {
+			return nil, nil, p.errorf("default values are not supported")
+		}
+		reason, deprecated, err := p.directives()
+		if err != nil {
+			return nil, nil, err
+		}
+		values[name] = ref
+		if deprecated {
+			if deprecations == nil {
+				deprecations = make(map[string]string)
+			}
+			deprecations[name] = reason
+		}
+	}
+	return values, deprecations, p.next()
+}
+
+func (p *sdlParser) parseEnum() error {
+	name, err := p.name()
+	if err != nil {
+		return err
+	}
+	if _, _, err := p.directives(); err != nil {
+		return err
+	}
+	enum := &graphql.Enum{
+		Type:       name,
+		ReverseMap: make(map[interface{}]string),
+	}
+	if err := p.define(name, enum); err != nil {
+		return err
+	}
+
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for !p.peek("}") {
+		if _, err := p.description(); err != nil {
+			return err
+		}
+		value, err := p.name()
+		if err != nil {
+			return err
+		}
+		reason, deprecated, err := p.directives()
+		if err != nil {
+			return err
+		}
+		enum.Values = append(enum.Values, value)
+		enum.ReverseMap[value] = value
+		if deprecated {
+			if enum.Deprecations == nil {
+				enum.Deprecations = make(map[string]string)
+			}
+			enum.Deprecations[value] = reason
+		}
+	}
+	return p.next()
+}
+
+func (p *sdlParser) parseUnion(description string) error {
+	name, err := p.name()
+	if err != nil {
+		return err
+	}
+	if _, _, err := p.directives(); err != nil {
+		return err
+	}
+	union := &graphql.Union{
+		Name:        name,
+		Description: description,
+		Types:       make(map[string]*graphql.Object),
+	}
+	if err := p.define(name, union); err != nil {
+		return err
+	}
+
+	if err := p.expect("="); err != nil {
+		return err
+	}
+	if p.peek("|") {
+		if err := p.next(); err != nil {
+			return err
+		}
+	}
+	for {
+		member, err := p.name()
+		if err != nil {
+			return err
+		}
+		p.pending = append(p.pending, func() error {
+			object, ok := p.types[member].(*graphql.Object)
+			if !ok {
+				return fmt.Errorf("union %s: member %s is not an object type", name, member)
+			}
+			union.Types[member] = object
+			return nil
+		})
+		if !p.peek("|") {
+			return nil
+		}
+		if err := p.next(); err != nil {
+			return err
+		}
+	}
+}
+
+// parseDirectiveDefinition skips a directive definition.
+func (p *sdlParser) parseDirectiveDefinition() error {
+	if err := p.expect("@"); err != nil {
+		return err
+	}
+	if _, err := p.name(); err != nil {
+		return err
+	}
+	if p.peek("(") {
+		if _, _, err := p.inputValues("(", ")"); err != nil {
+			return err
+		}
+	}
+	if err := p.expect("on"); err != nil {
+		return err
+	}
+	if p.peek("|") {
+		if err := p.next(); err != nil {
+			return err
+		}
+	}
+	for {
+		if _, err := p.name(); err != nil {
+			return err
+		}
+		if !p.peek("|") {
+			return nil
+		}
+		if err := p.next(); err != nil {
+			return err
+		}
+	}
+}
+
+// directives parses optional directives, returning whether they include
+// @deprecated and its reason.
+func (p *sdlParser) directives() (string, bool, error) {
+	var reason string
+	var deprecated bool
+	for p.peek("@") {
+		name, args, err := p.directive()
+		if err != nil {
+			return "", false, err
+		}
+		if name == "deprecated" {
+			deprecated = true
+			reason, _ = args["reason"].(string)
+		}
+	}
+	return reason, deprecated, nil
+}
+
+// directive parses a single directive, returning its name and arguments.
+func (p *sdlParser) directive() (string, map[string]interface{}, error) {
+	if err := p.expect("@"); err != nil {
+		return "", nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return "", nil, err
+	}
+	args := make(map[string]interface{})
+	if p.peek("(") {
+		if err := p.next(); err != nil {
+			return "", nil, err
+		}
+		for !p.peek(")") {
+			arg, err := p.name()
+			if err != nil {
+				return "", nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return "", nil, err
+			}
+			value, err := p.value()
+			if err != nil {
+				return "", nil, err
+			}
+			args[arg] = value
+		}
+		if err := p.next(); err != nil {
+			return "", nil, err
+		}
+	}
+	return name, args, nil
+}
+
+// value parses a constant value, returning strings as strings.
+func (p *sdlParser) value() (interface{}, error) {
+	token := p.token
+	switch {
+	case token.kind == tokenString:
+		return token.value, p.next()
+	case token.kind == tokenName || token.kind == tokenNumber:
+		return nil, p.next()
+	case p.peek("["), p.peek("{"):
+		close := "]"
+		if p.peek("{") {
+			close = "}"
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		for !p.peek(close) {
+			if close == "}" {
+				if _, err := p.name(); err != nil {
+					return nil, err
+				}
+				if err := p.expect(":"); err != nil {
+					return nil, err
+				}
+			}
+			if _, err := p.value(); err != nil {
+				return nil, err
+			}
+		}
+		return nil, p.next()
+	}
+	return nil, p.errorf("expected a value, got %q", token.value)
+}
+
+func (p *sdlParser) typeRef() (*typeRef, error) {
+	ref := &typeRef{}
+	if p.peek("[") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		inner, err := p.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		ref.list = inner
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		ref.name = name
+	}
+	if p.peek("!") {
+		ref.nonNull = true
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	return ref, nil
+}
+
+// builtinScalars are the scalars of GraphQL that SDL may use without
+// defining them.
+var builtinScalars = map[string]bool{
+	"String":  true,
+	"Int":     true,
+	"Float":   true,
+	"Boolean": true,
+	"ID":      true,
+}
+
+func (p *sdlParser) lookup(ref *typeRef) (graphql.Type, error) {
+	var typ graphql.Type
+	if ref.list != nil {
+		inner, err := p.lookup(ref.list)
+		if err != nil {
+			return nil, err
+		}
+		typ = &graphql.List{Type: inner}
+	} else {
+		named, ok := p.types[ref.name]
+		if !ok {
+			if !builtinScalars[ref.name] {
+				return nil, fmt.Errorf("unknown type %s", ref.name)
+			}
+			named = &graphql.Scalar{Type: ref.name}
+			p.types[ref.name] = named
+		}
+		typ = named
+	}
+	if ref.nonNull {
+		typ = &graphql.NonNull{Type: typ}
+	}
+	return typ, nil
+}
+
+func (p *sdlParser) resolve() error {
+	for _, resolve := range p.pending {
+		if err := resolve(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package introspection
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// PrintSchema prints schema in the GraphQL schema definition language (SDL),
+// eg. to store it in a schema registry or diff it against a previous version.
+// Types, fields, arguments and enum values are sorted by name so that the
+// output is stable, and introspection types and fields are left out.
+//
+// PrintSchema works on any *graphql.Schema, including the merged schema of a
+// federated executor. For example:
+//   sdl := introspection.PrintSchema(schema.MustBuild())
+//
+// extraTypes are printed as well, with the types they reference, even if no
+// field of the schema references them.
+func PrintSchema(schema *graphql.Schema, extraTypes ...graphql.Type) string {
+	types := schemaTypes(schema)
+	for _, typ := range extraTypes {
+		collectTypes(typ, types)
+	}
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var blocks []string
+	if block := printSchemaDefinition(schema); block != "" {
+		blocks = append(blocks, block)
+	}
+	// The skip and include directives are built into GraphQL.
+	blocks = append(blocks, printDirective(typeAsOptionalDirective))
+	for _, name := range names {
+		if block := printType(types[name]); block != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	return strings.Join(blocks, "\n\n") + "\n"
+}
+
+// schemaTypes returns the types of schema by name, leaving out the types only
+// used by introspection fields.
+func schemaTypes(schema *graphql.Schema) map[string]graphql.Type {
+	// Collect the types from the root fields, rather than the root types, to
+	// skip the introspection fields.
+	types := make(map[string]graphql.Type)
+	for _, root := range []graphql.Type{schema.Query, schema.Mutation} {
+		object, ok := root.(*graphql.Object)
+		if !ok {
+			continue
+		}
+		types[object.Name] = object
+		for name, field := range object.Fields {
+			if isIntrospectionName(name) {
+				continue
+			}
+			collectTypes(field.Type, types)
+			for _, arg := range field.Args {
+				collectTypes(arg, types)
+			}
+		}
+	}
+	return types
+}
+
+// isIntrospectionName reports whether name is reserved for introspection.
+func isIntrospectionName(name string) bool {
+	return strings.HasPrefix(name, "__")
+}
+
+// printSchemaDefinition prints the schema definition, which is only needed if
+// the root types have names other than Query and Mutation.
+func printSchemaDefinition(schema *graphql.Schema) string {
+	query, _ := schema.Query.(*graphql.Object)
+	mutation, _ := schema.Mutation.(*graphql.Object)
+	if (query == nil || query.Name == "Query") && (mutation == nil || mutation.Name == "Mutation") {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("schema {\n")
+	if query != nil {
+		fmt.Fprintf(&b, "  query: %s\n", query.Name)
+	}
+	if mutation != nil && len(mutation.Fields) > 0 {
+		fmt.Fprintf(&b, "  mutation: %s\n", mutation.Name)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+func printDirective(directive Directive) string {
+	var b strings.Builder
+	printDescription(&b, directive.Description)
+	fmt.Fprintf(&b, "directive @%s", directive.Name)
+	if len(directive.Args) > 0 {
+		args := make([]string, 0, len(directive.Args))
+		for _, arg := range directive.Args {
+			args = append(args, fmt.Sprintf("%s: %s", arg.Name, arg.Type.Inner))
+		}
+		fmt.Fprintf(&b, "(%s)", strings.Join(args, ", "))
+	}
+	locations := make([]string, 0, len(directive.Locations))
+	for _, location := range directive.Locations {
+		locations = append(locations, string(location))
+	}
+	fmt.Fprintf(&b, " on %s", strings.Join(locations, " | "))
+	return b.String()
+}
+
+// printType prints the definition of typ, or "" for an object without any
+// fields, such as an empty Mutation type, which SDL cannot express.
+func printType(typ graphql.Type) string {
+	var b strings.Builder
+	switch typ := typ.(type) {
+	case *graphql.Scalar:
+		fmt.Fprintf(&b, "scalar %s", typ.Type)
+
+	case *graphql.Enum:
+		values := append([]string{}, typ.Values...)
+		sort.Strings(values)
+		fmt.Fprintf(&b, "enum %s {\n", typ.Type)
+		for _, value := range values {
+			b.WriteString("  " + value)
+			if reason, ok := typ.Deprecations[value]; ok {
+				b.WriteString(printDeprecated(reason))
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("}")
+
+	case *graphql.Union:
+		members := make([]string, 0, len(typ.Types))
+		for name := range typ.Types {
+			members = append(members, name)
+		}
+		sort.Strings(members)
+		printDescription(&b, typ.Description)
+		fmt.Fprintf(&b, "union %s = %s", typ.Name, strings.Join(members, " | "))
+
+	case *graphql.InputObject:
+		if typ.OneOf {
+			fmt.Fprintf(&b, "input %s @oneOf {\n", typ.Name)
+		} else {
+			fmt.Fprintf(&b, "input %s {\n", typ.Name)
+		}
+		for _, name := range sortedKeys(typ.InputFields) {
+			b.WriteString("  " + printInputValue(name, typ.InputFields[name], typ.Deprecations) + "\n")
+		}
+		b.WriteString("}")
+
+	case *graphql.Object:
+		var names []string
+		for name := range typ.Fields {
+			if !isIntrospectionName(name) {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			return ""
+		}
+		sort.Strings(names)
+
+		printDescription(&b, typ.Description)
+		fmt.Fprintf(&b, "type %s {\n", typ.Name)
+		for _, name := range names {
+			field := typ.Fields[name]
+			b.WriteString("  " + name)
+			if len(field.Args) > 0 {
+				args := make([]string, 0, len(field.Args))
+				for _, arg := range sortedKeys(field.Args) {
+					args = append(args, printInputValue(arg, field.Args[arg], field.ArgDeprecations))
+				}
+				fmt.Fprintf(&b, "(%s)", strings.Join(args, ", "))
+			}
+			fmt.Fprintf(&b, ": %s\n", field.Type)
+		}
+		b.WriteString("}")
+	}
+	return b.String()
+}
+
+func printInputValue(name string, typ graphql.Type, deprecations map[string]string) string {
+	s := fmt.Sprintf("%s: %s", name, typ)
+	if reason, ok := deprecations[name]; ok {
+		s += printDeprecated(reason)
+	}
+	return s
+}
+
+func printDeprecated(reason string) string {
+	if reason == "" {
+		return " @deprecated"
+	}
+	return fmt.Sprintf(" @deprecated(reason: %s)", strconv.Quote(reason))
+}
+
+// printDescription prints description as a block string before a definition.
+func printDescription(b *strings.Builder, description string) {
+	if description == "" {
+		return
+	}
+	description = strings.Replace(description, `"""`, `\"""`, -1)
+	if strings.Contains(description, "\n") {
+		fmt.Fprintf(b, "\"\"\"\n%s\n\"\"\"\n", description)
+	} else {
+		fmt.Fprintf(b, "\"\"\"%s\"\"\"\n", description)
+	}
+}
+
+func sortedKeys(types map[string]graphql.Type) []string {
+	keys := make([]string, 0, len(types))
+	for key := range types {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,103 @@
+package introspection_test
+
+import (
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/introspection"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+)
+
+type PrintedUser struct {
+	Name string
+}
+
+type PrintedPet struct {
+	Species string
+}
+
+type printedSearchable struct {
+	schemabuilder.Union
+	*PrintedUser
+	*PrintedPet
+}
+
+type printedFilter struct {
+	Name     *string
+	Nickname *string
+}
+
+type printedRole int
+
+// buildPrintedSchema builds a schema using every kind of type.
+func buildPrintedSchema() *graphql.Schema {
+	schema := schemabuilder.NewSchema()
+	schema.Enum(printedRole(0), map[string]printedRole{"admin": 1, "member": 2, "guest": 3})
+	schema.DeprecateEnumValue(printedRole(0), "guest", "use member")
+	schema.DeprecateInputField(printedFilter{}, "nickname", "")
+	schema.Object("User", PrintedUser{}).Description = "A user of the app.\nUsers have a name."
+	schema.Object("Pet", PrintedPet{})
+
+	query := schema.Query()
+	query.FieldFunc("users", func(args struct {
+		Filter *printedFilter
+		Role   *printedRole
+		First  int64
+	}) []*PrintedUser {
+		return nil
+	}, schemabuilder.DeprecatedArg("role", "filter by name"))
+	query.FieldFunc("search", func() []printedSearchable { return nil })
+	mutation := schema.Mutation()
+	mutation.FieldFunc("rename", func(args struct{ Name string }) *PrintedUser { return nil })
+
+	builtSchema := schema.MustBuild()
+	introspection.AddIntrospectionToSchema(builtSchema)
+	return builtSchema
+}
+
+func TestPrintSchema(t *testing.T) {
+	builtSchema := buildPrintedSchema()
+
+	assert.Equal(t, `"""Client-side-only directive that instructs the type generator to mark this field as optional. This is useful for making the generated types compliant with Troy persistence schema."""
+directive @type_as_optional on FIELD
+
+type Mutation {
+  rename(name: string!): User
+}
+
+type Pet {
+  species: string!
+}
+
+type Query {
+  search: [printedSearchable!]!
+  users(filter: printedFilter_InputObject, first: int64!, role: printedRole @deprecated(reason: "filter by name")): [User!]!
+}
+
+"""
+A user of the app.
+Users have a name.
+"""
+type User {
+  name: string!
+}
+
+scalar int64
+
+input printedFilter_InputObject {
+  name: string
+  nickname: string @deprecated
+}
+
+enum printedRole {
+  admin
+  guest @deprecated(reason: "use member")
+  member
+}
+
+union printedSearchable = Pet | User
+
+scalar string
+`, introspection.PrintSchema(builtSchema))
+}
@@ -2,6 +2,9 @@ package graphql
 
 import (
 	"context"
+	"time"
+
+	"github.com/samsarahq/thunder/batch"
 )
 
 type ComputationInput struct {
@@ -41,3 +44,14 @@ func RunMiddlewares(middlewares []MiddlewareFunc, input *ComputationInput) *Comp
 
 	return run(0, middlewares, input)
 }
+
+// DebounceMiddleware returns a middleware that executes queries in a context
+// in which batches wait for at least window after their latest invocation,
+// see batch.WithDebounce. It can be passed to HTTPHandler, or to a
+// connection's Use.
+func DebounceMiddleware(window time.Duration) MiddlewareFunc {
+	return func(input *ComputationInput, next MiddlewareNextFunc) *ComputationOutput {
+		input.Ctx = batch.WithDebounce(input.Ctx, window)
+		return next(input)
+	}
+}
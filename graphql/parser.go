@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/location"
 	"github.com/graphql-go/graphql/language/parser"
 )
 
@@ -104,6 +105,15 @@ func argsToJson(input []*ast.Argument, vars map[string]interface{}) (map[string]
 	return args, nil
 }
 
+// nodeLocation returns the location in the source of a node spanning loc.
+func nodeLocation(loc *ast.Location) *Location {
+	if loc == nil || loc.Source == nil {
+		return nil
+	}
+	sourceLocation := location.GetLocation(loc.Source, loc.Start)
+	return &Location{Line: sourceLocation.Line, Column: sourceLocation.Column}
+}
+
 // parseSelectionSet takes a grapqhl-go selection set and converts it to a
 // simplified *SelectionSet, bindings vars
 func parseSelectionSet(input *ast.SelectionSet, globalFragments map[string]*Fragment, vars map[string]interface{}) (*SelectionSet, error) {
@@ -141,6 +151,7 @@ func parseSelectionSet(input *ast.SelectionSet, globalFragments map[string]*Frag
 				Name:         selection.Name.Value,
 				UnparsedArgs: args,
 				SelectionSet: selectionSet,
+				Location:     nodeLocation(selection.Loc),
 			}
 
 			if len(selection.Directives) > 0 {
@@ -209,7 +220,7 @@ const (
 
 // detectCyclesAndUnusedFragments finds cycles in fragments that include
 // eachother as well as fragments that don't appear anywhere
-func detectCyclesAndUnusedFragments(selectionSet *SelectionSet, globalFragments map[string]*Fragment) error {
+func detectCyclesAndUnusedFragments(selectionSet *SelectionSet, globalFragments map[string]*Fragment, checkUnused bool) error {
 	state := make(map[*Fragment]visitState)
 
 	var visitFragment func(*Fragment) error
@@ -256,6 +267,9 @@ func detectCyclesAndUnusedFragments(selectionSet *SelectionSet, globalFragments
 		return err
 	}
 
+	if !checkUnused {
+		return nil
+	}
 	for _, fragment := range globalFragments {
 		if state[fragment] != visited {
 			return NewClientError("unused fragment")
@@ -264,6 +278,46 @@ func detectCyclesAndUnusedFragments(selectionSet *SelectionSet, globalFragments
 	return nil
 }
 
+// detectUnusedFragmentDefinitions finds fragments that none of the operations
+// of a document use, directly or through other fragments.
+func detectUnusedFragmentDefinitions(operations []*ast.OperationDefinition, fragments map[string]*ast.FragmentDefinition) error {
+	used := make(map[string]bool)
+
+	var visitSelectionSet func(*ast.SelectionSet)
+	visitSelectionSet = func(selectionSet *ast.SelectionSet) {
+		if selectionSet == nil {
+			return
+		}
+		for _, selection := range selectionSet.Selections {
+			switch selection := selection.(type) {
+			case *ast.Field:
+				visitSelectionSet(selection.SelectionSet)
+			case *ast.InlineFragment:
+				visitSelectionSet(selection.SelectionSet)
+			case *ast.FragmentSpread:
+				name := selection.Name.Value
+				if used[name] {
+					continue
+				}
+				used[name] = true
+				if fragment, ok := fragments[name]; ok {
+					visitSelectionSet(fragment.SelectionSet)
+				}
+			}
+		}
+	}
+
+	for _, operation := range operations {
+		visitSelectionSet(operation.SelectionSet)
+	}
+	for name := range fragments {
+		if !used[name] {
+			return NewClientError("unused fragment")
+		}
+	}
+	return nil
+}
+
 // detectConflicts finds conflicts
 //
 // Conflicts are selections that can not be merged, for example
@@ -332,12 +386,21 @@ type Query struct {
 // does not validate that the query is legal under a given schema, which
 // instead is done by PrepareQuery.
 func Parse(source string, vars map[string]interface{}) (*Query, error) {
+	return ParseOperation(source, vars, "")
+}
+
+// ParseOperation parses the operation named operationName in a document that
+// may contain multiple operations. If operationName is empty, the document
+// must contain a single operation.
+func ParseOperation(source string, vars map[string]interface{}, operationName string) (*Query, error) {
 	document, err := parser.Parse(parser.ParseParams{Source: source})
 	if err != nil {
 		return nil, NewClientError(err.Error())
 	}
 
 	var queryDefinition *ast.OperationDefinition
+	var operationDefinitions []*ast.OperationDefinition
+	operationNames := make(map[string]bool)
 	fragmentDefinitions := make(map[string]*ast.FragmentDefinition)
 
 	for _, definition := range document.Definitions {
@@ -353,10 +416,23 @@ func Parse(source string, vars map[string]interface{}) (*Query, error) {
 			if definition.Operation != "query" && definition.Operation != "mutation" {
 				return nil, NewClientError("only support queries or mutations")
 			}
-			if queryDefinition != nil {
-				return nil, NewClientError("only support a single query")
+			operationDefinitions = append(operationDefinitions, definition)
+			if definition.Name != nil {
+				name := definition.Name.Value
+				if operationNames[name] {
+					return nil, NewClientError("duplicate operation %s", name)
+				}
+				operationNames[name] = true
+			}
+
+			if operationName == "" {
+				if queryDefinition != nil {
+					return nil, NewClientError("only support a single query")
+				}
+				queryDefinition = definition
+			} else if definition.Name != nil && definition.Name.Value == operationName {
+				queryDefinition = definition
 			}
-			queryDefinition = definition
 
 		default:
 			return nil, NewClientError("unsupported definition")
@@ -364,6 +440,9 @@ func Parse(source string, vars map[string]interface{}) (*Query, error) {
 	}
 
 	if queryDefinition == nil {
+		if operationName != "" {
+			return nil, NewClientError("unknown operation %s", operationName)
+		}
 		return nil, NewClientError("must have a single query")
 	}
 
@@ -441,9 +520,16 @@ func Parse(source string, vars map[string]interface{}) (*Query, error) {
 		return rv, err
 	}
 
-	if err := detectCyclesAndUnusedFragments(selectionSet, globalFragments); err != nil {
+	// Fragments may be used by other operations in the document, so those
+	// are checked against the fragments that all operations use.
+	if err := detectCyclesAndUnusedFragments(selectionSet, globalFragments, len(operationDefinitions) == 1); err != nil {
 		return rv, err
 	}
+	if len(operationDefinitions) > 1 {
+		if err := detectUnusedFragmentDefinitions(operationDefinitions, fragmentDefinitions); err != nil {
+			return rv, err
+		}
+	}
 
 	if err := detectConflicts(selectionSet); err != nil {
 		return rv, err
@@ -480,9 +566,12 @@ func MustParse(source string, vars map[string]interface{}) *Query {
 //     groups: { name name id { widgets { name } } }
 //
 // Flatten does _not_ flatten out the inner queries, so the name above does not
-// get flattened out yet.
+// get flattened out yet. The selections are returned in the order they first
+// appear in.
 func Flatten(selectionSet *SelectionSet) ([]*Selection, error) {
 	grouped := make(map[string][]*Selection)
+	// aliases keeps the selections in the order they first appear in.
+	var aliases []string
 
 	state := make(map[*SelectionSet]visitState)
 	var visit func(*SelectionSet) error
@@ -492,6 +581,9 @@ func Flatten(selectionSet *SelectionSet) ([]*Selection, error) {
 		}
 
 		for _, selection := range selectionSet.Selections {
+			if _, ok := grouped[selection.Alias]; !ok {
+				aliases = append(aliases, selection.Alias)
+			}
 			grouped[selection.Alias] = append(grouped[selection.Alias], selection)
 		}
 
@@ -516,7 +608,8 @@ func Flatten(selectionSet *SelectionSet) ([]*Selection, error) {
 	}
 
 	var flattened []*Selection
-	for _, selections := range grouped {
+	for _, alias := range aliases {
+		selections := grouped[alias]
 		if len(selections) == 1 || selections[0].SelectionSet == nil {
 			flattened = append(flattened, selections[0])
 			continue
@@ -535,6 +628,7 @@ func Flatten(selectionSet *SelectionSet) ([]*Selection, error) {
 			UnparsedArgs: selections[0].UnparsedArgs,
 			Args:         selections[0].Args,
 			SelectionSet: merged,
+			Location:     selections[0].Location,
 		})
 	}
 
@@ -41,31 +41,36 @@ fragment Bar on Foo {
 			Selections: []*Selection{
 				{
 					Name:         "foo",
+					Location:     &Location{Line: 3, Column: 2},
 					Alias:        "foo",
 					UnparsedArgs: map[string]interface{}{},
 					SelectionSet: &SelectionSet{
 						Selections: []*Selection{
 							{
 								Name:         "bar",
+								Location:     &Location{Line: 4, Column: 3},
 								Alias:        "alias",
 								UnparsedArgs: map[string]interface{}{},
 							},
 							{
 								Name:         "bar",
+								Location:     &Location{Line: 5, Column: 3},
 								Alias:        "alias",
 								UnparsedArgs: map[string]interface{}{},
 							},
 							{
-								Name:  "baz",
-								Alias: "baz",
+								Name:     "baz",
+								Location: &Location{Line: 6, Column: 3},
+								Alias:    "baz",
 								UnparsedArgs: map[string]interface{}{
 									"arg": float64(3),
 								},
 								SelectionSet: &SelectionSet{
 									Selections: []*Selection{
 										{
-											Name:  "bah",
-											Alias: "bah",
+											Name:     "bah",
+											Location: &Location{Line: 7, Column: 4},
+											Alias:    "bah",
 											UnparsedArgs: map[string]interface{}{
 												"x": float64(1),
 												"y": "123",
@@ -73,8 +78,9 @@ fragment Bar on Foo {
 											},
 										},
 										{
-											Name:  "hum",
-											Alias: "hum",
+											Name:     "hum",
+											Location: &Location{Line: 8, Column: 4},
+											Alias:    "hum",
 											UnparsedArgs: map[string]interface{}{
 												"foo": map[string]interface{}{
 													"x": "var value!!",
@@ -96,6 +102,7 @@ fragment Bar on Foo {
 									Selections: []*Selection{
 										{
 											Name:         "asd",
+											Location:     &Location{Line: 11, Column: 4},
 											Alias:        "asd",
 											UnparsedArgs: map[string]interface{}{},
 										},
@@ -107,6 +114,7 @@ fragment Bar on Foo {
 												Selections: []*Selection{
 													{
 														Name:         "zxc",
+														Location:     &Location{Line: 19, Column: 2},
 														Alias:        "zxc",
 														UnparsedArgs: map[string]interface{}{},
 													},
@@ -121,6 +129,7 @@ fragment Bar on Foo {
 				},
 				{
 					Name:         "xyz",
+					Location:     &Location{Line: 15, Column: 2},
 					Alias:        "xyz",
 					UnparsedArgs: map[string]interface{}{},
 				},
@@ -150,6 +159,7 @@ mutation foo($var: bar) {
 			Selections: []*Selection{
 				{
 					Name:         "baz",
+					Location:     &Location{Line: 3, Column: 2},
 					Alias:        "baz",
 					UnparsedArgs: map[string]interface{}{},
 				},
@@ -272,3 +282,92 @@ query Operation($x: int64 = 2) {
 		t.Errorf("expected 2, received %v", val)
 	}
 }
+
+func TestParseOperation(t *testing.T) {
+	source := `
+query First {
+	...Shared
+	first
+}
+
+query Second($x: int64 = 2) {
+	...Shared
+	second(x: $x)
+}
+
+fragment Shared on Query {
+	shared
+}`
+
+	query, err := ParseOperation(source, map[string]interface{}{}, "First")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.Name != "First" || len(query.SelectionSet.Selections) != 1 || query.SelectionSet.Selections[0].Name != "first" {
+		t.Error("unexpected parse for First", query)
+	}
+
+	query, err = ParseOperation(source, map[string]interface{}{}, "Second")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.Name != "Second" || query.SelectionSet.Selections[0].Name != "second" {
+		t.Error("unexpected parse for Second", query)
+	}
+	if val := query.SelectionSet.Selections[0].UnparsedArgs["x"]; val != float64(2) {
+		t.Errorf("expected 2, received %v", val)
+	}
+
+	_, err = ParseOperation(source, map[string]interface{}{}, "")
+	if err == nil || err.Error() != "only support a single query" {
+		t.Error("expected ambiguous operation to fail", err)
+	}
+
+	_, err = ParseOperation(source, map[string]interface{}{}, "Third")
+	if err == nil || err.Error() != "unknown operation Third" {
+		t.Error("expected missing operation to fail", err)
+	}
+
+	_, err = ParseOperation(`
+query First {
+	a
+}
+
+query First {
+	b
+}`, map[string]interface{}{}, "First")
+	if err == nil || err.Error() != "duplicate operation First" {
+		t.Error("expected duplicate operation to fail", err)
+	}
+
+	// Fragments must be used by one of the operations, possibly through
+	// other fragments.
+	_, err = ParseOperation(source+`
+
+fragment Unused on Query {
+	unused
+}`, map[string]interface{}{}, "First")
+	if err == nil || err.Error() != "unused fragment" {
+		t.Error("expected unused fragment to fail", err)
+	}
+
+	_, err = ParseOperation(`
+query First {
+	first
+}
+
+query Second {
+	...Outer
+}
+
+fragment Outer on Query {
+	...Inner
+}
+
+fragment Inner on Query {
+	inner
+}`, map[string]interface{}{}, "First")
+	if err != nil {
+		t.Error("expected fragments used by another operation to parse", err)
+	}
+}
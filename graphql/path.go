@@ -0,0 +1,58 @@
+package graphql
+
+import (
+	"context"
+	"strconv"
+)
+
+type pathKey struct{}
+
+type pathMapperKey struct{}
+
+// PathMapper translates the response path of a field, see WithPathMapper.
+type PathMapper func(path []interface{}) []interface{}
+
+// withPath returns ctx carrying the path of the field resolved with it.
+func withPath(ctx context.Context, tracker *pathTracker) context.Context {
+	return context.WithValue(ctx, pathKey{}, tracker)
+}
+
+// PathFromContext returns the response path of the field being resolved with
+// ctx, ie. the aliases of the fields and the indices of the list elements
+// leading to it, eg. ["users", 0, "name"]. Batch resolvers, which resolve a
+// field for several objects at once, and contexts outside of resolvers have no
+// path.
+func PathFromContext(ctx context.Context) []interface{} {
+	tracker, ok := ctx.Value(pathKey{}).(*pathTracker)
+	if !ok {
+		return nil
+	}
+	var reversed []interface{}
+	// The root of the tracker is the operation, which isn't part of the path.
+	for cur := tracker; cur != nil && cur.parent != nil; cur = cur.parent {
+		if cur.path == "" {
+			continue
+		}
+		// Aliases can't start with a digit, so numeric steps are list indices.
+		if idx, err := strconv.Atoi(cur.path); err == nil {
+			reversed = append(reversed, idx)
+		} else {
+			reversed = append(reversed, cur.path)
+		}
+	}
+	path := make([]interface{}, 0, len(reversed))
+	for i := len(reversed) - 1; i >= 0; i-- {
+		path = append(path, reversed[i])
+	}
+	if mapper, ok := ctx.Value(pathMapperKey{}).(PathMapper); ok {
+		path = mapper(path)
+	}
+	return path
+}
+
+// WithPathMapper returns a context in which PathFromContext translates the
+// paths of fields with mapper, eg. on a server that executes a part of
+// another server's query, to report paths in that query.
+func WithPathMapper(ctx context.Context, mapper PathMapper) context.Context {
+	return context.WithValue(ctx, pathMapperKey{}, mapper)
+}
@@ -0,0 +1,82 @@
+package graphql_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathFromContext(t *testing.T) {
+	type User struct {
+		Name string
+	}
+	var mu sync.Mutex
+	var paths []string
+	record := func(ctx context.Context) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, fmt.Sprint(graphql.PathFromContext(ctx)))
+	}
+
+	builder := schemabuilder.NewSchema()
+	query := builder.Query()
+	query.FieldFunc("users", func(ctx context.Context) []*User {
+		record(ctx)
+		return []*User{{Name: "a"}, {Name: "b"}}
+	})
+	user := builder.Object("User", User{})
+	user.FieldFunc("greeting", func(ctx context.Context, u *User) string {
+		record(ctx)
+		return "hi " + u.Name
+	})
+	user.FieldFunc("friend", func(ctx context.Context, u *User) *User {
+		record(ctx)
+		return &User{Name: u.Name + "'s friend"}
+	}, schemabuilder.Expensive)
+	schema := builder.MustBuild()
+
+	q := graphql.MustParse(`query named {
+		users { greeting buddy: friend { hello: greeting } }
+	}`, nil)
+	require.NoError(t, graphql.PrepareQuery(context.Background(), schema.Query, q.SelectionSet))
+	e := graphql.NewExecutor(graphql.NewImmediateGoroutineScheduler())
+	_, err := e.Execute(context.Background(), schema.Query, nil, q)
+	require.NoError(t, err)
+
+	sort.Strings(paths)
+	assert.Equal(t, []string{
+		"[users 0 buddy hello]",
+		"[users 0 buddy]",
+		"[users 0 greeting]",
+		"[users 1 buddy hello]",
+		"[users 1 buddy]",
+		"[users 1 greeting]",
+		"[users]",
+	}, paths)
+
+	// A mapper translates the paths, which hold aliases as strings and list
+	// indices as ints.
+	paths = nil
+	ctx := graphql.WithPathMapper(context.Background(), func(path []interface{}) []interface{} {
+		assert.IsType(t, "", path[0])
+		if len(path) > 1 {
+			assert.IsType(t, 0, path[1])
+		}
+		return append([]interface{}{"mapped"}, path...)
+	})
+	q = graphql.MustParse(`{ users { greeting } }`, nil)
+	require.NoError(t, graphql.PrepareQuery(ctx, schema.Query, q.SelectionSet))
+	_, err = e.Execute(ctx, schema.Query, nil, q)
+	require.NoError(t, err)
+	sort.Strings(paths)
+	assert.Equal(t, []string{"[mapped users 0 greeting]", "[mapped users 1 greeting]", "[mapped users]"}, paths)
+
+	assert.Nil(t, graphql.PathFromContext(context.Background()))
+}
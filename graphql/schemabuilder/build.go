@@ -2,8 +2,10 @@ package schemabuilder
 
 import (
 	"encoding"
+	"errors"
 	"fmt"
 	"reflect"
+	"sync"
 	"time"
 
 	"github.com/samsarahq/thunder/graphql"
@@ -14,11 +16,18 @@ import (
 // we build out graphql types for our graphql schema.  Resolved graphQL "types"
 // are stored in the type map which we can use to see sections of the graph.
 type schemaBuilder struct {
-	types        map[reflect.Type]graphql.Type
-	typeNames    map[string]reflect.Type
-	objects      map[reflect.Type]*Object
-	enumMappings map[reflect.Type]*EnumMapping
-	typeCache    map[reflect.Type]cachedType // typeCache maps Go types to GraphQL datatypes
+	types           map[reflect.Type]graphql.Type
+	typeNames       map[string]reflect.Type
+	objects         map[reflect.Type]*Object
+	enumMappings    map[reflect.Type]*EnumMapping
+	interfaceUnions map[reflect.Type]*interfaceUnion // interfaceUnions are the interfaces registered as unions
+	typeCache       map[reflect.Type]cachedType      // typeCache maps Go types to GraphQL datatypes
+	fieldNamer      FieldNamer                       // fieldNamer names fields without a graphql tag
+
+	// inputDeprecations maps input structs to their deprecated fields.
+	inputDeprecations map[reflect.Type]map[string]string
+	// oneOfInputs are the input structs registered as input unions.
+	oneOfInputs map[reflect.Type]bool
 }
 
 // EnumMapping is a representation of an enum that includes both the mapping and
@@ -26,6 +35,9 @@ type schemaBuilder struct {
 type EnumMapping struct {
 	Map        map[string]interface{}
 	ReverseMap map[interface{}]string
+	// Deprecations maps deprecated values to the reasons they are
+	// deprecated.
+	Deprecations map[string]string
 }
 
 // cachedType is a container for GraphQL datatype and the list of its fields
@@ -43,7 +55,8 @@ func (sb *schemaBuilder) getType(nodeType reflect.Type) (graphql.Type, error) {
 	// Support scalars and optional scalars. Scalars have precedence over structs
 	// to have eg. time.Time function as a scalar.
 	if typeName, values, ok := sb.getEnum(nodeType); ok {
-		return &graphql.NonNull{Type: &graphql.Enum{Type: typeName, Values: values, ReverseMap: sb.enumMappings[nodeType].ReverseMap}}, nil
+		mapping := sb.enumMappings[nodeType]
+		return &graphql.NonNull{Type: &graphql.Enum{Type: typeName, Values: values, ReverseMap: mapping.ReverseMap, Deprecations: mapping.Deprecations}}, nil
 	}
 
 	if typeName, ok := getScalar(nodeType); ok {
@@ -59,6 +72,11 @@ func (sb *schemaBuilder) getType(nodeType reflect.Type) (graphql.Type, error) {
 		return sb.getTextMarshalerType(nodeType)
 	}
 
+	// Interfaces registered as unions
+	if union, ok := sb.interfaceUnions[nodeType]; ok {
+		return sb.buildInterfaceUnion(nodeType, union)
+	}
+
 	// Structs
 	if nodeType.Kind() == reflect.Struct {
 		if err := sb.buildStruct(nodeType); err != nil {
@@ -73,6 +91,27 @@ func (sb *schemaBuilder) getType(nodeType reflect.Type) (graphql.Type, error) {
 		return sb.types[nodeType.Elem()], nil
 	}
 
+	// Map-backed objects
+	if _, ok := sb.objects[nodeType]; ok && isMapObjectType(nodeType) {
+		if err := sb.buildStruct(nodeType); err != nil {
+			return nil, err
+		}
+		return &graphql.NonNull{Type: sb.types[nodeType]}, nil
+	}
+	if nodeType.Kind() == reflect.Ptr {
+		if _, ok := sb.objects[nodeType.Elem()]; ok && isMapObjectType(nodeType.Elem()) {
+			if err := sb.buildStruct(nodeType.Elem()); err != nil {
+				return nil, err
+			}
+			return sb.types[nodeType.Elem()], nil
+		}
+	}
+
+	// Maps of objects
+	if nodeType.Kind() == reflect.Map && nodeType.Key().Kind() == reflect.String {
+		return sb.getMapEntriesType(nodeType)
+	}
+
 	switch nodeType.Kind() {
 	case reflect.Slice:
 		elementType, err := sb.getType(nodeType.Elem())
@@ -136,7 +175,21 @@ func (sb *schemaBuilder) getEnum(typ reflect.Type) (string, []string, bool) {
 // getScalar grabs the appropriate scalar graphql field type name for the passed
 // in variable reflect type.
 func getScalar(typ reflect.Type) (string, bool) {
+	scalarsMu.RLock()
+	defer scalarsMu.RUnlock()
+	return lookupScalar(typ)
+}
+
+// lookupScalar is getScalar for callers holding scalarsMu.
+func lookupScalar(typ reflect.Type) (string, bool) {
+	if name, ok := scalars[typ]; ok {
+		return name, true
+	}
 	for match, name := range scalars {
+		if customScalars[match] {
+			// Custom scalars only match their exact type.
+			continue
+		}
 		if internal.TypesIdenticalOrScalarAliases(match, typ) {
 			return name, true
 		}
@@ -144,6 +197,57 @@ func getScalar(typ reflect.Type) (string, bool) {
 	return "", false
 }
 
+// RegisterScalar registers a custom scalar type. Values of typ are exposed in
+// the schema as a scalar named name, and are serialized in responses using
+// their default JSON encoding (eg. by implementing json.Marshaler).
+//
+// The parseValue function is used to coerce arguments of type typ. It is
+// called for both literal and variable inputs, and any error it returns is
+// reported as an argument validation error.
+//
+// For example, a DateTime scalar parsed from RFC3339 strings can be registered as:
+//   type DateTime struct {
+//     time.Time
+//   }
+//
+//   schemabuilder.RegisterScalar(reflect.TypeOf(DateTime{}), "DateTime", func(value interface{}, dest reflect.Value) error {
+//     asString, ok := value.(string)
+//     if !ok {
+//       return errors.New("not a string")
+//     }
+//     t, err := time.Parse(time.RFC3339, asString)
+//     if err != nil {
+//       return err
+//     }
+//     dest.Set(reflect.ValueOf(DateTime{Time: t}))
+//     return nil
+//   })
+func RegisterScalar(typ reflect.Type, name string, parseValue UnmarshalFunc) error {
+	if typ.Kind() == reflect.Ptr {
+		return errors.New("scalar type should not be a pointer type")
+	}
+	if parseValue == nil {
+		return fmt.Errorf("scalar %s requires a parse function", name)
+	}
+
+	scalarsMu.Lock()
+	defer scalarsMu.Unlock()
+	customScalars[typ] = true
+	scalars[typ] = name
+	scalarArgParsers[typ] = &argParser{
+		FromJSON: parseValue,
+		Type:     typ,
+	}
+	return nil
+}
+
+// scalarsMu protects scalars, customScalars and scalarArgParsers, which
+// RegisterScalar may modify while schemas are being built.
+var scalarsMu sync.RWMutex
+
+// customScalars holds the types registered with RegisterScalar.
+var customScalars = map[reflect.Type]bool{}
+
 var scalars = map[reflect.Type]string{
 	reflect.TypeOf(bool(false)): "bool",
 	reflect.TypeOf(int(0)):      "int",
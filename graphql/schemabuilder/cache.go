@@ -0,0 +1,281 @@
+package schemabuilder
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// CacheTTL is an option that can be passed to a FieldFunc to cache its results
+// for ttl. Results are keyed by the key of the parent object, the name of the
+// field and the field's arguments, so the object must have a key registered
+// with Object.Key unless it is the Query object. Results that depend on the
+// request, eg. on the user making it, must also be keyed with CacheKey. At
+// most 1000 results are cached, see CacheMaxEntries, and results are copied
+// in and out of the cache, so that modifying them does not modify the cache.
+//
+// For batch fields, sources with cached results are removed from the batch
+// before the batch function is called.
+func CacheTTL(ttl time.Duration) FieldFuncOption {
+	var cacheTTL fieldFuncOptionFunc = func(m *method) {
+		m.CacheTTL = ttl
+	}
+	return cacheTTL
+}
+
+// CacheMaxEntries is an option that can be passed to a FieldFunc cached with
+// CacheTTL to cache at most n of its results, evicting the least recently used
+// results first. By default, 1000 results are cached.
+func CacheMaxEntries(n int) FieldFuncOption {
+	var cacheMaxEntries fieldFuncOptionFunc = func(m *method) {
+		m.CacheMaxEntries = n
+	}
+	return cacheMaxEntries
+}
+
+// CacheKey is an option that can be passed to a FieldFunc cached with
+// CacheTTL to cache its results separately for every value key returns for
+// the context of a request, eg. the ID of the user making it, for fields whose
+// results depend on who asks. By default, results are shared by all requests.
+func CacheKey(key func(ctx context.Context) (interface{}, error)) FieldFuncOption {
+	var cacheKey fieldFuncOptionFunc = func(m *method) {
+		m.CacheKey = key
+	}
+	return cacheKey
+}
+
+const defaultCacheMaxEntries = 1000
+
+type fieldCacheEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// fieldCache holds the cached results of a single field, up to maxEntries of
+// them, evicting the least recently used results first.
+type fieldCache struct {
+	ttl        time.Duration
+	maxEntries int
+	// now returns the current time, and is replaced in tests.
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// lru holds the *fieldCacheEntry of entries, most recently used first.
+	lru *list.List
+}
+
+func newFieldCache(ttl time.Duration, maxEntries int) *fieldCache {
+	if maxEntries <= 0 {
+		maxEntries = defaultCacheMaxEntries
+	}
+	return &fieldCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// get returns a copy of the value cached for key, so that callers modifying
+// it do not modify the cache.
+func (c *fieldCache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*fieldCacheEntry)
+	if c.now().After(entry.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return copyValue(entry.value), true
+}
+
+// set caches a copy of value for key, so that the resolver modifying value
+// later does not modify the cache.
+func (c *fieldCache) set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &fieldCacheEntry{key: key, value: copyValue(value), expires: c.now().Add(c.ttl)}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *fieldCache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*fieldCacheEntry).key)
+}
+
+// copyValue returns a deep copy of value, a result of a resolver. Unexported
+// fields of structs are copied shallowly.
+func copyValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	return copyReflectValue(reflect.ValueOf(value)).Interface()
+}
+
+func copyReflectValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(copyReflectValue(v.Elem()))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(copyReflectValue(v.Elem()))
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if field := c.Field(i); field.CanSet() {
+				field.Set(copyReflectValue(v.Field(i)))
+			}
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(copyReflectValue(v.Index(i)))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(copyReflectValue(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), copyReflectValue(iter.Value()))
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// cacheField wraps the resolvers of the field name on object to cache their
+// results as configured by m, see CacheTTL.
+func cacheField(object *graphql.Object, name string, m *method) error {
+	if object.KeyField == nil && object.Name != "Query" {
+		return fmt.Errorf("cached field %s requires a key on object %s", name, object.Name)
+	}
+
+	cache := newFieldCache(m.CacheTTL, m.CacheMaxEntries)
+	cacheKey := func(ctx context.Context, source, args interface{}) (string, error) {
+		var parentKey interface{}
+		if object.KeyField != nil {
+			var err error
+			parentKey, err = object.KeyField.Resolve(ctx, source, nil, nil)
+			if err != nil {
+				return "", err
+			}
+		}
+		var requestKey interface{}
+		if m.CacheKey != nil {
+			var err error
+			requestKey, err = m.CacheKey(ctx)
+			if err != nil {
+				return "", err
+			}
+		}
+		key, err := json.Marshal([]interface{}{parentKey, name, args, requestKey})
+		if err != nil {
+			return "", fmt.Errorf("computing cache key: %s", err)
+		}
+		return string(key), nil
+	}
+
+	field := object.Fields[name]
+	if resolve := field.Resolve; resolve != nil {
+		field.Resolve = func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+			key, err := cacheKey(ctx, source, args)
+			if err != nil {
+				return nil, err
+			}
+			if value, ok := cache.get(key); ok {
+				return value, nil
+			}
+			value, err := resolve(ctx, source, args, selectionSet)
+			if err != nil {
+				return nil, err
+			}
+			cache.set(key, value)
+			return value, nil
+		}
+	}
+
+	if batchResolve := field.BatchResolver; batchResolve != nil {
+		field.BatchResolver = func(ctx context.Context, sources []interface{}, args interface{}, selectionSet *graphql.SelectionSet) ([]interface{}, error) {
+			results := make([]interface{}, len(sources))
+			var missedSources []interface{}
+			var missedIndices []int
+			var missedKeys []string
+			for i, source := range sources {
+				key, err := cacheKey(ctx, source, args)
+				if err != nil {
+					return nil, err
+				}
+				if value, ok := cache.get(key); ok {
+					results[i] = value
+					continue
+				}
+				missedSources = append(missedSources, source)
+				missedIndices = append(missedIndices, i)
+				missedKeys = append(missedKeys, key)
+			}
+			if len(missedSources) == 0 {
+				return results, nil
+			}
+
+			missedResults, err := batchResolve(ctx, missedSources, args, selectionSet)
+			if err != nil {
+				return nil, err
+			}
+			if len(missedResults) != len(missedSources) {
+				return nil, fmt.Errorf("batch resolver returned %d results for %d sources", len(missedResults), len(missedSources))
+			}
+			for i, value := range missedResults {
+				results[missedIndices[i]] = value
+				cache.set(missedKeys[i], value)
+			}
+			return results, nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package schemabuilder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldCache(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := newFieldCache(time.Minute, 2)
+	cache.now = func() time.Time { return now }
+
+	cache.set("a", 1)
+	value, ok := cache.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	// Entries expire after the TTL.
+	now = now.Add(time.Minute + time.Second)
+	_, ok = cache.get("a")
+	assert.False(t, ok)
+
+	// The least recently used entry is evicted once the cache is full.
+	cache.set("a", 1)
+	cache.set("b", 2)
+	cache.get("a")
+	cache.set("c", 3)
+	_, ok = cache.get("b")
+	assert.False(t, ok)
+	_, ok = cache.get("a")
+	assert.True(t, ok)
+	_, ok = cache.get("c")
+	assert.True(t, ok)
+	assert.Len(t, cache.entries, 2)
+}
+
+func TestFieldCacheCopiesValues(t *testing.T) {
+	type Item struct {
+		Name string
+		Tags []string
+	}
+	cache := newFieldCache(time.Minute, 0)
+
+	item := &Item{Name: "a", Tags: []string{"x"}}
+	cache.set("item", item)
+	// Modifying the value after caching it does not modify the cache...
+	item.Name = "b"
+	item.Tags[0] = "y"
+	value, ok := cache.get("item")
+	assert.True(t, ok)
+	assert.Equal(t, &Item{Name: "a", Tags: []string{"x"}}, value)
+
+	// ... and neither does modifying a cached value.
+	value.(*Item).Tags[0] = "z"
+	value, _ = cache.get("item")
+	assert.Equal(t, &Item{Name: "a", Tags: []string{"x"}}, value)
+
+	cache.set("nil", nil)
+	value, ok = cache.get("nil")
+	assert.True(t, ok)
+	assert.Nil(t, value)
+}
@@ -46,6 +46,10 @@ func (sb *schemaBuilder) buildFunctionAndFuncCtx(typ reflect.Type, m *method) (*
 		return nil, nil, err
 	}
 
+	if err := funcCtx.checkArgumentOrder(); err != nil {
+		return nil, nil, err
+	}
+
 	in := funcCtx.getFuncInputTypes()
 	in = funcCtx.consumeContextAndSource(in)
 
@@ -112,11 +116,17 @@ func (sb *schemaBuilder) buildFederatedFunction(typ reflect.Type, m *method) (*g
 	if err != nil {
 		return nil, oops.Wrapf(err, "Invalid return type")
 	}
+	resolve := func(ctx context.Context, source, funcRawArgs interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+		return source, nil
+	}
+	if m.KeyFunc != nil {
+		resolve, err = buildFederationKeyResolver(m.RootObjectType, m.KeyFunc)
+		if err != nil {
+			return nil, err
+		}
+	}
 	field := &graphql.Field{
-		Resolve: func(ctx context.Context, source, funcRawArgs interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
-			return source, nil
-
-		},
+		Resolve:                    resolve,
 		Args:                       make(map[string]graphql.Type),
 		Type:                       returnType,
 		ParseArguments:             argParser.Parse,
@@ -127,6 +137,47 @@ func (sb *schemaBuilder) buildFederatedFunction(typ reflect.Type, m *method) (*g
 	return field, nil
 }
 
+// buildFederationKeyResolver builds a resolver for the "_federation" field that
+// computes the federated key with keyFunc. Objects without a key resolve to nil.
+func buildFederationKeyResolver(rootObjectType reflect.Type, keyFunc interface{}) (graphql.Resolver, error) {
+	fun := reflect.ValueOf(keyFunc)
+	funType := fun.Type()
+	if funType.Kind() != reflect.Func {
+		return nil, fmt.Errorf("federation key func should be a function, got %s", funType)
+	}
+	if funType.NumIn() != 1 || funType.In(0) != rootObjectType {
+		return nil, fmt.Errorf("federation key func should take a single %s argument", rootObjectType)
+	}
+	if funType.NumOut() != 2 || funType.Out(0) != rootObjectType {
+		return nil, fmt.Errorf("federation key func should return (%s, bool) or (%s, error)", rootObjectType, rootObjectType)
+	}
+	hasBool := funType.Out(1).Kind() == reflect.Bool
+	if !hasBool && funType.Out(1) != errType {
+		return nil, fmt.Errorf("federation key func should return (%s, bool) or (%s, error)", rootObjectType, rootObjectType)
+	}
+
+	return func(ctx context.Context, source, funcRawArgs interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+		value := reflect.ValueOf(source)
+		if value.Kind() != reflect.Ptr {
+			ptr := reflect.New(value.Type())
+			ptr.Elem().Set(value)
+			value = ptr
+		}
+		out := fun.Call([]reflect.Value{value})
+		if hasBool {
+			if !out[1].Bool() {
+				return nil, nil
+			}
+		} else if !out[1].IsNil() {
+			return nil, out[1].Interface().(error)
+		}
+		if out[0].IsNil() {
+			return nil, nil
+		}
+		return out[0].Interface(), nil
+	}, nil
+}
+
 // buildShadowObjectFederationFunction builds a federation object and a field func that takes the
 // federation keys as args and constructs the shadow object. This is used for federated subqueries.
 // {
@@ -219,6 +270,40 @@ func (funcCtx *funcContext) getFuncInputTypes() []reflect.Type {
 	return in
 }
 
+// checkArgumentOrder checks that the input parameters of the function are in
+// the order [context][, source][, args][, selectionSet], with at most one args
+// parameter, and returns an error naming the misplaced parameter otherwise.
+// Misplaced parameters that could be mistaken for the args would otherwise
+// only fail when the function is called.
+func (funcCtx *funcContext) checkArgumentOrder() error {
+	funcType := funcCtx.funcType
+	ptr := reflect.PtrTo(funcCtx.typ)
+	var args reflect.Type
+	for i := 0; i < funcType.NumIn(); i++ {
+		in := funcType.In(i)
+		switch {
+		case in == contextType:
+			if i != 0 {
+				return fmt.Errorf("%s: context.Context should be the first argument", funcType)
+			}
+		case in == funcCtx.typ || in == ptr:
+			if i > 1 || (i == 1 && funcType.In(0) != contextType) {
+				return fmt.Errorf("%s: %s should be the first argument, or follow context.Context", funcType, in)
+			}
+		case in == selectionSetType:
+			if i != funcType.NumIn()-1 {
+				return fmt.Errorf("%s: *graphql.SelectionSet should be the last argument", funcType)
+			}
+		default:
+			if args != nil {
+				return fmt.Errorf("%s: takes both %s and %s as arguments, but only one args struct is allowed", funcType, args, in)
+			}
+			args = in
+		}
+	}
+	return nil
+}
+
 // consumeContextAndSource reads in the input parameters for the provided
 // function and determines whether the function has a Context input parameter
 // and/or whether it includes the "source" input parameter ("source" will be the
@@ -264,7 +349,7 @@ func (funcCtx *funcContext) getArgParserAndTyp(sb *schemaBuilder, in []reflect.T
 // graphql.SelectionSet for more infomation about selection sets.
 func (funcCtx *funcContext) consumeSelectionSet(in []reflect.Type) []reflect.Type {
 	if len(in) > 0 && in[0] == selectionSetType {
-		in = in[:len(in)-1]
+		in = in[1:]
 		funcCtx.hasSelectionSet = true
 		return in
 	}
@@ -291,7 +376,7 @@ func (funcCtx *funcContext) parseReturnSignature(m *method) (err error) {
 	}
 
 	if len(out) != 0 {
-		err = fmt.Errorf("%s return values should [result][, error]", funcCtx.funcType)
+		err = fmt.Errorf("%s return values should be [result][, error]", funcCtx.funcType)
 		return
 	}
 
@@ -45,10 +45,13 @@ type argField struct {
 	parser *argParser
 }
 
+// UnmarshalFunc parses a JSON input value into dest.
+type UnmarshalFunc func(value interface{}, dest reflect.Value) error
+
 // argParser is a struct that holds information for how to deserialize a JSON
 // input into a particular go variable.
 type argParser struct {
-	FromJSON func(interface{}, reflect.Value) error
+	FromJSON UnmarshalFunc
 	Type     reflect.Type
 }
 
@@ -91,6 +94,18 @@ func (sb *schemaBuilder) makeStructParser(typ reflect.Type) (*argParser, graphql
 				return errors.New("not an object")
 			}
 
+			if argType.OneOf {
+				set := 0
+				for name := range fields {
+					if asMap[name] != nil {
+						set++
+					}
+				}
+				if set != 1 {
+					return fmt.Errorf("exactly one field must be set, got %d", set)
+				}
+			}
+
 			for name, field := range fields {
 				value := asMap[name]
 				fieldDest := dest.FieldByIndex(field.field.Index)
@@ -130,26 +145,64 @@ func (sb *schemaBuilder) getStructObjectFields(typ reflect.Type) (*graphql.Input
 	// Cache type information ahead of time to catch self-reference
 	sb.typeCache[typ] = cachedType{argType, fields}
 
+	if err := sb.addStructObjectFields(typ, typ, nil, argType, fields); err != nil {
+		return nil, nil, err
+	}
+
+	if deprecations := sb.inputDeprecations[typ]; len(deprecations) > 0 {
+		for name := range deprecations {
+			if _, ok := argType.InputFields[name]; !ok {
+				return nil, nil, fmt.Errorf("bad arg type %s: cannot deprecate unknown field %s", typ, name)
+			}
+		}
+		argType.Deprecations = deprecations
+	}
+
+	if sb.oneOfInputs[typ] {
+		for name, fieldType := range argType.InputFields {
+			if _, ok := fieldType.(*graphql.NonNull); ok {
+				return nil, nil, fmt.Errorf("bad arg type %s: field %s of oneOf input should be optional", typ, name)
+			}
+		}
+		argType.OneOf = true
+	}
+
+	return argType, fields, nil
+}
+
+// addStructObjectFields adds the fields of the struct typ, found at index in
+// the args struct root, to argType and fields. The fields of embedded structs
+// are added as if they were fields of root, so that common args can be shared
+// between args structs.
+func (sb *schemaBuilder) addStructObjectFields(root reflect.Type, typ reflect.Type, index []int, argType *graphql.InputObject, fields map[string]argField) error {
 	for i := 0; i < typ.NumField(); i++ {
 		field := typ.Field(i)
+		field.Index = append(append([]int(nil), index...), field.Index...)
+
 		if field.Anonymous {
-			return nil, nil, fmt.Errorf("bad arg type %s: anonymous fields not supported", typ)
+			if field.Type.Kind() != reflect.Struct {
+				return fmt.Errorf("bad arg type %s: embedded field %s should be a struct", root, field.Name)
+			}
+			if err := sb.addStructObjectFields(root, field.Type, field.Index, argType, fields); err != nil {
+				return err
+			}
+			continue
 		}
 
-		fieldInfo, err := parseGraphQLFieldInfo(field)
+		fieldInfo, err := parseGraphQLFieldInfo(field, sb.fieldName)
 		if err != nil {
-			return nil, nil, fmt.Errorf("bad type %s: %s", typ, err.Error())
+			return fmt.Errorf("bad type %s: %s", root, err.Error())
 		}
 		if fieldInfo.Skipped {
 			continue
 		}
 
 		if _, ok := fields[fieldInfo.Name]; ok {
-			return nil, nil, fmt.Errorf("bad arg type %s: duplicate field %s", typ, fieldInfo.Name)
+			return fmt.Errorf("bad arg type %s: duplicate field %s", root, fieldInfo.Name)
 		}
 		parser, fieldArgTyp, err := sb.makeArgParser(field.Type)
 		if err != nil {
-			return nil, nil, err
+			return err
 		}
 		if fieldInfo.OptionalInputField {
 			parser, fieldArgTyp = wrapWithZeroValue(parser, fieldArgTyp)
@@ -161,8 +214,7 @@ func (sb *schemaBuilder) getStructObjectFields(typ reflect.Type) (*graphql.Input
 		}
 		argType.InputFields[fieldInfo.Name] = fieldArgTyp
 	}
-
-	return argType, fields, nil
+	return nil
 }
 
 // makeArgParser reads the information on a passed in variable type and returns
@@ -274,7 +326,7 @@ func (sb *schemaBuilder) getEnumArgParser(typ reflect.Type) (*argParser, graphql
 		}
 		dest.Set(reflect.ValueOf(val).Convert(dest.Type()))
 		return nil
-	}, Type: typ}, &graphql.Enum{Type: typ.Name(), Values: values, ReverseMap: sb.enumMappings[typ].ReverseMap}
+	}, Type: typ}, &graphql.Enum{Type: typ.Name(), Values: values, ReverseMap: sb.enumMappings[typ].ReverseMap, Deprecations: sb.enumMappings[typ].Deprecations}
 
 }
 
@@ -331,9 +383,18 @@ func (sb *schemaBuilder) makeSliceParser(typ reflect.Type) (*argParser, graphql.
 
 // getScalarArgParser creates an arg parser for a scalar type.
 func getScalarArgParser(typ reflect.Type) (*argParser, graphql.Type, bool) {
+	scalarsMu.RLock()
+	defer scalarsMu.RUnlock()
+	if argParser, ok := scalarArgParsers[typ]; ok {
+		return argParser, &graphql.Scalar{Type: scalars[typ]}, true
+	}
 	for match, argParser := range scalarArgParsers {
+		if customScalars[match] {
+			// Custom scalars only match their exact type.
+			continue
+		}
 		if internal.TypesIdenticalOrScalarAliases(match, typ) {
-			name, ok := getScalar(typ)
+			name, ok := lookupScalar(typ)
 			if !ok {
 				panic(typ)
 			}
@@ -0,0 +1,115 @@
+package schemabuilder
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/samsarahq/thunder/graphql"
+)
+
+// isMapObjectType returns whether typ can be registered as a map-backed object.
+func isMapObjectType(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Map && typ.Key().Kind() == reflect.String && typ.Name() != ""
+}
+
+// MapField exposes the value stored under key in a map-backed object as the
+// field name. typ is a value of the Go type of the field, eg. for an object
+// registered as
+//   entity := schema.Object("Entity", Entity{})
+// a string field can be registered as
+//   entity.MapField("displayName", "display_name", "")
+// A missing key resolves to the zero value of typ, so use a pointer type for
+// optional fields; such fields also accept values of the pointed-to type. A
+// value of any other type is an error.
+func (s *Object) MapField(name string, key string, typ interface{}, options ...FieldFuncOption) {
+	mapType := reflect.TypeOf(s.Type)
+	if !isMapObjectType(mapType) {
+		panic("MapField requires an object registered with a map type")
+	}
+	fieldType := reflect.TypeOf(typ)
+	if fieldType == nil {
+		panic("MapField requires a non-nil typ")
+	}
+
+	fnType := reflect.FuncOf([]reflect.Type{mapType}, []reflect.Type{fieldType, errType}, false)
+	fn := reflect.MakeFunc(fnType, func(in []reflect.Value) []reflect.Value {
+		value := in[0].MapIndex(reflect.ValueOf(key).Convert(mapType.Key()))
+		if value.IsValid() && value.Kind() == reflect.Interface {
+			value = value.Elem()
+		}
+		if !value.IsValid() {
+			return []reflect.Value{reflect.Zero(fieldType), reflect.Zero(errType)}
+		}
+		if fieldType.Kind() == reflect.Ptr && value.Type().AssignableTo(fieldType.Elem()) {
+			ptr := reflect.New(fieldType.Elem())
+			ptr.Elem().Set(value)
+			value = ptr
+		}
+		if !value.Type().AssignableTo(fieldType) {
+			err := fmt.Errorf("map key %s has type %s, expected %s", key, value.Type(), fieldType)
+			return []reflect.Value{reflect.Zero(fieldType), reflect.ValueOf(&err).Elem()}
+		}
+		out := reflect.New(fieldType).Elem()
+		out.Set(value)
+		return []reflect.Value{out, reflect.Zero(errType)}
+	})
+	s.FieldFunc(name, fn.Interface(), options...)
+}
+
+// getMapEntriesType returns the graphql type of a map with string keys that is
+// not a map-backed object. Such a map is resolved as a list of entries with the
+// fields key and value, sorted by key. The list is nullable, and a nil map is
+// resolved as null, unlike a nil slice, so that clients can tell a missing map
+// from an empty one. The entry type of a map of Foo objects is named FooEntry.
+func (sb *schemaBuilder) getMapEntriesType(typ reflect.Type) (graphql.Type, error) {
+	if entry, ok := sb.types[typ]; ok {
+		return &graphql.List{Type: &graphql.NonNull{Type: entry}}, nil
+	}
+
+	valueType, err := sb.getType(typ.Elem())
+	if err != nil {
+		return nil, err
+	}
+	namedType := valueType
+	if nonNull, ok := namedType.(*graphql.NonNull); ok {
+		namedType = nonNull.Type
+	}
+	var name string
+	switch namedType := namedType.(type) {
+	case *graphql.Object:
+		name = namedType.Name + "Entry"
+	case *graphql.Union:
+		name = namedType.Name + "Entry"
+	default:
+		return nil, fmt.Errorf("bad type %s: map values should be objects or unions", typ)
+	}
+	if originalType, ok := sb.typeNames[name]; ok {
+		return nil, fmt.Errorf("duplicate name %s: seen both %v and %v", name, originalType, typ)
+	}
+
+	entry := &graphql.Object{
+		Name: name,
+		Fields: map[string]*graphql.Field{
+			"key": {
+				Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+					return source.(graphql.MapEntry).Key, nil
+				},
+				Type:           &graphql.NonNull{Type: &graphql.Scalar{Type: "string"}},
+				ParseArguments: nilParseArguments,
+				Idempotent:     true,
+			},
+			"value": {
+				Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+					return source.(graphql.MapEntry).Value, nil
+				},
+				Type:           valueType,
+				ParseArguments: nilParseArguments,
+				Idempotent:     true,
+			},
+		},
+	}
+	sb.types[typ] = entry
+	sb.typeNames[name] = typ
+	return &graphql.List{Type: &graphql.NonNull{Type: entry}}, nil
+}
@@ -0,0 +1,87 @@
+package schemabuilder
+
+import (
+	"strings"
+	"unicode"
+)
+
+// FieldNamer converts the name of a Go struct field, eg. "HTTPStatus", into
+// the name of the GraphQL field or argument it is exposed as.
+type FieldNamer func(name string) string
+
+// DefaultFieldNamer lowercases the first letter of the name, converting
+// "MyField" into "myField" and "HTTPStatus" into "hTTPStatus".
+var DefaultFieldNamer FieldNamer = makeGraphql
+
+// CamelCaseFieldNamer converts names into camelCase, lowercasing leading
+// initialisms: "MyField" becomes "myField", "HTTPStatus" becomes "httpStatus"
+// and "ID" becomes "id".
+var CamelCaseFieldNamer FieldNamer = func(name string) string {
+	words := splitFieldName(name)
+	for i, word := range words {
+		if i == 0 {
+			words[i] = strings.ToLower(word)
+		}
+	}
+	return strings.Join(words, "")
+}
+
+// SnakeCaseFieldNamer converts names into snake_case: "MyField" becomes
+// "my_field" and "HTTPStatus" becomes "http_status".
+var SnakeCaseFieldNamer FieldNamer = func(name string) string {
+	words := splitFieldName(name)
+	for i, word := range words {
+		words[i] = strings.ToLower(word)
+	}
+	return strings.Join(words, "_")
+}
+
+// splitFieldName splits a Go name into words at case changes, keeping
+// initialisms together: "HTTPStatusCode" is split into "HTTP", "Status" and
+// "Code".
+func splitFieldName(name string) []string {
+	runes := []rune(name)
+	var words []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		switch {
+		// "fooBar": a lowercase letter or digit followed by an uppercase one.
+		case unicode.IsUpper(cur) && !unicode.IsUpper(prev) && prev != '_':
+		// "HTTPStatus": the last letter of an initialism starts the next word.
+		case unicode.IsUpper(prev) && unicode.IsUpper(cur) && i+1 < len(runes) && unicode.IsLower(runes[i+1]):
+		case cur == '_':
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+			continue
+		default:
+			continue
+		}
+		if i > start {
+			words = append(words, string(runes[start:i]))
+		}
+		start = i
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+// SetFieldNamer sets the function used to name the GraphQL fields and
+// arguments of struct fields without a name in their graphql tag. It applies
+// to object fields, arguments and federation keys alike. By default,
+// DefaultFieldNamer is used.
+func (s *Schema) SetFieldNamer(namer FieldNamer) {
+	s.fieldNamer = namer
+}
+
+// fieldName returns the GraphQL name of the struct field named name.
+func (sb *schemaBuilder) fieldName(name string) string {
+	if sb.fieldNamer == nil {
+		return makeGraphql(name)
+	}
+	return sb.fieldNamer(name)
+}
@@ -22,7 +22,7 @@ func (sb *schemaBuilder) buildStruct(typ reflect.Type) error {
 		return fmt.Errorf("schemabuilder.Union can only be used as an embedded anonymous non-pointer struct")
 	}
 
-	if hasUnionMarkerEmbedded(typ) {
+	if typ.Kind() == reflect.Struct && hasUnionMarkerEmbedded(typ) {
 		return sb.buildUnionStruct(typ)
 	}
 
@@ -30,11 +30,13 @@ func (sb *schemaBuilder) buildStruct(typ reflect.Type) error {
 	var description string
 	var methods Methods
 	var objectKey string
+	var external []string
 	if object, ok := sb.objects[typ]; ok {
 		name = object.Name
 		description = object.Description
 		methods = object.Methods
 		objectKey = object.key
+		external = object.external
 	}
 
 	if name == "" {
@@ -55,9 +57,10 @@ func (sb *schemaBuilder) buildStruct(typ reflect.Type) error {
 	sb.types[typ] = object
 	sb.typeNames[name] = typ
 
-	for i := 0; i < typ.NumField(); i++ {
+	// Map-backed objects only have the fields registered as methods.
+	for i := 0; typ.Kind() == reflect.Struct && i < typ.NumField(); i++ {
 		field := typ.Field(i)
-		fieldInfo, err := parseGraphQLFieldInfo(field)
+		fieldInfo, err := parseGraphQLFieldInfo(field, sb.fieldName)
 		if err != nil {
 			return fmt.Errorf("bad type %s: %s", typ, fieldInfo.Name)
 		}
@@ -132,9 +135,29 @@ func (sb *schemaBuilder) buildStruct(typ reflect.Type) error {
 		if err != nil {
 			return fmt.Errorf("bad method %s on type %s: %s", name, typ, err)
 		}
+		for arg := range method.ArgDeprecations {
+			if _, ok := built.Args[arg]; !ok {
+				return fmt.Errorf("bad method %s on type %s: cannot deprecate unknown argument %s", name, typ, arg)
+			}
+		}
+		built.ArgDeprecations = method.ArgDeprecations
 		object.Fields[name] = built
 	}
 
+	isMutation := typ == reflect.TypeOf(mutation{})
+	for _, name := range names {
+		object.Fields[name].Idempotent = methods[name].idempotent(isMutation)
+		object.Fields[name].Timeout = methods[name].Timeout
+	}
+
+	for _, name := range external {
+		field, ok := object.Fields[name]
+		if !ok {
+			return fmt.Errorf("bad type %s: external field %s doesn't exist on object", typ, name)
+		}
+		field.FederationExternal = true
+	}
+
 	if objectKey != "" {
 		keyPtr, ok := object.Fields[objectKey]
 		if !ok {
@@ -147,6 +170,14 @@ func (sb *schemaBuilder) buildStruct(typ reflect.Type) error {
 		object.KeyField = keyPtr
 	}
 
+	for _, name := range names {
+		if methods[name].CacheTTL > 0 {
+			if err := cacheField(object, name, methods[name]); err != nil {
+				return fmt.Errorf("bad method %s on type %s: %s", name, typ, err)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -215,6 +246,57 @@ func (sb *schemaBuilder) buildUnionStruct(typ reflect.Type) error {
 	return nil
 }
 
+// buildInterfaceUnion builds a graphql.Union for an interface type, whose
+// values are resolved to the member of their concrete type.
+func (sb *schemaBuilder) buildInterfaceUnion(typ reflect.Type, u *interfaceUnion) (graphql.Type, error) {
+	if union, ok := sb.types[typ]; ok {
+		return union, nil
+	}
+	if originalType, ok := sb.typeNames[u.name]; ok {
+		return nil, fmt.Errorf("duplicate name %s: seen both %v and %v", u.name, originalType, typ)
+	}
+
+	union := &graphql.Union{
+		Name:  u.name,
+		Types: make(map[string]*graphql.Object),
+	}
+	sb.types[typ] = union
+	sb.typeNames[u.name] = typ
+
+	memberNames := make(map[reflect.Type]string, len(u.members))
+	for _, member := range u.members {
+		memberType, err := sb.getType(member)
+		if err != nil {
+			return nil, err
+		}
+		if nonNull, ok := memberType.(*graphql.NonNull); ok {
+			memberType = nonNull.Type
+		}
+		obj, ok := memberType.(*graphql.Object)
+		if !ok {
+			return nil, fmt.Errorf("bad type %s: union type member must be a struct or a pointer to a struct, received %s", u.name, memberType.String())
+		}
+		if union.Types[obj.Name] != nil {
+			return nil, fmt.Errorf("bad type %s: union type member may only appear once", u.name)
+		}
+		union.Types[obj.Name] = obj
+		memberNames[member] = obj.Name
+	}
+
+	union.MemberOf = func(value interface{}) (string, interface{}, error) {
+		v := reflect.ValueOf(value)
+		if !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+			return "", nil, nil
+		}
+		name, ok := memberNames[v.Type()]
+		if !ok {
+			return "", nil, fmt.Errorf("%s is not a member of union %s", v.Type(), u.name)
+		}
+		return name, value, nil
+	}
+	return union, nil
+}
+
 // isScalarType returns whether a graphql.Type is a scalar type (or a non-null
 // wrapped scalar type).
 func isScalarType(typ graphql.Type) bool {
@@ -245,5 +327,6 @@ func (sb *schemaBuilder) buildField(field reflect.StructField) (*graphql.Field,
 		},
 		Type:           retType,
 		ParseArguments: nilParseArguments,
+		Idempotent:     true,
 	}, nil
 }
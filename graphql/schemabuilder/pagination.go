@@ -984,13 +984,22 @@ func (sb *schemaBuilder) getKeyFieldOnStruct(nodeType reflect.Type) (string, err
 	if nodeObj == nil {
 		return "", fmt.Errorf("%s must be a struct and registered as an object along with its key", nodeType)
 	}
-	nodeKey := reverseGraphqlFieldName(nodeObj.key)
-	if nodeKey == "" {
-		return nodeKey, fmt.Errorf("a key field must be registered for paginated objects")
+	if nodeObj.key == "" {
+		return "", fmt.Errorf("a key field must be registered for paginated objects")
 	}
 	if nodeType.Kind() == reflect.Ptr {
 		nodeType = nodeType.Elem()
 	}
+	nodeKey := reverseGraphqlFieldName(nodeObj.key)
+	// With a custom field namer, the key is found by the GraphQL name of
+	// the struct field.
+	for i := 0; nodeType.Kind() == reflect.Struct && i < nodeType.NumField(); i++ {
+		fieldInfo, err := parseGraphQLFieldInfo(nodeType.Field(i), sb.fieldName)
+		if err == nil && !fieldInfo.Skipped && fieldInfo.Name == nodeObj.key {
+			nodeKey = nodeType.Field(i).Name
+			break
+		}
+	}
 	if _, ok := nodeType.FieldByName(nodeKey); !ok {
 		return nodeKey, fmt.Errorf("field doesn't exist on struct")
 	}
@@ -1121,6 +1130,10 @@ func (sb *schemaBuilder) buildPaginatedFunctionAndFuncCtx(typ reflect.Type, m *m
 		return nil, c, err
 	}
 
+	if err := c.checkArgumentOrder(); err != nil {
+		return nil, c, err
+	}
+
 	in := c.getFuncInputTypes()
 	in = c.consumeContextAndSource(in)
 
@@ -1283,7 +1296,7 @@ func (sb *schemaBuilder) buildEmbeddedPaginatedArgParser(typ reflect.Type) (*arg
 			continue
 		}
 
-		name := makeGraphql(field.Name)
+		name := sb.fieldName(field.Name)
 
 		var parser *argParser
 		var fieldArgTyp graphql.Type
@@ -1376,7 +1389,7 @@ func (sb *schemaBuilder) buildPaginatedArgParser(originalArgType reflect.Type) (
 			continue
 		}
 
-		name := makeGraphql(field.Name)
+		name := sb.fieldName(field.Name)
 
 		var parser *argParser
 		var fieldArgTyp graphql.Type
@@ -56,8 +56,9 @@ type graphQLFieldInfo struct {
 }
 
 // parseGraphQLFieldInfo parses a struct field and returns a struct with the
-// parsed information about the field (tag info, name, etc).
-func parseGraphQLFieldInfo(field reflect.StructField) (*graphQLFieldInfo, error) {
+// parsed information about the field (tag info, name, etc). Fields without a
+// name in their tag are named with fieldName.
+func parseGraphQLFieldInfo(field reflect.StructField, fieldName FieldNamer) (*graphQLFieldInfo, error) {
 	if field.PkgPath != "" {
 		return &graphQLFieldInfo{Skipped: true}, nil
 	}
@@ -67,7 +68,7 @@ func parseGraphQLFieldInfo(field reflect.StructField) (*graphQLFieldInfo, error)
 		name = tags[0]
 	}
 	if name == "" {
-		name = makeGraphql(field.Name)
+		name = fieldName(field.Name)
 	}
 	if name == "-" {
 		return &graphQLFieldInfo{Skipped: true}, nil
@@ -262,6 +262,29 @@ func TestExpensiveField(t *testing.T) {
 	}
 }
 
+func TestExternalField(t *testing.T) {
+	type User struct {
+		Id   int64
+		Name string
+	}
+	schema := NewSchema()
+	user := schema.Object("User", User{})
+	user.External("id")
+	user.FieldFunc("greeting", func(u *User) string { return "hi " + u.Name })
+	schema.Query().FieldFunc("user", func() *User { return &User{} })
+
+	builtSchema := schema.MustBuild()
+	builtUser := builtSchema.Query.(*graphql.Object).Fields["user"].Type.(*graphql.Object)
+	assert.True(t, builtUser.Fields["id"].FederationExternal)
+	assert.False(t, builtUser.Fields["name"].FederationExternal)
+	assert.False(t, builtUser.Fields["greeting"].FederationExternal)
+
+	user.External("missing")
+	_, err := schema.Build()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "external field missing doesn't exist on object")
+}
+
 func TestEnumMapKeys(t *testing.T) {
 	schema := NewSchema()
 	defer func() {
@@ -425,6 +448,70 @@ func TestMakeGraphql(t *testing.T) {
 	testMakeGraphql(t, "ABC", "aBC")
 }
 
+func testFieldNamer(t *testing.T, namer FieldNamer, s, expected string) {
+	actual := namer(s)
+	if actual != expected {
+		t.Errorf("namer(%s) = %s, expected %s", s, actual, expected)
+	}
+}
+
+func TestFieldNamers(t *testing.T) {
+	testFieldNamer(t, DefaultFieldNamer, "HTTPStatus", "hTTPStatus")
+
+	testFieldNamer(t, CamelCaseFieldNamer, "Name", "name")
+	testFieldNamer(t, CamelCaseFieldNamer, "FooBar", "fooBar")
+	testFieldNamer(t, CamelCaseFieldNamer, "HTTPStatus", "httpStatus")
+	testFieldNamer(t, CamelCaseFieldNamer, "ID", "id")
+	testFieldNamer(t, CamelCaseFieldNamer, "UserID", "userID")
+	testFieldNamer(t, CamelCaseFieldNamer, "Base64Data", "base64Data")
+
+	testFieldNamer(t, SnakeCaseFieldNamer, "Name", "name")
+	testFieldNamer(t, SnakeCaseFieldNamer, "FooBar", "foo_bar")
+	testFieldNamer(t, SnakeCaseFieldNamer, "HTTPStatus", "http_status")
+	testFieldNamer(t, SnakeCaseFieldNamer, "UserID", "user_id")
+	testFieldNamer(t, SnakeCaseFieldNamer, "Foo_Bar", "foo_bar")
+}
+
+func TestSchemaFieldNamer(t *testing.T) {
+	type Response struct {
+		HTTPStatus int64
+		UserID     int64
+		Body       string `graphql:"content"`
+	}
+	type Filter struct {
+		MinHTTPStatus int64
+	}
+
+	schema := NewSchema()
+	schema.SetFieldNamer(SnakeCaseFieldNamer)
+	schema.Object("Response", Response{})
+	schema.Query().FieldFunc("responses", func(args struct {
+		UserID int64
+		Filter *Filter
+	}) []*Response {
+		return []*Response{{HTTPStatus: 200, UserID: args.UserID, Body: "ok"}, {HTTPStatus: 404, UserID: args.UserID}}
+	})
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`{
+		responses(user_id: 7, filter: {min_http_status: 300}) { http_status user_id content }
+	}`, nil)
+	require.NoError(t, graphql.PrepareQuery(context.Background(), builtSchema.Query, q.SelectionSet))
+	e := graphql.NewExecutor(graphql.NewImmediateGoroutineScheduler())
+	res, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"responses": []interface{}{
+			map[string]interface{}{"http_status": int64(200), "user_id": int64(7), "content": "ok"},
+			map[string]interface{}{"http_status": int64(404), "user_id": int64(7), "content": ""},
+		},
+	}, res)
+
+	// Unknown names are still rejected.
+	q = graphql.MustParse(`{ responses(userId: 7) { http_status } }`, nil)
+	assert.Error(t, graphql.PrepareQuery(context.Background(), builtSchema.Query, q.SelectionSet))
+}
+
 type inner struct {
 	Custom float64 `graphql:"foo"`
 	Child  *inner  `graphql:"bar"`
@@ -459,7 +546,7 @@ type kitchenSinkArgs struct {
 }
 
 type anonymous struct {
-	kitchenSinkArgs
+	*inner
 }
 
 type duplicate struct {
@@ -663,7 +750,7 @@ func TestArgParser(t *testing.T) {
 	}
 
 	if _, _, err := sb.makeArgParser(reflect.TypeOf(&anonymous{})); err == nil {
-		t.Error("expected anonymous fields to fail")
+		t.Error("expected embedded pointers to fail")
 	}
 
 	if _, _, err := sb.makeArgParser(reflect.TypeOf(&unsupported{})); err == nil {
@@ -967,3 +1054,202 @@ func TestBatchFieldFuncValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestFieldFuncSignatures(t *testing.T) {
+	type Object struct {
+		Name string
+	}
+	type Args struct {
+		Suffix string
+	}
+
+	schema := NewSchema()
+	query := schema.Query()
+	query.FieldFunc("object", func() *Object { return &Object{Name: "o"} })
+	object := schema.Object("Object", Object{})
+	object.FieldFunc("plain", func() string { return "plain" })
+	object.FieldFunc("withError", func() (string, error) { return "withError", nil })
+	object.FieldFunc("onlyError", func(ctx context.Context) error { return nil })
+	object.FieldFunc("byValue", func(o Object) string { return o.Name })
+	object.FieldFunc("withContext", func(ctx context.Context, o *Object) (string, error) { return o.Name, nil })
+	object.FieldFunc("withArgs", func(o *Object, args Args) string { return o.Name + args.Suffix })
+	object.FieldFunc("onlyArgs", func(ctx context.Context, args Args) string { return args.Suffix })
+	object.FieldFunc("withSelectionSet", func(ctx context.Context, o *Object, args Args, selectionSet *graphql.SelectionSet) *Object {
+		return &Object{Name: o.Name + args.Suffix + selectionSet.Selections[0].Name}
+	})
+	object.FieldFunc("onlySelectionSet", func(selectionSet *graphql.SelectionSet) *Object {
+		return &Object{Name: selectionSet.Selections[0].Name}
+	})
+	builtSchema := schema.MustBuild()
+
+	q := graphql.MustParse(`{
+		object {
+			plain withError onlyError byValue withContext
+			withArgs(suffix: "!") onlyArgs(suffix: "?")
+			withSelectionSet(suffix: "-") { name }
+			onlySelectionSet { name }
+		}
+	}`, nil)
+	require.NoError(t, graphql.PrepareQuery(context.Background(), builtSchema.Query, q.SelectionSet))
+	e := graphql.NewExecutor(graphql.NewImmediateGoroutineScheduler())
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"object": map[string]interface{}{
+			"plain":            "plain",
+			"withError":        "withError",
+			"onlyError":        true,
+			"byValue":          "o",
+			"withContext":      "o",
+			"withArgs":         "o!",
+			"onlyArgs":         "?",
+			"withSelectionSet": map[string]interface{}{"name": "o-name"},
+			"onlySelectionSet": map[string]interface{}{"name": "name"},
+		},
+	}, val)
+
+	testCases := []struct {
+		name  string
+		f     interface{}
+		error string
+	}{
+		{
+			name:  "context after object",
+			f:     func(o *Object, ctx context.Context) string { return "" },
+			error: "func(*schemabuilder.Object, context.Context) string: context.Context should be the first argument",
+		},
+		{
+			name:  "object after args",
+			f:     func(args Args, o *Object) string { return "" },
+			error: "func(schemabuilder.Args, *schemabuilder.Object) string: *schemabuilder.Object should be the first argument, or follow context.Context",
+		},
+		{
+			name:  "selection set before args",
+			f:     func(selectionSet *graphql.SelectionSet, args Args) string { return "" },
+			error: "func(*graphql.SelectionSet, schemabuilder.Args) string: *graphql.SelectionSet should be the last argument",
+		},
+		{
+			name:  "two args structs",
+			f:     func(args Args, other Args) string { return "" },
+			error: "func(schemabuilder.Args, schemabuilder.Args) string: takes both schemabuilder.Args and schemabuilder.Args as arguments, but only one args struct is allowed",
+		},
+		{
+			name:  "two results",
+			f:     func() (string, string) { return "", "" },
+			error: "func() (string, string) return values should be [result][, error]",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			schema := NewSchema()
+			schema.Query().FieldFunc("object", func() *Object { return &Object{} })
+			schema.Object("Object", Object{}).FieldFunc("bad", testCase.f)
+			_, err := schema.Build()
+			require.Error(t, err)
+			assert.True(t, strings.HasSuffix(err.Error(), "bad method bad on type schemabuilder.Object: "+testCase.error), err.Error())
+		})
+	}
+}
+
+type commonArgs struct {
+	First int64
+	After *string
+}
+
+func TestEmbeddedArgs(t *testing.T) {
+	type Args struct {
+		commonArgs
+		Filter string
+	}
+	type DuplicateArgs struct {
+		commonArgs
+		First int64
+	}
+
+	schema := NewSchema()
+	query := schema.Query()
+	query.FieldFunc("items", func(args Args) []string {
+		after := ""
+		if args.After != nil {
+			after = *args.After
+		}
+		return []string{args.Filter, after, strings.Repeat("x", int(args.First))}
+	})
+	builtSchema := schema.MustBuild()
+
+	field := builtSchema.Query.(*graphql.Object).Fields["items"]
+	assert.Equal(t, map[string]graphql.Type{
+		"first":  &graphql.NonNull{Type: &graphql.Scalar{Type: "int64"}},
+		"after":  &graphql.Scalar{Type: "string"},
+		"filter": &graphql.NonNull{Type: &graphql.Scalar{Type: "string"}},
+	}, field.Args)
+
+	q := graphql.MustParse(`{ items(first: 2, after: "a", filter: "f") }`, nil)
+	require.NoError(t, graphql.PrepareQuery(context.Background(), builtSchema.Query, q.SelectionSet))
+	e := graphql.NewExecutor(graphql.NewImmediateGoroutineScheduler())
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"items": []interface{}{"f", "a", "xx"},
+	}, val)
+
+	schema = NewSchema()
+	schema.Query().FieldFunc("items", func(args DuplicateArgs) int64 { return args.First })
+	_, err = schema.Build()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "duplicate field first")
+}
+
+type userBy struct {
+	Id    *int64
+	Email *string
+}
+
+func TestOneOfInput(t *testing.T) {
+	schema := NewSchema()
+	schema.OneOfInput(userBy{})
+	schema.Query().FieldFunc("user", func(args struct{ By userBy }) string {
+		if args.By.Id != nil {
+			return "id"
+		}
+		return "email " + *args.By.Email
+	})
+	builtSchema := schema.MustBuild()
+
+	by := builtSchema.Query.(*graphql.Object).Fields["user"].Args["by"].(*graphql.NonNull).Type.(*graphql.InputObject)
+	assert.True(t, by.OneOf)
+
+	run := func(query string) (interface{}, error) {
+		q := graphql.MustParse(query, nil)
+		if err := graphql.PrepareQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+			return nil, err
+		}
+		e := graphql.NewExecutor(graphql.NewImmediateGoroutineScheduler())
+		return e.Execute(context.Background(), builtSchema.Query, nil, q)
+	}
+
+	val, err := run(`{ user(by: {email: "a@b.c"}) }`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"user": "email a@b.c"}, val)
+
+	_, err = run(`{ user(by: {}) }`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exactly one field must be set, got 0")
+
+	_, err = run(`{ user(by: {id: 1, email: "a@b.c"}) }`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exactly one field must be set, got 2")
+
+	type RequiredUserBy struct {
+		Id    int64
+		Email *string
+	}
+	schema = NewSchema()
+	schema.OneOfInput(RequiredUserBy{})
+	schema.Query().FieldFunc("user", func(args struct{ By RequiredUserBy }) string { return "" })
+	_, err = schema.Build()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "field id of oneOf input should be optional")
+
+	assert.Panics(t, func() { NewSchema().OneOfInput(0) })
+}
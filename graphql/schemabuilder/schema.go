@@ -18,6 +18,21 @@ type Schema struct {
 	Name      string
 	objects   map[string]*Object
 	enumTypes map[reflect.Type]*EnumMapping
+	// interfaceUnions are the interface types registered as unions.
+	interfaceUnions map[reflect.Type]*interfaceUnion
+	// inputDeprecations maps input structs to their deprecated fields.
+	inputDeprecations map[reflect.Type]map[string]string
+	// oneOfInputs are the input structs registered as input unions.
+	oneOfInputs map[reflect.Type]bool
+
+	fieldNamer FieldNamer
+}
+
+// interfaceUnion is a union whose values are of an interface type, see
+// InterfaceUnion.
+type interfaceUnion struct {
+	name    string
+	members []reflect.Type
 }
 
 // NewSchema creates a new schema.
@@ -78,6 +93,74 @@ func (s *Schema) Enum(val interface{}, enumMap interface{}) {
 	s.enumTypes[typ] = &EnumMapping{Map: eMap, ReverseMap: rMap}
 }
 
+// DeprecateEnumValue marks the value name of the enum registered with val as
+// deprecated, with an optional reason. Deprecated values can still be used,
+// but are reported as deprecated by introspection.
+//
+// For example, the value "two" of the enum above can be deprecated with:
+//   s.DeprecateEnumValue(enumType(1), "two", "use three instead")
+func (s *Schema) DeprecateEnumValue(val interface{}, name string, reason string) {
+	mapping, ok := s.enumTypes[reflect.TypeOf(val)]
+	if !ok {
+		panic("enum not registered")
+	}
+	if _, ok := mapping.Map[name]; !ok {
+		panic(fmt.Sprintf("unknown enum value %s", name))
+	}
+	if mapping.Deprecations == nil {
+		mapping.Deprecations = make(map[string]string)
+	}
+	mapping.Deprecations[name] = reason
+}
+
+// DeprecateInputField marks the field name of the input struct val, used as
+// an argument or inside of one, as deprecated, with an optional reason.
+// Deprecated fields can still be used, but are reported as deprecated by
+// introspection.
+//
+// For example, a field of an argument struct can be deprecated with:
+//   s.DeprecateInputField(UserFilter{}, "nickname", "use name instead")
+func (s *Schema) DeprecateInputField(val interface{}, name string, reason string) {
+	typ := reflect.TypeOf(val)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		panic("input must be a struct")
+	}
+	if s.inputDeprecations == nil {
+		s.inputDeprecations = make(map[reflect.Type]map[string]string)
+	}
+	if s.inputDeprecations[typ] == nil {
+		s.inputDeprecations[typ] = make(map[string]string)
+	}
+	s.inputDeprecations[typ][name] = reason
+}
+
+// OneOfInput registers the input struct val as an input union, or oneOf input
+// object: exactly one of its fields must be set when it is used as an
+// argument or inside of one, and all of its fields must be optional.
+//
+// For example, a user can be looked up by either id or email with:
+//   type UserBy struct {
+//     Id    *int64
+//     Email *string
+//   }
+//   s.OneOfInput(UserBy{})
+func (s *Schema) OneOfInput(val interface{}) {
+	typ := reflect.TypeOf(val)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		panic("input must be a struct")
+	}
+	if s.oneOfInputs == nil {
+		s.oneOfInputs = make(map[reflect.Type]bool)
+	}
+	s.oneOfInputs[typ] = true
+}
+
 func getEnumMap(enumMap interface{}, typ reflect.Type) (map[string]interface{}, map[interface{}]string) {
 	rMap := make(map[interface{}]string)
 	eMap := make(map[string]interface{})
@@ -157,11 +240,46 @@ func FetchObjectFromKeys(f interface{}, options ...ObjectOption) ObjectOption {
 	return FetchObjectFromKeysField
 }
 
+// InterfaceUnion registers an interface type as a GraphQL union named name,
+// with the types of members as its members. The iface should be a nil pointer
+// to the interface, and members values of the member types, eg.
+//   type Pet interface{ isPet() }
+//   schema.InterfaceUnion("Pet", (*Pet)(nil), &Dog{}, &Cat{})
+// Fields returning a Pet resolve to the member of the concrete type of the
+// value, without a struct embedding schemabuilder.Union. Members are structs
+// or pointers to structs implementing the interface.
+func (s *Schema) InterfaceUnion(name string, iface interface{}, members ...interface{}) {
+	ifaceType := reflect.TypeOf(iface)
+	if ifaceType == nil || ifaceType.Kind() != reflect.Ptr || ifaceType.Elem().Kind() != reflect.Interface {
+		panic("InterfaceUnion should be passed a nil pointer to an interface")
+	}
+	ifaceType = ifaceType.Elem()
+
+	union := &interfaceUnion{name: name}
+	for _, member := range members {
+		memberType := reflect.TypeOf(member)
+		if memberType == nil || !memberType.Implements(ifaceType) {
+			panic(fmt.Sprintf("union %s member %v does not implement %s", name, memberType, ifaceType))
+		}
+		union.members = append(union.members, memberType)
+	}
+
+	if s.interfaceUnions == nil {
+		s.interfaceUnions = make(map[reflect.Type]*interfaceUnion)
+	}
+	s.interfaceUnions[ifaceType] = union
+}
+
 // Object registers a struct as a GraphQL Object in our Schema.
 // (https://facebook.github.io/graphql/June2018/#sec-Objects)
 // We'll read the fields of the struct to determine it's basic "Fields" and
 // we'll return an Object struct that we can use to register custom
 // relationships and fields on the object.
+//
+// The type can also be a named map type with string keys, eg.
+//   type Entity map[string]interface{}
+// Such objects have no fields of their own; expose the values in the map with
+// MapField or FieldFunc.
 func (s *Schema) Object(name string, typ interface{}, options ...ObjectOption) *Object {
 	if object, ok := s.objects[name]; ok {
 		if reflect.TypeOf(object.Type) != reflect.TypeOf(typ) {
@@ -206,11 +324,16 @@ func (s *Schema) Mutation() *Object {
 // other Objects that we can resolve in our GraphQL graph.
 func (s *Schema) Build() (*graphql.Schema, error) {
 	sb := &schemaBuilder{
-		types:        make(map[reflect.Type]graphql.Type),
-		typeNames:    make(map[string]reflect.Type),
-		objects:      make(map[reflect.Type]*Object),
-		enumMappings: s.enumTypes,
-		typeCache:    make(map[reflect.Type]cachedType, 0),
+		types:           make(map[reflect.Type]graphql.Type),
+		typeNames:       make(map[string]reflect.Type),
+		objects:         make(map[reflect.Type]*Object),
+		enumMappings:    s.enumTypes,
+		interfaceUnions: s.interfaceUnions,
+		typeCache:       make(map[reflect.Type]cachedType, 0),
+		fieldNamer:      s.fieldNamer,
+
+		inputDeprecations: s.inputDeprecations,
+		oneOfInputs:       s.oneOfInputs,
 	}
 
 	s.Object("Query", query{})
@@ -218,8 +341,8 @@ func (s *Schema) Build() (*graphql.Schema, error) {
 
 	for _, object := range s.objects {
 		typ := reflect.TypeOf(object.Type)
-		if typ.Kind() != reflect.Struct {
-			return nil, fmt.Errorf("object.Type should be a struct, not %s", typ.String())
+		if typ.Kind() != reflect.Struct && !isMapObjectType(typ) {
+			return nil, fmt.Errorf("object.Type should be a struct or a map with string keys, not %s", typ.String())
 		}
 
 		if _, ok := sb.objects[typ]; ok {
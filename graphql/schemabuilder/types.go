@@ -3,6 +3,7 @@ package schemabuilder
 import (
 	"context"
 	"reflect"
+	"time"
 )
 
 // A Object represents a Go type and set of methods to be converted into an
@@ -13,6 +14,7 @@ type Object struct {
 	Type        interface{}
 	Methods     Methods // Deprecated, use FieldFunc instead.
 	key         string
+	external    []string
 	ServiceName string
 }
 
@@ -50,6 +52,46 @@ var Expensive fieldFuncOptionFunc = func(m *method) {
 	m.Expensive = true
 }
 
+// Idempotent is an option that can be passed to a FieldFunc to indicate that
+// the function is safe to retry. Query fields are idempotent by default.
+var Idempotent fieldFuncOptionFunc = func(m *method) {
+	m.MarkedIdempotent = true
+}
+
+// NonIdempotent is an option that can be passed to a FieldFunc to indicate
+// that the function is not safe to retry. Mutation fields are non-idempotent
+// by default.
+var NonIdempotent fieldFuncOptionFunc = func(m *method) {
+	m.MarkedNonIdempotent = true
+}
+
+// Timeout is an option that can be passed to a FieldFunc to limit how long
+// resolving the field may take. The executor resolves the field with a
+// context that expires after timeout, and resolves nullable fields whose
+// resolvers fail once it expires to null, see graphql.FieldTimeoutError.
+// The timeout is reported by introspection to federation executors, which,
+// when configured with WithFieldTimeouts, fetch the field in a subquery of
+// its own and null it, if possible, once the timeout expires.
+func Timeout(timeout time.Duration) FieldFuncOption {
+	var fieldTimeout fieldFuncOptionFunc = func(m *method) {
+		m.Timeout = timeout
+	}
+	return fieldTimeout
+}
+
+// DeprecatedArg is an option that can be passed to a FieldFunc to mark its
+// argument name as deprecated, with an optional reason. Deprecated arguments
+// can still be used, but are reported as deprecated by introspection.
+func DeprecatedArg(name string, reason string) FieldFuncOption {
+	var deprecatedArg fieldFuncOptionFunc = func(m *method) {
+		if m.ArgDeprecations == nil {
+			m.ArgDeprecations = make(map[string]string)
+		}
+		m.ArgDeprecations[name] = reason
+	}
+	return deprecatedArg
+}
+
 func FilterField(name string, filter interface{}, options ...FieldFuncOption) FieldFuncOption {
 	textFilterMethod := &method{Fn: filter, Batch: false, MarkedNonNullable: true}
 	for _, opt := range options {
@@ -165,8 +207,14 @@ func BatchSortFieldWithFallback(name string, batchSort interface{}, sort interfa
 }
 
 // FieldFunc exposes a field on an object. The function f can take a number of
-// optional arguments:
-// func([ctx context.Context], [o *Type], [args struct {}]) ([Result], [error])
+// optional arguments, in this order, and return an optional result and an
+// optional error:
+// func([ctx context.Context], [o *Type], [args struct {}], [selectionSet *graphql.SelectionSet]) ([Result], [error])
+//
+// The object can be passed by value or by pointer. Any combination of the
+// optional arguments and return values is accepted, and functions with other
+// signatures, eg. taking the context after the object, fail to build with an
+// error describing the problem.
 //
 // For example, for an object of type User, a fullName field might take just an
 // instance of the object:
@@ -182,6 +230,19 @@ func BatchSortFieldWithFallback(name string, batchSort interface{}, sort interfa
 //        userID, err := db.AddUser(ctx, args.FirstName, args.LastName)
 //        return userID, err
 //    })
+//
+// The fields of structs embedded in the args struct become arguments of the
+// field, so that common arguments can be shared between fields:
+//    type PageArgs struct {
+//        First int64
+//        After *string
+//    }
+//    query.FieldFunc("users", func(args struct{
+//        PageArgs
+//        Filter string
+//    }) []*User {
+//        ...
+//    })
 func (s *Object) FieldFunc(name string, f interface{}, options ...FieldFuncOption) {
 	if s.Methods == nil {
 		s.Methods = make(Methods)
@@ -275,10 +336,40 @@ func (s *Object) Key(f string) {
 	s.key = f
 }
 
+// External declares fields of the object, eg. fields of its federated key,
+// that the schema references but that another service owns and resolves.
+// Federation executors never send external fields to the service.
+// For example:
+//   object.External("name")
+func (s *Object) External(fields ...string) {
+	s.external = append(s.external, fields...)
+}
+
+// FederationKey registers a function that computes the federated key of an
+// object registered with FetchObjectFromKeys. The function takes a pointer to
+// the object and returns a pointer to the key object along with either a bool
+// or an error, eg.
+//   func(user *User) (*User, bool)
+//   func(user *User) (*User, error)
+// If the function returns false or a nil key, the object is not dispatched to
+// other services, and its federated fields resolve to null, or the object
+// itself if one of them is non-null.
+func (s *Object) FederationKey(f interface{}) {
+	m, ok := s.Methods[federationField]
+	if !ok || m.RootObjectType == nil {
+		panic("FederationKey requires the object to be registered with FetchObjectFromKeys")
+	}
+	m.KeyFunc = f
+}
+
 type method struct {
 	MarkedNonNullable bool
 	Fn                interface{}
 
+	// Whether or not the FieldFunc has been marked as safe or unsafe to retry.
+	MarkedIdempotent    bool
+	MarkedNonIdempotent bool
+
 	// Whether or not the FieldFunc is paginated.
 	Paginated bool
 
@@ -296,6 +387,21 @@ type method struct {
 	// Whether the FieldFunc is a batchField
 	Batch bool
 
+	// How long the results of the FieldFunc are cached for, if at all.
+	CacheTTL time.Duration
+	// How many results of the FieldFunc are cached at most, if cached.
+	CacheMaxEntries int
+	// CacheKey returns the part of the cache key of the FieldFunc's results
+	// that depends on the request, if any.
+	CacheKey func(ctx context.Context) (interface{}, error)
+
+	// How long resolving the FieldFunc may take, if limited.
+	Timeout time.Duration
+
+	// ArgDeprecations maps the deprecated arguments of the FieldFunc to the
+	// reasons they are deprecated.
+	ArgDeprecations map[string]string
+
 	BatchArgs batchArgs
 
 	ManualPaginationArgs manualPaginationArgs
@@ -304,12 +410,27 @@ type method struct {
 	// that can be exposed over federation
 	RootObjectType reflect.Type
 
+	// KeyFunc optionally computes the federated key of a root object.
+	KeyFunc interface{}
+
 	// ShadowObjectType is the reflect type of parent object if it
 	// is a shadow object. A shadow object's fields are each of the
 	// field that are sent as args to a federated sunquery.
 	ShadowObjectType reflect.Type
 }
 
+// idempotent returns whether the method is safe to retry. Unless marked
+// otherwise, mutations are not safe to retry and all other fields are.
+func (m *method) idempotent(isMutation bool) bool {
+	if m.MarkedIdempotent {
+		return true
+	}
+	if m.MarkedNonIdempotent {
+		return false
+	}
+	return !isMutation
+}
+
 type concurrencyArgs struct {
 	numParallelInvocationsFunc NumParallelInvocationsFunc
 }
@@ -353,3 +474,29 @@ type Methods map[string]*method
 type Union struct{}
 
 var unionType = reflect.TypeOf(Union{})
+
+// Error is an object for returning errors as data, as a member of a union
+// with the result of a field, for clients that prefer handling errors in
+// the response over failing the whole query.
+//
+// For example, a field that may fail to load a vehicle might return:
+//   type VehicleResult struct {
+//     schemabuilder.Union
+//     *Vehicle
+//     *schemabuilder.Error
+//   }
+//
+// which clients can query with
+//   vehicle { ... on Vehicle { name } ... on Error { message } }
+//
+// Since every service uses the same Error object, federated fields can
+// return errors as data from any service.
+type Error struct {
+	Message string
+}
+
+// NewError returns an Error with the message of err, to return as the error
+// branch of a union.
+func NewError(err error) *Error {
+	return &Error{Message: err.Error()}
+}
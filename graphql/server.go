@@ -49,6 +49,21 @@ type SubscriptionLogger interface {
 	Unsubscribe(ctx context.Context, id string)
 }
 
+// errorTags returns the tags to log an execution error with. Errors of
+// resolvers that panicked are tagged with panic=true.
+func errorTags(err error, tags map[string]string) map[string]string {
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		return tags
+	}
+	panicTags := make(map[string]string, len(tags)+1)
+	for k, v := range tags {
+		panicTags[k] = v
+	}
+	panicTags["panic"] = "true"
+	return panicTags
+}
+
 type conn struct {
 	writeMu sync.Mutex
 	socket  JSONSocket
@@ -88,16 +103,34 @@ type outEnvelope struct {
 	Type     string                 `json:"type"`
 	Message  interface{}            `json:"message,omitempty"`
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// Locations are the locations in the query of the error of an error
+	// message, see ErrorLocations.
+	Locations []Location `json:"locations,omitempty"`
+	// Errors are the sanitized errors of the fields of an update or result
+	// that resolved to null, see FieldErrors.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// sanitizeFieldErrors returns the sanitized messages of the field errors
+// recorded in ctx.
+func sanitizeFieldErrors(ctx context.Context) []string {
+	var messages []string
+	for _, err := range FieldErrors(ctx) {
+		messages = append(messages, SanitizeError(err))
+	}
+	return messages
 }
 
 type subscribeMessage struct {
-	Query     string                 `json:"query"`
-	Variables map[string]interface{} `json:"variables"`
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
 }
 
 type mutateMessage struct {
-	Query     string                 `json:"query"`
-	Variables map[string]interface{} `json:"variables"`
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
 }
 
 func (c *conn) writeOrClose(out outEnvelope) {
@@ -140,7 +173,7 @@ func (c *conn) handleSubscribe(in *inEnvelope) error {
 
 	tags := map[string]string{"url": c.url, "query": subscribe.Query, "queryVariables": mustMarshalJson(subscribe.Variables), "id": id}
 
-	query, err := Parse(subscribe.Query, subscribe.Variables)
+	query, err := ParseOperation(subscribe.Query, subscribe.Variables, subscribe.OperationName)
 	if query != nil {
 		tags["queryType"] = query.Kind
 		tags["queryName"] = query.Name
@@ -163,6 +196,7 @@ func (c *conn) handleSubscribe(in *inEnvelope) error {
 	c.subscriptions[id] = reactive.NewRerunner(c.ctx, func(ctx context.Context) (interface{}, error) {
 		ctx = c.makeCtx(ctx)
 		ctx = batch.WithBatching(ctx)
+		ctx = WithFieldErrors(ctx)
 
 		start := time.Now()
 
@@ -208,28 +242,30 @@ func (c *conn) handleSubscribe(in *inEnvelope) error {
 					for k, v := range tags {
 						extraTags[k] = v
 					}
-					c.logger.Error(ctx, err, extraTags)
+					c.logger.Error(ctx, err, errorTags(err, extraTags))
 				}
 
 				return nil, reactive.RetrySentinelError
 			}
 
 			c.writeOrClose(outEnvelope{
-				ID:       id,
-				Type:     "error",
-				Message:  SanitizeError(err),
-				Metadata: output.Metadata,
+				ID:        id,
+				Type:      "error",
+				Message:   SanitizeError(err),
+				Metadata:  output.Metadata,
+				Locations: ErrorLocations(err),
 			})
 			go c.closeSubscription(id)
 
 			if _, ok := err.(SanitizedError); !ok {
-				c.logger.Error(ctx, err, tags)
+				c.logger.Error(ctx, err, errorTags(err, tags))
 			}
 			return nil, err
 		}
 
 		d := diff.Diff(computationInput.Previous, current)
 		previous = current
+		fieldErrors := sanitizeFieldErrors(ctx)
 
 		if d != nil {
 			c.writeOrClose(outEnvelope{
@@ -237,14 +273,17 @@ func (c *conn) handleSubscribe(in *inEnvelope) error {
 				Type:     "update",
 				Message:  d,
 				Metadata: output.Metadata,
+				Errors:   fieldErrors,
 			})
-		} else if initial {
+		} else if initial || len(fieldErrors) > 0 {
 			// When a client first subscribes, they expect a response with the new diff (even if the diff is unchanged).
+			// Field errors are sent with an empty diff too, as the fields stay null.
 			c.writeOrClose(outEnvelope{
 				ID:       id,
 				Type:     "update",
 				Message:  struct{}{}, // This is an empty diff for any message, rather than nil which means the new message is empty.
 				Metadata: output.Metadata,
+				Errors:   fieldErrors,
 			})
 		}
 
@@ -268,7 +307,7 @@ func (c *conn) handleMutate(in *inEnvelope) error {
 
 	tags := map[string]string{"url": c.url, "query": mutate.Query, "queryVariables": mustMarshalJson(mutate.Variables), "id": id}
 
-	query, err := Parse(mutate.Query, mutate.Variables)
+	query, err := ParseOperation(mutate.Query, mutate.Variables, mutate.OperationName)
 	if query != nil {
 		tags["queryType"] = query.Kind
 		tags["queryName"] = query.Name
@@ -291,6 +330,7 @@ func (c *conn) handleMutate(in *inEnvelope) error {
 
 		ctx = c.makeCtx(ctx)
 		ctx = batch.WithBatching(ctx)
+		ctx = WithFieldErrors(ctx)
 
 		start := time.Now()
 		c.logger.StartExecution(ctx, tags, true)
@@ -321,10 +361,11 @@ func (c *conn) handleMutate(in *inEnvelope) error {
 
 		if err != nil {
 			c.writeOrClose(outEnvelope{
-				ID:       id,
-				Type:     "error",
-				Message:  SanitizeError(err),
-				Metadata: output.Metadata,
+				ID:        id,
+				Type:      "error",
+				Message:   SanitizeError(err),
+				Metadata:  output.Metadata,
+				Locations: ErrorLocations(err),
 			})
 
 			go c.closeSubscription(id)
@@ -334,7 +375,7 @@ func (c *conn) handleMutate(in *inEnvelope) error {
 			}
 
 			if _, ok := err.(SanitizedError); !ok {
-				c.logger.Error(ctx, err, tags)
+				c.logger.Error(ctx, err, errorTags(err, tags))
 			}
 			return nil, err
 		}
@@ -344,6 +385,7 @@ func (c *conn) handleMutate(in *inEnvelope) error {
 			Type:     "result",
 			Message:  diff.Diff(nil, current),
 			Metadata: output.Metadata,
+			Errors:   sanitizeFieldErrors(ctx),
 		})
 
 		go c.rerunSubscriptionsImmediately()
@@ -521,6 +563,14 @@ func WithExecutor(executor ExecutorRunner) ConnectionOption {
 	}
 }
 
+// WithScheduler makes the connection execute queries and mutations with
+// scheduler, eg. NewSequentialScheduler() to debug resolvers.
+func WithScheduler(scheduler WorkScheduler) ConnectionOption {
+	return func(c *conn) {
+		c.executor = NewExecutor(scheduler)
+	}
+}
+
 func WithExecutionLogger(logger GraphqlLogger) ConnectionOption {
 	return func(c *conn) {
 		c.logger = logger
@@ -585,10 +635,11 @@ func (c *conn) ServeJSONSocket() {
 		if err := c.handle(&envelope); err != nil {
 			log.Println("c.handle:", err)
 			c.writeOrClose(outEnvelope{
-				ID:       envelope.ID,
-				Type:     "error",
-				Message:  SanitizeError(err),
-				Metadata: nil,
+				ID:        envelope.ID,
+				Type:      "error",
+				Message:   SanitizeError(err),
+				Metadata:  nil,
+				Locations: ErrorLocations(err),
 			})
 		}
 	}
@@ -3,6 +3,7 @@ package graphql
 import (
 	"context"
 	"fmt"
+	"time"
 )
 
 // Type represents a GraphQL type, and should be either an Object, a Scalar,
@@ -33,6 +34,9 @@ type Enum struct {
 	Type       string
 	Values     []string
 	ReverseMap map[interface{}]string
+	// Deprecations maps deprecated values to the reasons they are
+	// deprecated, which may be empty.
+	Deprecations map[string]string
 }
 
 func (e *Enum) isType() {}
@@ -59,11 +63,18 @@ func (o *Object) String() string {
 	return o.Name
 }
 
-// List is a collection of other values
+// List is a collection of other values. A list resolves slices, and maps with
+// string keys as a list of their MapEntry sorted by key, or null for nil maps.
 type List struct {
 	Type Type
 }
 
+// MapEntry is an entry of a map resolved as a List.
+type MapEntry struct {
+	Key   string
+	Value interface{}
+}
+
 func (l *List) isType() {}
 
 func (l *List) String() string {
@@ -73,6 +84,12 @@ func (l *List) String() string {
 type InputObject struct {
 	Name        string
 	InputFields map[string]Type
+	// Deprecations maps deprecated input fields to the reasons they are
+	// deprecated, which may be empty.
+	Deprecations map[string]string
+	// OneOf marks an input union: exactly one of its input fields must be
+	// set, and all of them are nullable.
+	OneOf bool
 }
 
 func (io *InputObject) isType() {}
@@ -97,6 +114,11 @@ type Union struct {
 	Name        string
 	Description string
 	Types       map[string]*Object
+	// MemberOf returns the name of the member type of a union value and the
+	// value of that member, or "" for a null value. If nil, union values are
+	// structs with a pointer field for every member, named after the member
+	// type, of which at most one is set.
+	MemberOf func(value interface{}) (string, interface{}, error)
 }
 
 func (*Union) isType() {}
@@ -142,6 +164,21 @@ type Field struct {
 
 	// FederatedKey tells us which services need this field as federated key.
 	FederatedKey map[string]bool
+
+	// Idempotent indicates that resolving the field has no side effects, so
+	// requests selecting it are safe to retry.
+	Idempotent bool
+
+	// ArgDeprecations maps deprecated arguments to the reasons they are
+	// deprecated, which may be empty.
+	ArgDeprecations map[string]string
+
+	// Timeout, if positive, is how long resolving the field may take.
+	Timeout time.Duration
+
+	// FederationExternal indicates that another service owns and resolves
+	// the field, see schemabuilder.Object.External.
+	FederationExternal bool
 }
 
 type Schema struct {
@@ -200,6 +237,16 @@ type Selection struct {
 
 	// ParentType is the type that this field hangs off of.
 	ParentType string
+
+	// Location is the location of the selection in the source of the query,
+	// if it was parsed.
+	Location *Location `json:"-"`
+}
+
+// Location is a location in the source of a query.
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
 }
 
 // A Fragment represents a reusable part of a GraphQL query
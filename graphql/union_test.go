@@ -84,6 +84,129 @@ func TestUnionType(t *testing.T) {
 	}
 }
 
+// TestUnionTypenameOnly tests that a union's __typename can be resolved
+// without selecting any fields on its members.
+func TestUnionTypenameOnly(t *testing.T) {
+	type Vehicle struct {
+		Name string
+	}
+	type Asset struct {
+		Name string
+	}
+
+	type Gateway struct {
+		schemabuilder.Union
+
+		*Vehicle
+		*Asset
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("gateways", func() []*Gateway {
+		return []*Gateway{
+			{Vehicle: &Vehicle{Name: "a"}},
+			{Asset: &Asset{Name: "b"}},
+		}
+	})
+
+	builtSchema := schema.MustBuild()
+
+	ctx := context.Background()
+
+	for _, queryString := range []string{
+		`{ gateways { __typename } }`,
+		`{ gateways { __typename ... on Vehicle { __typename } } }`,
+	} {
+		q := graphql.MustParse(queryString, nil)
+
+		if err := graphql.PrepareQuery(ctx, builtSchema.Query, q.SelectionSet); err != nil {
+			t.Error(err)
+		}
+
+		e := testgraphql.NewExecutorWrapper(t)
+
+		result, err := e.Execute(ctx, builtSchema.Query, nil, q)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if d := pretty.Compare(internal.AsJSON(result), internal.ParseJSON(`
+			{"gateways": [{"__typename": "Vehicle"}, {"__typename": "Asset"}]}`)); d != "" {
+			t.Errorf("expected did not match result for %s: %s", queryString, d)
+		}
+	}
+}
+
+// TestUnionCommonFields tests that fields that all members of a union have can
+// be selected on the union itself, and are resolved by each member.
+func TestUnionCommonFields(t *testing.T) {
+	type Vehicle struct {
+		Name  string
+		Speed int64
+	}
+	type Asset struct {
+		Name string
+	}
+
+	type Gateway struct {
+		schemabuilder.Union
+
+		*Vehicle
+		*Asset
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("gateways", func() []*Gateway {
+		return []*Gateway{
+			{Vehicle: &Vehicle{Name: "a", Speed: 50}},
+			{Asset: &Asset{Name: "b"}},
+		}
+	})
+	schema.Object("Vehicle", Vehicle{}).FieldFunc("label", func(v *Vehicle, args struct{ Prefix string }) string {
+		return args.Prefix + "vehicle " + v.Name
+	})
+	schema.Object("Asset", Asset{}).FieldFunc("label", func(a *Asset, args struct{ Prefix string }) string {
+		return args.Prefix + "asset " + a.Name
+	})
+
+	builtSchema := schema.MustBuild()
+
+	ctx := context.Background()
+
+	q := graphql.MustParse(`{
+		gateways {
+			__typename
+			name
+			label(prefix: "> ")
+			... on Vehicle { speed }
+		}
+	}`, nil)
+	if err := graphql.PrepareQuery(ctx, builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	e := testgraphql.NewExecutorWrapper(t)
+	result, err := e.Execute(ctx, builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := pretty.Compare(internal.AsJSON(result), internal.ParseJSON(`{
+		"gateways": [
+			{"__typename": "Vehicle", "name": "a", "label": "> vehicle a", "speed": 50},
+			{"__typename": "Asset", "name": "b", "label": "> asset b"}
+		]
+	}`)); d != "" {
+		t.Errorf("expected did not match result: %s", d)
+	}
+
+	// Fields that only some members have must be selected in fragments.
+	q = graphql.MustParse(`{ gateways { speed } }`, nil)
+	if err := graphql.PrepareQuery(ctx, builtSchema.Query, q.SelectionSet); err == nil || err.Error() != `unknown field "speed"` {
+		t.Errorf("expected an unknown field error, got %v", err)
+	}
+}
+
 type UnionPart1 struct{ OtherThing string }
 type UnionPart2 struct{ Thing string }
 
@@ -302,3 +425,87 @@ func TestUnionStruct(t *testing.T) {
 		t.Errorf("expected did not match result: %s", d)
 	}
 }
+
+type Pet interface {
+	isPet()
+}
+
+type PetDog struct {
+	Name  string
+	Barks bool
+}
+
+func (*PetDog) isPet() {}
+
+type PetCat struct {
+	Name  string
+	Lives int64
+}
+
+func (PetCat) isPet() {}
+
+func TestInterfaceUnion(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Object("Dog", PetDog{})
+	schema.Object("Cat", PetCat{})
+	schema.InterfaceUnion("Pet", (*Pet)(nil), &PetDog{}, PetCat{})
+	query := schema.Query()
+	query.FieldFunc("pets", func() []Pet {
+		return []Pet{&PetDog{Name: "rex", Barks: true}, PetCat{Name: "tom", Lives: 9}}
+	})
+	query.FieldFunc("favorite", func() Pet {
+		return PetCat{Name: "felix", Lives: 7}
+	})
+	query.FieldFunc("none", func() Pet {
+		return nil
+	})
+	query.FieldFunc("stray", func() Pet {
+		return &PetCat{Name: "stray"}
+	})
+	builtSchema := schema.MustBuild()
+
+	ctx := context.Background()
+	q := graphql.MustParse(`{
+		pets { __typename ... on Dog { name barks } ... on Cat { name lives } }
+		favorite { ... on Cat { name } }
+		none { __typename }
+	}`, nil)
+	if err := graphql.PrepareQuery(ctx, builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	e := testgraphql.NewExecutorWrapper(t)
+	result, err := e.Execute(ctx, builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := pretty.Compare(internal.AsJSON(result), internal.ParseJSON(`{
+		"pets": [
+			{"__typename": "Dog", "name": "rex", "barks": true},
+			{"__typename": "Cat", "name": "tom", "lives": 9}
+		],
+		"favorite": {"name": "felix"},
+		"none": null
+	}`)); d != "" {
+		t.Errorf("expected did not match result: %s", d)
+	}
+
+	// Values of types that aren't members of the union fail.
+	q = graphql.MustParse(`{ stray { __typename } }`, nil)
+	if err := graphql.PrepareQuery(ctx, builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	_, err = graphql.NewExecutor(graphql.NewImmediateGoroutineScheduler()).Execute(ctx, builtSchema.Query, nil, q)
+	if err == nil || !strings.Contains(err.Error(), "*graphql_test.PetCat is not a member of union Pet") {
+		t.Errorf("expected a member error, got %v", err)
+	}
+}
+
+func TestBadInterfaceUnion(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected InterfaceUnion to panic for a member not implementing the interface")
+		}
+	}()
+	schema.InterfaceUnion("Pet", (*Pet)(nil), &PetDog{}, UnionPart1{})
+}
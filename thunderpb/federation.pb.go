@@ -23,6 +23,8 @@ type Selection struct {
 	Alias        string        `protobuf:"bytes,2,opt,name=alias,proto3" json:"alias,omitempty"`
 	SelectionSet *SelectionSet `protobuf:"bytes,3,opt,name=selection_set,json=selectionSet" json:"selection_set,omitempty"`
 	Arguments    []byte        `protobuf:"bytes,4,opt,name=arguments,proto3" json:"arguments,omitempty"`
+	Line         int32         `protobuf:"varint,5,opt,name=line,proto3" json:"line,omitempty"`
+	Column       int32         `protobuf:"varint,6,opt,name=column,proto3" json:"column,omitempty"`
 }
 
 func (m *Selection) Reset()                    { *m = Selection{} }
@@ -58,6 +60,20 @@ func (m *Selection) GetArguments() []byte {
 	return nil
 }
 
+func (m *Selection) GetLine() int32 {
+	if m != nil {
+		return m.Line
+	}
+	return 0
+}
+
+func (m *Selection) GetColumn() int32 {
+	if m != nil {
+		return m.Column
+	}
+	return 0
+}
+
 type Fragment struct {
 	On           string        `protobuf:"bytes,1,opt,name=on,proto3" json:"on,omitempty"`
 	SelectionSet *SelectionSet `protobuf:"bytes,2,opt,name=selection_set,json=selectionSet" json:"selection_set,omitempty"`
@@ -155,7 +171,8 @@ func (m *ExecuteRequest) GetQuery() *Query {
 }
 
 type ExecuteResponse struct {
-	Result []byte `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	Result     []byte `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	Extensions []byte `protobuf:"bytes,2,opt,name=extensions,proto3" json:"extensions,omitempty"`
 }
 
 func (m *ExecuteResponse) Reset()                    { *m = ExecuteResponse{} }
@@ -170,6 +187,13 @@ func (m *ExecuteResponse) GetResult() []byte {
 	return nil
 }
 
+func (m *ExecuteResponse) GetExtensions() []byte {
+	if m != nil {
+		return m.Extensions
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Selection)(nil), "thunderpb.Selection")
 	proto.RegisterType((*Fragment)(nil), "thunderpb.Fragment")
@@ -294,6 +318,16 @@ func (m *Selection) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintFederation(dAtA, i, uint64(len(m.Arguments)))
 		i += copy(dAtA[i:], m.Arguments)
 	}
+	if m.Line != 0 {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintFederation(dAtA, i, uint64(m.Line))
+	}
+	if m.Column != 0 {
+		dAtA[i] = 0x30
+		i++
+		i = encodeVarintFederation(dAtA, i, uint64(m.Column))
+	}
 	return i, nil
 }
 
@@ -462,6 +496,12 @@ func (m *ExecuteResponse) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintFederation(dAtA, i, uint64(len(m.Result)))
 		i += copy(dAtA[i:], m.Result)
 	}
+	if len(m.Extensions) > 0 {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintFederation(dAtA, i, uint64(len(m.Extensions)))
+		i += copy(dAtA[i:], m.Extensions)
+	}
 	return i, nil
 }
 
@@ -493,6 +533,12 @@ func (m *Selection) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovFederation(uint64(l))
 	}
+	if m.Line != 0 {
+		n += 1 + sovFederation(uint64(m.Line))
+	}
+	if m.Column != 0 {
+		n += 1 + sovFederation(uint64(m.Column))
+	}
 	return n
 }
 
@@ -563,6 +609,10 @@ func (m *ExecuteResponse) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovFederation(uint64(l))
 	}
+	l = len(m.Extensions)
+	if l > 0 {
+		n += 1 + l + sovFederation(uint64(l))
+	}
 	return n
 }
 
@@ -730,6 +780,44 @@ func (m *Selection) Unmarshal(dAtA []byte) error {
 				m.Arguments = []byte{}
 			}
 			iNdEx = postIndex
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Line", wireType)
+			}
+			m.Line = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowFederation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Line |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Column", wireType)
+			}
+			m.Column = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowFederation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Column |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipFederation(dAtA[iNdEx:])
@@ -1259,6 +1347,37 @@ func (m *ExecuteResponse) Unmarshal(dAtA []byte) error {
 				m.Result = []byte{}
 			}
 			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Extensions", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowFederation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthFederation
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Extensions = append(m.Extensions[:0], dAtA[iNdEx:postIndex]...)
+			if m.Extensions == nil {
+				m.Extensions = []byte{}
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipFederation(dAtA[iNdEx:])
@@ -1388,30 +1507,33 @@ var (
 func init() { proto.RegisterFile("federation.proto", fileDescriptorFederation) }
 
 var fileDescriptorFederation = []byte{
-	// 399 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x52, 0xcd, 0x6e, 0xd3, 0x40,
-	0x10, 0x66, 0xdd, 0xa6, 0xd4, 0x93, 0x50, 0xaa, 0xa5, 0x02, 0x13, 0xa1, 0xc8, 0xf2, 0xa1, 0x32,
-	0x07, 0x6c, 0x61, 0x7a, 0xe0, 0xc0, 0xa9, 0x12, 0x1c, 0x91, 0xd8, 0x5e, 0x10, 0x17, 0xb4, 0x4e,
-	0x26, 0x8e, 0x45, 0xbc, 0x9b, 0xec, 0x8f, 0x80, 0xc7, 0xe0, 0xad, 0x38, 0xf2, 0x08, 0x28, 0x4f,
-	0x82, 0xbc, 0x76, 0xdc, 0x45, 0xe4, 0x82, 0x7a, 0x9b, 0x6f, 0x67, 0xbe, 0x1f, 0x7d, 0x5a, 0x38,
-	0x5f, 0xe2, 0x02, 0x15, 0x37, 0xb5, 0x14, 0xd9, 0x46, 0x49, 0x23, 0x69, 0x68, 0x56, 0x56, 0x2c,
-	0x50, 0x6d, 0xca, 0xe9, 0x8b, 0xaa, 0x36, 0x2b, 0x5b, 0x66, 0x73, 0xd9, 0xe4, 0x95, 0xac, 0x64,
-	0xee, 0x2e, 0x4a, 0xbb, 0x74, 0xc8, 0x01, 0x37, 0x75, 0xcc, 0xe4, 0x07, 0x81, 0xf0, 0x06, 0xd7,
-	0x38, 0x6f, 0xd5, 0x28, 0x85, 0x63, 0xc1, 0x1b, 0x8c, 0x48, 0x4c, 0xd2, 0x90, 0xb9, 0x99, 0x5e,
-	0xc0, 0x88, 0xaf, 0x6b, 0xae, 0xa3, 0xc0, 0x3d, 0x76, 0x80, 0xbe, 0x81, 0x07, 0x7a, 0x4f, 0xfb,
-	0xac, 0xd1, 0x44, 0x47, 0x31, 0x49, 0xc7, 0xc5, 0x93, 0x6c, 0x48, 0x92, 0x0d, 0xb2, 0x37, 0x68,
-	0xd8, 0x44, 0x7b, 0x88, 0x3e, 0x83, 0x90, 0xab, 0xca, 0x36, 0x28, 0x8c, 0x8e, 0x8e, 0x63, 0x92,
-	0x4e, 0xd8, 0xed, 0x43, 0xf2, 0x11, 0x4e, 0xdf, 0x29, 0x5e, 0xb5, 0x80, 0x9e, 0x41, 0x20, 0x45,
-	0x9f, 0x27, 0x90, 0xe2, 0x5f, 0xdf, 0xe0, 0x3f, 0x7c, 0x93, 0xaf, 0x30, 0xf1, 0xb7, 0xf4, 0x0a,
-	0x60, 0xd8, 0xeb, 0x88, 0xc4, 0x47, 0xe9, 0xb8, 0xb8, 0x38, 0x24, 0xc5, 0xbc, 0x3b, 0xfa, 0x12,
-	0xc2, 0x65, 0x9f, 0xaf, 0x6d, 0xa5, 0x25, 0x3d, 0xf2, 0x48, 0xfb, 0xec, 0xec, 0xf6, 0x2a, 0x69,
-	0x60, 0xf4, 0xc1, 0xa2, 0xfa, 0xde, 0x36, 0xfc, 0xa5, 0x16, 0x8b, 0x7d, 0xc3, 0xed, 0x3c, 0xb4,
-	0x1e, 0x78, 0xad, 0xdf, 0xa9, 0xdf, 0xe4, 0x35, 0x9c, 0xbd, 0xfd, 0x86, 0x73, 0x6b, 0x90, 0xe1,
-	0xd6, 0xa2, 0x36, 0xf4, 0x12, 0x46, 0xdb, 0x36, 0x80, 0x33, 0x1e, 0x17, 0xe7, 0x9e, 0x8e, 0x0b,
-	0xc6, 0xba, 0x75, 0xf2, 0x1c, 0x1e, 0x0e, 0x4c, 0xbd, 0x91, 0x42, 0x23, 0x7d, 0x0c, 0x27, 0x0a,
-	0xb5, 0x5d, 0x1b, 0xc7, 0x9d, 0xb0, 0x1e, 0x15, 0xef, 0xe1, 0xb4, 0x3b, 0x95, 0x8a, 0x5e, 0xc3,
-	0xfd, 0x9e, 0x46, 0x9f, 0x7a, 0xd2, 0x7f, 0x87, 0x98, 0x4e, 0x0f, 0xad, 0x3a, 0x97, 0xe4, 0xde,
-	0xf5, 0xd5, 0xcf, 0xdd, 0x8c, 0xfc, 0xda, 0xcd, 0xc8, 0xef, 0xdd, 0x8c, 0x7c, 0xba, 0xf4, 0xfe,
-	0xb1, 0xe6, 0x8d, 0xe6, 0x8a, 0xaf, 0xb6, 0x79, 0xcf, 0xcf, 0x07, 0x9d, 0xf2, 0xc4, 0xfd, 0xe3,
-	0x57, 0x7f, 0x02, 0x00, 0x00, 0xff, 0xff, 0xaf, 0x5d, 0x70, 0x78, 0x15, 0x03, 0x00, 0x00,
+	// 433 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x93, 0xcf, 0x8e, 0xd3, 0x30,
+	0x10, 0xc6, 0x71, 0x76, 0x53, 0x36, 0xd3, 0xb2, 0xac, 0xcc, 0x0a, 0x42, 0x85, 0xaa, 0x28, 0x87,
+	0x55, 0x2e, 0xb4, 0xa2, 0xec, 0x81, 0x03, 0xa7, 0x95, 0x40, 0xe2, 0x82, 0x84, 0xf7, 0x82, 0xb8,
+	0x20, 0xb7, 0x9d, 0xa6, 0x11, 0x89, 0xdd, 0xfa, 0x8f, 0x58, 0x1e, 0x8e, 0x3b, 0x47, 0x1e, 0x01,
+	0xf5, 0x49, 0x90, 0x9d, 0x34, 0x6b, 0xc4, 0x5e, 0x10, 0xb7, 0xf9, 0x66, 0xfc, 0x7d, 0xfe, 0xc9,
+	0x23, 0xc3, 0xd9, 0x1a, 0x57, 0xa8, 0xb8, 0xa9, 0xa4, 0x98, 0x6e, 0x95, 0x34, 0x92, 0x26, 0x66,
+	0x63, 0xc5, 0x0a, 0xd5, 0x76, 0x31, 0x7e, 0x5e, 0x56, 0x66, 0x63, 0x17, 0xd3, 0xa5, 0x6c, 0x66,
+	0xa5, 0x2c, 0xe5, 0xcc, 0x9f, 0x58, 0xd8, 0xb5, 0x57, 0x5e, 0xf8, 0xaa, 0x75, 0xe6, 0xdf, 0x09,
+	0x24, 0xd7, 0x58, 0xe3, 0xd2, 0xa5, 0x51, 0x0a, 0xc7, 0x82, 0x37, 0x98, 0x92, 0x8c, 0x14, 0x09,
+	0xf3, 0x35, 0x3d, 0x87, 0x98, 0xd7, 0x15, 0xd7, 0x69, 0xe4, 0x9b, 0xad, 0xa0, 0xaf, 0xe1, 0x81,
+	0x3e, 0xd8, 0x3e, 0x6b, 0x34, 0xe9, 0x51, 0x46, 0x8a, 0xe1, 0xfc, 0xc9, 0xb4, 0x27, 0x99, 0xf6,
+	0xb1, 0xd7, 0x68, 0xd8, 0x48, 0x07, 0x8a, 0x3e, 0x83, 0x84, 0xab, 0xd2, 0x36, 0x28, 0x8c, 0x4e,
+	0x8f, 0x33, 0x52, 0x8c, 0xd8, 0x6d, 0xc3, 0x51, 0xd4, 0x95, 0xc0, 0x34, 0xce, 0x48, 0x11, 0x33,
+	0x5f, 0xd3, 0xc7, 0x30, 0x58, 0xca, 0xda, 0x36, 0x22, 0x1d, 0xf8, 0x6e, 0xa7, 0xf2, 0x8f, 0x70,
+	0xf2, 0x56, 0xf1, 0xd2, 0x19, 0xe9, 0x29, 0x44, 0x52, 0x74, 0xec, 0x91, 0x14, 0x7f, 0x33, 0x46,
+	0xff, 0xc0, 0x98, 0x7f, 0x85, 0x51, 0x38, 0xa5, 0x97, 0x00, 0xfd, 0x5c, 0xa7, 0x24, 0x3b, 0x2a,
+	0x86, 0xf3, 0xf3, 0xbb, 0xa2, 0x58, 0x70, 0x8e, 0xbe, 0x80, 0x64, 0xdd, 0xf1, 0xb9, 0x17, 0x74,
+	0xa6, 0x47, 0x81, 0xe9, 0xc0, 0xce, 0x6e, 0x4f, 0xe5, 0x0d, 0xc4, 0x1f, 0x2c, 0xaa, 0x6f, 0xee,
+	0x1d, 0xbe, 0x54, 0x62, 0x75, 0xd8, 0x86, 0xab, 0xfb, 0x0d, 0x45, 0xc1, 0x86, 0xfe, 0x6b, 0x17,
+	0xf9, 0x2b, 0x38, 0x7d, 0x73, 0x83, 0x4b, 0x6b, 0x90, 0xe1, 0xce, 0xa2, 0x36, 0xf4, 0x02, 0xe2,
+	0x9d, 0x03, 0xf0, 0x17, 0x0f, 0xe7, 0x67, 0x41, 0x8e, 0x07, 0x63, 0xed, 0x38, 0x7f, 0x07, 0x0f,
+	0x7b, 0xa7, 0xde, 0x4a, 0xa1, 0xfd, 0x9a, 0x14, 0x6a, 0x5b, 0x1b, 0xef, 0x1d, 0xb1, 0x4e, 0xd1,
+	0x09, 0x00, 0xde, 0x18, 0x14, 0xda, 0x3f, 0x5e, 0xe4, 0x67, 0x41, 0x67, 0xfe, 0x1e, 0x4e, 0xda,
+	0x28, 0xa9, 0xe8, 0x15, 0xdc, 0x6f, 0x6b, 0xa4, 0x4f, 0x83, 0xab, 0xff, 0x84, 0x1c, 0x8f, 0xef,
+	0x1a, 0xb5, 0x14, 0xf9, 0xbd, 0xab, 0xcb, 0x1f, 0xfb, 0x09, 0xf9, 0xb9, 0x9f, 0x90, 0x5f, 0xfb,
+	0x09, 0xf9, 0x74, 0x11, 0xfc, 0x09, 0xcd, 0x1b, 0xcd, 0x15, 0xdf, 0xec, 0x66, 0x9d, 0x7f, 0xd6,
+	0xe7, 0x2c, 0x06, 0xfe, 0x4f, 0xbc, 0xfc, 0x3d, 0x00, 0xc2, 0x63, 0x7e, 0xd5, 0x61, 0x03, 0x00,
+	0x00,
 }